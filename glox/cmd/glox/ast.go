@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Run the "ast" subcommand with the given arguments and return the exit status.
+// The syntax tree of the file is printed in a parenthesized form, as JSON with
+// "-json", or as a Graphviz graph with "-dot". The file is only parsed, so
+// the tree is printed even if the file has resolution errors, or syntax errors
+// with "-partial", which puts error nodes where it couldn't be parsed.
+func runAST(args []string) int {
+	flags := flag.NewFlagSet("ast", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the tree as JSON, see lox.MarshalAST for the format")
+	asDot := flags.Bool("dot", false, "print the tree as a graph in the DOT language of Graphviz")
+	partial := flags.Bool("partial", false, "print what could be parsed of a file with syntax errors")
+	reporting := addReporterFlags(flags, "color the errors: auto, always, or never")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox ast [flags] file")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+	if !reporting.parse() {
+		return 64
+	}
+
+	source, err := ioutil.ReadFile(flags.Arg(0))
+	exitOnError(err, 1)
+	reporter := newReporter(os.Stderr, reporting)
+	setSource(reporter, flags.Arg(0), source, reporting)
+	tokens := lox.NewScanner(source, reporter).Scan()
+	parser := lox.NewParser(tokens, reporter)
+	parser.SetTolerant(*partial)
+	statements := parser.Parse()
+	summarize(reporter)
+	if reporter.HadError() && !*partial {
+		return 65
+	}
+	switch {
+	case *asJSON:
+		data, err := lox.MarshalAST(statements)
+		exitOnError(err, 1)
+		fmt.Println(string(data))
+	case *asDot:
+		fmt.Print(lox.NewDotPrinter().PrintStmts(statements))
+	default:
+		fmt.Print(lox.NewAstPrinter().PrintStmts(statements))
+	}
+	if reporter.HadError() {
+		return 65
+	}
+	return 0
+}
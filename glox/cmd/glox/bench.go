@@ -0,0 +1,222 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Run the "bench" subcommand with the given arguments and return the exit
+// status. Each script is a benchmark that is run many times after a few runs
+// to warm up, its output is discarded. A script that declares global functions
+// whose names start with "bench_" is run once instead, and each of those
+// functions is a benchmark of its own. Directories are searched for ".lox"
+// files, e.g. the benchmarks of the test suite. The status is 65 if a script
+// has a compile error, and 70 if a benchmark has a runtime error.
+func runBench(args []string) int {
+	flags := flag.NewFlagSet("bench", flag.ExitOnError)
+	runs := flags.Int("n", 10, "number of measured runs of each benchmark")
+	warmup := flags.Int("warmup", 1, "number of runs of each benchmark before the measured ones")
+	benchstat := flags.Bool("benchstat", false, "print every run in the format of Go benchmarks, which benchstat compares")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox bench [flags] path...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 || *runs < 1 || *warmup < 0 {
+		flags.Usage()
+		return 64
+	}
+
+	status := 0
+	var results []*benchResult
+	for _, root := range flags.Args() {
+		err := filepath.Walk(root, func(fpath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || (fpath != root && filepath.Ext(fpath) != ".lox") {
+				return nil
+			}
+			source, err := ioutil.ReadFile(fpath)
+			if err != nil {
+				return err
+			}
+			fileResults, fileStatus := benchFile(fpath, source, *warmup, *runs)
+			results = append(results, fileResults...)
+			if fileStatus > status {
+				status = fileStatus
+			}
+			return nil
+		})
+		exitOnError(err, 1)
+	}
+
+	if *benchstat {
+		writeBenchstat(os.Stdout, results)
+	} else {
+		writeBenchTable(os.Stdout, results)
+	}
+	return status
+}
+
+// benchResult holds the measurements of each run of a benchmark, which is a
+// script or one of its functions.
+type benchResult struct {
+	script string
+	fn     string
+	times  []time.Duration
+	bytes  []uint64
+	allocs []uint64
+}
+
+func (r *benchResult) name() string {
+	if r.fn == "" {
+		return r.script
+	}
+	return r.script + ":" + r.fn
+}
+
+// benchFile runs the benchmarks of a script and returns their results, and
+// the exit status of the script.
+func benchFile(fpath string, source []byte, warmup, runs int) ([]*benchResult, int) {
+	reporter := lox.NewSimpleReporter(&prefixWriter{prefix: fpath + ": ", w: os.Stderr})
+	statements := parse(source, reporter)
+	if reporter.HadError() {
+		return nil, 65
+	}
+	var benches []string
+	for _, stmt := range statements {
+		if fn, ok := stmt.(*lox.FunctionStmt); ok && strings.HasPrefix(fn.Name.Lexeme, "bench_") {
+			benches = append(benches, fn.Name.Lexeme)
+		}
+	}
+
+	if len(benches) == 0 {
+		// every run gets a fresh interpreter, only the execution is measured
+		result := &benchResult{script: fpath}
+		for i := 0; i < warmup+runs; i++ {
+			interpreter := lox.NewInterpreter(ioutil.Discard, reporter, false)
+			lox.NewResolver(interpreter, reporter).Resolve(statements)
+			if reporter.HadError() {
+				return nil, 65
+			}
+			measure(result, i >= warmup, func() { interpreter.Interpret(statements) })
+			if reporter.HadRuntimeError() {
+				return nil, 70
+			}
+		}
+		return []*benchResult{result}, 0
+	}
+
+	interpreter := lox.NewInterpreter(ioutil.Discard, reporter, false)
+	execute(statements, interpreter, reporter)
+	if status := exitStatus(reporter); status != 0 {
+		return nil, status
+	}
+	var results []*benchResult
+	for _, bench := range benches {
+		call := parse([]byte(bench+"();"), reporter)
+		lox.NewResolver(interpreter, reporter).Resolve(call)
+		result := &benchResult{script: fpath, fn: bench}
+		for i := 0; i < warmup+runs; i++ {
+			measure(result, i >= warmup, func() { interpreter.Interpret(call) })
+			if reporter.HadRuntimeError() {
+				return results, 70
+			}
+		}
+		results = append(results, result)
+	}
+	return results, 0
+}
+
+// measure runs the function and records how long it took and what it
+// allocated if the run counts. The garbage of the previous runs is collected
+// first so it doesn't slow down this one.
+func measure(result *benchResult, counts bool, run func()) {
+	var before, after runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&before)
+	start := time.Now()
+	run()
+	elapsed := time.Since(start)
+	runtime.ReadMemStats(&after)
+	if counts {
+		result.times = append(result.times, elapsed)
+		result.bytes = append(result.bytes, after.TotalAlloc-before.TotalAlloc)
+		result.allocs = append(result.allocs, after.Mallocs-before.Mallocs)
+	}
+}
+
+func writeBenchTable(w io.Writer, results []*benchResult) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "benchmark\truns\tmean\tstddev\tmin\tmedian\tmax\tB/op\tallocs/op\t")
+	for _, r := range results {
+		sorted := append([]time.Duration(nil), r.times...)
+		sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+		var sum float64
+		for _, t := range sorted {
+			sum += float64(t)
+		}
+		mean := sum / float64(len(sorted))
+		var variance float64
+		for _, t := range sorted {
+			variance += (float64(t) - mean) * (float64(t) - mean)
+		}
+		stddev := 0.0
+		if len(sorted) > 1 {
+			stddev = math.Sqrt(variance / float64(len(sorted)-1))
+		}
+		var bytes, allocs uint64
+		for i := range r.bytes {
+			bytes += r.bytes[i]
+			allocs += r.allocs[i]
+		}
+		n := len(sorted)
+		fmt.Fprintf(tw, "%s\t%d\t%v\t±%.1f%%\t%v\t%v\t%v\t%d\t%d\t\n",
+			r.name(), n, roundDuration(time.Duration(mean)), 100*stddev/mean,
+			roundDuration(sorted[0]), roundDuration(sorted[n/2]),
+			roundDuration(sorted[n-1]), bytes/uint64(n), allocs/uint64(n))
+	}
+	tw.Flush()
+}
+
+// writeBenchstat writes a line per run, named like a Go benchmark after the
+// script, and the function as a sub-benchmark, e.g. "BenchmarkFib" or
+// "BenchmarkStrings/bench_concat".
+func writeBenchstat(w io.Writer, results []*benchResult) {
+	for _, r := range results {
+		name := strings.TrimSuffix(filepath.Base(r.script), filepath.Ext(r.script))
+		name = strings.Join(strings.Fields(name), "_")
+		if name != "" {
+			name = strings.ToUpper(name[:1]) + name[1:]
+		}
+		name = "Benchmark" + name
+		if r.fn != "" {
+			name += "/" + r.fn
+		}
+		for i, t := range r.times {
+			fmt.Fprintf(w, "%s\t1\t%d ns/op\t%d B/op\t%d allocs/op\n", name, t.Nanoseconds(), r.bytes[i], r.allocs[i])
+		}
+	}
+}
+
+// roundDuration keeps 4 significant digits of the duration.
+func roundDuration(d time.Duration) time.Duration {
+	unit := time.Duration(1)
+	for d/unit >= 10000 {
+		unit *= 10
+	}
+	return d.Round(unit)
+}
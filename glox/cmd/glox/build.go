@@ -0,0 +1,82 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Run the "compile" subcommand, which is also named "build", with the given
+// arguments and return the exit status. The script is translated to Go and
+// compiled to a native executable with the go tool, which must be installed.
+// The executable is named after the script and written to the current
+// directory unless another path is given. The positions in the executable are
+// those of the script, e.g. in the stack traces of Go and in debuggers. The
+// status is 65 if the script has a compile error, and 1 if the go tool failed.
+func runBuild(args []string) int {
+	flags := flag.NewFlagSet("compile", flag.ExitOnError)
+	output := flags.String("o", "", "write the executable to the given file")
+	emit := flags.Bool("emit", false, "print the generated Go source instead of compiling it")
+	lines := flags.Bool("lines", true, "map the positions in the generated source to the lines of the script")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox compile [flags] script")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+
+	fpath := flags.Arg(0)
+	source, err := ioutil.ReadFile(fpath)
+	exitOnError(err, 1)
+	reporter := lox.NewSimpleReporter(os.Stderr)
+	statements := parse(source, reporter)
+	if !reporter.HadError() {
+		interpreter := lox.NewInterpreter(ioutil.Discard, reporter, false)
+		lox.NewResolver(interpreter, reporter).Resolve(statements)
+	}
+	if reporter.HadError() {
+		return 65
+	}
+	// the directives name the script by its absolute path, so the tools that
+	// read them find it from anywhere
+	script, err := filepath.Abs(fpath)
+	exitOnError(err, 1)
+	program, err := lox.TranspileGo(script, statements, *lines)
+	exitOnError(err, 1)
+	if *emit {
+		os.Stdout.Write(program)
+		return 0
+	}
+
+	if *output == "" {
+		*output = strings.TrimSuffix(filepath.Base(fpath), filepath.Ext(fpath))
+	}
+	// the go tool runs in the temporary module, so the path must not be
+	// relative to the current directory
+	out, err := filepath.Abs(*output)
+	exitOnError(err, 1)
+	dir, err := ioutil.TempDir("", "glox-build")
+	exitOnError(err, 1)
+	defer os.RemoveAll(dir)
+	exitOnError(ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module loxprogram\n\ngo 1.16\n"), 0644), 1)
+	exitOnError(ioutil.WriteFile(filepath.Join(dir, "main.go"), program, 0644), 1)
+
+	cmd := exec.Command("go", "build", "-o", out, ".")
+	cmd.Dir = dir
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Run the "check" subcommand with the given arguments and return the exit
+// status. Each file is scanned, parsed, and resolved without being run, and
+// directories are searched for ".lox" files. Every file is checked even after
+// an error is found, and the status is 65 if any file has an error.
+func runCheck(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	reporting := addReporterFlags(flags, "color the errors: auto, always, or never")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox check [flags] path...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 64
+	}
+	if !reporting.parse() {
+		return 64
+	}
+
+	status := 0
+	for _, root := range flags.Args() {
+		err := filepath.Walk(root, func(fpath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			// files that are named explicitly are checked whatever their
+			// extension is
+			if info.IsDir() || (fpath != root && filepath.Ext(fpath) != ".lox") {
+				return nil
+			}
+			if !checkFile(fpath, reporting) {
+				status = 65
+			}
+			return nil
+		})
+		exitOnError(err, 1)
+	}
+	return status
+}
+
+// Check a single file and return true if it has no error. Errors are prefixed
+// with the file's path, since many files can be checked at once, or have it in
+// a field when they are written as JSON.
+func checkFile(fpath string, reporting *reporterOptions) bool {
+	source, err := ioutil.ReadFile(fpath)
+	exitOnError(err, 1)
+
+	var w io.Writer = os.Stderr
+	if !reporting.json {
+		w = &prefixWriter{prefix: fpath + ": ", w: os.Stderr}
+	}
+	reporter := newReporter(w, reporting)
+	setSource(reporter, fpath, source, reporting)
+	statements := parse(source, reporter)
+	if !reporter.HadError() {
+		// the interpreter is only used to record the resolved scopes
+		interpreter := lox.NewInterpreter(ioutil.Discard, reporter, false)
+		lox.NewResolver(interpreter, reporter).Resolve(statements)
+	}
+	summarize(reporter)
+	return !reporter.HadError()
+}
+
+// prefixWriter writes the prefix at the start of every line
+type prefixWriter struct {
+	prefix string
+	w      io.Writer
+	// midLine is true if the last write didn't end with a line break
+	midLine bool
+}
+
+func (pw *prefixWriter) Write(p []byte) (int, error) {
+	var buf bytes.Buffer
+	for _, line := range bytes.SplitAfter(p, []byte("\n")) {
+		if len(line) == 0 {
+			continue
+		}
+		if !pw.midLine {
+			buf.WriteString(pw.prefix)
+		}
+		buf.Write(line)
+		pw.midLine = line[len(line)-1] != '\n'
+	}
+	if _, err := pw.w.Write(buf.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
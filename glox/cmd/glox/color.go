@@ -0,0 +1,19 @@
+package main
+
+import "os"
+
+// useColor returns whether the output written to the file is colored for the
+// given value of -color: "always", "never", or "auto" to color it if the file
+// is a terminal and the NO_COLOR environment variable isn't set. ok is false
+// if the value isn't one of those.
+func useColor(mode string, f *os.File) (enabled bool, ok bool) {
+	switch mode {
+	case "always":
+		return true, true
+	case "never":
+		return false, true
+	case "auto":
+		return os.Getenv("NO_COLOR") == "" && isTerminal(f.Fd()), true
+	}
+	return false, false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configName is the name of the configuration file.
+const configName = ".gloxrc"
+
+// configSettings are the flags whose defaults can be set in the configuration
+// file.
+var configSettings = []string{
+	"color",
+	"columns",
+	"snippets",
+	"stack",
+	"warnings",
+	"warnings-as-errors",
+	"diagnostics",
+	"codes",
+	"suppress",
+	"max-errors",
+	"modules",
+	"strict-vars",
+	"echo",
+	"max-steps",
+	"timeout",
+	"max-objects",
+	"max-string-bytes",
+	"max-call-depth",
+	"ast-cache",
+	"trace",
+	"profile",
+	"ext",
+}
+
+// homeConfigSettings are the settings that only the configuration file of
+// the home directory can give, since the one of the current directory may
+// come with untrusted code, e.g. in a cloned repository, and these would let
+// it loosen the sandbox of the scripts or load Go code.
+var homeConfigSettings = []string{
+	"allow",
+	"deny",
+	"plugin",
+}
+
+// loadConfig reads the configuration file in the home directory and then the
+// one in the current directory, and sets the flags that they give, so the
+// settings of the current directory override the ones of the home directory,
+// and the flags on the command line override both. Each line of a file is a
+// setting such as "max-steps = 1000000", whose name is one of configSettings,
+// or of homeConfigSettings in the home directory, empty lines and lines that
+// start with '#' are ignored. It's not an error if there's no configuration
+// file.
+func loadConfig(flags *flag.FlagSet) error {
+	homePath := ""
+	if home, err := os.UserHomeDir(); err == nil {
+		homePath = filepath.Join(home, configName)
+		if err := readConfig(flags, homePath, true); err != nil {
+			return err
+		}
+	}
+	// the home directory's file isn't read twice from there
+	if abs, err := filepath.Abs(configName); err == nil && abs == homePath {
+		return nil
+	}
+	return readConfig(flags, configName, false)
+}
+
+// readConfig sets the flags given by the configuration file at the path, home
+// is set if it's the one of the home directory, see homeConfigSettings.
+func readConfig(flags *flag.FlagSet, fpath string, home bool) error {
+	f, err := os.Open(fpath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	s := bufio.NewScanner(f)
+	for n := 1; s.Scan(); n++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.Index(line, "=")
+		if i < 0 {
+			return fmt.Errorf("%s:%d: Expect 'name = value'.", fpath, n)
+		}
+		name, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if isSetting(homeConfigSettings, name) && !home {
+			return fmt.Errorf("%s:%d: Setting '%s' can only be in ~/%s.", fpath, n, name, configName)
+		}
+		if !isSetting(configSettings, name) && !isSetting(homeConfigSettings, name) {
+			return fmt.Errorf("%s:%d: Unknown setting '%s'.", fpath, n, name)
+		}
+		if err := flags.Set(name, value); err != nil {
+			return fmt.Errorf("%s:%d: Invalid value '%s' for '%s'.", fpath, n, value, name)
+		}
+	}
+	return s.Err()
+}
+
+func isSetting(settings []string, name string) bool {
+	for _, setting := range settings {
+		if setting == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Run the "conformance" subcommand with the given arguments and return the
+// exit status. The test suite of Crafting Interpreters is run and the results
+// are summarized by the chapter of the book that each test belongs to. The
+// suite is looked up in the repository if its directory isn't given. The
+// status is 1 if any test failed.
+func runConformance(args []string) int {
+	flags := flag.NewFlagSet("conformance", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "list every test that is run, not only the failed ones")
+	timeout := flags.Duration("timeout", 10*time.Second, "maximum duration of each test, 0 for no limit")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox conformance [flags] [dir]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() > 1 {
+		flags.Usage()
+		return 64
+	}
+
+	root := flags.Arg(0)
+	if root == "" {
+		// the command is usually run from the root of the repository or from
+		// the directory of glox
+		for _, dir := range []string{"testsuite/test", "../testsuite/test"} {
+			if info, err := os.Stat(dir); err == nil && info.IsDir() {
+				root = dir
+				break
+			}
+		}
+		if root == "" {
+			fmt.Fprintln(os.Stderr, "The test suite wasn't found, give its directory.")
+			return 64
+		}
+	}
+
+	type chapterResult struct {
+		passed, failed, skipped int
+	}
+	chapters := make(map[string]*chapterResult)
+	err := filepath.Walk(root, func(fpath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(fpath) != ".lox" {
+			return err
+		}
+		rel, err := filepath.Rel(root, fpath)
+		if err != nil {
+			return err
+		}
+		chapter, skip := lox.ConformanceChapter(rel)
+		result, ok := chapters[chapter]
+		if !ok {
+			result = new(chapterResult)
+			chapters[chapter] = result
+		}
+		if skip != "" {
+			if *verbose {
+				fmt.Printf("SKIP %s (%s)\n", fpath, skip)
+			}
+			result.skipped++
+			return nil
+		}
+
+		source, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return err
+		}
+		if runTestFile(lox.NewScriptTest(fpath, source), *timeout, *verbose).Passed() {
+			result.passed++
+		} else {
+			result.failed++
+		}
+		return nil
+	})
+	exitOnError(err, 1)
+
+	names := make([]string, 0, len(chapters))
+	for name := range chapters {
+		names = append(names, name)
+	}
+	// chapters are sorted by their number, "Other" is last
+	sort.Slice(names, func(i, j int) bool {
+		var ni, nj int
+		fmt.Sscanf(names[i], "%d.", &ni)
+		fmt.Sscanf(names[j], "%d.", &nj)
+		if ni == 0 || nj == 0 {
+			return ni > nj
+		}
+		return ni < nj
+	})
+
+	failed := 0
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "\nchapter\tpassed\tfailed\tskipped\t")
+	for _, name := range names {
+		result := chapters[name]
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t\n", name, result.passed, result.failed, result.skipped)
+		failed += result.failed
+	}
+	tw.Flush()
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
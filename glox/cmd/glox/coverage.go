@@ -0,0 +1,38 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Write the coverage records to the files that were asked for, an empty path
+// means the report isn't wanted. The percentage of executed lines is printed
+// for every script.
+func writeCoverage(coverages []*lox.Coverage, profile string, html string) {
+	if profile == "" && html == "" {
+		return
+	}
+	for _, c := range coverages {
+		total, covered := c.Lines()
+		if total == 0 {
+			continue
+		}
+		fmt.Fprintf(os.Stderr, "coverage: %.1f%% of lines in %s\n", 100*float64(covered)/float64(total), c.Name())
+	}
+	if profile != "" {
+		writeCoverageFile(profile, func(w io.Writer) error { return lox.WriteLCOV(w, coverages) })
+	}
+	if html != "" {
+		writeCoverageFile(html, func(w io.Writer) error { return lox.WriteCoverageHTML(w, coverages) })
+	}
+}
+
+func writeCoverageFile(fpath string, write func(w io.Writer) error) {
+	f, err := os.Create(fpath)
+	exitOnError(err, 1)
+	defer f.Close()
+	exitOnError(write(f), 1)
+}
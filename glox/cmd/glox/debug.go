@@ -0,0 +1,247 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+const debugHelp = `Commands:
+  break [file:]line  set a breakpoint (b)
+  delete line        remove a breakpoint (d)
+  breakpoints        list the breakpoints
+  step               run until the next statement, entering calls (s)
+  next               run until the next statement in this function (n)
+  finish             run until the current function returns (f)
+  continue           run until a breakpoint or a watch changes (c)
+  locals             print the variables of every enclosing scope (l)
+  print expr         evaluate an expression in the current scope (p)
+  watch expr         pause whenever the value of an expression changes (w)
+  unwatch id         remove a watch
+  watches            list the watches and their values
+  backtrace          print the call stack (bt, where)
+  up [n]             select the frame of the caller (u)
+  down [n]           select the frame of the callee
+  quit               stop the script (q)
+`
+
+// Run the "debug" subcommand with the given arguments and return the exit
+// status. The script is paused before its first statement, commands are then
+// read from stdin whenever the script is paused.
+func runDebug(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox debug script")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+
+	fpath := flags.Arg(0)
+	source, err := ioutil.ReadFile(fpath)
+	exitOnError(err, 1)
+
+	reporter := lox.NewSimpleReporter(os.Stderr)
+	interpreter := lox.NewInterpreter(os.Stdout, reporter, false)
+	session := &debugSession{
+		script: filepath.Base(fpath),
+		lines:  strings.Split(string(source), "\n"),
+		input:  bufio.NewScanner(os.Stdin),
+		output: os.Stdout,
+	}
+	interpreter.SetDebugger(lox.NewDebugger(session.pause))
+
+	statements := parse(source, reporter)
+	if reporter.HadError() {
+		return 65
+	}
+	execute(statements, interpreter, reporter)
+	return exitStatus(reporter)
+}
+
+// debugSession reads the debugger's commands and prints their results
+type debugSession struct {
+	script string
+	lines  []string
+	input  *bufio.Scanner
+	output io.Writer
+}
+
+// pause shows where the script is paused, then runs commands until one of
+// them resumes the script.
+func (s *debugSession) pause(d *lox.Debugger, line int) lox.DebugAction {
+	s.showLine(line)
+	for _, w := range d.Watches() {
+		if w.Changed {
+			s.showWatch(w)
+		}
+	}
+	for {
+		fmt.Fprint(s.output, "(debug) ")
+		if !s.input.Scan() {
+			return lox.DebugQuit
+		}
+		cmd, arg := splitCommand(s.input.Text())
+		switch cmd {
+		case "":
+		case "s", "step":
+			return lox.DebugStep
+		case "n", "next":
+			return lox.DebugNext
+		case "f", "finish":
+			return lox.DebugFinish
+		case "c", "continue":
+			return lox.DebugContinue
+		case "q", "quit":
+			return lox.DebugQuit
+		case "b", "break":
+			if line, ok := s.parseLocation(arg); ok {
+				d.SetBreakpoint(line)
+				fmt.Fprintf(s.output, "Breakpoint at %s:%d\n", s.script, line)
+			}
+		case "d", "delete":
+			if line, ok := s.parseLocation(arg); ok && !d.ClearBreakpoint(line) {
+				fmt.Fprintf(s.output, "No breakpoint at %s:%d\n", s.script, line)
+			}
+		case "breakpoints":
+			for _, line := range d.Breakpoints() {
+				fmt.Fprintf(s.output, "%s:%d\n", s.script, line)
+			}
+		case "l", "locals":
+			scopes := d.Locals()
+			for i, scope := range scopes {
+				if len(scope) == 0 {
+					continue
+				}
+				if i == len(scopes)-1 {
+					fmt.Fprintln(s.output, "globals:")
+				} else {
+					fmt.Fprintf(s.output, "scope %d:\n", i)
+				}
+				for _, v := range scope {
+					fmt.Fprintf(s.output, "  %s = %s\n", v.Name, v.Value)
+				}
+			}
+		case "p", "print":
+			val, err := d.Eval(arg)
+			if err != nil {
+				fmt.Fprintln(s.output, err)
+			} else {
+				fmt.Fprintln(s.output, val)
+			}
+		case "w", "watch":
+			if _, err := d.Watch(arg); err != nil {
+				fmt.Fprintln(s.output, err)
+			} else {
+				watches := d.Watches()
+				s.showWatch(watches[len(watches)-1])
+			}
+		case "unwatch":
+			id, err := strconv.Atoi(arg)
+			if err != nil || !d.Unwatch(id) {
+				fmt.Fprintf(s.output, "No watch %q.\n", arg)
+			}
+		case "watches":
+			for _, w := range d.Watches() {
+				s.showWatch(w)
+			}
+		case "bt", "where", "backtrace":
+			for i, frame := range d.Frames() {
+				marker := " "
+				if i == d.Frame() {
+					marker = ">"
+				}
+				fmt.Fprintf(s.output, "%s #%d %s\n", marker, i, s.frameName(frame))
+			}
+		case "u", "up", "down":
+			n := 1
+			if arg != "" {
+				var err error
+				if n, err = strconv.Atoi(arg); err != nil {
+					fmt.Fprintf(s.output, "Invalid count %q.\n", arg)
+					continue
+				}
+			}
+			if cmd == "down" {
+				n = -n
+			}
+			if !d.SelectFrame(d.Frame() + n) {
+				fmt.Fprintln(s.output, "No such frame.")
+				continue
+			}
+			frame := d.Frames()[d.Frame()]
+			fmt.Fprintf(s.output, "#%d %s\n", d.Frame(), s.frameName(frame))
+		case "h", "help":
+			fmt.Fprint(s.output, debugHelp)
+		default:
+			fmt.Fprintf(s.output, "Unknown command %q, try \"help\".\n", cmd)
+		}
+	}
+}
+
+func (s *debugSession) showLine(line int) {
+	text := ""
+	if line > 0 && line <= len(s.lines) {
+		text = strings.TrimSpace(s.lines[line-1])
+	}
+	fmt.Fprintf(s.output, "%s:%d  %s\n", s.script, line, text)
+}
+
+// frameName describes a frame as "function() at script:line"
+func (s *debugSession) frameName(frame lox.DebugFrame) string {
+	name := "<script>"
+	if frame.Function != "" {
+		name = frame.Function + "()"
+	}
+	if frame.Line == 0 {
+		return fmt.Sprintf("%s at %s, in a call from the host", name, s.script)
+	}
+	return fmt.Sprintf("%s at %s:%d", name, s.script, frame.Line)
+}
+
+// showWatch prints a watch as "id: expr = value", with the error of the last
+// evaluation if it failed.
+func (s *debugSession) showWatch(w lox.DebugWatch) {
+	if w.Err != nil {
+		fmt.Fprintf(s.output, "  %d: %s = %s (%v)\n", w.ID, w.Expr, w.Value, w.Err)
+	} else {
+		fmt.Fprintf(s.output, "  %d: %s = %s\n", w.ID, w.Expr, w.Value)
+	}
+}
+
+// parseLocation parses a breakpoint location, either "line" or "file:line"
+// where file is the name of the script.
+func (s *debugSession) parseLocation(loc string) (int, bool) {
+	if i := strings.LastIndexByte(loc, ':'); i >= 0 {
+		if filepath.Base(loc[:i]) != s.script {
+			fmt.Fprintf(s.output, "Unknown file %q.\n", loc[:i])
+			return 0, false
+		}
+		loc = loc[i+1:]
+	}
+	line, err := strconv.Atoi(loc)
+	if err != nil || line < 1 {
+		fmt.Fprintf(s.output, "Invalid line %q.\n", loc)
+		return 0, false
+	}
+	return line, true
+}
+
+func splitCommand(text string) (string, string) {
+	text = strings.TrimSpace(text)
+	if i := strings.IndexAny(text, " \t"); i >= 0 {
+		return text[:i], strings.TrimSpace(text[i+1:])
+	}
+	return text, ""
+}
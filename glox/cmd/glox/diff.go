@@ -0,0 +1,59 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Write the line-based difference between a and b, lines only in a are
+// prefixed with "-", lines only in b with "+", and common lines with " ". The
+// difference is computed from the longest common subsequence of lines, which
+// is good enough for source files of moderate size.
+func writeDiff(w io.Writer, a, b []byte) {
+	linesA := splitLines(a)
+	linesB := splitLines(b)
+
+	// lcs[i][j] is the length of the longest common subsequence of
+	// linesA[i:] and linesB[j:]
+	lcs := make([][]int, len(linesA)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(linesB)+1)
+	}
+	for i := len(linesA) - 1; i >= 0; i-- {
+		for j := len(linesB) - 1; j >= 0; j-- {
+			if linesA[i] == linesB[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(linesA) || j < len(linesB) {
+		switch {
+		case i < len(linesA) && j < len(linesB) && linesA[i] == linesB[j]:
+			fmt.Fprintf(w, " %s\n", linesA[i])
+			i++
+			j++
+		case j == len(linesB) || (i < len(linesA) && lcs[i+1][j] >= lcs[i][j+1]):
+			fmt.Fprintf(w, "-%s\n", linesA[i])
+			i++
+		default:
+			fmt.Fprintf(w, "+%s\n", linesB[j])
+			j++
+		}
+	}
+}
+
+func splitLines(s []byte) []string {
+	s = bytes.TrimSuffix(s, []byte("\n"))
+	if len(s) == 0 {
+		return nil
+	}
+	return strings.Split(string(s), "\n")
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Run the "explain" subcommand with the given arguments and return the exit
+// status. The codes of the diagnostics are printed with the descriptions of
+// their problems, all of them if none is given, and the codes given in lower
+// case are found too. The status is 1 if a code isn't assigned.
+func runExplain(args []string) int {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox explain [code...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	codes := lox.Codes()
+	if flags.NArg() > 0 {
+		codes = codes[:0]
+		for _, arg := range flags.Args() {
+			code := lox.Code(strings.ToUpper(arg))
+			if code.Description() == "" {
+				fmt.Fprintf(os.Stderr, "Unknown code '%s'.\n", arg)
+				return 1
+			}
+			codes = append(codes, code)
+		}
+	}
+	for _, code := range codes {
+		fmt.Printf("%s  %s\n", code, code.Description())
+	}
+	return 0
+}
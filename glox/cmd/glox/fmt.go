@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Run the "fmt" subcommand with the given arguments and return the exit status.
+// Each file is printed to stdout in its canonical format unless "-w" is given,
+// in which case the file is overwritten. With "-check", nothing is written and
+// the differences from the canonical format are printed instead.
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result to the source file instead of stdout")
+	check := flags.Bool("check", false, "print a diff and exit with status 1 if a file isn't formatted")
+	reporting := addReporterFlags(flags, "color the errors: auto, always, or never")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox fmt [flags] file...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 64
+	}
+	if !reporting.parse() {
+		return 64
+	}
+
+	status := 0
+	for _, fpath := range flags.Args() {
+		source, err := ioutil.ReadFile(fpath)
+		exitOnError(err, 1)
+
+		// only well-formed scripts are formatted
+		reporter := newReporter(os.Stderr, reporting)
+		setSource(reporter, fpath, source, reporting)
+		tokens := lox.NewScanner(source, reporter).Scan()
+		lox.NewParser(tokens, reporter).Parse()
+		summarize(reporter)
+		if reporter.HadError() {
+			status = 65
+			continue
+		}
+
+		formatted := lox.Format(tokens)
+		switch {
+		case *check:
+			if !bytes.Equal(source, formatted) {
+				fmt.Printf("--- %s\n+++ %s (formatted)\n", fpath, fpath)
+				writeDiff(os.Stdout, source, formatted)
+				if status == 0 {
+					status = 1
+				}
+			}
+		case *write:
+			if !bytes.Equal(source, formatted) {
+				info, err := os.Stat(fpath)
+				exitOnError(err, 1)
+				exitOnError(ioutil.WriteFile(fpath, formatted, info.Mode()), 1)
+			}
+		default:
+			os.Stdout.Write(formatted)
+		}
+	}
+	return status
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Run the "highlight" subcommand with the given arguments and return the exit
+// status. The file is printed with its tokens colored for a terminal, or as
+// HTML with "-html". Scan errors are reported, but the source is still
+// printed with the invalid characters left as they are.
+func runHighlight(args []string) int {
+	flags := flag.NewFlagSet("highlight", flag.ExitOnError)
+	asHTML := flags.Bool("html", false, "print an HTML fragment instead of coloring for a terminal")
+	page := flags.Bool("page", false, "with -html, print a standalone page that includes a stylesheet")
+	lines := flags.String("lines", "", "only print the given range of lines, e.g. \"3:7\" or \"3\"")
+	numbers := flags.Bool("n", false, "print the line numbers")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox highlight [flags] file")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+
+	fpath := flags.Arg(0)
+	source, err := ioutil.ReadFile(fpath)
+	exitOnError(err, 1)
+	reporter := lox.NewSimpleReporter(os.Stderr)
+	tokens := lox.NewScanner(source, reporter).Scan()
+	format := lox.HighlightANSI
+	if *asHTML {
+		format = lox.HighlightHTML
+	}
+	highlighted := lox.HighlightLines(source, tokens, format)
+	// a final line break doesn't start another line
+	if len(highlighted) > 1 && highlighted[len(highlighted)-1] == "" {
+		highlighted = highlighted[:len(highlighted)-1]
+	}
+
+	first, last := 1, len(highlighted)
+	if *lines != "" {
+		n, _ := fmt.Sscanf(*lines, "%d:%d", &first, &last)
+		if n == 1 {
+			last = first
+		}
+		if n == 0 || first < 1 || last < first {
+			fmt.Fprintf(os.Stderr, "Invalid range of lines '%s'.\n", *lines)
+			return 64
+		}
+		if last > len(highlighted) {
+			last = len(highlighted)
+		}
+	}
+
+	var out strings.Builder
+	width := len(fmt.Sprint(last))
+	for i := first - 1; i < last; i++ {
+		if *numbers {
+			fmt.Fprintf(&out, "%*d  ", width, i+1)
+		}
+		out.WriteString(highlighted[i])
+		out.WriteString("\n")
+	}
+	if *asHTML {
+		code := `<pre class="lox"><code>` + out.String() + "</code></pre>\n"
+		if *page {
+			code = "<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n<title>" +
+				html.EscapeString(filepath.Base(fpath)) + "</title>\n<style>\n" + lox.HighlightCSS +
+				"</style>\n</head>\n<body>\n" + code + "</body>\n</html>\n"
+		}
+		fmt.Print(code)
+	} else {
+		fmt.Print(out.String())
+	}
+	if reporter.HadError() {
+		return 65
+	}
+	return 0
+}
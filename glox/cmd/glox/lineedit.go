@@ -0,0 +1,300 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// errInterrupted is returned by a line reader when the user pressed Ctrl-C,
+// the input that was being entered is then discarded.
+var errInterrupted = errors.New("interrupted")
+
+// lineReader reads the lines of the REPL, io.EOF is returned once there are
+// no more lines.
+type lineReader interface {
+	readLine(prompt string) (string, error)
+}
+
+// scannerReader reads lines without editing them, it's used when the input
+// isn't a terminal.
+type scannerReader struct {
+	input  *bufio.Scanner
+	output io.Writer
+}
+
+func (r *scannerReader) readLine(prompt string) (string, error) {
+	fmt.Fprint(r.output, prompt)
+	if !r.input.Scan() {
+		if err := r.input.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.input.Text(), nil
+}
+
+// lineEditor reads lines from a terminal in raw mode, so they can be edited
+// as they are typed: the cursor is moved with the arrows, Home and End, or
+// Ctrl-A and Ctrl-E, Ctrl-K, Ctrl-U and Ctrl-W delete to the end, to the
+// start, and the previous word, the lines read before are recalled with Up
+// and Down, and Tab completes the name before the cursor. The line is colored
+// like "glox highlight" does while it's typed, unless color isn't set. Bytes
+// are read one at a time, so nothing is buffered that the debugger's prompt
+// wouldn't see when it reads the same input.
+type lineEditor struct {
+	input  *os.File
+	output io.Writer
+	// complete returns the words that the name at the end of the text can be
+	// completed to.
+	complete func(text string) []string
+	// context holds the lines of the input before the one that is read, so
+	// the coloring knows whether the line starts within a string or a comment.
+	context []string
+	history []string
+	// color is set if the line is colored
+	color bool
+
+	line   []rune
+	cursor int
+}
+
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	restore, err := enableRawMode(e.input.Fd())
+	if err != nil {
+		return "", err
+	}
+	defer restore()
+
+	e.line = e.line[:0]
+	e.cursor = 0
+	// the entry past the last one of the history is the line being typed
+	recalled := len(e.history)
+	var typed []rune
+	e.refresh(prompt)
+	for {
+		r, err := e.readRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(e.output, "\r\n")
+			line := string(e.line)
+			if strings.TrimSpace(line) != "" {
+				e.history = append(e.history, line)
+			}
+			return line, nil
+		case 3: // Ctrl-C
+			fmt.Fprint(e.output, "^C\r\n")
+			return "", errInterrupted
+		case 4: // Ctrl-D
+			if len(e.line) == 0 {
+				fmt.Fprint(e.output, "\r\n")
+				return "", io.EOF
+			}
+			e.delete(e.cursor, e.cursor+1)
+		case 127, 8: // Backspace
+			e.delete(e.cursor-1, e.cursor)
+		case 1: // Ctrl-A
+			e.cursor = 0
+		case 5: // Ctrl-E
+			e.cursor = len(e.line)
+		case 2: // Ctrl-B
+			e.move(-1)
+		case 6: // Ctrl-F
+			e.move(1)
+		case 11: // Ctrl-K
+			e.delete(e.cursor, len(e.line))
+		case 21: // Ctrl-U
+			e.delete(0, e.cursor)
+		case 23: // Ctrl-W
+			start := e.cursor
+			for start > 0 && e.line[start-1] == ' ' {
+				start--
+			}
+			for start > 0 && e.line[start-1] != ' ' {
+				start--
+			}
+			e.delete(start, e.cursor)
+		case '\t':
+			e.completeWord()
+		case 16, 14: // Ctrl-P, Ctrl-N
+			recalled, typed = e.recall(recalled, typed, r == 16)
+		case 27: // escape sequences of the special keys
+			key, err := e.readEscape()
+			if err != nil {
+				return "", err
+			}
+			switch key {
+			case "[D":
+				e.move(-1)
+			case "[C":
+				e.move(1)
+			case "[H", "[1~", "OH":
+				e.cursor = 0
+			case "[F", "[4~", "OF":
+				e.cursor = len(e.line)
+			case "[3~":
+				e.delete(e.cursor, e.cursor+1)
+			case "[A", "[B":
+				recalled, typed = e.recall(recalled, typed, key == "[A")
+			}
+		default:
+			if r >= ' ' {
+				e.line = append(e.line, 0)
+				copy(e.line[e.cursor+1:], e.line[e.cursor:])
+				e.line[e.cursor] = r
+				e.cursor++
+			}
+		}
+		e.refresh(prompt)
+	}
+}
+
+// readRune reads a character, which can take several bytes.
+func (e *lineEditor) readRune() (rune, error) {
+	var buf [utf8.UTFMax]byte
+	if _, err := io.ReadFull(e.input, buf[:1]); err != nil {
+		return 0, err
+	}
+	n := 1
+	for ; n < len(buf) && !utf8.FullRune(buf[:n]); n++ {
+		if _, err := io.ReadFull(e.input, buf[n:n+1]); err != nil {
+			return 0, err
+		}
+	}
+	r, _ := utf8.DecodeRune(buf[:n])
+	return r, nil
+}
+
+// readEscape reads the rest of an escape sequence, e.g. "[A" for Up, which
+// ends with a letter or '~'.
+func (e *lineEditor) readEscape() (string, error) {
+	var key []byte
+	var buf [1]byte
+	for {
+		if _, err := e.input.Read(buf[:]); err != nil {
+			return "", err
+		}
+		key = append(key, buf[0])
+		c := buf[0]
+		if len(key) > 1 && (c == '~' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z')) {
+			return string(key), nil
+		}
+		// Alt and a key sends ESC followed by the key alone
+		if len(key) == 1 && c != '[' && c != 'O' {
+			return string(key), nil
+		}
+	}
+}
+
+func (e *lineEditor) move(n int) {
+	e.cursor += n
+	if e.cursor < 0 {
+		e.cursor = 0
+	} else if e.cursor > len(e.line) {
+		e.cursor = len(e.line)
+	}
+}
+
+// delete removes the characters in [start, end) that are on the line.
+func (e *lineEditor) delete(start, end int) {
+	if start < 0 {
+		start = 0
+	}
+	if end > len(e.line) {
+		end = len(e.line)
+	}
+	if start >= end {
+		return
+	}
+	e.line = append(e.line[:start], e.line[end:]...)
+	if e.cursor > end {
+		e.cursor -= end - start
+	} else if e.cursor > start {
+		e.cursor = start
+	}
+}
+
+// recall replaces the line with the previous or the next line of the history,
+// and returns the position of the line in the history. The line that was
+// being typed is kept, and brought back after the last line of the history.
+func (e *lineEditor) recall(recalled int, typed []rune, previous bool) (int, []rune) {
+	if recalled == len(e.history) {
+		typed = append(typed[:0], e.line...)
+	}
+	if previous && recalled > 0 {
+		recalled--
+	} else if !previous && recalled < len(e.history) {
+		recalled++
+	} else {
+		return recalled, typed
+	}
+	if recalled == len(e.history) {
+		e.line = append(e.line[:0], typed...)
+	} else {
+		e.line = append(e.line[:0], []rune(e.history[recalled])...)
+	}
+	e.cursor = len(e.line)
+	return recalled, typed
+}
+
+// completeWord completes the name before the cursor with the part that all
+// the completions have in common, the completions are listed if there's
+// nothing to add.
+func (e *lineEditor) completeWord() {
+	if e.complete == nil {
+		return
+	}
+	before := string(e.line[:e.cursor])
+	words := e.complete(before)
+	if len(words) == 0 {
+		return
+	}
+	common := words[0]
+	for _, word := range words[1:] {
+		for !strings.HasPrefix(word, common) {
+			common = common[:len(common)-1]
+		}
+	}
+	start := len(before)
+	for start > 0 && isNameChar(before[start-1]) {
+		start--
+	}
+	if added := []rune(common[len(before)-start:]); len(added) > 0 {
+		e.line = append(e.line[:e.cursor], append(added, e.line[e.cursor:]...)...)
+		e.cursor += len(added)
+		return
+	}
+	if len(words) > 1 {
+		fmt.Fprintf(e.output, "\r\n%s\r\n", strings.Join(words, "  "))
+	}
+}
+
+func isNameChar(c byte) bool {
+	return c == '_' || (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// refresh redraws the prompt and the colored line, and puts the cursor back
+// where it is in the line.
+func (e *lineEditor) refresh(prompt string) {
+	line := string(e.line)
+	if e.color {
+		source := strings.Join(append(append([]string(nil), e.context...), line), "\n")
+		reporter := lox.NewSimpleReporter(ioutil.Discard)
+		lines := lox.HighlightLines([]byte(source), lox.NewScanner([]byte(source), reporter).Scan(), lox.HighlightANSI)
+		line = lines[len(lines)-1]
+	}
+	fmt.Fprintf(e.output, "\r%s%s\x1b[K\r", prompt, line)
+	if column := utf8.RuneCountInString(prompt) + e.cursor; column > 0 {
+		fmt.Fprintf(e.output, "\x1b[%dC", column)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Run the "lint" subcommand with the given arguments and return the exit
+// status. Every rule is enabled by default and can be turned off with its own
+// flag, e.g. "-shadow=false". With "-fix", the warnings that have a mechanical
+// fix are fixed, missing semicolons at the end of lines are added, and the
+// files are rewritten in their canonical format; only the warnings that remain
+// are reported. The status is 1 if any warning was reported, and 65 if a file
+// has syntax or resolution errors.
+func runLint(args []string) int {
+	flags := flag.NewFlagSet("lint", flag.ExitOnError)
+	fix := flags.Bool("fix", false, "fix what can be fixed and rewrite the files with the formatter")
+	enabled := make(map[lox.LintRule]*bool)
+	for _, rule := range lox.LintRules {
+		enabled[rule] = flags.Bool(rule.String(), true, "report "+lintRuleUsage[rule])
+	}
+	reporting := addReporterFlags(flags, "color the errors and the warnings: auto, always, or never")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox lint [flags] file...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 64
+	}
+	if !reporting.parse() {
+		return 64
+	}
+
+	var rules lox.LintRule
+	for rule, on := range enabled {
+		if *on {
+			rules |= rule
+		}
+	}
+
+	status := 0
+	for _, fpath := range flags.Args() {
+		source, err := ioutil.ReadFile(fpath)
+		exitOnError(err, 1)
+		if *fix {
+			if fixed, ok := fixFile(source, rules); ok && !bytes.Equal(fixed, source) {
+				info, err := os.Stat(fpath)
+				exitOnError(err, 1)
+				exitOnError(ioutil.WriteFile(fpath, fixed, info.Mode()), 1)
+				source = fixed
+			}
+		}
+
+		reporter := newReporter(os.Stderr, reporting)
+		setSource(reporter, fpath, source, reporting)
+		statements := parse(source, reporter)
+		if !reporter.HadError() {
+			interpreter := lox.NewInterpreter(ioutil.Discard, reporter, false)
+			lox.NewResolver(interpreter, reporter).Resolve(statements)
+		}
+		summarize(reporter)
+		if reporter.HadError() {
+			status = 65
+			continue
+		}
+
+		warnings := newReporter(os.Stdout, reporting)
+		setSource(warnings, fpath, source, reporting)
+		lox.NewLinter(rules, warnings).Lint(statements)
+		summarize(warnings)
+		if warnings.HadWarning() && status == 0 {
+			status = 1
+		}
+	}
+	return status
+}
+
+// fixFile returns the fixed and formatted source, it's false if the source
+// can't be fixed because it has errors that aren't missing semicolons. The
+// fixes are repeated since each round can make new ones possible.
+func fixFile(source []byte, rules lox.LintRule) ([]byte, bool) {
+	reporter := lox.NewSimpleReporter(ioutil.Discard)
+	tokens := lox.NewScanner(source, reporter).Scan()
+	if reporter.HadError() {
+		return nil, false
+	}
+	tokens, _ = lox.FixSemicolons(tokens)
+
+	for round := 0; round < 10; round++ {
+		statements := lox.NewParser(tokens, reporter).Parse()
+		if !reporter.HadError() {
+			interpreter := lox.NewInterpreter(ioutil.Discard, reporter, false)
+			lox.NewResolver(interpreter, reporter).Resolve(statements)
+		}
+		if reporter.HadError() {
+			return nil, false
+		}
+		linter := lox.NewLinter(rules, lox.NewSimpleReporter(ioutil.Discard))
+		linter.Lint(statements)
+		var fixed int
+		if tokens, fixed = linter.Fix(tokens); fixed == 0 {
+			break
+		}
+		// the fixed tokens are scanned again from their canonical format, so
+		// they have the positions that the next round relies on
+		tokens = lox.NewScanner(lox.Format(tokens), reporter).Scan()
+	}
+
+	formatted := lox.Format(tokens)
+	// a fix must never break the script
+	tokens = lox.NewScanner(formatted, reporter).Scan()
+	lox.NewParser(tokens, reporter).Parse()
+	if reporter.HadError() {
+		return nil, false
+	}
+	return formatted, true
+}
+
+var lintRuleUsage = map[lox.LintRule]string{
+	lox.LintUnusedVariable:    "local variables that are never used",
+	lox.LintUnusedParameter:   "function parameters that are never used",
+	lox.LintShadow:            "declarations that shadow a variable in an outer scope",
+	lox.LintSelfAssign:        "variables and properties assigned to themselves",
+	lox.LintConstantCondition: "conditions that are always true or always false",
+	lox.LintBoolCompare:       "comparisons of booleans with true or false",
+}
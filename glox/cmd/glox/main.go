@@ -3,39 +3,82 @@ package main
 // This is an interpreter for the Lox programming language written in Go.
 
 import (
-	"bufio"
 	"fmt"
 	"io/ioutil"
 	"os"
+	"runtime"
+	"runtime/pprof"
 
 	"github.com/letung3105/lox/glox/internal/lox"
 )
 
 func main() {
-	args := os.Args[1:]
-	if len(args) > 1 {
-		fmt.Println("Usage: glox [script]")
-		os.Exit(64)
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "fmt":
+			os.Exit(runFmt(os.Args[2:]))
+		case "lint":
+			os.Exit(runLint(os.Args[2:]))
+		case "check":
+			os.Exit(runCheck(os.Args[2:]))
+		case "ast":
+			os.Exit(runAST(os.Args[2:]))
+		case "tokens":
+			os.Exit(runTokens(os.Args[2:]))
+		case "debug":
+			os.Exit(runDebug(os.Args[2:]))
+		case "test":
+			os.Exit(runTest(os.Args[2:]))
+		case "conformance":
+			os.Exit(runConformance(os.Args[2:]))
+		case "run", "repl":
+			os.Exit(runInterpreter(os.Args[1], os.Args[2:]))
+		case "compile", "build":
+			os.Exit(runBuild(os.Args[2:]))
+		case "highlight":
+			os.Exit(runHighlight(os.Args[2:]))
+		case "bench":
+			os.Exit(runBench(os.Args[2:]))
+		case "min":
+			os.Exit(runMin(os.Args[2:]))
+		case "explain":
+			os.Exit(runExplain(os.Args[2:]))
+		}
 	}
 
-	reporter := lox.NewSimpleReporter(os.Stderr)
-	interpreter := lox.NewInterpreter(os.Stdout, reporter, false)
-	if len(args) != 1 {
-		runPrompt(interpreter, reporter)
-	} else {
-		runFile(args[0], interpreter, reporter)
-	}
+	// without a subcommand, the scripts that are given are run, or the REPL
+	// is started if there's none
+	os.Exit(runInterpreter("", os.Args[1:]))
 }
 
-func run(script string, interpreter *lox.Interpreter, reporter lox.Reporter) {
-	scanner := lox.NewScanner([]rune(script), reporter)
-	tokens := scanner.Scan()
-	parser := lox.NewParser(tokens, reporter)
-	statements := parser.Parse()
+func run(name string, source []byte, interpreter *lox.Interpreter, reporter lox.Reporter, reporting *reporterOptions) {
+	setSource(reporter, name, source, reporting)
+	statements := parse(source, reporter)
 	if reporter.HadError() {
 		return
 	}
+	execute(statements, interpreter, reporter)
+}
+
+func parse(source []byte, reporter lox.Reporter) []lox.Stmt {
+	scanner := lox.NewScanner(source, reporter)
+	tokens := scanner.Scan()
+	parser := lox.NewParser(tokens, reporter)
+	return parser.Parse()
+}
+
+// showWarnings is set if the resolver reports warnings, see -warnings.
+var showWarnings bool
+
+// newResolver creates a resolver that reports warnings if showWarnings is set.
+func newResolver(interpreter *lox.Interpreter, reporter lox.Reporter) *lox.Resolver {
 	resolver := lox.NewResolver(interpreter, reporter)
+	resolver.SetWarnings(showWarnings)
+	return resolver
+}
+
+func execute(statements []lox.Stmt, interpreter *lox.Interpreter, reporter lox.Reporter) {
+	resolver := newResolver(interpreter, reporter)
 	resolver.Resolve(statements)
 	if reporter.HadError() {
 		return
@@ -43,29 +86,108 @@ func run(script string, interpreter *lox.Interpreter, reporter lox.Reporter) {
 	interpreter.Interpret(statements)
 }
 
-// Run the interpreter in REPL mode
-func runPrompt(interpreter *lox.Interpreter, reporter lox.Reporter) {
-	s := bufio.NewScanner(os.Stdin)
-	s.Split(bufio.ScanLines)
-	for {
-		fmt.Print("> ")
-		if !s.Scan() {
+// script is the source of a script and the name that it's reported with.
+type script struct {
+	name   string
+	source []byte
+}
+
+// readScripts reads the scripts at the given paths, "-" is the standard input.
+func readScripts(fpaths []string) []script {
+	scripts := make([]script, len(fpaths))
+	for i, fpath := range fpaths {
+		var err error
+		if fpath == "-" {
+			scripts[i].name = "<stdin>"
+			scripts[i].source, err = ioutil.ReadAll(os.Stdin)
+		} else {
+			scripts[i].name = fpath
+			scripts[i].source, err = ioutil.ReadFile(fpath)
+		}
+		exitOnError(err, 1)
+	}
+	return scripts
+}
+
+// Run the given scripts in order and return the exit status. The scripts
+// share the global environment, so the ones that come first can define what
+// the next ones use. None of them is run if any has a compile error, whose
+// report starts with the name of the script if there are several of them.
+// The syntax trees are taken from the cache when possible if one is given.
+func runScripts(scripts []script, interpreter *lox.Interpreter, reporter lox.Reporter, reporting *reporterOptions, cache *lox.ASTCache) int {
+	programs := make([][]lox.Stmt, len(scripts))
+	hadError := false
+	for i, s := range scripts {
+		compileReporter := reporter
+		// the JSON errors have the name of their script in a field instead
+		if len(scripts) > 1 && !reporting.json {
+			compileReporter = newReporter(&prefixWriter{prefix: s.name + ": ", w: os.Stderr}, reporting)
+		}
+		setSource(compileReporter, s.name, s.source, reporting)
+		programs[i] = parseCached(s.source, compileReporter, cache)
+		if !compileReporter.HadError() {
+			newResolver(interpreter, compileReporter).Resolve(programs[i])
+		}
+		summarize(compileReporter)
+		hadError = hadError || compileReporter.HadError()
+	}
+	if hadError {
+		return 65
+	}
+	for i, statements := range programs {
+		setSource(reporter, scripts[i].name, scripts[i].source, reporting)
+		interpreter.Interpret(statements)
+		if reporter.HadRuntimeError() {
 			break
 		}
-		run(s.Text(), interpreter, reporter)
-		reporter.Reset()
 	}
-	exitOnError(s.Err(), 1)
+	return exitStatus(reporter)
 }
 
-// Run the given file as script
-func runFile(fpath string, interpreter *lox.Interpreter, reporter lox.Reporter) {
-	bytes, err := ioutil.ReadFile(fpath)
-	exitOnError(err, 1)
+// exitStatus returns the exit status for the errors that were reported, as
+// in sysexits.h: 65 (EX_DATAERR) if the script has a compile error, i.e. a
+// scan, parse, or resolution error, 70 (EX_SOFTWARE) if it has a runtime
+// error, and 0 otherwise.
+func exitStatus(reporter lox.Reporter) int {
+	if reporter.HadError() {
+		return 65
+	}
+	if reporter.HadRuntimeError() {
+		return 70
+	}
+	return 0
+}
+
+// parseCached parses the source, the syntax tree is taken from the cache when
+// possible if one is given, and stored in it otherwise.
+func parseCached(source []byte, reporter lox.Reporter, cache *lox.ASTCache) []lox.Stmt {
+	if cache != nil {
+		if statements, ok := cache.Load(source); ok {
+			return statements
+		}
+	}
+	statements := parse(source, reporter)
+	if cache != nil && !reporter.HadError() {
+		// failing to cache shouldn't stop the script from running
+		cache.Store(source, statements)
+	}
+	return statements
+}
+
+// stdinIsTerminal returns true if the standard input is a terminal rather
+// than a file or a pipe.
+func stdinIsTerminal() bool {
+	info, err := os.Stdin.Stat()
+	return err != nil || info.Mode()&os.ModeCharDevice != 0
+}
 
-	run(string(bytes), interpreter, reporter)
-	exitIf(reporter.HadError(), 65)
-	exitIf(reporter.HadRuntimeError(), 70)
+func writeHeapProfile(fpath string) {
+	f, err := os.Create(fpath)
+	exitOnError(err, 1)
+	defer f.Close()
+	// get up-to-date statistics
+	runtime.GC()
+	exitOnError(pprof.WriteHeapProfile(f), 1)
 }
 
 func exitOnError(err error, status int) {
@@ -74,9 +196,3 @@ func exitOnError(err error, status int) {
 		os.Exit(status)
 	}
 }
-
-func exitIf(cond bool, status int) {
-	if cond {
-		os.Exit(status)
-	}
-}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Run the "min" subcommand with the given arguments and return the exit
+// status. The script is printed on a single line without comments and
+// without the spaces that aren't needed, and with "-rename" its local
+// variables and parameters get short names. The status is 65 if the script
+// has a compile error, in which case nothing is printed.
+func runMin(args []string) int {
+	flags := flag.NewFlagSet("min", flag.ExitOnError)
+	rename := flags.Bool("rename", false, "give short names to the local variables and parameters")
+	output := flags.String("o", "", "write the minified script to the given file instead of stdout")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox min [flags] file")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+
+	source, err := ioutil.ReadFile(flags.Arg(0))
+	exitOnError(err, 1)
+	reporter := lox.NewSimpleReporter(os.Stderr)
+	tokens := lox.NewScanner(source, reporter).Scan()
+	statements := lox.NewParser(tokens, reporter).Parse()
+	if !reporter.HadError() {
+		interpreter := lox.NewInterpreter(ioutil.Discard, reporter, false)
+		lox.NewResolver(interpreter, reporter).Resolve(statements)
+	}
+	if reporter.HadError() {
+		return 65
+	}
+	var names map[*lox.Token]string
+	if *rename {
+		names = lox.RenameLocals(statements)
+	}
+	minified := lox.Minify(tokens, names)
+
+	if *output == "" {
+		os.Stdout.Write(minified)
+		return 0
+	}
+	exitOnError(ioutil.WriteFile(*output, minified, 0644), 1)
+	return 0
+}
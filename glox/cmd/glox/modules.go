@@ -0,0 +1,42 @@
+package main
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// moduleSearchPath finds the modules in the first of its directories that has
+// them, see -modules.
+type moduleSearchPath []lox.FSResolver
+
+// newModuleSearchPath creates the search path of the directories in the list,
+// which are separated as in PATH, e.g. "lib:vendor/lib" on Unix.
+func newModuleSearchPath(list string) moduleSearchPath {
+	var path moduleSearchPath
+	for _, dir := range filepath.SplitList(list) {
+		if dir != "" {
+			path = append(path, lox.FSResolver{FS: os.DirFS(dir)})
+		}
+	}
+	return path
+}
+
+func (path moduleSearchPath) Resolve(name string) (string, error) {
+	for _, resolver := range path {
+		source, err := resolver.Resolve(name)
+		if !errors.Is(err, fs.ErrNotExist) {
+			return source, err
+		}
+	}
+	return "", &fs.PathError{Op: "open", Path: name + ".lox", Err: fs.ErrNotExist}
+}
+
+// Needs returns the capabilities needed to read the files of the modules, see
+// lox.PrivilegedResolver.
+func (path moduleSearchPath) Needs() lox.Capability {
+	return lox.CapFilesystem
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"plugin"
+	"sort"
+	"strings"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// pluginSymbol is the variable that a plugin exports with the functions that
+// it provides, e.g.
+//
+//	var Natives = map[string]interface{}{"sqrt": math.Sqrt}
+//
+// The functions are registered with lox.Interpreter.RegisterFunc, so a plugin
+// doesn't depend on the interpreter.
+const pluginSymbol = "Natives"
+
+// pluginCapabilitiesSymbol is the variable that a plugin can export with the
+// capabilities that its functions need, as comma-separated names, e.g.
+//
+//	var Capabilities = map[string]string{"readFile": "filesystem"}
+//
+// The functions are then subject to the policy set with -allow and -deny, see
+// lox.Interpreter.RegisterPrivilegedFunc. The functions that aren't listed
+// need no capability, so the policy doesn't restrict them, and a plugin runs
+// with all the access of glox as it's loaded: only the plugins that are
+// trusted should be loaded.
+const pluginCapabilitiesSymbol = "Capabilities"
+
+// loadExtensions registers the native functions of the extensions and of the
+// plugins with the interpreter, both are comma-separated lists, of names and
+// of paths to the .so files built with "go build -buildmode=plugin".
+func loadExtensions(interpreter *lox.Interpreter, extensions, plugins string) error {
+	for _, name := range splitList(extensions) {
+		if err := interpreter.LoadExtension(name); err != nil {
+			return err
+		}
+	}
+	for _, fpath := range splitList(plugins) {
+		if err := loadPlugin(interpreter, fpath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func loadPlugin(interpreter *lox.Interpreter, fpath string) error {
+	p, err := plugin.Open(fpath)
+	if err != nil {
+		return err
+	}
+	sym, err := p.Lookup(pluginSymbol)
+	if err != nil {
+		return err
+	}
+	natives, ok := sym.(*map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%s: %s is a %T rather than a map[string]interface{}", fpath, pluginSymbol, sym)
+	}
+	needs, err := pluginCapabilities(p)
+	if err != nil {
+		return fmt.Errorf("%s: %v", fpath, err)
+	}
+	names := make([]string, 0, len(*natives))
+	for name := range *natives {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// the functions that the policy denies are still defined, calling
+		// them is a runtime error
+		err := interpreter.RegisterPrivilegedFunc(name, needs[name], (*natives)[name])
+		if err != nil && !errors.Is(err, lox.ErrNotAllowed) {
+			return fmt.Errorf("%s: %s: %v", fpath, name, err)
+		}
+	}
+	return nil
+}
+
+// pluginCapabilities returns the capabilities that the functions of the plugin
+// need, by their names, see pluginCapabilitiesSymbol.
+func pluginCapabilities(p *plugin.Plugin) (map[string]lox.Capability, error) {
+	needs := make(map[string]lox.Capability)
+	sym, err := p.Lookup(pluginCapabilitiesSymbol)
+	if err != nil {
+		// declaring the capabilities is optional
+		return needs, nil
+	}
+	names, ok := sym.(*map[string]string)
+	if !ok {
+		return nil, fmt.Errorf("%s is a %T rather than a map[string]string", pluginCapabilitiesSymbol, sym)
+	}
+	for name, list := range *names {
+		caps, err := lox.ParseCapabilities(list)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		needs[name] = caps
+	}
+	return needs, nil
+}
+
+// splitList splits a comma-separated list, leaving out the empty elements.
+func splitList(list string) []string {
+	var elems []string
+	for _, elem := range strings.Split(list, ",") {
+		if elem = strings.TrimSpace(elem); elem != "" {
+			elems = append(elems, elem)
+		}
+	}
+	return elems
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"strings"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+const replHelp = `Commands:
+  :complete text  print the words that the name at the end of the text can be completed to
+  :type expr      evaluate an expression and print the type of its value
+  :time expr      evaluate an expression and print how long it took and the number of calls
+  :echo on|off    print the values of calls and assignments or not, see -echo
+  :load file      run a script in the session
+  :save file      write the code that was run in the session to a script
+  :reset          discard the globals and the history of the session
+  :help           print this help
+`
+
+// repl holds the state of a REPL session.
+type repl struct {
+	interpreter *lox.Interpreter
+	reporter    lox.Reporter
+	reporting   *reporterOptions
+	output      io.Writer
+	// history holds the code that was run without errors, in order, which
+	// ":save" writes. The code that failed is left out, since running the
+	// saved script would stop at it.
+	history []string
+}
+
+// Run the interpreter in REPL mode. A single scanner and parser is used for all
+// the entered lines, so the token buffer is reused instead of being allocated
+// for each line. On a terminal, the lines are edited and colored as they are
+// typed, see lineEditor, and Ctrl-C discards the input. While code is
+// running, Ctrl-C stops it and goes back to the prompt. An input that is
+// incomplete, e.g. a function whose body isn't closed yet, is continued on the
+// next lines with another prompt, and an empty line runs it as it is. Lines
+// that start with ':' are commands of the REPL rather than Lox code, see
+// replHelp. The value of an expression that is entered is printed, even if
+// it's a call or an assignment unless "-echo=false" was given or ":echo off"
+// was entered, and it's kept in the variable "_". A debugger is attached that
+// only pauses when the script calls "breakpoint", the debugger's prompt then
+// reads from the same input.
+// The lines being typed and the values printed are colored if color is set.
+func runPrompt(interpreter *lox.Interpreter, reporter lox.Reporter, reporting *reporterOptions, color bool) {
+	r := &repl{interpreter: interpreter, reporter: reporter, reporting: reporting, output: os.Stdout}
+	// Ctrl-C stops the code that is running rather than the REPL
+	interpreter.SetInterruptible(true)
+	interrupts := make(chan os.Signal, 1)
+	signal.Notify(interrupts, os.Interrupt)
+	defer signal.Stop(interrupts)
+	go func() {
+		for range interrupts {
+			interpreter.Interrupt()
+		}
+	}()
+	scanner := lox.NewScanner(nil, reporter)
+	parser := lox.NewParser(nil, reporter)
+	s := bufio.NewScanner(os.Stdin)
+	s.Split(bufio.ScanLines)
+
+	session := &debugSession{script: "<stdin>", input: s, output: os.Stdout}
+	debugger := lox.NewDebugger(session.pause)
+	debugger.SetAction(lox.DebugContinue)
+	interpreter.SetDebugger(debugger)
+
+	var reader lineReader = &scannerReader{input: s, output: os.Stdout}
+	var editor *lineEditor
+	if isTerminal(os.Stdin.Fd()) && isTerminal(os.Stdout.Fd()) {
+		editor = &lineEditor{input: os.Stdin, output: os.Stdout, complete: interpreter.Complete, color: color}
+		reader = editor
+	}
+	var lines []string
+	for {
+		prompt := "> "
+		if len(lines) > 0 {
+			prompt = "... "
+		}
+		if editor != nil {
+			editor.context = lines
+		}
+		text, err := reader.readLine(prompt)
+		if err == errInterrupted {
+			lines = nil
+			continue
+		}
+		if err == io.EOF {
+			break
+		}
+		exitOnError(err, 1)
+		if len(lines) == 0 && strings.HasPrefix(text, ":") {
+			r.command(text)
+			continue
+		}
+		// an empty line ends the input, whose errors are then reported
+		if len(lines) == 0 || strings.TrimSpace(text) != "" {
+			lines = append(lines, text)
+			if lox.Incomplete([]byte(strings.Join(lines, "\n"))) {
+				continue
+			}
+		}
+		session.lines = lines
+		input := strings.Join(lines, "\n")
+		// the errors of an input don't stop the next ones from running, the
+		// globals that were defined before the error are kept
+		reporter.Reset()
+		setSource(reporter, session.script, []byte(input), reporting)
+		scanner.Reset([]byte(input))
+		parser.Reset(scanner.Scan())
+		statements := parser.Parse()
+		if !reporter.HadError() {
+			execute(statements, interpreter, reporter)
+		}
+		summarize(reporter)
+		if !reporter.HadError() && !reporter.HadRuntimeError() && strings.TrimSpace(input) != "" {
+			r.history = append(r.history, input)
+		}
+		lines = nil
+	}
+}
+
+// command runs a line of the REPL that starts with ':'.
+func (r *repl) command(line string) {
+	cmd, arg := splitCommand(line)
+	switch cmd {
+	case ":complete":
+		// the spaces at the end are kept, "print " completes a new name
+		arg = strings.TrimPrefix(strings.TrimLeft(line, " \t"), cmd)
+		if arg != "" {
+			arg = arg[1:]
+		}
+		for _, word := range r.interpreter.Complete(arg) {
+			fmt.Fprintln(r.output, word)
+		}
+	case ":type":
+		typ, err := r.interpreter.TypeOf(arg)
+		if err != nil {
+			fmt.Fprintln(r.output, err)
+		} else {
+			fmt.Fprintln(r.output, typ)
+		}
+	case ":time":
+		timing, err := r.interpreter.Time(arg)
+		if err != nil {
+			fmt.Fprintln(r.output, err)
+		} else {
+			fmt.Fprintln(r.output, timing.Value)
+			fmt.Fprintf(r.output, "time: %v, calls: %d\n", roundDuration(timing.Elapsed), timing.Calls)
+		}
+	case ":echo":
+		switch arg {
+		case "on":
+			r.interpreter.SetEchoAll(true)
+		case "off":
+			r.interpreter.SetEchoAll(false)
+		default:
+			fmt.Fprintln(r.output, "Usage: :echo on|off")
+		}
+	case ":load":
+		r.load(arg)
+	case ":save":
+		r.save(arg)
+	case ":reset":
+		// the settings given on the command line stay, so the interpreter is
+		// reset rather than replaced
+		r.interpreter.Reset()
+		r.reporter.Reset()
+		r.history = nil
+	case ":h", ":help":
+		fmt.Fprint(r.output, replHelp)
+	default:
+		fmt.Fprintf(r.output, "Unknown command %q, try \":help\".\n", cmd)
+	}
+}
+
+// load runs a script in the session, its globals are then defined for the
+// code entered afterward. The script is kept in the history as it's written,
+// so saving the session doesn't depend on the file.
+func (r *repl) load(fpath string) {
+	if fpath == "" {
+		fmt.Fprintln(r.output, "Usage: :load file")
+		return
+	}
+	source, err := ioutil.ReadFile(fpath)
+	if err != nil {
+		fmt.Fprintln(r.output, err)
+		return
+	}
+	run(fpath, source, r.interpreter, r.reporter, r.reporting)
+	summarize(r.reporter)
+	if !r.reporter.HadError() && !r.reporter.HadRuntimeError() {
+		r.history = append(r.history, fmt.Sprintf("// :load %s\n%s", fpath, strings.TrimRight(string(source), "\n")))
+	}
+	r.reporter.Reset()
+}
+
+// save writes the history to a script, which defines the globals of this
+// session again when it's loaded in a new one.
+func (r *repl) save(fpath string) {
+	if fpath == "" {
+		fmt.Fprintln(r.output, "Usage: :save file")
+		return
+	}
+	var script strings.Builder
+	for _, input := range r.history {
+		script.WriteString(input)
+		script.WriteString("\n")
+	}
+	if err := ioutil.WriteFile(fpath, []byte(script.String()), 0644); err != nil {
+		fmt.Fprintln(r.output, err)
+		return
+	}
+	inputs := "inputs"
+	if len(r.history) == 1 {
+		inputs = "input"
+	}
+	fmt.Fprintf(r.output, "Saved %d %s to %s\n", len(r.history), inputs, fpath)
+}
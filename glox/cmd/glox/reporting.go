@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// reporterOptions sets how the reporters write the errors, it's filled by the
+// flags of the subcommands that report errors.
+type reporterOptions struct {
+	// color and diagnostics are the values of -color and -diagnostics, which
+	// set colored and json once they are checked, see parse.
+	color       string
+	diagnostics string
+	// colored is set if the errors are written with colors.
+	colored bool
+	// json is set if the errors are written as JSON objects.
+	json bool
+	// columns is set if the errors show the columns of their positions, see
+	// -columns.
+	columns bool
+	// snippets is set if the errors show the lines of the source where they
+	// are, see -snippets.
+	snippets bool
+	// stack is set if the runtime errors show the calls that they went
+	// through, see -stack.
+	stack bool
+	// warningsAsErrors is set if the warnings count as errors, see
+	// -warnings-as-errors.
+	warningsAsErrors bool
+	// codes is set if the errors show their codes, see -codes.
+	codes bool
+	// suppressed holds the codes of the warnings that aren't reported, see
+	// -suppress.
+	suppressed []lox.Code
+	// maxErrors is the number of errors and warnings that are written before
+	// the next ones are only counted, see -max-errors.
+	maxErrors int
+}
+
+// addReporterFlags defines -color, -diagnostics, -codes, and -max-errors in the
+// flag set, and returns the options that they set. colorUsage is the usage of
+// -color since it can color more than the errors.
+func addReporterFlags(flags *flag.FlagSet, colorUsage string) *reporterOptions {
+	opts := new(reporterOptions)
+	flags.StringVar(&opts.color, "color", "auto", colorUsage)
+	flags.StringVar(&opts.diagnostics, "diagnostics", "text", "write the errors as text, or as JSON objects with \"json\", one per line")
+	flags.BoolVar(&opts.codes, "codes", false, "show the code of each error, e.g. \"[E0101]\", see \"glox explain\"")
+	flags.IntVar(&opts.maxErrors, "max-errors", 20, "write at most the given number of errors and warnings, followed by how many more there are, 0 for no limit")
+	return opts
+}
+
+// parse checks the values of -color and -diagnostics once the flags are
+// parsed. It returns false after writing why to stderr if one is invalid.
+func (opts *reporterOptions) parse() bool {
+	var ok bool
+	opts.colored, ok = useColor(opts.color, os.Stderr)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Invalid color mode '%s'.\n", opts.color)
+		return false
+	}
+	switch opts.diagnostics {
+	case "text":
+	case "json":
+		opts.json = true
+	default:
+		fmt.Fprintf(os.Stderr, "Invalid diagnostics format '%s'.\n", opts.diagnostics)
+		return false
+	}
+	return true
+}
+
+// newReporter creates a reporter that writes the errors to w the way the
+// options say.
+func newReporter(w io.Writer, opts *reporterOptions) *lox.SimpleReporter {
+	var reporter *lox.SimpleReporter
+	if opts.json {
+		reporter = lox.NewJSONReporter(w).(*lox.SimpleReporter)
+	} else if opts.colored {
+		reporter = lox.NewColorReporter(w).(*lox.SimpleReporter)
+	} else {
+		reporter = lox.NewSimpleReporter(w).(*lox.SimpleReporter)
+	}
+	reporter.ShowColumns(opts.columns)
+	reporter.ShowStack(opts.stack)
+	reporter.SetWarningsAsErrors(opts.warningsAsErrors)
+	reporter.ShowCodes(opts.codes)
+	reporter.Suppress(opts.suppressed...)
+	reporter.SetMaxReported(opts.maxErrors)
+	return reporter
+}
+
+// setSource gives the reporter the name and the source of the script whose
+// errors it reports next, so they show the lines where they are if the
+// options ask for snippets, and the JSON errors have the name of their file.
+func setSource(reporter lox.Reporter, name string, source []byte, opts *reporterOptions) {
+	r, ok := reporter.(*lox.SimpleReporter)
+	if !ok {
+		return
+	}
+	r.SetFile(name)
+	if opts.snippets {
+		r.SetSource(source)
+	}
+}
+
+// summarize writes how many errors the reporter left out because there were
+// more than the maximum, if it did.
+func summarize(reporter lox.Reporter) {
+	if r, ok := reporter.(*lox.SimpleReporter); ok {
+		r.Summarize()
+	}
+}
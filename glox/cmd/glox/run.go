@@ -0,0 +1,263 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime/pprof"
+	"strings"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// usage lists the ways glox is run.
+const usage = `Usage: glox [flags] [-i] [script...] [-- arg...]
+       glox [flags] -e program [-- arg...]
+       glox run [flags] [-i] [script...] [-- arg...]
+       glox repl [flags] [-- arg...]
+       glox fmt [flags] file...
+       glox lint [flags] file...
+       glox check [flags] path...
+       glox ast [flags] file
+       glox tokens [flags] file
+       glox debug script
+       glox test [flags] [path...]
+       glox conformance [flags] [dir]
+       glox compile [flags] script
+       glox highlight [flags] file
+       glox bench [flags] path...
+       glox min [flags] file
+       glox explain [code...]`
+
+// Run the interpreter with the given arguments and return the exit status.
+// The command is "run" to run scripts, which are read from the standard input
+// if none is given, "repl" to start the REPL, or empty when glox is run
+// without a subcommand, which starts the REPL if there's no script and the
+// input is a terminal. Every argument after "--" is given to the scripts.
+func runInterpreter(command string, args []string) int {
+	flags := flag.NewFlagSet("glox", flag.ExitOnError)
+	if command != "" {
+		flags = flag.NewFlagSet(command, flag.ExitOnError)
+	}
+	maxSteps := flags.Int("max-steps", 0, "maximum number of statements and expressions evaluated per run, 0 for no limit")
+	timeout := flags.Duration("timeout", 0, "maximum duration of each run, 0 for no limit")
+	maxObjects := flags.Int("max-objects", 0, "maximum number of objects allocated per run, 0 for no limit")
+	maxStringBytes := flags.Int("max-string-bytes", 0, "maximum number of string bytes allocated per run, 0 for no limit")
+	maxCallDepth := flags.Int("max-call-depth", lox.DefaultMaxCallDepth, "maximum number of nested calls, 0 for no limit")
+	cpuProfile := flags.String("cpuprofile", "", "write a Go CPU profile of the interpreter to the given file")
+	memProfile := flags.String("memprofile", "", "write a Go heap profile of the interpreter to the given file")
+	trace := flags.Bool("trace", false, "log every statement and expression evaluated to stderr")
+	profile := flags.Bool("profile", false, "print the number of calls and time spent in each Lox function after running")
+	astCache := flags.Bool("ast-cache", false, "cache the syntax trees of scripts in the user's cache directory")
+	coverProfile := flags.String("coverprofile", "", "write the lines executed by the script to the given file in the LCOV format")
+	coverHTML := flags.String("coverhtml", "", "write the lines executed by the script to the given file as an HTML page")
+	var program string
+	flags.StringVar(&program, "e", "", "run the given program instead of a script")
+	flags.StringVar(&program, "eval", "", "same as -e")
+	showVersion := flags.Bool("version", false, "print the version of glox and exit")
+	reporting := addReporterFlags(flags, "color the errors and the values printed by the REPL: auto, always, or never")
+	flags.BoolVar(&reporting.columns, "columns", false, "show the columns of the positions of the errors, e.g. \"[line 2:5]\"")
+	flags.BoolVar(&reporting.snippets, "snippets", false, "show the line of the source of each error with the token at fault underlined")
+	flags.BoolVar(&reporting.stack, "stack", false, "show the calls that each runtime error went through, e.g. \"in fib at line 4\"")
+	flags.BoolVar(&showWarnings, "warnings", false, "report the unused local variables, the shadowed variables, the parameters named after fields, and the unreachable code")
+	flags.BoolVar(&reporting.warningsAsErrors, "warnings-as-errors", false, "report the warnings and stop the scripts that have any, with the exit status of a compile error")
+	suppress := flags.String("suppress", "", "don't report the warnings with the given comma-separated codes, e.g. \"W0201,W0202\"")
+	strictVars := flags.Bool("strict-vars", false, "make reading a variable declared without a value, and never assigned, a runtime error instead of nil")
+	echo := flags.Bool("echo", true, "print the values of the calls and the assignments entered in the REPL")
+	interactive := flags.Bool("i", false, "start the REPL after running the scripts, with the globals that they defined")
+	extensions := flags.String("ext", "", "load the native functions of the given comma-separated extensions, one of "+strings.Join(lox.ExtensionNames(), ", "))
+	plugins := flags.String("plugin", "", "load the native functions of the given comma-separated Go plugins, which export them in \"var "+pluginSymbol+" map[string]interface{}\", and their capabilities in \"var "+pluginCapabilitiesSymbol+" map[string]string\"")
+	modules := flags.String("modules", "", "look up the modules imported by the scripts in the given directories, separated as in PATH, instead of the directory of the first script")
+	allow := flags.String("allow", "all", "only let the native functions and import use the given comma-separated capabilities, of filesystem, network, exec, and env, or all")
+	deny := flags.String("deny", "", "don't let the native functions and import use the given comma-separated capabilities, e.g. \"exec,network\"")
+	stopAfter := flags.String("stop-after", "", "run the scripts up to the given stage, one of "+strings.Join(stages, ", ")+", and print its output instead of running them")
+	flags.Usage = func() {
+		switch command {
+		case "run":
+			fmt.Fprintln(flags.Output(), "Usage: glox run [flags] [-i] [script...] [-- arg...]\n       glox run [flags] -e program [-- arg...]")
+		case "repl":
+			fmt.Fprintln(flags.Output(), "Usage: glox repl [flags] [-- arg...]")
+		default:
+			fmt.Fprintln(flags.Output(), usage)
+		}
+		flags.PrintDefaults()
+		fmt.Fprintln(flags.Output(), "The exit status is 64 for a usage error, 65 for a compile error, 70 for a runtime error, and 78 for an invalid "+configName+" file.")
+	}
+	// everything after "--" is given to the script rather than to glox
+	gloxArgs, scriptArgs := args, []string(nil)
+	for i, arg := range gloxArgs {
+		if arg == "--" {
+			gloxArgs, scriptArgs = gloxArgs[:i], gloxArgs[i+1:]
+			break
+		}
+	}
+	// the configuration file sets the defaults of the flags
+	if err := loadConfig(flags); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 78
+	}
+	flags.Parse(gloxArgs)
+	if *showVersion {
+		printVersion(os.Stdout)
+		return 0
+	}
+
+	args = flags.Args()
+	// an empty program is still run rather than starting the REPL
+	hasProgram := false
+	flags.Visit(func(f *flag.Flag) {
+		hasProgram = hasProgram || f.Name == "e" || f.Name == "eval"
+	})
+	// the REPL is only started by "repl" or -i
+	if (hasProgram && len(args) > 0) ||
+		(command == "repl" && (hasProgram || len(args) > 0 || *interactive || *stopAfter != "")) {
+		flags.Usage()
+		return 64
+	}
+	// the lines of a script are only recorded for the script itself, the
+	// ones of the scripts that it's run with would be mixed up with them
+	if len(args) > 1 && (*coverProfile != "" || *coverHTML != "") {
+		fmt.Fprintln(os.Stderr, "Coverage can only be recorded for a single script.")
+		return 64
+	}
+	// the program is read from the standard input when there's no script and
+	// "run" is used, or the input isn't typed on a terminal, e.g. it's piped
+	if len(args) == 0 && !hasProgram && !*interactive && command != "repl" &&
+		(command == "run" || !stdinIsTerminal()) {
+		args = []string{"-"}
+	}
+	isREPL := len(args) == 0 && !hasProgram
+	if *stopAfter != "" && (isREPL || *interactive || !isStage(*stopAfter)) {
+		flags.Usage()
+		return 64
+	}
+
+	if !reporting.parse() {
+		return 64
+	}
+	colorOutput, _ := useColor(reporting.color, os.Stdout)
+	showWarnings = showWarnings || reporting.warningsAsErrors
+	for _, code := range splitList(*suppress) {
+		reporting.suppressed = append(reporting.suppressed, lox.Code(strings.ToUpper(code)))
+	}
+	allowed, err := lox.ParseCapabilities(*allow)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid capabilities '%s'.\n", *allow)
+		return 64
+	}
+	denied, err := lox.ParseCapabilities(*deny)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid capabilities '%s'.\n", *deny)
+		return 64
+	}
+	reporter := newReporter(os.Stderr, reporting)
+	// the modules are looked up in the directories of -modules, or else next
+	// to the first script, or in the current directory if there's no script
+	// file
+	moduleDirs := *modules
+	if moduleDirs == "" {
+		moduleDirs = "."
+		if len(args) > 0 && args[0] != "-" {
+			moduleDirs = filepath.Dir(args[0])
+		}
+	}
+	options := []lox.Option{
+		lox.WithStderr(os.Stderr),
+		lox.WithModules(newModuleSearchPath(moduleDirs)),
+	}
+	if allowed != lox.AllCapabilities || denied != 0 {
+		options = append(options, lox.WithPolicy(lox.Policy{Allow: allowed, Deny: denied}))
+	}
+	// the input is left to the REPL, or to the program when it's read from
+	// there, rather than given to "readLine"
+	if !isREPL && !*interactive && !containsStdin(args) {
+		options = append(options, lox.WithStdin(os.Stdin))
+	}
+	// the REPL prints the values of the expressions that are entered, and
+	// keeps the last one in "_"
+	interpreter := lox.NewInterpreter(os.Stdout, reporter, isREPL, options...)
+	interpreter.SetLimits(*maxSteps, *timeout)
+	interpreter.SetMemoryBudget(*maxObjects, *maxStringBytes)
+	interpreter.SetMaxCallDepth(*maxCallDepth)
+	interpreter.SetArgs(scriptArgs)
+	interpreter.SetStrictVariables(*strictVars)
+	if err := loadExtensions(interpreter, *extensions, *plugins); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if *trace {
+		interpreter.SetTrace(os.Stderr)
+	}
+	var profiler *lox.Profiler
+	if *profile {
+		profiler = lox.NewProfiler()
+		interpreter.SetProfiler(profiler)
+	}
+
+	if *cpuProfile != "" {
+		f, err := os.Create(*cpuProfile)
+		exitOnError(err, 1)
+		exitOnError(pprof.StartCPUProfile(f), 1)
+		interpreter.SetPprofLabels(true)
+	}
+
+	var cache *lox.ASTCache
+	if *astCache {
+		dir, err := lox.DefaultASTCacheDir()
+		exitOnError(err, 1)
+		cache = lox.NewASTCache(dir)
+	}
+
+	status := 0
+	if !isREPL {
+		scripts := []script{{name: "<eval>", source: []byte(program)}}
+		if !hasProgram {
+			scripts = readScripts(args)
+		}
+		if *stopAfter != "" {
+			status = runStages(scripts, *stopAfter)
+		} else {
+			var coverage *lox.Coverage
+			if *coverProfile != "" || *coverHTML != "" {
+				coverage = lox.NewCoverage(scripts[0].name, scripts[0].source)
+				interpreter.SetCoverage(coverage)
+			}
+			status = runScripts(scripts, interpreter, reporter, reporting, cache)
+			if coverage != nil {
+				writeCoverage([]*lox.Coverage{coverage}, *coverProfile, *coverHTML)
+			}
+		}
+	}
+	if isREPL || *interactive {
+		// the scripts run with -i don't print the values of their expressions,
+		// but the lines entered afterward do
+		interpreter.SetREPL(true)
+		reporter.Reset()
+		interpreter.SetColor(colorOutput)
+		interpreter.SetEchoAll(*echo)
+		runPrompt(interpreter, reporter, reporting, colorOutput)
+		status = 0
+	}
+	if profiler != nil {
+		profiler.WriteReport(os.Stderr)
+	}
+	if *cpuProfile != "" {
+		pprof.StopCPUProfile()
+	}
+	if *memProfile != "" {
+		writeHeapProfile(*memProfile)
+	}
+	return status
+}
+
+// containsStdin returns true if one of the scripts is read from the standard
+// input.
+func containsStdin(fpaths []string) bool {
+	for _, fpath := range fpaths {
+		if fpath == "-" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// stages are the stages of the pipeline that "-stop-after" accepts.
+var stages = []string{"scan", "parse", "resolve"}
+
+func isStage(name string) bool {
+	for _, stage := range stages {
+		if stage == name {
+			return true
+		}
+	}
+	return false
+}
+
+// runStages runs the scripts through the pipeline up to the given stage and
+// prints what that stage produces: the tokens of "scan" as a table, the
+// syntax tree of "parse" in a parenthesized form, and the local variables
+// found by "resolve" with the number of scopes to their declarations. The
+// scripts aren't run, and the output of each one is preceded by its name if
+// there are several of them. The exit status is 65 if any of them has an
+// error in the stages that were run.
+func runStages(scripts []script, stage string) int {
+	status := 0
+	for i, s := range scripts {
+		if len(scripts) > 1 {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("==> %s <==\n", s.name)
+		}
+		reporter := lox.NewSimpleReporter(os.Stderr)
+		tokens := lox.NewScanner(s.source, reporter).Scan()
+		switch stage {
+		case "scan":
+			printTokens(os.Stdout, tokens)
+		case "parse":
+			statements := lox.NewParser(tokens, reporter).Parse()
+			if !reporter.HadError() {
+				fmt.Print(lox.NewAstPrinter().PrintStmts(statements))
+			}
+		case "resolve":
+			statements := lox.NewParser(tokens, reporter).Parse()
+			if !reporter.HadError() {
+				// the interpreter is only used to record the resolved scopes
+				interpreter := lox.NewInterpreter(ioutil.Discard, reporter, false)
+				lox.NewResolver(interpreter, reporter).Resolve(statements)
+				printResolutions(interpreter.Resolutions())
+			}
+		}
+		if reporter.HadError() {
+			status = 65
+		}
+	}
+	return status
+}
+
+func printResolutions(resolutions []lox.Resolution) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE:COL\tNAME\tDEPTH")
+	for _, res := range resolutions {
+		fmt.Fprintf(w, "%d:%d\t%s\t%d\n", res.Name.Line, res.Name.Column, res.Name.Lexeme, res.Depth)
+	}
+	exitOnError(w.Flush(), 1)
+}
@@ -0,0 +1,15 @@
+//go:build !darwin && !freebsd && !linux && !netbsd && !openbsd
+// +build !darwin,!freebsd,!linux,!netbsd,!openbsd
+
+package main
+
+import "errors"
+
+// enableRawMode isn't supported, the REPL then reads whole lines.
+func enableRawMode(fd uintptr) (func(), error) {
+	return nil, errors.New("raw mode isn't supported")
+}
+
+func isTerminal(fd uintptr) bool {
+	return false
+}
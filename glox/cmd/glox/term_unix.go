@@ -0,0 +1,44 @@
+//go:build darwin || freebsd || linux || netbsd || openbsd
+// +build darwin freebsd linux netbsd openbsd
+
+package main
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+// enableRawMode puts the terminal in raw mode, where every key is read as soon
+// as it's pressed and nothing is echoed, and returns a function that brings
+// back the previous mode. The output is still processed, so "\n" starts a new
+// line. An error is returned if the file isn't a terminal.
+func enableRawMode(fd uintptr) (func(), error) {
+	var prev syscall.Termios
+	if err := termios(fd, ioctlGetTermios, &prev); err != nil {
+		return nil, err
+	}
+	raw := prev
+	raw.Iflag &^= syscall.IGNBRK | syscall.BRKINT | syscall.PARMRK | syscall.ISTRIP |
+		syscall.INLCR | syscall.IGNCR | syscall.ICRNL | syscall.IXON
+	raw.Lflag &^= syscall.ECHO | syscall.ECHONL | syscall.ICANON | syscall.ISIG | syscall.IEXTEN
+	raw.Cc[syscall.VMIN] = 1
+	raw.Cc[syscall.VTIME] = 0
+	if err := termios(fd, ioctlSetTermios, &raw); err != nil {
+		return nil, err
+	}
+	return func() { termios(fd, ioctlSetTermios, &prev) }, nil
+}
+
+// isTerminal returns true if the file is a terminal.
+func isTerminal(fd uintptr) bool {
+	var t syscall.Termios
+	return termios(fd, ioctlGetTermios, &t) == nil
+}
+
+func termios(fd uintptr, request uintptr, t *syscall.Termios) error {
+	_, _, errno := syscall.Syscall(syscall.SYS_IOCTL, fd, request, uintptr(unsafe.Pointer(t)))
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
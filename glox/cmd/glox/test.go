@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Run the "test" subcommand with the given arguments and return the exit
+// status. Directories are searched for ".lox" files that are tests, i.e. whose
+// name ends with "_test.lox" or that have "// expect" comments, and files that
+// are named explicitly are always run. The status is 1 if any test failed.
+func runTest(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	verbose := flags.Bool("v", false, "list every test that is run, not only the failed ones")
+	timeout := flags.Duration("timeout", 10*time.Second, "maximum duration of each test, 0 for no limit")
+	coverProfile := flags.String("coverprofile", "", "write the lines executed by the tests to the given file in the LCOV format")
+	coverHTML := flags.String("coverhtml", "", "write the lines executed by the tests to the given file as an HTML page")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox test [flags] [path...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	roots := flags.Args()
+	if len(roots) == 0 {
+		roots = []string{"."}
+	}
+
+	passed, failed := 0, 0
+	var coverages []*lox.Coverage
+	for _, root := range roots {
+		err := filepath.Walk(root, func(fpath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() || (fpath != root && filepath.Ext(fpath) != ".lox") {
+				return nil
+			}
+			source, err := ioutil.ReadFile(fpath)
+			if err != nil {
+				return err
+			}
+			if fpath != root && !lox.IsScriptTest(fpath, source) {
+				return nil
+			}
+			test := lox.NewScriptTest(fpath, source)
+			test.Cover = *coverProfile != "" || *coverHTML != ""
+			result := runTestFile(test, *timeout, *verbose)
+			if result.Passed() {
+				passed++
+			} else {
+				failed++
+			}
+			if result.Coverage != nil {
+				coverages = append(coverages, result.Coverage)
+			}
+			return nil
+		})
+		exitOnError(err, 1)
+	}
+	writeCoverage(coverages, *coverProfile, *coverHTML)
+
+	fmt.Printf("%d passed, %d failed\n", passed, failed)
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
+// Run a single test and print its result. Failed tests are followed by the
+// reasons of the failure and the difference between the expected and the
+// actual output and errors.
+func runTestFile(test *lox.ScriptTest, timeout time.Duration, verbose bool) *lox.ScriptTestResult {
+	result := test.Run(timeout)
+	if result.Passed() {
+		if verbose {
+			fmt.Printf("PASS %s\n", test.Name)
+		}
+		return result
+	}
+
+	fmt.Printf("FAIL %s\n", test.Name)
+	for _, failure := range result.Failures {
+		fmt.Printf("    %s\n", failure)
+	}
+	indented := &prefixWriter{prefix: "    ", w: os.Stdout}
+	if expected, actual := joinLines(test.Output), joinLines(result.Output); !bytes.Equal(expected, actual) {
+		fmt.Fprintln(indented, "--- expected output\n+++ actual output")
+		writeDiff(indented, expected, actual)
+	}
+	if expected, actual := joinLines(test.Errors), joinLines(result.Errors); !bytes.Equal(expected, actual) {
+		fmt.Fprintln(indented, "--- expected errors\n+++ actual errors")
+		writeDiff(indented, expected, actual)
+	}
+	return result
+}
+
+func joinLines(lines []string) []byte {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	return []byte(b.String())
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"text/tabwriter"
+
+	"github.com/letung3105/lox/glox/internal/lox"
+)
+
+// Run the "tokens" subcommand with the given arguments and return the exit
+// status. The tokens scanned from the file are printed as a table, or as a
+// JSON array with "-json". Scan errors are reported, but the tokens that were
+// found are still printed.
+func runTokens(args []string) int {
+	flags := flag.NewFlagSet("tokens", flag.ExitOnError)
+	asJSON := flags.Bool("json", false, "print the tokens as a JSON array")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Usage: glox tokens [flags] file")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 64
+	}
+
+	source, err := ioutil.ReadFile(flags.Arg(0))
+	exitOnError(err, 1)
+	reporter := lox.NewSimpleReporter(os.Stderr)
+	tokens := lox.NewScanner(source, reporter).Scan()
+
+	if *asJSON {
+		data, err := json.Marshal(tokens)
+		exitOnError(err, 1)
+		fmt.Println(string(data))
+	} else {
+		printTokens(os.Stdout, tokens)
+	}
+	if reporter.HadError() {
+		return 65
+	}
+	return 0
+}
+
+// printTokens writes the tokens as a table, with their position, type, lexeme,
+// and literal value.
+func printTokens(out io.Writer, tokens []*lox.Token) {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "LINE:COL\tTYPE\tLEXEME\tLITERAL")
+	for _, tok := range tokens {
+		literal := ""
+		switch v := tok.Literal.(type) {
+		case string:
+			literal = fmt.Sprintf("%q", v)
+		case float64:
+			literal = fmt.Sprint(v)
+		}
+		fmt.Fprintf(w, "%d:%d\t%s\t%q\t%s\n", tok.Line, tok.Column, tok.Type.String(), tok.Lexeme, literal)
+	}
+	exitOnError(w.Flush(), 1)
+}
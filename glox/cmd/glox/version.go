@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/debug"
+)
+
+// version and commit can be set when glox is built, e.g. with
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse --short HEAD)"
+//
+// the version of the module is used if no version is given, which is known
+// when glox is installed with "go install".
+var (
+	version string
+	commit  string
+)
+
+// printVersion writes the version of glox, the commit that it was built from,
+// and the version of Go that built it.
+func printVersion(w io.Writer) {
+	v := version
+	if v == "" {
+		v = "(devel)"
+		if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" {
+			v = info.Main.Version
+		}
+	}
+	c := commit
+	if c == "" {
+		c = "unknown"
+	}
+	fmt.Fprintf(w, "glox %s (commit %s) %s %s/%s\n", v, c, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+}
@@ -23,6 +23,9 @@ func main() {
 		// Call stores the token for the closing parenthesis so the token's location
 		// can be used when we report RuntimeError caused by a function call.
 		"Call: Callee Expr, Paren *Token, Args []Expr",
+		// Error stands for an expression that couldn't be parsed, at the token
+		// where it was expected, see Parser.SetTolerant.
+		"Error: Token *Token",
 		"Get: Obj Expr, Name *Token",
 		"Group: Expr Expr",
 		"Literal: Val interface{}",
@@ -36,13 +39,18 @@ func main() {
 	statementTypes := []string{
 		"Block: Stmts []Stmt",
 		"Class: Name *Token, Super *VarExpr, Methods []*FunctionStmt",
+		// Error stands for a statement that couldn't be parsed, and holds the
+		// tokens that were skipped, see Parser.SetTolerant.
+		"Error: Tokens []*Token",
 		"Expr: Expr Expr",
 		"Function: Name *Token, Params []*Token, Body []Stmt",
-		"If: Cond Expr, ThenBranch Stmt, ElseBranch Stmt",
-		"Print: Expr Expr",
+		"If: Keyword *Token, Cond Expr, ThenBranch Stmt, ElseBranch Stmt",
+		"Print: Keyword *Token, Expr Expr",
 		"Return: Keyword *Token, Val Expr",
 		"Var: Name *Token, Init Expr",
-		"While: Cond Expr, Body Stmt",
+		// While stores the "while" or "for" keyword, so every statement can be
+		// located even when its expressions don't hold any token.
+		"While: Keyword *Token, Cond Expr, Body Stmt",
 	}
 
 	defineAst(outputDir, "Expr", expressionTypes)
@@ -72,6 +80,9 @@ func defineAst(outputDir string, baseName string, types []string) {
 	// Interface for Expr in AST
 	fmt.Fprintf(writer, "type %s interface {\n", baseName)
 	fmt.Fprintf(writer, "\tAccept(visitor %sVisitor) (interface{}, error)\n", baseName)
+	// every node embeds a Span, which gives it these methods
+	fmt.Fprintf(writer, "\tspan() *Span\n")
+	fmt.Fprintf(writer, "\tsetSpan(start, end *Token)\n")
 	fmt.Fprintf(writer, "}\n")
 
 	defineVisitor(writer, baseName, types)
@@ -117,6 +128,8 @@ func defineType(
 	for _, f := range fields {
 		fmt.Fprintf(writer, "\t%s\n", f)
 	}
+	// the region of the source that the node was parsed from
+	fmt.Fprintf(writer, "\tSpan\n")
 	fmt.Fprintf(writer, "}\n")
 
 	// Constructor
@@ -127,11 +140,12 @@ func defineType(
 		fieldList,
 		typeName, baseName,
 	)
+	// the fields are keyed since the span is left out, it's set by the parser
 	var fieldNames []string
 	for _, f := range strings.Split(fieldList, ",") {
 		field := strings.TrimSpace(f)
 		fieldName := strings.TrimSpace(strings.Split(field, " ")[0])
-		fieldNames = append(fieldNames, fieldName)
+		fieldNames = append(fieldNames, fieldName+": "+fieldName)
 	}
 	fmt.Fprintf(
 		writer,
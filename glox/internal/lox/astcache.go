@@ -0,0 +1,99 @@
+package lox
+
+import (
+	"crypto/sha256"
+	"encoding/gob"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// astCacheVersion is mixed into every key, it must be changed whenever the
+// syntax tree changes shape so old entries are never decoded.
+const astCacheVersion = "glox-ast-6"
+
+func init() {
+	for _, node := range []interface{}{
+		new(AssignExpr), new(BinaryExpr), new(CallExpr), new(GetExpr),
+		new(GroupExpr), new(LiteralExpr), new(LogicalExpr), new(SetExpr),
+		new(SuperExpr), new(ThisExpr), new(UnaryExpr), new(VarExpr),
+		new(ErrorExpr), new(BlockStmt), new(ClassStmt), new(ExprStmt),
+		new(FunctionStmt), new(IfStmt), new(PrintStmt), new(ReturnStmt),
+		new(VarStmt), new(WhileStmt), new(ErrorStmt),
+	} {
+		gob.Register(node)
+	}
+}
+
+// ASTCache stores parsed syntax trees on disk, keyed by the hash of the source
+// they were parsed from. A script whose content hasn't changed can then skip
+// scanning and parsing. The variables still have to be resolved after loading
+// a syntax tree, since the resolved locals belong to each interpreter.
+type ASTCache struct {
+	dir string
+}
+
+// NewASTCache creates a cache that keeps its entries in the given directory.
+func NewASTCache(dir string) *ASTCache {
+	c := new(ASTCache)
+	c.dir = dir
+	return c
+}
+
+// DefaultASTCacheDir returns the directory for the cache within the user's
+// cache directory.
+func DefaultASTCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "glox", "ast"), nil
+}
+
+// Load returns the syntax tree that was stored for the given source, the
+// second value is false if there's no usable entry.
+func (c *ASTCache) Load(source []byte) ([]Stmt, bool) {
+	f, err := os.Open(c.path(source))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	var stmts []Stmt
+	if err := gob.NewDecoder(f).Decode(&stmts); err != nil {
+		return nil, false
+	}
+	return stmts, true
+}
+
+// Store saves the syntax tree of the given source. Only syntax trees that
+// were parsed without errors should be stored.
+func (c *ASTCache) Store(source []byte, stmts []Stmt) error {
+	if err := os.MkdirAll(c.dir, 0755); err != nil {
+		return err
+	}
+	// write to a temporary file first so a concurrent Load never sees a
+	// partially written entry
+	f, err := ioutil.TempFile(c.dir, "tmp-*")
+	if err != nil {
+		return err
+	}
+	if err := gob.NewEncoder(f).Encode(stmts); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return err
+	}
+	return os.Rename(f.Name(), c.path(source))
+}
+
+func (c *ASTCache) path(source []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(astCacheVersion))
+	hash.Write(source)
+	return filepath.Join(c.dir, hex.EncodeToString(hash.Sum(nil))+".gob")
+}
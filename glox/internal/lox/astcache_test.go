@@ -0,0 +1,48 @@
+package lox
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestASTCacheRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+	dir, err := ioutil.TempDir("", "glox-ast-cache")
+	assert.NoError(err)
+	defer os.RemoveAll(dir)
+
+	script := []byte(`
+class Greeter {
+	init(name) { this.name = name; }
+	greet() { print "Hello, " + this.name + "!"; }
+}
+class Loud < Greeter {
+	greet() { super.greet(); print "!!!"; }
+}
+for (var i = 0; i < 2; i = i + 1) Loud("world").greet();
+print -1.5 * (2 - 1) or nil;
+`)
+	cache := NewASTCache(dir)
+	_, ok := cache.Load(script)
+	assert.False(ok)
+
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false)
+	assert.NoError(cache.Store(script, parseScript(t, in, string(script))))
+	stmts, ok := cache.Load(script)
+	assert.True(ok)
+
+	var out, errs strings.Builder
+	reporter := NewSimpleReporter(&errs)
+	in = NewInterpreter(&out, reporter, false)
+	NewResolver(in, reporter).Resolve(stmts)
+	in.Interpret(stmts)
+	assert.Equal("Hello, world!\n!!!\nHello, world!\n!!!\n-1.5\n", out.String())
+	assert.Empty(errs.String())
+
+	_, ok = cache.Load(append(script, ' '))
+	assert.False(ok)
+}
@@ -0,0 +1,352 @@
+package lox
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// MarshalAST encodes the statements as JSON. Every node is an object whose
+// "node" field holds the name of its Go type, e.g. "BinaryExpr", and whose
+// other fields are named after the fields of that type in lower camel case.
+// Tokens are objects with "type", "lexeme", "literal", "line", and "column"
+// fields, so the position of every part of the tree is kept. The nodes whose
+// span is known have a "span" field with its "start" and "end" positions.
+// Missing optional children, like an "else" branch, are encoded as null.
+//
+// As an example, "print -1;" is encoded as:
+//
+//	[{"node":"PrintStmt","expr":{"node":"UnaryExpr","op":{"type":"-",...},
+//	  "expr":{"node":"LiteralExpr","val":1}}}]
+func MarshalAST(statements []Stmt) ([]byte, error) {
+	nodes := make([]interface{}, len(statements))
+	for i, stmt := range statements {
+		nodes[i] = encodeStmt(stmt)
+	}
+	return json.Marshal(nodes)
+}
+
+// UnmarshalAST decodes statements that were encoded with MarshalAST. The
+// returned statements must be resolved before they can be interpreted.
+func UnmarshalAST(data []byte) ([]Stmt, error) {
+	var nodes []json.RawMessage
+	if err := json.Unmarshal(data, &nodes); err != nil {
+		return nil, err
+	}
+	return decodeStmts(nodes)
+}
+
+// jsonNode is an object in the encoded tree
+type jsonNode = map[string]interface{}
+
+func encodeStmt(stmt Stmt) interface{} {
+	if stmt == nil {
+		return nil
+	}
+	return encodeSpan(encodeStmtNode(stmt), *stmt.span())
+}
+
+func encodeStmtNode(stmt Stmt) jsonNode {
+	switch stmt := stmt.(type) {
+	case *BlockStmt:
+		return jsonNode{"node": "BlockStmt", "stmts": encodeStmts(stmt.Stmts)}
+	case *ClassStmt:
+		methods := make([]interface{}, len(stmt.Methods))
+		for i, method := range stmt.Methods {
+			methods[i] = encodeStmt(method)
+		}
+		var super interface{}
+		if stmt.Super != nil {
+			super = encodeExpr(stmt.Super)
+		}
+		return jsonNode{"node": "ClassStmt", "name": stmt.Name, "super": super, "methods": methods}
+	case *ErrorStmt:
+		return jsonNode{"node": "ErrorStmt", "tokens": stmt.Tokens}
+	case *ExprStmt:
+		return jsonNode{"node": "ExprStmt", "expr": encodeExpr(stmt.Expr)}
+	case *FunctionStmt:
+		return jsonNode{"node": "FunctionStmt", "name": stmt.Name, "params": stmt.Params, "body": encodeStmts(stmt.Body)}
+	case *IfStmt:
+		return jsonNode{"node": "IfStmt", "keyword": stmt.Keyword, "cond": encodeExpr(stmt.Cond), "thenBranch": encodeStmt(stmt.ThenBranch), "elseBranch": encodeStmt(stmt.ElseBranch)}
+	case *PrintStmt:
+		return jsonNode{"node": "PrintStmt", "keyword": stmt.Keyword, "expr": encodeExpr(stmt.Expr)}
+	case *ReturnStmt:
+		return jsonNode{"node": "ReturnStmt", "keyword": stmt.Keyword, "val": encodeExpr(stmt.Val)}
+	case *VarStmt:
+		return jsonNode{"node": "VarStmt", "name": stmt.Name, "init": encodeExpr(stmt.Init)}
+	case *WhileStmt:
+		return jsonNode{"node": "WhileStmt", "keyword": stmt.Keyword, "cond": encodeExpr(stmt.Cond), "body": encodeStmt(stmt.Body)}
+	}
+	panic(fmt.Sprintf("unexpected statement %T", stmt))
+}
+
+func encodeStmts(statements []Stmt) []interface{} {
+	nodes := make([]interface{}, len(statements))
+	for i, stmt := range statements {
+		nodes[i] = encodeStmt(stmt)
+	}
+	return nodes
+}
+
+func encodeExpr(expr Expr) interface{} {
+	if expr == nil {
+		return nil
+	}
+	return encodeSpan(encodeExprNode(expr), *expr.span())
+}
+
+// encodeSpan adds the span to the node if it's known.
+func encodeSpan(node jsonNode, span Span) jsonNode {
+	if !span.IsZero() {
+		node["span"] = span
+	}
+	return node
+}
+
+func encodeExprNode(expr Expr) jsonNode {
+	switch expr := expr.(type) {
+	case *AssignExpr:
+		return jsonNode{"node": "AssignExpr", "name": expr.Name, "val": encodeExpr(expr.Val)}
+	case *BinaryExpr:
+		return jsonNode{"node": "BinaryExpr", "op": expr.Op, "lhs": encodeExpr(expr.Lhs), "rhs": encodeExpr(expr.Rhs)}
+	case *CallExpr:
+		args := make([]interface{}, len(expr.Args))
+		for i, arg := range expr.Args {
+			args[i] = encodeExpr(arg)
+		}
+		return jsonNode{"node": "CallExpr", "callee": encodeExpr(expr.Callee), "paren": expr.Paren, "args": args}
+	case *ErrorExpr:
+		return jsonNode{"node": "ErrorExpr", "token": expr.Token}
+	case *GetExpr:
+		return jsonNode{"node": "GetExpr", "obj": encodeExpr(expr.Obj), "name": expr.Name}
+	case *GroupExpr:
+		return jsonNode{"node": "GroupExpr", "expr": encodeExpr(expr.Expr)}
+	case *LiteralExpr:
+		return jsonNode{"node": "LiteralExpr", "val": expr.Val}
+	case *LogicalExpr:
+		return jsonNode{"node": "LogicalExpr", "op": expr.Op, "lhs": encodeExpr(expr.Lhs), "rhs": encodeExpr(expr.Rhs)}
+	case *SetExpr:
+		return jsonNode{"node": "SetExpr", "obj": encodeExpr(expr.Obj), "name": expr.Name, "val": encodeExpr(expr.Val)}
+	case *SuperExpr:
+		return jsonNode{"node": "SuperExpr", "keyword": expr.Keyword, "method": expr.Method}
+	case *ThisExpr:
+		return jsonNode{"node": "ThisExpr", "keyword": expr.Keyword}
+	case *UnaryExpr:
+		return jsonNode{"node": "UnaryExpr", "op": expr.Op, "expr": encodeExpr(expr.Expr)}
+	case *VarExpr:
+		return jsonNode{"node": "VarExpr", "name": expr.Name}
+	}
+	panic(fmt.Sprintf("unexpected expression %T", expr))
+}
+
+// jsonDecoder decodes the fields of a single node, the first error that is
+// found is kept and every later call is a no-op.
+type jsonDecoder struct {
+	fields map[string]json.RawMessage
+	err    error
+}
+
+func newJSONDecoder(data json.RawMessage) (*jsonDecoder, string) {
+	d := new(jsonDecoder)
+	d.err = json.Unmarshal(data, &d.fields)
+	var node string
+	if d.err == nil {
+		d.err = json.Unmarshal(d.fields["node"], &node)
+	}
+	return d, node
+}
+
+func (d *jsonDecoder) isNull(field string) bool {
+	raw, ok := d.fields[field]
+	return !ok || string(raw) == "null"
+}
+
+// value decodes the field into v, v is left untouched if the field is missing
+func (d *jsonDecoder) value(field string, v interface{}) {
+	if raw, ok := d.fields[field]; ok && d.err == nil {
+		if d.err = json.Unmarshal(raw, v); d.err != nil {
+			d.err = fmt.Errorf("field %q: %w", field, d.err)
+		}
+	}
+}
+
+func (d *jsonDecoder) token(field string) *Token {
+	var tok *Token
+	d.value(field, &tok)
+	if d.err == nil && tok == nil {
+		d.err = fmt.Errorf("field %q: missing token", field)
+	}
+	return tok
+}
+
+func (d *jsonDecoder) expr(field string) Expr {
+	if d.err == nil && d.isNull(field) {
+		d.err = fmt.Errorf("field %q: missing expression", field)
+	}
+	return d.optExpr(field)
+}
+
+// optExpr decodes an expression that can be null
+func (d *jsonDecoder) optExpr(field string) Expr {
+	if d.err != nil || d.isNull(field) {
+		return nil
+	}
+	var expr Expr
+	expr, d.err = decodeExpr(d.fields[field])
+	return expr
+}
+
+func (d *jsonDecoder) stmt(field string) Stmt {
+	if d.err == nil && d.isNull(field) {
+		d.err = fmt.Errorf("field %q: missing statement", field)
+	}
+	return d.optStmt(field)
+}
+
+// optStmt decodes a statement that can be null
+func (d *jsonDecoder) optStmt(field string) Stmt {
+	if d.err != nil || d.isNull(field) {
+		return nil
+	}
+	var stmt Stmt
+	stmt, d.err = decodeStmt(d.fields[field])
+	return stmt
+}
+
+func (d *jsonDecoder) stmts(field string) []Stmt {
+	var nodes []json.RawMessage
+	d.value(field, &nodes)
+	if d.err != nil {
+		return nil
+	}
+	var stmts []Stmt
+	stmts, d.err = decodeStmts(nodes)
+	return stmts
+}
+
+func decodeStmts(nodes []json.RawMessage) ([]Stmt, error) {
+	statements := make([]Stmt, len(nodes))
+	for i, node := range nodes {
+		stmt, err := decodeStmt(node)
+		if err != nil {
+			return nil, err
+		}
+		statements[i] = stmt
+	}
+	return statements, nil
+}
+
+func decodeStmt(data json.RawMessage) (Stmt, error) {
+	d, node := newJSONDecoder(data)
+	if d.err != nil {
+		return nil, d.err
+	}
+	var stmt Stmt
+	switch node {
+	case "BlockStmt":
+		stmt = NewBlockStmt(d.stmts("stmts"))
+	case "ClassStmt":
+		name := d.token("name")
+		var super *VarExpr
+		if expr := d.optExpr("super"); expr != nil {
+			var ok bool
+			if super, ok = expr.(*VarExpr); !ok && d.err == nil {
+				d.err = fmt.Errorf("field \"super\": expected a VarExpr")
+			}
+		}
+		var methods []*FunctionStmt
+		for _, s := range d.stmts("methods") {
+			method, ok := s.(*FunctionStmt)
+			if !ok {
+				d.err = fmt.Errorf("field \"methods\": expected a FunctionStmt")
+				break
+			}
+			methods = append(methods, method)
+		}
+		stmt = NewClassStmt(name, super, methods)
+	case "ErrorStmt":
+		var tokens []*Token
+		d.value("tokens", &tokens)
+		stmt = NewErrorStmt(tokens)
+	case "ExprStmt":
+		stmt = NewExprStmt(d.expr("expr"))
+	case "FunctionStmt":
+		var params []*Token
+		d.value("params", &params)
+		stmt = NewFunctionStmt(d.token("name"), params, d.stmts("body"))
+	case "IfStmt":
+		stmt = NewIfStmt(d.token("keyword"), d.expr("cond"), d.stmt("thenBranch"), d.optStmt("elseBranch"))
+	case "PrintStmt":
+		stmt = NewPrintStmt(d.token("keyword"), d.expr("expr"))
+	case "ReturnStmt":
+		stmt = NewReturnStmt(d.token("keyword"), d.optExpr("val"))
+	case "VarStmt":
+		stmt = NewVarStmt(d.token("name"), d.optExpr("init"))
+	case "WhileStmt":
+		stmt = NewWhileStmt(d.token("keyword"), d.expr("cond"), d.stmt("body"))
+	default:
+		return nil, fmt.Errorf("unknown statement %q", node)
+	}
+	d.value("span", stmt.span())
+	if d.err != nil {
+		return nil, fmt.Errorf("%s: %w", node, d.err)
+	}
+	return stmt, nil
+}
+
+func decodeExpr(data json.RawMessage) (Expr, error) {
+	d, node := newJSONDecoder(data)
+	if d.err != nil {
+		return nil, d.err
+	}
+	var expr Expr
+	switch node {
+	case "AssignExpr":
+		expr = NewAssignExpr(d.token("name"), d.expr("val"))
+	case "BinaryExpr":
+		expr = NewBinaryExpr(d.token("op"), d.expr("lhs"), d.expr("rhs"))
+	case "CallExpr":
+		callee := d.expr("callee")
+		paren := d.token("paren")
+		var nodes []json.RawMessage
+		d.value("args", &nodes)
+		var args []Expr
+		for _, node := range nodes {
+			if d.err != nil {
+				break
+			}
+			var arg Expr
+			arg, d.err = decodeExpr(node)
+			args = append(args, arg)
+		}
+		expr = NewCallExpr(callee, paren, args)
+	case "ErrorExpr":
+		expr = NewErrorExpr(d.token("token"))
+	case "GetExpr":
+		expr = NewGetExpr(d.expr("obj"), d.token("name"))
+	case "GroupExpr":
+		expr = NewGroupExpr(d.expr("expr"))
+	case "LiteralExpr":
+		var val interface{}
+		d.value("val", &val)
+		expr = NewLiteralExpr(val)
+	case "LogicalExpr":
+		expr = NewLogicalExpr(d.token("op"), d.expr("lhs"), d.expr("rhs"))
+	case "SetExpr":
+		expr = NewSetExpr(d.expr("obj"), d.token("name"), d.expr("val"))
+	case "SuperExpr":
+		expr = NewSuperExpr(d.token("keyword"), d.token("method"))
+	case "ThisExpr":
+		expr = NewThisExpr(d.token("keyword"))
+	case "UnaryExpr":
+		expr = NewUnaryExpr(d.token("op"), d.expr("expr"))
+	case "VarExpr":
+		expr = NewVarExpr(d.token("name"))
+	default:
+		return nil, fmt.Errorf("unknown expression %q", node)
+	}
+	d.value("span", expr.span())
+	if d.err != nil {
+		return nil, fmt.Errorf("%s: %w", node, d.err)
+	}
+	return expr, nil
+}
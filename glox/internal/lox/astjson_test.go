@@ -0,0 +1,100 @@
+package lox
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const astJSONScript = `
+class A { init(x) { this.x = x; } get() { return this.x; } }
+class B < A { get() { return "b" + super.get(); } }
+fun twice(f, x) { return f(f(x)); }
+fun id(x) { return x; }
+var b = B("!");
+for (var i = 0; i < 2 and !false; i = i + 1) {
+	if (i == 0) print b.get(); else print -i;
+}
+print twice(id, nil) == nil or true;
+`
+
+func TestASTJSONRoundTrip(t *testing.T) {
+	assert := assert.New(t)
+
+	reporter := NewSimpleReporter(ioutil.Discard)
+	tokens := NewScanner([]byte(astJSONScript), reporter).Scan()
+	stmts := NewParser(tokens, reporter).Parse()
+	assert.False(reporter.HadError())
+
+	data, err := MarshalAST(stmts)
+	assert.NoError(err)
+	decoded, err := UnmarshalAST(data)
+	assert.NoError(err)
+
+	printer := NewAstPrinter()
+	assert.Equal(printer.PrintStmts(stmts), printer.PrintStmts(decoded))
+	again, err := MarshalAST(decoded)
+	assert.NoError(err)
+	assert.JSONEq(string(data), string(again))
+
+	// the decoded tree can be resolved and run
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	NewResolver(in, NewSimpleReporter(&errs)).Resolve(decoded)
+	in.Interpret(decoded)
+	assert.Equal("b!\n-1\ntrue\n", out.String())
+	assert.Empty(errs.String())
+}
+
+func TestASTJSONFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	tokens := NewScanner([]byte("print -1;"), NewSimpleReporter(ioutil.Discard)).Scan()
+	data, err := MarshalAST(NewParser(tokens, NewSimpleReporter(ioutil.Discard)).Parse())
+	assert.NoError(err)
+	assert.JSONEq(`[{
+		"node": "PrintStmt",
+		"keyword": {"type": "PRINT", "lexeme": "print", "literal": null, "line": 1, "column": 1, "offset": 0},
+		"expr": {
+			"node": "UnaryExpr",
+			"op": {"type": "-", "lexeme": "-", "literal": null, "line": 1, "column": 7, "offset": 6},
+			"expr": {
+				"node": "LiteralExpr",
+				"val": 1,
+				"span": {"start": {"line": 1, "column": 8, "offset": 7}, "end": {"line": 1, "column": 9, "offset": 8}}
+			},
+			"span": {"start": {"line": 1, "column": 7, "offset": 6}, "end": {"line": 1, "column": 9, "offset": 8}}
+		},
+		"span": {"start": {"line": 1, "column": 1, "offset": 0}, "end": {"line": 1, "column": 10, "offset": 9}}
+	}]`, string(data))
+}
+
+func TestASTJSONErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := UnmarshalAST([]byte(`[{"node": "GotoStmt"}]`))
+	assert.EqualError(err, `unknown statement "GotoStmt"`)
+	_, err = UnmarshalAST([]byte(`[{"node": "ExprStmt", "expr": {"node": "VarExpr"}}]`))
+	assert.EqualError(err, `ExprStmt: VarExpr: field "name": missing token`)
+	_, err = UnmarshalAST([]byte(`[{"node": "ExprStmt", "expr": {"node": "VarExpr", "name": {"type": "?"}}}]`))
+	assert.EqualError(err, `ExprStmt: VarExpr: field "name": unknown token type "?"`)
+	_, err = UnmarshalAST([]byte(`[{"node": "ExprStmt", "expr": null}]`))
+	assert.EqualError(err, `ExprStmt: field "expr": missing expression`)
+	_, err = UnmarshalAST([]byte(`[{"node": "ExprStmt", "expr": {"node": "ErrorExpr"}}]`))
+	assert.EqualError(err, `ExprStmt: ErrorExpr: field "token": missing token`)
+}
+
+func TestASTJSONErrorNodes(t *testing.T) {
+	assert := assert.New(t)
+
+	stmts, _ := ParsePartial([]byte("var = 1;\nprint 1 +;"))
+	data, err := MarshalAST(stmts)
+	assert.NoError(err)
+	decoded, err := UnmarshalAST(data)
+	assert.NoError(err)
+	printer := NewAstPrinter()
+	assert.Equal("(error var = 1 ;)\n(print (+ 1 (error)))\n", printer.PrintStmts(decoded))
+	assert.Equal(stmts[1].span(), decoded[1].span())
+}
@@ -0,0 +1,231 @@
+package lox
+
+import (
+	"strconv"
+	"strings"
+)
+
+// AstPrinter renders syntax trees in a parenthesized, Lisp-like form where
+// every node is written as its operator followed by its operands, e.g. the
+// expression "-123 * (45.67)" is rendered as "(* (- 123) (group 45.67))". The
+// nesting of the output makes the precedence and associativity chosen by the
+// parser explicit.
+type AstPrinter struct {
+	builder strings.Builder
+}
+
+func NewAstPrinter() *AstPrinter {
+	return new(AstPrinter)
+}
+
+// Print renders a single expression.
+func (p *AstPrinter) Print(expr Expr) string {
+	p.builder.Reset()
+	p.expr(expr)
+	return p.builder.String()
+}
+
+// PrintStmts renders the statements, one top-level statement per line.
+func (p *AstPrinter) PrintStmts(statements []Stmt) string {
+	p.builder.Reset()
+	for _, stmt := range statements {
+		p.stmt(stmt)
+		p.builder.WriteByte('\n')
+	}
+	return p.builder.String()
+}
+
+func (p *AstPrinter) VisitBlockStmt(stmt *BlockStmt) (interface{}, error) {
+	p.builder.WriteString("(block")
+	for _, inner := range stmt.Stmts {
+		p.builder.WriteByte(' ')
+		p.stmt(inner)
+	}
+	p.builder.WriteByte(')')
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitClassStmt(stmt *ClassStmt) (interface{}, error) {
+	p.builder.WriteString("(class ")
+	p.builder.WriteString(stmt.Name.Lexeme)
+	if stmt.Super != nil {
+		p.builder.WriteString(" < ")
+		p.builder.WriteString(stmt.Super.Name.Lexeme)
+	}
+	for _, method := range stmt.Methods {
+		p.builder.WriteByte(' ')
+		p.function("method", method)
+	}
+	p.builder.WriteByte(')')
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitErrorStmt(stmt *ErrorStmt) (interface{}, error) {
+	p.builder.WriteString("(error")
+	for _, token := range stmt.Tokens {
+		p.builder.WriteByte(' ')
+		p.builder.WriteString(token.Lexeme)
+	}
+	p.builder.WriteByte(')')
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitExprStmt(stmt *ExprStmt) (interface{}, error) {
+	p.parenthesize(";", stmt.Expr)
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitFunctionStmt(stmt *FunctionStmt) (interface{}, error) {
+	p.function("fun", stmt)
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitIfStmt(stmt *IfStmt) (interface{}, error) {
+	p.builder.WriteString("(if ")
+	p.expr(stmt.Cond)
+	p.builder.WriteByte(' ')
+	p.stmt(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		p.builder.WriteByte(' ')
+		p.stmt(stmt.ElseBranch)
+	}
+	p.builder.WriteByte(')')
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitPrintStmt(stmt *PrintStmt) (interface{}, error) {
+	p.parenthesize("print", stmt.Expr)
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitReturnStmt(stmt *ReturnStmt) (interface{}, error) {
+	if stmt.Val == nil {
+		p.builder.WriteString("(return)")
+	} else {
+		p.parenthesize("return", stmt.Val)
+	}
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitVarStmt(stmt *VarStmt) (interface{}, error) {
+	if stmt.Init == nil {
+		p.builder.WriteString("(var " + stmt.Name.Lexeme + ")")
+	} else {
+		p.parenthesize("var "+stmt.Name.Lexeme, stmt.Init)
+	}
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitWhileStmt(stmt *WhileStmt) (interface{}, error) {
+	p.builder.WriteString("(while ")
+	p.expr(stmt.Cond)
+	p.builder.WriteByte(' ')
+	p.stmt(stmt.Body)
+	p.builder.WriteByte(')')
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitAssignExpr(expr *AssignExpr) (interface{}, error) {
+	p.parenthesize("= "+expr.Name.Lexeme, expr.Val)
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
+	p.parenthesize(expr.Op.Lexeme, expr.Lhs, expr.Rhs)
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitCallExpr(expr *CallExpr) (interface{}, error) {
+	p.parenthesize("call", append([]Expr{expr.Callee}, expr.Args...)...)
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitErrorExpr(expr *ErrorExpr) (interface{}, error) {
+	p.builder.WriteString("(error)")
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitGetExpr(expr *GetExpr) (interface{}, error) {
+	p.parenthesize(". "+expr.Name.Lexeme, expr.Obj)
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitGroupExpr(expr *GroupExpr) (interface{}, error) {
+	p.parenthesize("group", expr.Expr)
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitLiteralExpr(expr *LiteralExpr) (interface{}, error) {
+	// strings are quoted so they can't be mistaken for other nodes
+	if s, ok := expr.Val.(string); ok {
+		p.builder.WriteString(strconv.Quote(s))
+	} else {
+		p.builder.WriteString(stringify(expr.Val))
+	}
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitLogicalExpr(expr *LogicalExpr) (interface{}, error) {
+	p.parenthesize(expr.Op.Lexeme, expr.Lhs, expr.Rhs)
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitSetExpr(expr *SetExpr) (interface{}, error) {
+	p.parenthesize("=. "+expr.Name.Lexeme, expr.Obj, expr.Val)
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitSuperExpr(expr *SuperExpr) (interface{}, error) {
+	p.builder.WriteString("(super " + expr.Method.Lexeme + ")")
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitThisExpr(expr *ThisExpr) (interface{}, error) {
+	p.builder.WriteString("this")
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitUnaryExpr(expr *UnaryExpr) (interface{}, error) {
+	p.parenthesize(expr.Op.Lexeme, expr.Expr)
+	return nil, nil
+}
+
+func (p *AstPrinter) VisitVarExpr(expr *VarExpr) (interface{}, error) {
+	p.builder.WriteString(expr.Name.Lexeme)
+	return nil, nil
+}
+
+// function renders a function declaration as its keyword, its name, the list
+// of its parameters, and the statements in its body.
+func (p *AstPrinter) function(keyword string, fn *FunctionStmt) {
+	p.builder.WriteString("(" + keyword + " " + fn.Name.Lexeme + " (")
+	for i, param := range fn.Params {
+		if i > 0 {
+			p.builder.WriteByte(' ')
+		}
+		p.builder.WriteString(param.Lexeme)
+	}
+	p.builder.WriteByte(')')
+	for _, stmt := range fn.Body {
+		p.builder.WriteByte(' ')
+		p.stmt(stmt)
+	}
+	p.builder.WriteByte(')')
+}
+
+func (p *AstPrinter) parenthesize(name string, exprs ...Expr) {
+	p.builder.WriteString("(" + name)
+	for _, expr := range exprs {
+		p.builder.WriteByte(' ')
+		p.expr(expr)
+	}
+	p.builder.WriteByte(')')
+}
+
+func (p *AstPrinter) stmt(stmt Stmt) {
+	stmt.Accept(p)
+}
+
+func (p *AstPrinter) expr(expr Expr) {
+	expr.Accept(p)
+}
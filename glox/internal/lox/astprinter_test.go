@@ -0,0 +1,39 @@
+package lox
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAstPrinterExpr(t *testing.T) {
+	assert := assert.New(t)
+
+	expr := NewBinaryExpr(
+		NewToken(STAR, "*", nil, 1),
+		NewUnaryExpr(NewToken(MINUS, "-", nil, 1), NewLiteralExpr(123.0)),
+		NewGroupExpr(NewLiteralExpr(45.67)),
+	)
+	assert.Equal("(* (- 123) (group 45.67))", NewAstPrinter().Print(expr))
+}
+
+func TestAstPrinterStmts(t *testing.T) {
+	assert := assert.New(t)
+
+	reporter := NewSimpleReporter(ioutil.Discard)
+	tokens := NewScanner([]byte(`
+class B < A { init(x) { this.x = x; super.init(); } }
+fun f() { return; }
+var a = "s";
+for (var i = 0; i < 2; i = i + 1) if (a or nil) print b.c(1, 2); else {}
+`), reporter).Scan()
+	stmts := NewParser(tokens, reporter).Parse()
+	assert.False(reporter.HadError())
+
+	assert.Equal(`(class B < A (method init (x) (; (=. x this x)) (; (call (super init)))))
+(fun f () (return))
+(var a "s")
+(block (var i 0) (while (< i 2) (block (if (or a nil) (print (call (. c b) 1 2)) (block)) (; (= i (+ i 1))))))
+`, NewAstPrinter().PrintStmts(stmts))
+}
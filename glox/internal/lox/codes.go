@@ -0,0 +1,164 @@
+package lox
+
+import "sort"
+
+// Code identifies the kind of problem of a diagnostic, so it can be looked up
+// or suppressed, e.g. "E0101" for an unexpected token. The letter is "E" for
+// the errors found before running a script, "W" for the warnings, and "R" for
+// the runtime errors, the first two digits are the stage, and the last two
+// the problem. A code keeps its meaning once it's assigned.
+type Code string
+
+const (
+	// the errors of the scanner
+	CodeUnexpectedCharacter Code = "E0001"
+	CodeUnterminatedString  Code = "E0002"
+	CodeUnterminatedComment Code = "E0003"
+
+	// the errors of the parser
+	CodeUnexpectedToken   Code = "E0101"
+	CodeInvalidAssignment Code = "E0102"
+	CodeTooManyArguments  Code = "E0103"
+	CodeUnsupportedUnary  Code = "E0104"
+	CodeTooMuchNesting    Code = "E0105"
+
+	// the errors of the resolver
+	CodeInheritFromSelf        Code = "E0201"
+	CodeTopLevelReturn         Code = "E0202"
+	CodeInitializerReturn      Code = "E0203"
+	CodeSuperOutsideClass      Code = "E0204"
+	CodeSuperWithoutSuperclass Code = "E0205"
+	CodeThisOutsideClass       Code = "E0206"
+	CodeReadInOwnInitializer   Code = "E0207"
+	CodeAlreadyDeclared        Code = "E0208"
+	CodeDuplicateMethod        Code = "E0209"
+	CodeDuplicateParameter     Code = "E0210"
+
+	// the warnings of the resolver, see Resolver.SetWarnings
+	CodeUnusedLocal              Code = "W0201"
+	CodeShadowedVariable         Code = "W0202"
+	CodeParameterNamedAfterField Code = "W0203"
+	CodeUnreachableCode          Code = "W0204"
+
+	// the warnings of the linter, one for each rule
+	CodeLintUnusedVariable    Code = "W0301"
+	CodeLintUnusedParameter   Code = "W0302"
+	CodeLintShadow            Code = "W0303"
+	CodeLintSelfAssign        Code = "W0304"
+	CodeLintConstantCondition Code = "W0305"
+	CodeLintBoolCompare       Code = "W0306"
+
+	// the runtime errors about the types of the values
+	CodeOperandType        Code = "R0101"
+	CodeNotCallable        Code = "R0102"
+	CodeArity              Code = "R0103"
+	CodeGetOnNonInstance   Code = "R0104"
+	CodeSetOnNonInstance   Code = "R0105"
+	CodeSuperclassNotClass Code = "R0106"
+
+	// the runtime errors about the names
+	CodeUndefinedVariable Code = "R0201"
+	CodeAssignUndefined   Code = "R0202"
+	CodeUndefinedProperty Code = "R0203"
+	CodeUninitialized     Code = "R0204"
+
+	// the runtime errors that stop a script from outside, see
+	// Interpreter.SetLimits
+	CodeStepLimit     Code = "R0301"
+	CodeTimeLimit     Code = "R0302"
+	CodeObjectLimit   Code = "R0303"
+	CodeStringLimit   Code = "R0304"
+	CodeStackOverflow Code = "R0305"
+	CodeInterrupted   Code = "R0306"
+	CodeCanceled      Code = "R0307"
+
+	// the runtime errors of the functions given by the host program
+	CodeArgumentIndex    Code = "R0401"
+	CodeNativeNotAllowed Code = "R0402"
+	CodeNativeFailed     Code = "R0403"
+	CodeModuleName       Code = "R0404"
+	CodeModuleNotFound   Code = "R0405"
+	CodeModuleError      Code = "R0406"
+	CodeSyntaxErrorNode  Code = "R0407"
+)
+
+var codeDescriptions = map[Code]string{
+	CodeUnexpectedCharacter: "a character that isn't part of any token",
+	CodeUnterminatedString:  "a string that isn't closed before the end of the script",
+	CodeUnterminatedComment: "a multiline comment that isn't closed before the end of the script",
+
+	CodeUnexpectedToken:   "a token that the grammar doesn't allow there, e.g. a missing ';'",
+	CodeInvalidAssignment: "an assignment to something that isn't a variable or a property",
+	CodeTooManyArguments:  "a call or a function with more than 255 arguments or parameters",
+	CodeUnsupportedUnary:  "a unary '+', '/', or '*', which Lox doesn't have",
+	CodeTooMuchNesting:    "expressions or statements nested too deeply to be parsed",
+
+	CodeInheritFromSelf:        "a class that inherits from itself",
+	CodeTopLevelReturn:         "a 'return' outside of a function",
+	CodeInitializerReturn:      "a 'return' with a value in an initializer",
+	CodeSuperOutsideClass:      "a 'super' outside of a class",
+	CodeSuperWithoutSuperclass: "a 'super' in a class that has no superclass",
+	CodeThisOutsideClass:       "a 'this' outside of a class",
+	CodeReadInOwnInitializer:   "a local variable read in its own initializer",
+	CodeAlreadyDeclared:        "a local variable declared twice in the same scope",
+	CodeDuplicateMethod:        "a method declared twice in the same class",
+	CodeDuplicateParameter:     "a parameter declared twice in the same function",
+
+	CodeUnusedLocal:              "a local variable, function, or class that is never read",
+	CodeShadowedVariable:         "a local declaration that hides a variable of an enclosing scope",
+	CodeParameterNamedAfterField: "a parameter of a method with the name of a field that it doesn't initialize",
+	CodeUnreachableCode:          "a statement that comes after a 'return' and never runs",
+
+	CodeLintUnusedVariable:    "a local variable, function, or class that is never read",
+	CodeLintUnusedParameter:   "a parameter that is never read",
+	CodeLintShadow:            "a local declaration that hides a variable of an enclosing scope",
+	CodeLintSelfAssign:        "an assignment of a variable or a property to itself",
+	CodeLintConstantCondition: "a condition that is always true or always false",
+	CodeLintBoolCompare:       "a comparison with 'true' or 'false'",
+
+	CodeOperandType:        "an operator applied to values of the wrong types",
+	CodeNotCallable:        "a call of a value that isn't a function or a class",
+	CodeArity:              "a call with the wrong number of arguments",
+	CodeGetOnNonInstance:   "a property read on a value that isn't an instance",
+	CodeSetOnNonInstance:   "a field set on a value that isn't an instance",
+	CodeSuperclassNotClass: "a superclass that isn't a class",
+
+	CodeUndefinedVariable: "a read of a variable that isn't defined",
+	CodeAssignUndefined:   "an assignment to a variable that isn't defined",
+	CodeUndefinedProperty: "a read of a property that the instance doesn't have",
+	CodeUninitialized:     "a read of a variable that was declared without a value and never assigned",
+
+	CodeStepLimit:     "a script that ran more steps than it's allowed",
+	CodeTimeLimit:     "a script that ran longer than it's allowed",
+	CodeObjectLimit:   "a script that allocated more objects than it's allowed",
+	CodeStringLimit:   "a script that allocated more string bytes than it's allowed",
+	CodeStackOverflow: "calls nested deeper than it's allowed",
+	CodeInterrupted:   "a script stopped by an interrupt, e.g. Ctrl-C",
+	CodeCanceled:      "a script stopped because its context is done",
+
+	CodeArgumentIndex:    "an argument of the script that doesn't exist",
+	CodeNativeNotAllowed: "a native function that needs capabilities that aren't allowed",
+	CodeNativeFailed:     "a native function that returned an error",
+	CodeModuleName:       "an import with a name that isn't a string",
+	CodeModuleNotFound:   "an import of a module that can't be found",
+	CodeModuleError:      "an import of a module that has a compile error",
+	CodeSyntaxErrorNode:  "a node of a partial syntax tree that stands for a syntax error",
+}
+
+// Description describes the problem of the code, it's empty if the code isn't
+// assigned.
+func (c Code) Description() string {
+	return codeDescriptions[c]
+}
+
+// Codes returns all the assigned codes in order.
+func Codes() []Code {
+	codes := make([]Code, 0, len(codeDescriptions))
+	for code := range codeDescriptions {
+		codes = append(codes, code)
+	}
+	sort.Slice(codes, func(i, j int) bool {
+		return codes[i] < codes[j]
+	})
+	return codes
+}
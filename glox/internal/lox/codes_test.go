@@ -0,0 +1,61 @@
+package lox
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCodes(t *testing.T) {
+	assert := assert.New(t)
+
+	codes := Codes()
+	assert.Len(codes, len(codeDescriptions))
+	assert.Equal(CodeUnexpectedCharacter, codes[0])
+	for _, code := range codes {
+		assert.Len(code, 5)
+		assert.NotEmpty(code.Description())
+	}
+	assert.Empty(Code("E9999").Description())
+	for _, rule := range LintRules {
+		assert.NotEmpty(rule.code().Description())
+	}
+}
+
+func TestDiagnosticCodes(t *testing.T) {
+	assert := assert.New(t)
+
+	_, diagnostics := Parse([]byte("print 1 +;\nvar a = \"b"))
+	if assert.Len(diagnostics, 3) {
+		assert.Equal(CodeUnterminatedString, diagnostics[0].Code)
+		assert.Equal(CodeUnexpectedToken, diagnostics[1].Code)
+	}
+	statements, _ := Parse([]byte("return 1;"))
+	_, diagnostics = Resolve(statements)
+	if assert.Len(diagnostics, 1) {
+		assert.Equal(CodeTopLevelReturn, diagnostics[0].Code)
+	}
+
+	var out strings.Builder
+	r := NewSimpleReporter(&out).(*SimpleReporter)
+	r.ShowCodes(true)
+	in := NewInterpreter(ioutil.Discard, r, false)
+	in.Interpret(parseScript(t, in, "print a;"))
+	NewParser(NewScanner([]byte("print 1 +;"), r).Scan(), r).Parse()
+	assert.Equal("Undefined variable 'a'. [R0201]\n[line 1]\n[line 1] Error at ';': Expect expression. [E0101]\n", out.String())
+}
+
+func TestSimpleReporterSuppress(t *testing.T) {
+	assert := assert.New(t)
+
+	statements, _ := Parse([]byte("fun f() {\n  var a = 1;\n  { var a = 2; }\n}"))
+	var out strings.Builder
+	r := NewSimpleReporter(&out).(*SimpleReporter)
+	r.Suppress(CodeUnusedLocal)
+	resolver := NewResolver(NewInterpreter(ioutil.Discard, r, false), r)
+	resolver.SetWarnings(true)
+	resolver.Resolve(statements)
+	assert.Equal("[line 3] Warning at 'a': Local variable 'a' shadows a variable of an enclosing scope.\n", out.String())
+}
@@ -0,0 +1,97 @@
+package lox
+
+import (
+	"path"
+	"path/filepath"
+)
+
+// The test suite of Crafting Interpreters is vendored in the "testsuite"
+// directory at the root of the repository, next to the official tool that
+// runs it. Its tests are grouped in directories named after language
+// features, each of them is mapped to the chapter of the book that
+// introduced the feature so failures point at the part of the interpreter
+// that regressed. Paths are relative to the suite's "test" directory, and
+// files take precedence over their directory.
+var conformanceChapters = map[string]string{
+	"comments":                 "4. Scanning",
+	"unexpected_character.lox": "4. Scanning",
+	"empty_file.lox":           "4. Scanning",
+	"precedence.lox":           "6. Parsing Expressions",
+	"bool":                     "7. Evaluating Expressions",
+	"nil":                      "7. Evaluating Expressions",
+	"number":                   "7. Evaluating Expressions",
+	"operator":                 "7. Evaluating Expressions",
+	"string":                   "7. Evaluating Expressions",
+	"assignment":               "8. Statements and State",
+	"block":                    "8. Statements and State",
+	"print":                    "8. Statements and State",
+	"variable":                 "8. Statements and State",
+	"if":                       "9. Control Flow",
+	"logical_operator":         "9. Control Flow",
+	"for":                      "9. Control Flow",
+	"while":                    "9. Control Flow",
+	"call":                     "10. Functions",
+	"closure":                  "10. Functions",
+	"function":                 "10. Functions",
+	"return":                   "10. Functions",
+	"limit":                    "10. Functions",
+	"regression/40.lox":        "10. Functions",
+
+	"closure/assign_to_shadowed_later.lox":    "11. Resolving and Binding",
+	"function/local_mutual_recursion.lox":     "11. Resolving and Binding",
+	"return/at_top_level.lox":                 "11. Resolving and Binding",
+	"variable/collide_with_parameter.lox":     "11. Resolving and Binding",
+	"variable/duplicate_local.lox":            "11. Resolving and Binding",
+	"variable/duplicate_parameter.lox":        "11. Resolving and Binding",
+	"variable/early_bound.lox":                "11. Resolving and Binding",
+	"variable/use_local_in_initializer.lox":   "11. Resolving and Binding",
+	"class":                                   "12. Classes",
+	"constructor":                             "12. Classes",
+	"field":                                   "12. Classes",
+	"method":                                  "12. Classes",
+	"this":                                    "12. Classes",
+	"assignment/to_this.lox":                  "12. Classes",
+	"call/object.lox":                         "12. Classes",
+	"closure/close_over_method_parameter.lox": "12. Classes",
+	"operator/equals_class.lox":               "12. Classes",
+	"operator/equals_method.lox":              "12. Classes",
+	"operator/not_class.lox":                  "12. Classes",
+	"return/in_method.lox":                    "12. Classes",
+	"variable/local_from_method.lox":          "12. Classes",
+	"inheritance":                             "13. Inheritance",
+	"super":                                   "13. Inheritance",
+	"regression/394.lox":                      "13. Inheritance",
+}
+
+// conformanceSkips holds the tests of the suite that don't apply to glox and
+// the reason why, the same ones are skipped for jlox by the official tool.
+var conformanceSkips = map[string]string{
+	"benchmark":                    "benchmarks aren't tests",
+	"scanning":                     "only for the scanning chapter",
+	"expressions":                  "only for the chapters about expressions",
+	"limit/loop_too_large.lox":     "no hard-coded limit",
+	"limit/no_reuse_constants.lox": "no hard-coded limit",
+	"limit/too_many_constants.lox": "no hard-coded limit",
+	"limit/too_many_locals.lox":    "no hard-coded limit",
+	"limit/too_many_upvalues.lox":  "no hard-coded limit",
+}
+
+// ConformanceChapter returns the chapter of Crafting Interpreters that the
+// test of the official suite belongs to, given its path relative to the
+// suite's "test" directory. The returned reason isn't empty if the test
+// doesn't apply to this interpreter and should be skipped.
+func ConformanceChapter(rel string) (chapter string, skip string) {
+	rel = path.Clean(filepath.ToSlash(rel))
+	return conformanceLookup(conformanceChapters, rel, "Other"), conformanceLookup(conformanceSkips, rel, "")
+}
+
+// conformanceLookup returns the value of the path itself or of its closest
+// parent directory.
+func conformanceLookup(m map[string]string, rel string, missing string) string {
+	for p := rel; p != "." && p != "/"; p = path.Dir(p) {
+		if v, ok := m[p]; ok {
+			return v
+		}
+	}
+	return missing
+}
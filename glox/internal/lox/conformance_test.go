@@ -0,0 +1,50 @@
+package lox
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConformanceChapter(t *testing.T) {
+	assert := assert.New(t)
+
+	chapter, skip := ConformanceChapter("variable/in_nested_block.lox")
+	assert.Equal("8. Statements and State", chapter)
+	assert.Empty(skip)
+	chapter, _ = ConformanceChapter(filepath.Join("variable", "duplicate_local.lox"))
+	assert.Equal("11. Resolving and Binding", chapter)
+	chapter, skip = ConformanceChapter("scanning/keywords.lox")
+	assert.Equal("Other", chapter)
+	assert.NotEmpty(skip)
+}
+
+// TestConformance runs the vendored test suite of Crafting Interpreters, so
+// regressions in the semantics of the language are caught by "go test".
+func TestConformance(t *testing.T) {
+	root := filepath.Join("..", "..", "..", "testsuite", "test")
+	if _, err := os.Stat(root); err != nil {
+		t.Skip("the test suite isn't vendored")
+	}
+	filepath.Walk(root, func(fpath string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || filepath.Ext(fpath) != ".lox" {
+			return err
+		}
+		rel, _ := filepath.Rel(root, fpath)
+		chapter, skip := ConformanceChapter(rel)
+		if skip != "" {
+			return nil
+		}
+		source, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return err
+		}
+		result := NewScriptTest(rel, source).Run(10 * time.Second)
+		assert.Empty(t, result.Failures, "%s (%s)", rel, chapter)
+		return nil
+	})
+}
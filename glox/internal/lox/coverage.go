@@ -0,0 +1,196 @@
+package lox
+
+import (
+	"bufio"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strings"
+)
+
+// Coverage records how many times each line of a script is executed by the
+// interpreter it's attached to. A line is executable if a statement starts on
+// it, blocks and class declarations aside since the statements that they hold
+// are counted instead.
+type Coverage struct {
+	name   string
+	source []byte
+	hits   map[int]int
+}
+
+// NewCoverage creates a new coverage record for the script with the given name
+// and source, which are only used in the reports.
+func NewCoverage(name string, source []byte) *Coverage {
+	c := new(Coverage)
+	c.name = name
+	c.source = source
+	c.hits = make(map[int]int)
+	return c
+}
+
+// SetCoverage attaches the coverage record to the interpreter, giving nil
+// detaches the current one. The statements given to Interpret are added to
+// the record before they are executed.
+func (in *Interpreter) SetCoverage(coverage *Coverage) {
+	in.coverage = coverage
+}
+
+// Name returns the name of the script.
+func (c *Coverage) Name() string {
+	return c.name
+}
+
+// Lines returns the number of executable lines and the number of those that
+// were executed at least once.
+func (c *Coverage) Lines() (total int, covered int) {
+	for _, n := range c.hits {
+		total++
+		if n > 0 {
+			covered++
+		}
+	}
+	return total, covered
+}
+
+// addStmts marks the lines of the statements, and of the statements nested in
+// them, as executable.
+func (c *Coverage) addStmts(statements []Stmt) {
+	for _, stmt := range statements {
+		c.addStmt(stmt)
+	}
+}
+
+func (c *Coverage) addStmt(stmt Stmt) {
+	switch stmt := stmt.(type) {
+	case *BlockStmt:
+		c.addStmts(stmt.Stmts)
+		return
+	case *ClassStmt:
+		for _, method := range stmt.Methods {
+			c.addStmts(method.Body)
+		}
+	case *FunctionStmt:
+		c.addStmts(stmt.Body)
+	case *IfStmt:
+		c.addStmt(stmt.ThenBranch)
+		if stmt.ElseBranch != nil {
+			c.addStmt(stmt.ElseBranch)
+		}
+	case *WhileStmt:
+		c.addStmt(stmt.Body)
+	}
+	if line := stmtLine(stmt); line != 0 {
+		if _, ok := c.hits[line]; !ok {
+			c.hits[line] = 0
+		}
+	}
+}
+
+// stmt is called before the interpreter executes a statement.
+func (c *Coverage) stmt(stmt Stmt) {
+	if _, ok := stmt.(*BlockStmt); ok {
+		return
+	}
+	if line := stmtLine(stmt); line != 0 {
+		c.hits[line]++
+	}
+}
+
+// sortedLines returns the executable lines in increasing order.
+func (c *Coverage) sortedLines() []int {
+	lines := make([]int, 0, len(c.hits))
+	for line := range c.hits {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// WriteLCOV writes the coverage records in the LCOV tracefile format, which is
+// understood by genhtml and by most editors and code hosting services.
+func WriteLCOV(w io.Writer, coverages []*Coverage) error {
+	bw := bufio.NewWriter(w)
+	for _, c := range coverages {
+		fmt.Fprintf(bw, "SF:%s\n", c.name)
+		for _, line := range c.sortedLines() {
+			fmt.Fprintf(bw, "DA:%d,%d\n", line, c.hits[line])
+		}
+		total, covered := c.Lines()
+		fmt.Fprintf(bw, "LF:%d\nLH:%d\nend_of_record\n", total, covered)
+	}
+	return bw.Flush()
+}
+
+var coverageTemplate = template.Must(template.New("coverage").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Lox coverage</title>
+<style>
+body { font-family: sans-serif; }
+table.source { border-collapse: collapse; font-family: monospace; }
+table.source td { padding: 0 8px; white-space: pre; }
+td.num, td.hits { color: #888; text-align: right; }
+tr.covered { background: #dfd; }
+tr.missed { background: #fdd; }
+</style>
+</head>
+<body>
+<h1>Lox coverage</h1>
+<ul>
+{{- range $i, $f := .}}
+<li><a href="#file{{$i}}">{{$f.Name}}</a> {{$f.Percent}}</li>
+{{- end}}
+</ul>
+{{- range $i, $f := .}}
+<h2 id="file{{$i}}">{{$f.Name}} {{$f.Percent}}</h2>
+<table class="source">
+{{- range $f.Lines}}
+<tr class="{{.Class}}"><td class="num">{{.Num}}</td><td class="hits">{{.Hits}}</td><td>{{.Text}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+</body>
+</html>
+`))
+
+type coverageFile struct {
+	Name    string
+	Percent string
+	Lines   []coverageLine
+}
+
+type coverageLine struct {
+	Num   int
+	Hits  string
+	Class string
+	Text  string
+}
+
+// WriteCoverageHTML writes the coverage records as a single HTML page that
+// shows the source of every script, with the lines that were executed in
+// green and the executable lines that weren't in red.
+func WriteCoverageHTML(w io.Writer, coverages []*Coverage) error {
+	files := make([]coverageFile, len(coverages))
+	for i, c := range coverages {
+		total, covered := c.Lines()
+		files[i].Name = c.name
+		files[i].Percent = "(no statement)"
+		if total > 0 {
+			files[i].Percent = fmt.Sprintf("(%.1f%%)", 100*float64(covered)/float64(total))
+		}
+		for j, text := range strings.Split(string(c.source), "\n") {
+			line := coverageLine{Num: j + 1, Text: text}
+			if n, ok := c.hits[line.Num]; ok {
+				line.Hits = fmt.Sprintf("%dx", n)
+				line.Class = "missed"
+				if n > 0 {
+					line.Class = "covered"
+				}
+			}
+			files[i].Lines = append(files[i].Lines, line)
+		}
+	}
+	return coverageTemplate.Execute(w, files)
+}
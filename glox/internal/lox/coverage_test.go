@@ -0,0 +1,70 @@
+package lox
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const coverageScript = `class A {
+	m() {
+		return 1;
+	}
+}
+fun f(x) {
+	if (x > 1) {
+		print "big";
+	} else {
+		print "small";
+	}
+}
+for (var i = 0; i < 2; i = i + 1) f(i);
+`
+
+func TestCoverage(t *testing.T) {
+	assert := assert.New(t)
+
+	coverage := NewCoverage("a.lox", []byte(coverageScript))
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false)
+	in.SetCoverage(coverage)
+	in.Interpret(parseScript(t, in, coverageScript))
+
+	total, covered := coverage.Lines()
+	assert.Equal(7, total)
+	assert.Equal(5, covered)
+
+	var lcov strings.Builder
+	assert.Nil(WriteLCOV(&lcov, []*Coverage{coverage}))
+	assert.Equal(`SF:a.lox
+DA:1,1
+DA:3,0
+DA:6,1
+DA:7,2
+DA:8,0
+DA:10,2
+DA:13,6
+LF:7
+LH:5
+end_of_record
+`, lcov.String())
+
+	var html strings.Builder
+	assert.Nil(WriteCoverageHTML(&html, []*Coverage{coverage}))
+	assert.Contains(html.String(), `<tr class="missed"><td class="num">8</td><td class="hits">0x</td><td>		print &#34;big&#34;;</td></tr>`)
+	assert.Contains(html.String(), `<tr class=""><td class="num">9</td><td class="hits"></td><td>	} else {</td></tr>`)
+	assert.Contains(html.String(), "(71.4%)")
+}
+
+func TestScriptTestCoverage(t *testing.T) {
+	assert := assert.New(t)
+
+	test := NewScriptTest("a.lox", []byte("print 1; // expect: 1\nif (false) print 2;"))
+	test.Cover = true
+	result := test.Run(0)
+	assert.True(result.Passed())
+	total, covered := result.Coverage.Lines()
+	assert.Equal(2, total)
+	assert.Equal(2, covered)
+}
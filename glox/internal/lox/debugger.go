@@ -0,0 +1,402 @@
+package lox
+
+import (
+	"errors"
+	"sort"
+	"strings"
+)
+
+// DebugAction tells the interpreter how to carry on after it was paused by a
+// debugger.
+type DebugAction int
+
+const (
+	// DebugContinue runs until the next breakpoint.
+	DebugContinue DebugAction = iota
+	// DebugStep pauses at the next statement, entering function calls.
+	DebugStep
+	// DebugNext pauses at the next statement that isn't in a function called
+	// from the paused statement.
+	DebugNext
+	// DebugFinish pauses once the current function returns.
+	DebugFinish
+	// DebugQuit stops running the script.
+	DebugQuit
+)
+
+// errDebugQuit unwinds the interpreter when the debugger quits, it's never
+// reported.
+var errDebugQuit = errors.New("debugger quit")
+
+// DebugWatch is an expression that the debugger evaluates before every
+// statement, the script is paused whenever its value changes.
+type DebugWatch struct {
+	ID   int
+	Expr string
+	// Value is the last value of the expression formatted the way "print"
+	// would write it, and Err is the error from the last evaluation, e.g. if
+	// a variable is out of scope. Value is kept when the evaluation fails.
+	Value string
+	Err   error
+	// Changed is true if the last evaluation gave a new value
+	Changed bool
+}
+
+// DebugFrame is a call to a Lox function that hasn't returned, or the top
+// level of the script if Function is empty. Line is the line being executed
+// in the frame, i.e. the paused line for the innermost frame and the line of
+// the call to the next frame for the others. It's 0 if the next frame was
+// called by the host program, e.g. by a native function with Value.Call,
+// since there's no call expression.
+type DebugFrame struct {
+	Function string
+	Line     int
+}
+
+// DebugVar is a variable shown by the debugger, with its value formatted the
+// way "print" would write it.
+type DebugVar struct {
+	Name  string
+	Value string
+}
+
+// Debugger pauses a running script at breakpoints or after stepping, and lets
+// its state be inspected. The interpreter is paused before executing a
+// statement, blocks aren't paused at since their first statement is. Stepping
+// goes from line to line, statements on the line where the interpreter was
+// last paused are skipped unless they're in another function call. Whenever
+// it's paused, the interpreter calls the given function, which can inspect the
+// script's state through the debugger and which decides how to carry on. The
+// debugger starts by stepping, so it pauses at the first statement. Scripts
+// can also pause themselves by calling the "breakpoint" native function.
+type Debugger struct {
+	in          *Interpreter
+	pause       func(d *Debugger, line int) DebugAction
+	breakpoints map[int]bool
+	action      DebugAction
+	// target is the number of frames when the last "next" or "finish" was
+	// given, and frame is the selected frame counting from the innermost one.
+	target int
+	frame  int
+	// lastLine and lastStmt are the location and the statement that the
+	// interpreter was last paused, or could have been paused, at.
+	lastLine int
+	lastStmt Stmt
+	// pausedLine and pausedDepth locate the last pause, stepping only pauses
+	// once the line or the function changed.
+	pausedLine  int
+	pausedDepth int
+	watches     []*DebugWatch
+	nextWatchID int
+}
+
+func NewDebugger(pause func(d *Debugger, line int) DebugAction) *Debugger {
+	d := new(Debugger)
+	d.pause = pause
+	d.breakpoints = make(map[int]bool)
+	d.action = DebugStep
+	return d
+}
+
+// SetAction sets how the script is run until the next pause, the default is
+// DebugStep so the first statement is paused at. Use DebugContinue to only
+// pause at breakpoints and calls to the "breakpoint" native function.
+func (d *Debugger) SetAction(action DebugAction) {
+	d.action = action
+}
+
+// SetDebugger attaches the debugger to the interpreter, giving nil detaches it.
+func (in *Interpreter) SetDebugger(d *Debugger) {
+	in.debugger = d
+	if d != nil {
+		d.in = in
+	}
+}
+
+// SetBreakpoint makes the interpreter pause at the statements on the line.
+func (d *Debugger) SetBreakpoint(line int) {
+	d.breakpoints[line] = true
+}
+
+// ClearBreakpoint removes the breakpoint on the line, false is returned if
+// there was none.
+func (d *Debugger) ClearBreakpoint(line int) bool {
+	if !d.breakpoints[line] {
+		return false
+	}
+	delete(d.breakpoints, line)
+	return true
+}
+
+// Breakpoints returns the lines with a breakpoint in increasing order.
+func (d *Debugger) Breakpoints() []int {
+	lines := make([]int, 0, len(d.breakpoints))
+	for line := range d.breakpoints {
+		lines = append(lines, line)
+	}
+	sort.Ints(lines)
+	return lines
+}
+
+// Frames returns the call stack starting with the innermost frame, the last
+// frame is the top level of the script.
+func (d *Debugger) Frames() []DebugFrame {
+	frames := d.in.frames
+	stack := make([]DebugFrame, 0, len(frames)+1)
+	line := d.pausedLine
+	for i := len(frames) - 1; i >= 0; i-- {
+		stack = append(stack, DebugFrame{Function: frames[i].decl.Name.Lexeme, Line: line})
+		line = 0
+		if frames[i].call != nil {
+			line = frames[i].call.Line
+		}
+	}
+	return append(stack, DebugFrame{Line: line})
+}
+
+// Frame returns the index of the selected frame in the list returned by
+// Frames. The innermost frame is selected whenever the script is paused.
+func (d *Debugger) Frame() int {
+	return d.frame
+}
+
+// SelectFrame selects the frame that Locals and Eval inspect, false is
+// returned if there's no such frame.
+func (d *Debugger) SelectFrame(frame int) bool {
+	if frame < 0 || frame > len(d.in.frames) {
+		return false
+	}
+	d.frame = frame
+	return true
+}
+
+// frameEnv returns the environment being used by the frame.
+func (d *Debugger) frameEnv(frame int) *environment {
+	if frame == 0 {
+		return d.in.environment
+	}
+	return d.in.frames[len(d.in.frames)-frame].callerEnv
+}
+
+// Locals returns the variables of every environment in the chain of the
+// selected frame, starting with the innermost one. The last environment is
+// the global one. Variables are sorted by name within an environment.
+func (d *Debugger) Locals() [][]DebugVar {
+	var scopes [][]DebugVar
+	for env := d.frameEnv(d.frame); env != nil; env = env.enclosing {
+		scope := make([]DebugVar, 0, len(env.values))
+		for name, val := range env.values {
+			scope = append(scope, DebugVar{Name: name, Value: stringify(val)})
+		}
+		sort.Slice(scope, func(i, j int) bool { return scope[i].Name < scope[j].Name })
+		scopes = append(scopes, scope)
+	}
+	return scopes
+}
+
+// Eval evaluates an expression as if it were written in place of the paused
+// statement of the selected frame, and returns its value formatted the way
+// "print" would write it. The expression can have side effects, e.g.
+// assignments and calls, but it isn't paused at breakpoints.
+func (d *Debugger) Eval(source string) (string, error) {
+	return d.eval(source, d.frame)
+}
+
+func (d *Debugger) eval(source string, frame int) (string, error) {
+	env := d.frameEnv(frame)
+	var errs strings.Builder
+	reporter := NewSimpleReporter(&errs)
+	tokens := NewScanner([]byte(source), reporter).Scan()
+	expr := NewParser(tokens, reporter).ParseExpr()
+	if !reporter.HadError() {
+		d.resolver(env, reporter).resolveExpr(expr)
+	}
+	if reporter.HadError() {
+		return "", errors.New(strings.TrimSpace(errs.String()))
+	}
+
+	prevEnv := d.in.environment
+	d.in.debugger = nil
+	d.in.environment = env
+	val, err := d.in.eval(expr)
+	d.in.environment = prevEnv
+	d.in.debugger = d
+	// the expression is thrown away, so is its resolution
+	forgetLocals(d.in, expr)
+	if err != nil {
+		// the line of the error is the line in the expression, not in the script
+		if rerr, ok := err.(*runtimeError); ok {
+			return "", errors.New(rerr.message)
+		}
+		return "", err
+	}
+	return stringify(val), nil
+}
+
+// Watch adds an expression that is evaluated before every statement, the
+// script is paused whenever its value changes. The expression is evaluated
+// right away to get its initial value. The returned identifier is used to
+// remove the watch.
+func (d *Debugger) Watch(source string) (int, error) {
+	var errs strings.Builder
+	reporter := NewSimpleReporter(&errs)
+	tokens := NewScanner([]byte(source), reporter).Scan()
+	if NewParser(tokens, reporter).ParseExpr(); reporter.HadError() {
+		return 0, errors.New(strings.TrimSpace(errs.String()))
+	}
+
+	d.nextWatchID++
+	w := &DebugWatch{ID: d.nextWatchID, Expr: source}
+	d.evalWatch(w)
+	w.Changed = false
+	d.watches = append(d.watches, w)
+	return w.ID, nil
+}
+
+// Unwatch removes a watch, false is returned if there's no such watch.
+func (d *Debugger) Unwatch(id int) bool {
+	for i, w := range d.watches {
+		if w.ID == id {
+			d.watches = append(d.watches[:i], d.watches[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Watches returns the watched expressions with their latest values.
+func (d *Debugger) Watches() []DebugWatch {
+	watches := make([]DebugWatch, len(d.watches))
+	for i, w := range d.watches {
+		watches[i] = *w
+	}
+	return watches
+}
+
+// evalWatch evaluates the watched expression in the innermost frame, and
+// returns true if its value changed. Since the environment is different for
+// every statement, the expression is parsed and resolved again every time.
+func (d *Debugger) evalWatch(w *DebugWatch) bool {
+	val, err := d.eval(w.Expr, 0)
+	w.Err = err
+	w.Changed = err == nil && val != w.Value
+	if err == nil {
+		w.Value = val
+	}
+	return w.Changed
+}
+
+// resolver returns a resolver whose scopes are built from the given chain of
+// environments, so that local variables are resolved to the right
+// environment.
+func (d *Debugger) resolver(env *environment, reporter Reporter) *Resolver {
+	r := NewResolver(d.in, reporter)
+	r.currentFn = functionTypeFunction
+	for ; env != d.in.globals; env = env.enclosing {
+		scope := make(scopeMap)
+		for name := range env.values {
+			scope[name] = true
+		}
+		if _, ok := scope["this"]; ok && r.currentClass == classTypeNone {
+			r.currentClass = classTypeClass
+		}
+		if _, ok := scope["super"]; ok {
+			r.currentClass = classTypeSubclass
+		}
+		r.scopes.PushBack(scope)
+	}
+	return r
+}
+
+// stmt is called before the interpreter executes a statement.
+func (d *Debugger) stmt(stmt Stmt) error {
+	if _, ok := stmt.(*BlockStmt); ok {
+		return nil
+	}
+	line := stmtLine(stmt)
+	if line == 0 {
+		line = d.lastLine
+	}
+
+	var pause bool
+	depth := len(d.in.frames)
+	moved := line != d.pausedLine || depth != d.pausedDepth
+	switch d.action {
+	case DebugStep:
+		pause = moved
+	case DebugNext:
+		pause = moved && depth <= d.target
+	case DebugFinish:
+		pause = depth < d.target
+	}
+	// a statement nested in an "if" or a loop on the same line is considered
+	// part of the same stop
+	if !pause && d.breakpoints[line] {
+		switch d.lastStmt.(type) {
+		case *IfStmt, *WhileStmt:
+			pause = line != d.lastLine
+		default:
+			pause = true
+		}
+	}
+	d.lastLine = line
+	d.lastStmt = stmt
+	for _, w := range d.watches {
+		if d.evalWatch(w) {
+			pause = true
+		}
+	}
+	if !pause {
+		return nil
+	}
+	return d.pauseAt(line)
+}
+
+// breakpoint is called by the "breakpoint" native function, the interpreter is
+// paused at the statement that called it.
+func (d *Debugger) breakpoint() error {
+	return d.pauseAt(d.lastLine)
+}
+
+func (d *Debugger) pauseAt(line int) error {
+	d.pausedLine = line
+	d.pausedDepth = len(d.in.frames)
+	d.frame = 0
+	d.action = d.pause(d, line)
+	d.frame = 0
+	d.target = len(d.in.frames)
+	if d.action == DebugQuit {
+		return errDebugQuit
+	}
+	return nil
+}
+
+// forgetLocals removes the resolved depth of the expression and of all its
+// subexpressions from the interpreter.
+func forgetLocals(in *Interpreter, expr Expr) {
+	delete(in.locals, expr)
+	switch expr := expr.(type) {
+	case *AssignExpr:
+		forgetLocals(in, expr.Val)
+	case *BinaryExpr:
+		forgetLocals(in, expr.Lhs)
+		forgetLocals(in, expr.Rhs)
+	case *CallExpr:
+		forgetLocals(in, expr.Callee)
+		for _, arg := range expr.Args {
+			forgetLocals(in, arg)
+		}
+	case *GetExpr:
+		forgetLocals(in, expr.Obj)
+	case *GroupExpr:
+		forgetLocals(in, expr.Expr)
+	case *LogicalExpr:
+		forgetLocals(in, expr.Lhs)
+		forgetLocals(in, expr.Rhs)
+	case *SetExpr:
+		forgetLocals(in, expr.Obj)
+		forgetLocals(in, expr.Val)
+	case *UnaryExpr:
+		forgetLocals(in, expr.Expr)
+	}
+}
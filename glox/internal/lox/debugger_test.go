@@ -0,0 +1,221 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const debugScript = `fun add(a, b) {
+	var sum = a + b;
+	return sum;
+}
+var x = 1;
+for (var i = 0; i < 3; i = i + 1) {
+	x = add(x, i);
+}
+print x;
+`
+
+// debugScenario runs the script, taking the next action from the list every
+// time the debugger pauses, and records the lines where it paused.
+func debugScenario(t *testing.T, actions []DebugAction, inspect func(d *Debugger)) []int {
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	var lines []int
+	in.SetDebugger(NewDebugger(func(d *Debugger, line int) DebugAction {
+		lines = append(lines, line)
+		if inspect != nil {
+			inspect(d)
+		}
+		if len(lines) > len(actions) {
+			return DebugQuit
+		}
+		return actions[len(lines)-1]
+	}))
+	in.Interpret(parseScript(t, in, debugScript))
+	assert.Empty(t, errs.String())
+	return lines
+}
+
+func TestDebuggerStepping(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal([]int{1, 5, 6, 7, 2, 3, 6, 7},
+		debugScenario(t, []DebugAction{DebugStep, DebugStep, DebugStep, DebugStep, DebugStep, DebugFinish, DebugNext}, nil))
+	assert.Equal([]int{1, 5, 6, 7, 6, 7, 6},
+		debugScenario(t, []DebugAction{DebugNext, DebugNext, DebugNext, DebugNext, DebugNext, DebugNext}, nil))
+}
+
+func TestDebuggerBreakpoints(t *testing.T) {
+	assert := assert.New(t)
+
+	var stacks []string
+	lines := debugScenario(t, []DebugAction{DebugContinue, DebugContinue, DebugContinue, DebugContinue}, func(d *Debugger) {
+		d.SetBreakpoint(3)
+		var names []string
+		for _, frame := range d.Frames() {
+			names = append(names, fmt.Sprintf("%s:%d", frame.Function, frame.Line))
+		}
+		stacks = append(stacks, strings.Join(names, ","))
+	})
+	assert.Equal([]int{1, 3, 3, 3}, lines)
+	assert.Equal([]string{":1", "add:3,:7", "add:3,:7", "add:3,:7"}, stacks)
+}
+
+func TestDebuggerInspection(t *testing.T) {
+	assert := assert.New(t)
+
+	var evals []string
+	debugScenario(t, []DebugAction{DebugContinue}, func(d *Debugger) {
+		d.SetBreakpoint(3)
+		if len(d.Frames()) == 1 {
+			return
+		}
+		locals := d.Locals()
+		assert.Equal([]DebugVar{{"a", "1"}, {"b", "0"}, {"sum", "1"}}, locals[0])
+		assert.Contains(locals[len(locals)-1], DebugVar{"x", "1"})
+		for _, expr := range []string{"sum * 10 + x", "add(a, 2)", "undefined", "a +"} {
+			val, err := d.Eval(expr)
+			evals = append(evals, fmt.Sprintf("%s|%v", val, err))
+		}
+	})
+	assert.Equal([]string{
+		"11|<nil>",
+		"3|<nil>",
+		"|Undefined variable 'undefined'.",
+		"|[line 1] Error at end: Expect expression.",
+	}, evals)
+}
+
+func TestBreakpointNative(t *testing.T) {
+	assert := assert.New(t)
+
+	script := `
+fun f(a) {
+	var b = a * 2;
+	breakpoint();
+	return b;
+}
+print f(3);
+`
+	// without a debugger, breakpoint does nothing
+	out, errs := runScript(t, script)
+	assert.Equal("6\n", out)
+	assert.Empty(errs)
+
+	var output, errors strings.Builder
+	in := NewInterpreter(&output, NewSimpleReporter(&errors), false)
+	var paused []string
+	debugger := NewDebugger(func(d *Debugger, line int) DebugAction {
+		val, err := d.Eval("b")
+		assert.NoError(err)
+		paused = append(paused, fmt.Sprintf("%d %s", line, val))
+		return DebugContinue
+	})
+	debugger.SetAction(DebugContinue)
+	in.SetDebugger(debugger)
+	in.Interpret(parseScript(t, in, script))
+	assert.Equal([]string{"4 6"}, paused)
+	assert.Equal("6\n", output.String())
+	assert.Empty(errors.String())
+}
+
+func TestDebuggerWatches(t *testing.T) {
+	assert := assert.New(t)
+
+	var values []string
+	lines := debugScenario(t, []DebugAction{DebugContinue, DebugContinue, DebugContinue, DebugContinue}, func(d *Debugger) {
+		if len(d.Watches()) == 0 {
+			id, err := d.Watch("x")
+			assert.Equal(1, id)
+			assert.Nil(err)
+			_, err = d.Watch("x +")
+			assert.NotNil(err)
+			return
+		}
+		w := d.Watches()[0]
+		assert.True(w.Changed)
+		values = append(values, w.Value)
+	})
+	assert.Equal([]int{1, 6, 6, 6}, lines)
+	assert.Equal([]string{"1", "2", "4"}, values)
+
+	lines = debugScenario(t, []DebugAction{DebugContinue, DebugContinue}, func(d *Debugger) {
+		if len(d.Watches()) == 0 {
+			d.Watch("x")
+		} else {
+			assert.True(d.Unwatch(1))
+			assert.False(d.Unwatch(1))
+		}
+	})
+	assert.Equal([]int{1, 6}, lines)
+}
+
+func TestDebuggerFrames(t *testing.T) {
+	assert := assert.New(t)
+
+	var evals []string
+	debugScenario(t, []DebugAction{DebugContinue, DebugContinue}, func(d *Debugger) {
+		d.SetBreakpoint(3)
+		assert.Equal(0, d.Frame())
+		if len(d.Frames()) == 1 || len(evals) > 0 {
+			return
+		}
+		assert.True(d.SelectFrame(1))
+		assert.False(d.SelectFrame(2))
+		assert.Equal(1, d.Frame())
+		var vars []DebugVar
+		for _, scope := range d.Locals() {
+			vars = append(vars, scope...)
+		}
+		assert.Contains(vars, DebugVar{"i", "0"})
+		assert.NotContains(vars, DebugVar{"a", "1"})
+		for _, expr := range []string{"x", "i", "sum"} {
+			val, err := d.Eval(expr)
+			evals = append(evals, fmt.Sprintf("%s|%v", val, err))
+		}
+	})
+	assert.Equal([]string{
+		"1|<nil>",
+		"0|<nil>",
+		"|Undefined variable 'sum'.",
+	}, evals)
+}
+
+func TestDebuggerFramesCalledByHost(t *testing.T) {
+	assert := assert.New(t)
+
+	script := `fun f() {
+	print 1;
+}
+fun g() {
+	apply(f);
+}
+g();
+`
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	assert.NoError(in.RegisterFunc("apply", func(fn Value) (Value, error) {
+		return fn.Call()
+	}))
+	var stacks []string
+	debugger := NewDebugger(func(d *Debugger, line int) DebugAction {
+		var names []string
+		for _, frame := range d.Frames() {
+			names = append(names, fmt.Sprintf("%s:%d", frame.Function, frame.Line))
+		}
+		stacks = append(stacks, strings.Join(names, ","))
+		return DebugContinue
+	})
+	debugger.SetBreakpoint(2)
+	debugger.SetAction(DebugContinue)
+	in.SetDebugger(debugger)
+	in.Interpret(parseScript(t, in, script))
+	// f is called by apply rather than by a call expression
+	assert.Equal([]string{"f:2,g:0,:7"}, stacks)
+	assert.Equal("1\n", out.String())
+	assert.Empty(errs.String())
+}
@@ -0,0 +1,225 @@
+package lox
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// DotPrinter renders syntax trees as graphs in the DOT language of Graphviz,
+// e.g. "glox ast -dot script.lox | dot -Tsvg > ast.svg". Every node of the tree
+// becomes a vertex labeled with its kind and its token, and every child
+// becomes an edge labeled with the name of the field that holds it.
+type DotPrinter struct {
+	builder strings.Builder
+	nextID  int
+}
+
+func NewDotPrinter() *DotPrinter {
+	return new(DotPrinter)
+}
+
+// PrintStmts renders the statements as a single graph rooted at a "program"
+// vertex.
+func (p *DotPrinter) PrintStmts(statements []Stmt) string {
+	p.builder.Reset()
+	p.nextID = 0
+	p.builder.WriteString("digraph ast {\n")
+	p.builder.WriteString("\tnode [shape=box, fontname=\"monospace\"];\n")
+	root := p.node("program")
+	for i, stmt := range statements {
+		p.edge(root, p.stmt(stmt), strconv.Itoa(i))
+	}
+	p.builder.WriteString("}\n")
+	return p.builder.String()
+}
+
+func (p *DotPrinter) VisitBlockStmt(stmt *BlockStmt) (interface{}, error) {
+	id := p.node("Block")
+	p.stmtList(id, stmt.Stmts)
+	return id, nil
+}
+
+func (p *DotPrinter) VisitClassStmt(stmt *ClassStmt) (interface{}, error) {
+	id := p.node("Class " + stmt.Name.Lexeme)
+	if stmt.Super != nil {
+		p.edge(id, p.expr(stmt.Super), "super")
+	}
+	for _, method := range stmt.Methods {
+		p.edge(id, p.stmt(method), "method")
+	}
+	return id, nil
+}
+
+func (p *DotPrinter) VisitErrorStmt(stmt *ErrorStmt) (interface{}, error) {
+	return p.node("Error"), nil
+}
+
+func (p *DotPrinter) VisitExprStmt(stmt *ExprStmt) (interface{}, error) {
+	id := p.node("Expr")
+	p.edge(id, p.expr(stmt.Expr), "expr")
+	return id, nil
+}
+
+func (p *DotPrinter) VisitFunctionStmt(stmt *FunctionStmt) (interface{}, error) {
+	params := make([]string, len(stmt.Params))
+	for i, param := range stmt.Params {
+		params[i] = param.Lexeme
+	}
+	id := p.node("Function " + stmt.Name.Lexeme + "(" + strings.Join(params, ", ") + ")")
+	p.stmtList(id, stmt.Body)
+	return id, nil
+}
+
+func (p *DotPrinter) VisitIfStmt(stmt *IfStmt) (interface{}, error) {
+	id := p.node("If")
+	p.edge(id, p.expr(stmt.Cond), "cond")
+	p.edge(id, p.stmt(stmt.ThenBranch), "then")
+	if stmt.ElseBranch != nil {
+		p.edge(id, p.stmt(stmt.ElseBranch), "else")
+	}
+	return id, nil
+}
+
+func (p *DotPrinter) VisitPrintStmt(stmt *PrintStmt) (interface{}, error) {
+	id := p.node("Print")
+	p.edge(id, p.expr(stmt.Expr), "expr")
+	return id, nil
+}
+
+func (p *DotPrinter) VisitReturnStmt(stmt *ReturnStmt) (interface{}, error) {
+	id := p.node("Return")
+	if stmt.Val != nil {
+		p.edge(id, p.expr(stmt.Val), "val")
+	}
+	return id, nil
+}
+
+func (p *DotPrinter) VisitVarStmt(stmt *VarStmt) (interface{}, error) {
+	id := p.node("Var " + stmt.Name.Lexeme)
+	if stmt.Init != nil {
+		p.edge(id, p.expr(stmt.Init), "init")
+	}
+	return id, nil
+}
+
+func (p *DotPrinter) VisitWhileStmt(stmt *WhileStmt) (interface{}, error) {
+	id := p.node("While")
+	p.edge(id, p.expr(stmt.Cond), "cond")
+	p.edge(id, p.stmt(stmt.Body), "body")
+	return id, nil
+}
+
+func (p *DotPrinter) VisitAssignExpr(expr *AssignExpr) (interface{}, error) {
+	id := p.node("Assign " + expr.Name.Lexeme)
+	p.edge(id, p.expr(expr.Val), "val")
+	return id, nil
+}
+
+func (p *DotPrinter) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
+	id := p.node("Binary " + expr.Op.Lexeme)
+	p.edge(id, p.expr(expr.Lhs), "lhs")
+	p.edge(id, p.expr(expr.Rhs), "rhs")
+	return id, nil
+}
+
+func (p *DotPrinter) VisitCallExpr(expr *CallExpr) (interface{}, error) {
+	id := p.node("Call")
+	p.edge(id, p.expr(expr.Callee), "callee")
+	for i, arg := range expr.Args {
+		p.edge(id, p.expr(arg), "arg "+strconv.Itoa(i))
+	}
+	return id, nil
+}
+
+func (p *DotPrinter) VisitErrorExpr(expr *ErrorExpr) (interface{}, error) {
+	return p.node("Error"), nil
+}
+
+func (p *DotPrinter) VisitGetExpr(expr *GetExpr) (interface{}, error) {
+	id := p.node("Get ." + expr.Name.Lexeme)
+	p.edge(id, p.expr(expr.Obj), "obj")
+	return id, nil
+}
+
+func (p *DotPrinter) VisitGroupExpr(expr *GroupExpr) (interface{}, error) {
+	id := p.node("Group")
+	p.edge(id, p.expr(expr.Expr), "expr")
+	return id, nil
+}
+
+func (p *DotPrinter) VisitLiteralExpr(expr *LiteralExpr) (interface{}, error) {
+	if s, ok := expr.Val.(string); ok {
+		return p.node("Literal " + strconv.Quote(s)), nil
+	}
+	return p.node("Literal " + stringify(expr.Val)), nil
+}
+
+func (p *DotPrinter) VisitLogicalExpr(expr *LogicalExpr) (interface{}, error) {
+	id := p.node("Logical " + expr.Op.Lexeme)
+	p.edge(id, p.expr(expr.Lhs), "lhs")
+	p.edge(id, p.expr(expr.Rhs), "rhs")
+	return id, nil
+}
+
+func (p *DotPrinter) VisitSetExpr(expr *SetExpr) (interface{}, error) {
+	id := p.node("Set ." + expr.Name.Lexeme)
+	p.edge(id, p.expr(expr.Obj), "obj")
+	p.edge(id, p.expr(expr.Val), "val")
+	return id, nil
+}
+
+func (p *DotPrinter) VisitSuperExpr(expr *SuperExpr) (interface{}, error) {
+	return p.node("Super ." + expr.Method.Lexeme), nil
+}
+
+func (p *DotPrinter) VisitThisExpr(expr *ThisExpr) (interface{}, error) {
+	return p.node("This"), nil
+}
+
+func (p *DotPrinter) VisitUnaryExpr(expr *UnaryExpr) (interface{}, error) {
+	id := p.node("Unary " + expr.Op.Lexeme)
+	p.edge(id, p.expr(expr.Expr), "expr")
+	return id, nil
+}
+
+func (p *DotPrinter) VisitVarExpr(expr *VarExpr) (interface{}, error) {
+	return p.node("Variable " + expr.Name.Lexeme), nil
+}
+
+// stmtList adds an edge labeled with the index of each statement
+func (p *DotPrinter) stmtList(parent string, statements []Stmt) {
+	for i, stmt := range statements {
+		p.edge(parent, p.stmt(stmt), strconv.Itoa(i))
+	}
+}
+
+// node declares a new vertex with the given label and returns its identifier
+func (p *DotPrinter) node(label string) string {
+	id := fmt.Sprintf("n%d", p.nextID)
+	p.nextID++
+	fmt.Fprintf(&p.builder, "\t%s [label=%s];\n", id, dotQuote(label))
+	return id
+}
+
+func (p *DotPrinter) edge(from, to, label string) {
+	fmt.Fprintf(&p.builder, "\t%s -> %s [label=%s];\n", from, to, dotQuote(label))
+}
+
+func (p *DotPrinter) stmt(stmt Stmt) string {
+	id, _ := stmt.Accept(p)
+	return id.(string)
+}
+
+func (p *DotPrinter) expr(expr Expr) string {
+	id, _ := expr.Accept(p)
+	return id.(string)
+}
+
+// dotQuote returns the text as a quoted DOT string, where only double quotes
+// and backslashes need to be escaped.
+func dotQuote(text string) string {
+	text = strings.ReplaceAll(text, `\`, `\\`)
+	text = strings.ReplaceAll(text, `"`, `\"`)
+	return `"` + text + `"`
+}
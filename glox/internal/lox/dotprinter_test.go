@@ -0,0 +1,41 @@
+package lox
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDotPrinter(t *testing.T) {
+	assert := assert.New(t)
+
+	reporter := NewSimpleReporter(ioutil.Discard)
+	tokens := NewScanner([]byte(`if (a) print -1; else f("x");`), reporter).Scan()
+	stmts := NewParser(tokens, reporter).Parse()
+	assert.False(reporter.HadError())
+
+	assert.Equal(`digraph ast {
+	node [shape=box, fontname="monospace"];
+	n0 [label="program"];
+	n1 [label="If"];
+	n2 [label="Variable a"];
+	n1 -> n2 [label="cond"];
+	n3 [label="Print"];
+	n4 [label="Unary -"];
+	n5 [label="Literal 1"];
+	n4 -> n5 [label="expr"];
+	n3 -> n4 [label="expr"];
+	n1 -> n3 [label="then"];
+	n6 [label="Expr"];
+	n7 [label="Call"];
+	n8 [label="Variable f"];
+	n7 -> n8 [label="callee"];
+	n9 [label="Literal \"x\""];
+	n7 -> n9 [label="arg 0"];
+	n6 -> n7 [label="expr"];
+	n1 -> n6 [label="else"];
+	n0 -> n1 [label="0"];
+}
+`, NewDotPrinter().PrintStmts(stmts))
+}
@@ -5,6 +5,14 @@ import "fmt"
 type environment struct {
 	enclosing *environment
 	values    map[string]interface{}
+	// captured is set once a function keeps a reference to this environment as
+	// its closure, such environments can outlive the block that created them so
+	// they must never be reused.
+	captured bool
+	// uninitialized holds the names of the variables that were declared
+	// without an initializer and haven't been assigned since, it's only kept
+	// by the interpreters that are strict, see Interpreter.SetStrictVariables.
+	uninitialized map[string]bool
 }
 
 func newEnvironment(enclosing *environment) *environment {
@@ -14,20 +22,61 @@ func newEnvironment(enclosing *environment) *environment {
 	return env
 }
 
+// capture marks the environment and all of its ancestors as captured.
+func (env *environment) capture() {
+	for iterEnv := env; iterEnv != nil && !iterEnv.captured; iterEnv = iterEnv.enclosing {
+		iterEnv.captured = true
+	}
+}
+
+// names returns the names of the variables of the environment and of its
+// ancestors.
+func (env *environment) names() []string {
+	var names []string
+	for iterEnv := env; iterEnv != nil; iterEnv = iterEnv.enclosing {
+		for name := range iterEnv.values {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
 func (env *environment) define(name string, value interface{}) {
 	env.values[name] = value
+	if env.uninitialized != nil {
+		delete(env.uninitialized, name)
+	}
+}
+
+// declare defines the variable without a value, reading it is an error until
+// it's assigned, see isUninitialized.
+func (env *environment) declare(name string) {
+	env.values[name] = nil
+	if env.uninitialized == nil {
+		env.uninitialized = make(map[string]bool)
+	}
+	env.uninitialized[name] = true
+}
+
+// isUninitialized returns true if the variable was declared and hasn't been
+// assigned since.
+func (env *environment) isUninitialized(name string) bool {
+	return env.uninitialized[name]
 }
 
 func (env *environment) assign(name *Token, value interface{}) error {
 	if _, ok := env.values[name.Lexeme]; ok {
 		env.values[name.Lexeme] = value
+		if env.uninitialized != nil {
+			delete(env.uninitialized, name.Lexeme)
+		}
 		return nil
 	}
 	if env.enclosing != nil {
 		return env.enclosing.assign(name, value)
 	}
 	msg := fmt.Sprintf("Undefined variable '%s'.", name.Lexeme)
-	return newRuntimeError(name, msg)
+	return newRuntimeError(name, CodeAssignUndefined, msg)
 }
 
 func (env *environment) get(name *Token) (interface{}, error) {
@@ -38,11 +87,15 @@ func (env *environment) get(name *Token) (interface{}, error) {
 		return env.enclosing.get(name)
 	}
 	msg := fmt.Sprintf("Undefined variable '%s'.", name.Lexeme)
-	return nil, newRuntimeError(name, msg)
+	return nil, newRuntimeError(name, CodeUndefinedVariable, msg)
 }
 
 func (env *environment) assignAt(steps int, name *Token, val interface{}) {
-	env.ancestor(steps).values[name.Lexeme] = val
+	target := env.ancestor(steps)
+	target.values[name.Lexeme] = val
+	if target.uninitialized != nil {
+		delete(target.uninitialized, name.Lexeme)
+	}
 }
 
 func (env *environment) getAt(steps int, name string) interface{} {
@@ -56,3 +109,40 @@ func (env *environment) ancestor(steps int) *environment {
 	}
 	return iterEnv
 }
+
+// maxPooledEnvs limits the number of free environments that an interpreter
+// holds on to, so a single deep recursion doesn't keep lots of memory alive.
+const maxPooledEnvs = 256
+
+// environmentPool is a free-list of environments that are no longer used.
+// Blocks and function calls create a new environment each time they are
+// executed, reusing them saves us from allocating a new map in every loop
+// iteration.
+type environmentPool struct {
+	free []*environment
+}
+
+func (pool *environmentPool) get(enclosing *environment) *environment {
+	n := len(pool.free)
+	if n == 0 {
+		return newEnvironment(enclosing)
+	}
+	env := pool.free[n-1]
+	pool.free = pool.free[:n-1]
+	env.enclosing = enclosing
+	return env
+}
+
+// put gives the environment back to the pool, environments that were captured
+// by a closure are left alone for the garbage collector.
+func (pool *environmentPool) put(env *environment) {
+	if env.captured || len(pool.free) >= maxPooledEnvs {
+		return
+	}
+	for name := range env.values {
+		delete(env.values, name)
+	}
+	env.enclosing = nil
+	env.uninitialized = nil
+	pool.free = append(pool.free, env)
+}
@@ -1,71 +1,256 @@
 package lox
 
-import "fmt"
+import (
+	"context"
+	"errors"
+	"fmt"
+)
 
+// ErrLimitExceeded is wrapped by the runtime errors that are reported when a
+// script goes over one of the limits that were set on the interpreter. Use
+// errors.Is to tell these errors apart from other runtime errors.
+var ErrLimitExceeded = errors.New("limit exceeded")
+
+// ErrCanceled is wrapped by the runtime error that is reported when the
+// context given to Interpreter.InterpretContext is canceled or its deadline
+// passes.
+var ErrCanceled = errors.New("canceled")
+
+// ErrNotAllowed is wrapped by the error that is returned when a native function
+// that needs a capability denied by the policy of the interpreter is
+// registered, and by the runtime error that is reported when it's called.
+var ErrNotAllowed = errors.New("not allowed")
+
+// ErrInterrupted is wrapped by the runtime error that is reported when a
+// script is stopped by Interpreter.Interrupt.
+var ErrInterrupted = errors.New("interrupted")
+
+// positionFormatter is implemented by the errors that can be formatted with
+// the settings of a reporter, e.g. to show the column of their position next
+// to the line, see SimpleReporter.ShowColumns.
+type positionFormatter interface {
+	format(f diagnosticFormat) string
+}
+
+// position formats the line, followed by the column if it's wanted and known.
+func position(line, column int, columns bool) string {
+	if columns && column > 0 {
+		return fmt.Sprintf("line %d:%d", line, column)
+	}
+	return fmt.Sprintf("line %d", line)
+}
+
+// scanError is found by the scanner at the given line and column, offset is
+// the number of bytes before it in the source, and length is the number of
+// bytes of the lexeme at fault, if any.
 type scanError struct {
 	line    int
+	column  int
+	offset  int
+	length  int
+	code    Code
 	message string
 }
 
-func newScanError(line int, message string) error {
+func newScanError(line, column, offset int, code Code, message string) error {
 	e := new(scanError)
 	e.line = line
+	e.column = column
+	e.offset = offset
+	e.code = code
 	e.message = message
 	return e
 }
 
 func (err *scanError) Error() string {
+	return err.format(diagnosticFormat{})
+}
+
+func (err *scanError) format(f diagnosticFormat) string {
 	return fmt.Sprintf(
-		"[line %d] Error: %s",
-		err.line,
-		err.message,
+		"[%s] %s: %s",
+		position(err.line, err.column, f.columns),
+		f.paint("Error", "error"),
+		f.withCode(err.message, err.code),
 	)
 }
 
 type compileError struct {
 	token   *Token
+	code    Code
 	message string
+	// warning is set if the code is legal but most likely a mistake
+	warning bool
+}
+
+func newCompileError(token *Token, code Code, message string) error {
+	e := new(compileError)
+	e.token = token
+	e.code = code
+	e.message = message
+	return e
 }
 
-func newCompileError(token *Token, message string) error {
+// newCompileWarning creates a warning found at the given token, see
+// Resolver.SetWarnings.
+func newCompileWarning(token *Token, code Code, message string) error {
 	e := new(compileError)
 	e.token = token
+	e.code = code
 	e.message = message
+	e.warning = true
 	return e
 }
 
 func (err *compileError) Error() string {
+	return err.format(diagnosticFormat{})
+}
+
+func (err *compileError) format(f diagnosticFormat) string {
 	var loc string
 	if err.token.Type == EOF {
 		loc = "end"
 	} else {
-		loc = "'" + err.token.Lexeme + "'"
+		loc = "'" + f.paint(err.token.Lexeme, "lexeme") + "'"
+	}
+	kind := f.paint("Error", "error")
+	if err.warning {
+		kind = f.paint("Warning", "warning")
 	}
 
 	return fmt.Sprintf(
-		"[line %d] Error at %s: %s",
-		err.token.Line,
+		"[%s] %s at %s: %s",
+		position(err.token.Line, err.token.Column, f.columns),
+		kind,
 		loc,
-		err.message,
+		f.withCode(err.message, err.code),
 	)
 }
 
 type runtimeError struct {
 	token   *Token
+	code    Code
 	message string
+	cause   error
+	// stack holds the calls that the error went through, see recordStack,
+	// it's empty if the error happened at the top level.
+	stack []StackFrame
+	// hint suggests how to fix the error, see suggest
+	hint string
+}
+
+// StackFrame is a call to a Lox function that a runtime error went through,
+// or the top level of the script if Function is empty. Line is the line being
+// run in the frame when the error happened, i.e. the line of the error for
+// the innermost frame and the line of the call to the next frame for the
+// others.
+type StackFrame struct {
+	Function string `json:"function,omitempty"`
+	Line     int    `json:"line"`
+}
+
+func (f StackFrame) String() string {
+	if f.Function == "" {
+		return fmt.Sprintf("at top level line %d", f.Line)
+	}
+	return fmt.Sprintf("in %s at line %d", f.Function, f.Line)
+}
+
+func newRuntimeError(token *Token, code Code, message string) error {
+	e := new(runtimeError)
+	e.token = token
+	e.code = code
+	e.message = message
+	return e
 }
 
-func newRuntimeError(token *Token, message string) error {
+// newLimitError creates a runtime error that is caused by going over an
+// execution limit. The token can be nil since some limits are checked for
+// every node, and most nodes don't hold one.
+func newLimitError(token *Token, code Code, message string) error {
 	e := new(runtimeError)
 	e.token = token
+	e.code = code
 	e.message = message
+	e.cause = ErrLimitExceeded
+	return e
+}
+
+// newInterruptError creates the runtime error that stops an interrupted
+// script, it has no token since the script can be stopped at any node.
+func newInterruptError() error {
+	e := new(runtimeError)
+	e.code = CodeInterrupted
+	e.message = "Interrupted."
+	e.cause = ErrInterrupted
+	return e
+}
+
+// newCancelError creates the runtime error that stops a script whose context
+// is done, at the given token if there's one.
+func newCancelError(token *Token, ctxErr error) error {
+	e := new(runtimeError)
+	e.token = token
+	e.code = CodeCanceled
+	e.message = "Canceled."
+	if ctxErr == context.DeadlineExceeded {
+		e.message = "Deadline exceeded."
+	}
+	e.cause = ErrCanceled
 	return e
 }
 
 func (err *runtimeError) Error() string {
+	return err.format(diagnosticFormat{})
+}
+
+func (err *runtimeError) format(f diagnosticFormat) string {
+	message := f.withCode(f.paint(err.message, "error"), err.code)
+	if err.token == nil {
+		return message
+	}
+	return fmt.Sprintf(
+		"%s\n[%s]",
+		message,
+		position(err.token.Line, err.token.Column, f.columns),
+	)
+}
+
+func (err *runtimeError) Unwrap() error {
+	return err.cause
+}
+
+// lintWarning is reported by the linter, the rule that found the problem is
+// included so that it can be looked up or disabled.
+type lintWarning struct {
+	line    int
+	rule    LintRule
+	code    Code
+	message string
+}
+
+func newLintWarning(line int, rule LintRule, message string) *lintWarning {
+	e := new(lintWarning)
+	e.line = line
+	e.rule = rule
+	e.code = rule.code()
+	e.message = message
+	return e
+}
+
+func (err *lintWarning) Error() string {
+	return err.format(diagnosticFormat{})
+}
+
+func (err *lintWarning) format(f diagnosticFormat) string {
+	if err.line == 0 {
+		return fmt.Sprintf("%s: %s", f.paint("Warning", "warning"), f.withCode(
+			fmt.Sprintf("%s (%s)", err.message, err.rule), err.code))
+	}
 	return fmt.Sprintf(
-		"%s\n[line %d]",
-		err.message,
-		err.token.Line,
+		"[line %d] %s: %s",
+		err.line,
+		f.paint("Warning", "warning"),
+		f.withCode(fmt.Sprintf("%s (%s)", err.message, err.rule), err.code),
 	)
 }
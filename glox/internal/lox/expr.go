@@ -2,11 +2,14 @@ package lox
 
 type Expr interface {
 	Accept(visitor ExprVisitor) (interface{}, error)
+	span() *Span
+	setSpan(start, end *Token)
 }
 type ExprVisitor interface {
 	VisitAssignExpr(expr *AssignExpr) (interface{}, error)
 	VisitBinaryExpr(expr *BinaryExpr) (interface{}, error)
 	VisitCallExpr(expr *CallExpr) (interface{}, error)
+	VisitErrorExpr(expr *ErrorExpr) (interface{}, error)
 	VisitGetExpr(expr *GetExpr) (interface{}, error)
 	VisitGroupExpr(expr *GroupExpr) (interface{}, error)
 	VisitLiteralExpr(expr *LiteralExpr) (interface{}, error)
@@ -20,10 +23,11 @@ type ExprVisitor interface {
 type AssignExpr struct {
 	Name *Token
 	Val  Expr
+	Span
 }
 
 func NewAssignExpr(Name *Token, Val Expr) *AssignExpr {
-	return &AssignExpr{Name, Val}
+	return &AssignExpr{Name: Name, Val: Val}
 }
 func (expr *AssignExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitAssignExpr(expr)
@@ -33,10 +37,11 @@ type BinaryExpr struct {
 	Op  *Token
 	Lhs Expr
 	Rhs Expr
+	Span
 }
 
 func NewBinaryExpr(Op *Token, Lhs Expr, Rhs Expr) *BinaryExpr {
-	return &BinaryExpr{Op, Lhs, Rhs}
+	return &BinaryExpr{Op: Op, Lhs: Lhs, Rhs: Rhs}
 }
 func (expr *BinaryExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitBinaryExpr(expr)
@@ -46,22 +51,36 @@ type CallExpr struct {
 	Callee Expr
 	Paren  *Token
 	Args   []Expr
+	Span
 }
 
 func NewCallExpr(Callee Expr, Paren *Token, Args []Expr) *CallExpr {
-	return &CallExpr{Callee, Paren, Args}
+	return &CallExpr{Callee: Callee, Paren: Paren, Args: Args}
 }
 func (expr *CallExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitCallExpr(expr)
 }
 
+type ErrorExpr struct {
+	Token *Token
+	Span
+}
+
+func NewErrorExpr(Token *Token) *ErrorExpr {
+	return &ErrorExpr{Token: Token}
+}
+func (expr *ErrorExpr) Accept(visitor ExprVisitor) (interface{}, error) {
+	return visitor.VisitErrorExpr(expr)
+}
+
 type GetExpr struct {
 	Obj  Expr
 	Name *Token
+	Span
 }
 
 func NewGetExpr(Obj Expr, Name *Token) *GetExpr {
-	return &GetExpr{Obj, Name}
+	return &GetExpr{Obj: Obj, Name: Name}
 }
 func (expr *GetExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitGetExpr(expr)
@@ -69,10 +88,11 @@ func (expr *GetExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 
 type GroupExpr struct {
 	Expr Expr
+	Span
 }
 
 func NewGroupExpr(Expr Expr) *GroupExpr {
-	return &GroupExpr{Expr}
+	return &GroupExpr{Expr: Expr}
 }
 func (expr *GroupExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitGroupExpr(expr)
@@ -80,10 +100,11 @@ func (expr *GroupExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 
 type LiteralExpr struct {
 	Val interface{}
+	Span
 }
 
 func NewLiteralExpr(Val interface{}) *LiteralExpr {
-	return &LiteralExpr{Val}
+	return &LiteralExpr{Val: Val}
 }
 func (expr *LiteralExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitLiteralExpr(expr)
@@ -93,10 +114,11 @@ type LogicalExpr struct {
 	Op  *Token
 	Lhs Expr
 	Rhs Expr
+	Span
 }
 
 func NewLogicalExpr(Op *Token, Lhs Expr, Rhs Expr) *LogicalExpr {
-	return &LogicalExpr{Op, Lhs, Rhs}
+	return &LogicalExpr{Op: Op, Lhs: Lhs, Rhs: Rhs}
 }
 func (expr *LogicalExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitLogicalExpr(expr)
@@ -106,10 +128,11 @@ type SetExpr struct {
 	Obj  Expr
 	Name *Token
 	Val  Expr
+	Span
 }
 
 func NewSetExpr(Obj Expr, Name *Token, Val Expr) *SetExpr {
-	return &SetExpr{Obj, Name, Val}
+	return &SetExpr{Obj: Obj, Name: Name, Val: Val}
 }
 func (expr *SetExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitSetExpr(expr)
@@ -118,10 +141,11 @@ func (expr *SetExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 type SuperExpr struct {
 	Keyword *Token
 	Method  *Token
+	Span
 }
 
 func NewSuperExpr(Keyword *Token, Method *Token) *SuperExpr {
-	return &SuperExpr{Keyword, Method}
+	return &SuperExpr{Keyword: Keyword, Method: Method}
 }
 func (expr *SuperExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitSuperExpr(expr)
@@ -129,10 +153,11 @@ func (expr *SuperExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 
 type ThisExpr struct {
 	Keyword *Token
+	Span
 }
 
 func NewThisExpr(Keyword *Token) *ThisExpr {
-	return &ThisExpr{Keyword}
+	return &ThisExpr{Keyword: Keyword}
 }
 func (expr *ThisExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitThisExpr(expr)
@@ -141,10 +166,11 @@ func (expr *ThisExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 type UnaryExpr struct {
 	Op   *Token
 	Expr Expr
+	Span
 }
 
 func NewUnaryExpr(Op *Token, Expr Expr) *UnaryExpr {
-	return &UnaryExpr{Op, Expr}
+	return &UnaryExpr{Op: Op, Expr: Expr}
 }
 func (expr *UnaryExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitUnaryExpr(expr)
@@ -152,10 +178,11 @@ func (expr *UnaryExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 
 type VarExpr struct {
 	Name *Token
+	Span
 }
 
 func NewVarExpr(Name *Token) *VarExpr {
-	return &VarExpr{Name}
+	return &VarExpr{Name: Name}
 }
 func (expr *VarExpr) Accept(visitor ExprVisitor) (interface{}, error) {
 	return visitor.VisitVarExpr(expr)
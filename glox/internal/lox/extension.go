@@ -0,0 +1,83 @@
+package lox
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+)
+
+// Extension registers a bundle of native functions with an interpreter, e.g.
+// with RegisterFunc, for the scripts of a domain. The error is the one of the
+// first function that couldn't be registered.
+type Extension func(in *Interpreter) error
+
+var (
+	extensionsMu sync.RWMutex
+	extensions   = make(map[string]Extension)
+)
+
+// RegisterExtension makes the extension available under the given name, so it
+// can be loaded with Interpreter.LoadExtension, e.g. by "glox -ext name". It's
+// meant to be called from the init function of the package that provides the
+// extension, and panics if the name is already taken.
+func RegisterExtension(name string, ext Extension) {
+	extensionsMu.Lock()
+	defer extensionsMu.Unlock()
+	if _, ok := extensions[name]; ok {
+		panic("lox: RegisterExtension called twice for extension " + name)
+	}
+	extensions[name] = ext
+}
+
+// ExtensionNames returns the names of the extensions that were registered, in
+// alphabetical order.
+func ExtensionNames() []string {
+	extensionsMu.RLock()
+	defer extensionsMu.RUnlock()
+	names := make([]string, 0, len(extensions))
+	for name := range extensions {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadExtension registers the native functions of the extension with the given
+// name with the interpreter. An error is returned if there's no such
+// extension, or if one of its functions couldn't be registered.
+func (in *Interpreter) LoadExtension(name string) error {
+	extensionsMu.RLock()
+	ext, ok := extensions[name]
+	extensionsMu.RUnlock()
+	if !ok {
+		return fmt.Errorf("lox: unknown extension %s", name)
+	}
+	if err := ext(in); err != nil {
+		return fmt.Errorf("lox: loading extension %s: %w", name, err)
+	}
+	return nil
+}
+
+func init() {
+	RegisterExtension("math", loadMath)
+}
+
+// loadMath registers the functions of the "math" extension.
+func loadMath(in *Interpreter) error {
+	for name, fn := range map[string]interface{}{
+		"abs":   math.Abs,
+		"ceil":  math.Ceil,
+		"floor": math.Floor,
+		"max":   math.Max,
+		"min":   math.Min,
+		"pow":   math.Pow,
+		"round": math.Round,
+		"sqrt":  math.Sqrt,
+	} {
+		if err := in.RegisterFunc(name, fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
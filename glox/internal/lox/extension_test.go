@@ -0,0 +1,37 @@
+package lox
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpreterLoadExtension(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&out), false)
+	assert.NoError(in.LoadExtension("math"))
+	in.Interpret(parseScript(t, in, "print sqrt(16) + floor(1.5) + max(2, pow(2, 3));"))
+	assert.Equal("13\n", out.String())
+
+	assert.EqualError(in.LoadExtension("unknown"), "lox: unknown extension unknown")
+}
+
+func TestRegisterExtension(t *testing.T) {
+	assert := assert.New(t)
+
+	errFailed := errors.New("failed")
+	RegisterExtension("test.failing", func(in *Interpreter) error {
+		return errFailed
+	})
+	assert.Contains(ExtensionNames(), "math")
+	assert.Contains(ExtensionNames(), "test.failing")
+	assert.Panics(func() { RegisterExtension("math", loadMath) })
+
+	in := NewInterpreter(nil, NewSimpleReporter(nil), false)
+	err := in.LoadExtension("test.failing")
+	assert.True(errors.Is(err, errFailed))
+}
@@ -0,0 +1,231 @@
+package lox
+
+import (
+	"bytes"
+	"strings"
+)
+
+// formatIndent is the string used for each level of indentation.
+const formatIndent = "  "
+
+// Format pretty-prints the source that the given tokens were scanned from with
+// canonical spacing, indentation, and brace placement. The formatter works on
+// the token stream rather than on the syntax tree, because the parser desugars
+// some statements, e.g. "for" loops, which must be printed as they were
+// written. Comments attached to the tokens are kept, and so is a single blank
+// line wherever there were blank lines between two statements. The tokens
+// should come from a source that parses without errors, otherwise the output
+// is unspecified.
+func Format(tokens []*Token) []byte {
+	f := new(formatter)
+	f.format(tokens)
+	return f.out.Bytes()
+}
+
+type formatter struct {
+	out    bytes.Buffer
+	indent int
+	// parens is the number of parentheses that are currently open, a ';'
+	// within parentheses separates the clauses of a "for" loop.
+	parens int
+	// newlines is the number of line breaks that must be written before the
+	// next token or comment. Line breaks are written lazily so that a comment
+	// can still be appended to the end of the current line.
+	newlines int
+	// lastLine is the source line on which the last written token or comment
+	// ends.
+	lastLine int
+	// lineStart is true if nothing but indentation has been written on the
+	// current line.
+	lineStart bool
+	// prev is the last written token, prevUnary is true if it's an unary
+	// operator, and prevComment is true if a comment was written after it.
+	prev        *Token
+	prevUnary   bool
+	prevComment bool
+}
+
+func (f *formatter) format(tokens []*Token) {
+	f.lineStart = true
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		f.writeComments(tok)
+		if tok.Type == EOF {
+			break
+		}
+		f.keepBlankLine(startLine(tok))
+		next := tokens[i+1]
+
+		switch tok.Type {
+		case L_BRACE:
+			f.write(tok, false)
+			if next.Type == R_BRACE && len(next.Comments) == 0 {
+				// an empty body stays on a single line
+				i++
+				next = tokens[i+1]
+				f.write(tokens[i], false)
+				f.afterRBrace(next)
+			} else {
+				f.indent++
+				f.newline()
+			}
+		case R_BRACE:
+			f.indent--
+			f.newline()
+			f.write(tok, false)
+			f.afterRBrace(next)
+		case SEMICOLON:
+			f.write(tok, false)
+			if f.parens == 0 {
+				f.newline()
+			}
+		case L_PAREN:
+			f.write(tok, false)
+			f.parens++
+		case R_PAREN:
+			f.write(tok, false)
+			f.parens--
+		case MINUS, BANG:
+			f.write(tok, tok.Type == BANG || !endsOperand(f.prev))
+		default:
+			f.write(tok, false)
+		}
+	}
+	if f.out.Len() != 0 {
+		f.out.WriteByte('\n')
+	}
+}
+
+// writeComments writes the comments that come before the given token. A
+// comment that starts on the line of the previous token stays at the end of
+// that line, other comments are written on their own lines unless they're
+// block comments followed by the token on the same line.
+func (f *formatter) writeComments(tok *Token) {
+	for _, c := range tok.Comments {
+		lineComment := strings.HasPrefix(c.Text, "//")
+		if f.prev != nil && c.Line == f.lastLine {
+			if f.prevComment || f.prev.Type != L_PAREN {
+				f.out.WriteByte(' ')
+			}
+		} else {
+			if f.prev != nil {
+				f.newline()
+				f.keepBlankLine(c.Line)
+			}
+			f.flush()
+			if !f.lineStart {
+				f.out.WriteByte(' ')
+			}
+		}
+		f.out.WriteString(c.Text)
+		f.lastLine = c.EndLine()
+		f.lineStart = false
+		f.prevComment = true
+		if lineComment || c.EndLine() < startLine(tok) {
+			f.newline()
+		}
+	}
+}
+
+// keepBlankLine keeps at most one of the blank lines that separate two
+// statements, the given line is where the next token or comment starts.
+func (f *formatter) keepBlankLine(line int) {
+	if f.newlines == 0 || line <= f.lastLine+1 {
+		return
+	}
+	if f.prev != nil && f.prev.Type == L_BRACE && f.lastLine == f.prev.Line {
+		return
+	}
+	f.newlines = 2
+}
+
+// afterRBrace decides what follows a closing brace, "else" is kept on the same
+// line, everything else goes on a new line.
+func (f *formatter) afterRBrace(next *Token) {
+	if next.Type != ELSE || len(next.Comments) != 0 {
+		f.newline()
+	}
+}
+
+// newline makes sure that the next token or comment starts on a new line.
+func (f *formatter) newline() {
+	if f.out.Len() != 0 && f.newlines == 0 {
+		f.newlines = 1
+	}
+}
+
+// flush writes the pending line breaks followed by the indentation.
+func (f *formatter) flush() {
+	if f.newlines == 0 {
+		return
+	}
+	for ; f.newlines > 0; f.newlines-- {
+		f.out.WriteByte('\n')
+	}
+	f.out.WriteString(strings.Repeat(formatIndent, f.indent))
+	f.lineStart = true
+}
+
+// write writes the token, separating it from the previous token if needed.
+func (f *formatter) write(tok *Token, unary bool) {
+	f.flush()
+	if !f.lineStart && f.needSpace(tok) {
+		f.out.WriteByte(' ')
+	}
+	f.out.WriteString(tok.Lexeme)
+	f.lastLine = tok.Line
+	f.lineStart = false
+	f.prev = tok
+	f.prevUnary = unary
+	f.prevComment = false
+}
+
+// needSpace returns true if a space must be written between the previous token
+// and the given token that is on the same line.
+func (f *formatter) needSpace(tok *Token) bool {
+	prev := f.prev
+	if f.prevComment {
+		return tok.Type != SEMICOLON && tok.Type != COMMA && tok.Type != R_PAREN
+	}
+	if f.prevUnary {
+		return false
+	}
+	switch prev.Type {
+	case L_PAREN, DOT:
+		return false
+	case L_BRACE:
+		return tok.Type != R_BRACE
+	case SEMICOLON:
+		// empty clauses of a "for" loop, e.g. "for (;;)"
+		return tok.Type != SEMICOLON && tok.Type != R_PAREN
+	}
+	switch tok.Type {
+	case SEMICOLON, COMMA, R_PAREN, DOT:
+		return false
+	case L_PAREN:
+		// calls and declarations are written without a space, but control
+		// flow keywords and operators are followed by one
+		return !endsOperand(prev)
+	}
+	return true
+}
+
+// endsOperand returns true if the token can be the last token of an operand,
+// which means that a following "-" is a binary operator and a following "("
+// is a call.
+func endsOperand(tok *Token) bool {
+	if tok == nil {
+		return false
+	}
+	switch tok.Type {
+	case IDENT, NUMBER, STRING, R_PAREN, TRUE, FALSE, NIL, THIS:
+		return true
+	}
+	return false
+}
+
+// startLine returns the line on which the token starts. Tokens store the line
+// where they end, which is different for multi-line strings.
+func startLine(tok *Token) int {
+	return tok.Line - strings.Count(tok.Lexeme, "\n")
+}
@@ -0,0 +1,94 @@
+package lox
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func format(source string) string {
+	tokens := NewScanner([]byte(source), NewSimpleReporter(ioutil.Discard)).Scan()
+	return string(Format(tokens))
+}
+
+func TestFormat(t *testing.T) {
+	assert := assert.New(t)
+
+	source := `class   Point<Base{init(x,y){this.x=x;this.y=y;}
+norm(){return -this.x*-1+!true;}  empty(){}}
+
+
+fun main( ) {
+for(var i=0;i<10;i=i+1)print i;
+for(;;){}
+if(a==nil)print "a";else if (b) { print(b); } else {print "multi
+line";}
+while (!done) done = f(1, -2)(3).g;
+}
+`
+	expected := `class Point < Base {
+  init(x, y) {
+    this.x = x;
+    this.y = y;
+  }
+  norm() {
+    return -this.x * -1 + !true;
+  }
+  empty() {}
+}
+
+fun main() {
+  for (var i = 0; i < 10; i = i + 1) print i;
+  for (;;) {}
+  if (a == nil) print "a";
+  else if (b) {
+    print (b);
+  } else {
+    print "multi
+line";
+  }
+  while (!done) done = f(1, -2)(3).g;
+}
+`
+	assert.Equal(expected, format(source))
+	// formatting is idempotent
+	assert.Equal(expected, format(expected))
+}
+
+func TestFormatKeepsComments(t *testing.T) {
+	assert := assert.New(t)
+
+	source := `// header comment
+
+
+var a = 1; // trailing
+/* block
+   comment */ var b = 2;
+fun f(/* none */) {  // after brace
+    print a;
+
+
+    // before brace
+}
+if (a) print a;   // after if
+else print b;
+// at the end
+`
+	expected := `// header comment
+
+var a = 1; // trailing
+/* block
+   comment */ var b = 2;
+fun f(/* none */) { // after brace
+  print a;
+
+  // before brace
+}
+if (a) print a; // after if
+else print b;
+// at the end
+`
+	assert.Equal(expected, format(source))
+	assert.Equal(expected, format(expected))
+}
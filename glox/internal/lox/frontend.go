@@ -0,0 +1,67 @@
+package lox
+
+// Scan scans the source and returns its tokens, which end with an EOF token,
+// and the diagnostics of the errors that were found. The characters that
+// can't be scanned are skipped, so the tokens can be used even if there are
+// errors, e.g. to highlight the source.
+func Scan(source []byte) ([]*Token, []*Diagnostic) {
+	collector := new(diagnosticCollector)
+	tokens := NewScanner(source, collector).Scan()
+	return tokens, collector.diagnostics
+}
+
+// Parse scans and parses the source, and returns its statements and the
+// diagnostics of the errors that were found. The parser skips to the next
+// statement after an error, the statements that couldn't be parsed are nil.
+// The statements aren't resolved, see NewResolver.
+func Parse(source []byte) ([]Stmt, []*Diagnostic) {
+	collector := new(diagnosticCollector)
+	tokens := NewScanner(source, collector).Scan()
+	statements := NewParser(tokens, collector).Parse()
+	return statements, collector.diagnostics
+}
+
+// ParsePartial is like Parse but keeps what it can of the statements that have
+// syntax errors, with error nodes in place of the parts that couldn't be
+// parsed, see Parser.SetTolerant. It's meant for tools that need a tree of a
+// file while it's being edited, e.g. for completion or an outline.
+func ParsePartial(source []byte) ([]Stmt, []*Diagnostic) {
+	collector := new(diagnosticCollector)
+	tokens := NewScanner(source, collector).Scan()
+	parser := NewParser(tokens, collector)
+	parser.SetTolerant(true)
+	statements := parser.Parse()
+	return statements, collector.diagnostics
+}
+
+// diagnosticCollector is a reporter that keeps the diagnostics that it's given.
+type diagnosticCollector struct {
+	diagnostics   []*Diagnostic
+	hadErr        bool
+	hadRuntimeErr bool
+}
+
+func (c *diagnosticCollector) Report(d *Diagnostic) {
+	c.diagnostics = append(c.diagnostics, d)
+	switch {
+	case d.Severity == SeverityWarning:
+	case d.Stage == StageRuntime:
+		c.hadRuntimeErr = true
+	default:
+		c.hadErr = true
+	}
+}
+
+func (c *diagnosticCollector) Reset() {
+	c.diagnostics = nil
+	c.hadErr = false
+	c.hadRuntimeErr = false
+}
+
+func (c *diagnosticCollector) HadError() bool {
+	return c.hadErr
+}
+
+func (c *diagnosticCollector) HadRuntimeError() bool {
+	return c.hadRuntimeErr
+}
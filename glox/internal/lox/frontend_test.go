@@ -0,0 +1,61 @@
+package lox
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScan(t *testing.T) {
+	assert := assert.New(t)
+
+	tokens, diagnostics := Scan([]byte("var a = @1;"))
+	var types []TokenType
+	for _, token := range tokens {
+		types = append(types, token.Type)
+	}
+	assert.Equal([]TokenType{VAR, IDENT, EQUAL, NUMBER, SEMICOLON, EOF}, types)
+	if assert.Len(diagnostics, 1) {
+		assert.Equal(StageScan, diagnostics[0].Stage)
+		assert.Equal("Unexpected character.", diagnostics[0].Message)
+		assert.Equal(1, diagnostics[0].Line)
+	}
+
+	_, diagnostics = Scan([]byte("print 1;"))
+	assert.Empty(diagnostics)
+}
+
+func TestParse(t *testing.T) {
+	assert := assert.New(t)
+
+	statements, diagnostics := Parse([]byte("print 1;\nvar = 2;\nprint \"a\" + @;"))
+	if assert.Len(statements, 3) {
+		assert.IsType(&PrintStmt{}, statements[0])
+		assert.Nil(statements[1])
+		assert.Nil(statements[2])
+	}
+	if assert.Len(diagnostics, 3) {
+		assert.Equal(StageScan, diagnostics[0].Stage)
+		assert.Equal(3, diagnostics[0].Line)
+		assert.Equal(StageParse, diagnostics[1].Stage)
+		assert.Equal("Expect variable name.", diagnostics[1].Message)
+		assert.Equal(2, diagnostics[1].Line)
+		assert.Equal(3, diagnostics[2].Line)
+	}
+
+	statements, diagnostics = Parse([]byte("fun f() { return 1; }"))
+	assert.Len(statements, 1)
+	assert.Empty(diagnostics)
+}
+
+func TestParsePartial(t *testing.T) {
+	assert := assert.New(t)
+
+	statements, diagnostics := ParsePartial([]byte("print 1;\nvar = 2;\nprint \"a\" + @;"))
+	if assert.Len(statements, 3) {
+		assert.IsType(&PrintStmt{}, statements[0])
+		assert.IsType(&ErrorStmt{}, statements[1])
+		assert.IsType(&ErrorExpr{}, statements[2].(*PrintStmt).Expr.(*BinaryExpr).Rhs)
+	}
+	assert.Len(diagnostics, 3)
+}
@@ -0,0 +1,82 @@
+//go:build go1.18
+// +build go1.18
+
+package lox
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// The fuzz targets check that no input can crash the scanner, the parser, the
+// resolver, or the interpreter. Errors are expected and ignored, only panics
+// fail. They are run with e.g. "go test -fuzz=FuzzParse ./internal/lox".
+
+// addFuzzSeeds adds a few scripts and the vendored test suite to the corpus.
+func addFuzzSeeds(f *testing.F) {
+	for _, seed := range []string{
+		"",
+		"print 1 + 2 * -3;",
+		`var a = "str"; { var a = a; }`,
+		"fun f(a, b) { return a(b); } f(clock, nil);",
+		"class A < B { init() { super.init(this.x); } }",
+		"for (;;) if (!true) while (false or nil) {}",
+		"/* unterminated",
+		"\"unterminated",
+		"print " + strings.Repeat("(", 2000) + "1;",
+	} {
+		f.Add([]byte(seed))
+	}
+	filepath.Walk(filepath.Join("..", "..", "..", "testsuite", "test"), func(fpath string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() && filepath.Ext(fpath) == ".lox" {
+			if source, err := ioutil.ReadFile(fpath); err == nil {
+				f.Add(source)
+			}
+		}
+		return nil
+	})
+}
+
+func FuzzScan(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, source []byte) {
+		NewScanner(source, NewSimpleReporter(ioutil.Discard)).Scan()
+	})
+}
+
+func FuzzParse(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, source []byte) {
+		reporter := NewSimpleReporter(ioutil.Discard)
+		tokens := NewScanner(source, reporter).Scan()
+		statements := NewParser(tokens, reporter).Parse()
+		if !reporter.HadError() {
+			in := NewInterpreter(ioutil.Discard, reporter, false)
+			NewResolver(in, reporter).Resolve(statements)
+		}
+	})
+}
+
+func FuzzInterpret(f *testing.F) {
+	addFuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, source []byte) {
+		reporter := NewSimpleReporter(ioutil.Discard)
+		in := NewInterpreter(ioutil.Discard, reporter, false)
+		// scripts that run forever or allocate without bounds aren't bugs
+		in.SetLimits(10000, 0)
+		in.SetMemoryBudget(1000, 1<<20)
+		in.SetMaxCallDepth(100)
+
+		tokens := NewScanner(source, reporter).Scan()
+		statements := NewParser(tokens, reporter).Parse()
+		if !reporter.HadError() {
+			NewResolver(in, reporter).Resolve(statements)
+		}
+		if !reporter.HadError() {
+			in.Interpret(statements)
+		}
+	})
+}
@@ -0,0 +1,566 @@
+package lox
+
+import (
+	_ "embed"
+	"errors"
+	"fmt"
+	goformat "go/format"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goRuntime is the prelude of every generated program, it holds the "main"
+// function and the helpers that implement the semantics of Lox. It's kept out
+// of the build of this package since it's the source of another one.
+//
+//go:embed goruntime.go.tmpl
+var goRuntime string
+
+// TranspileGo translates the statements of a script into the source of a Go
+// program of package "main" that behaves like glox running the script: it
+// prints the same output, and it stops at the first runtime error, which is
+// reported on stderr with the status 70. The report is followed by the Lox
+// calls that led to the error, from the innermost one, e.g. "in fib at line
+// 3" and then "at top level line 5". The statements must have been
+// resolved without errors, otherwise the program may not compile. A tree
+// with the nodes of a syntax error, see Parser.SetTolerant, can't be
+// translated. The name of the script is given in the header of the generated
+// file.
+//
+// With lines, every statement is preceded by a "//line" directive naming the
+// script and the line of the statement, so the positions that Go reports in
+// panics and stack traces, and that debuggers such as Delve show, are those
+// of the script instead of the generated file. The name should then be the
+// path of the script.
+//
+// The program is meant to be readable. Local variables become Go variables
+// with the same names, functions become Go closures that capture them, and
+// classes become structs holding the maps of their methods. Global variables
+// are looked up by name at runtime, since Lox allows using them before they
+// are declared.
+func TranspileGo(name string, statements []Stmt, lines bool) ([]byte, error) {
+	t := new(goTranspiler)
+	if lines {
+		t.script = name
+	}
+	fmt.Fprintf(&t.out, "// Code generated by glox from %s. DO NOT EDIT.\n\npackage main\n\n", name)
+	t.out.WriteString(goRuntime)
+	t.out.WriteString("\nfunc loxMain() {\n")
+	for _, stmt := range statements {
+		t.stmt(stmt)
+	}
+	if t.err != nil {
+		return nil, t.err
+	}
+	t.out.WriteString("}\n")
+	return goformat.Source([]byte(t.out.String()))
+}
+
+// goReserved holds the names that a Lox identifier can't keep in Go, the
+// predeclared identifiers and the packages imported by the runtime. The
+// names starting with "lox" are reserved as well for the runtime.
+var goReserved = map[string]bool{
+	"_": true, "break": true, "case": true, "chan": true, "const": true,
+	"continue": true, "default": true, "defer": true, "else": true,
+	"fallthrough": true, "for": true, "func": true, "go": true, "goto": true,
+	"if": true, "import": true, "interface": true, "map": true,
+	"package": true, "range": true, "return": true, "select": true,
+	"struct": true, "switch": true, "type": true, "var": true,
+	"any": true, "append": true, "bool": true, "byte": true, "cap": true,
+	"clear": true, "close": true, "comparable": true, "complex": true,
+	"complex64": true, "complex128": true, "copy": true, "delete": true,
+	"error": true, "false": true, "float32": true, "float64": true,
+	"imag": true, "int": true, "int8": true, "int16": true, "int32": true,
+	"int64": true, "iota": true, "len": true, "make": true, "max": true,
+	"min": true, "new": true, "nil": true, "panic": true, "print": true,
+	"println": true, "real": true, "recover": true, "rune": true,
+	"string": true, "true": true, "uint": true, "uint8": true,
+	"uint16": true, "uint32": true, "uint64": true, "uintptr": true,
+	"bufio": true, "fmt": true, "math": true, "os": true, "strconv": true,
+	"time": true, "this": true,
+}
+
+// goName returns the name of the Go variable holding the Lox variable.
+func goName(name string) string {
+	if goReserved[name] || strings.HasPrefix(name, "lox") {
+		return name + "_"
+	}
+	return name
+}
+
+// goVar is a local variable of the generated program.
+type goVar struct {
+	name string
+	// used is true if the variable is read, otherwise the Go compiler rejects
+	// its declaration and a blank assignment is added after it.
+	used bool
+	// declEnd is the offset in the output right after the declaration.
+	declEnd int
+}
+
+type goScope struct {
+	vars  map[string]*goVar
+	order []*goVar
+}
+
+// goTranspiler writes the Go source of the statements as it visits them.
+// Expressions are visited for the source of the Go expression that they
+// become. Formatting is left to go/format.
+type goTranspiler struct {
+	out strings.Builder
+	// script is the name of the script in the line directives, there's no
+	// directive if it's empty.
+	script string
+	// scopes holds the local scopes from the outermost to the innermost,
+	// variables that aren't found in them are global.
+	scopes []*goScope
+	// super is the Go variable holding the superclass in the methods of a
+	// subclass, and nSupers is the number of such variables so far.
+	super   string
+	nSupers int
+	// initializer is true within the body of an "init" method, which returns
+	// "this" instead of nil.
+	initializer bool
+	// err is the first node of a syntax error that was found
+	err error
+}
+
+func (t *goTranspiler) VisitBlockStmt(stmt *BlockStmt) (interface{}, error) {
+	t.out.WriteString("{\n")
+	t.block(stmt.Stmts)
+	t.out.WriteString("}\n")
+	return nil, nil
+}
+
+func (t *goTranspiler) VisitClassStmt(stmt *ClassStmt) (interface{}, error) {
+	enclosingSuper := t.super
+	t.super = ""
+	super := "nil"
+	if stmt.Super != nil {
+		t.nSupers++
+		t.super = fmt.Sprintf("loxSuper%d", t.nSupers)
+		fmt.Fprintf(&t.out, "%s := loxSuperclass(%d, %s)\n", t.super, stmt.Super.Name.Line, t.expr(stmt.Super))
+		super = t.super
+	}
+
+	var class strings.Builder
+	fmt.Fprintf(&class, "loxNewClass(%q, %s", stmt.Name.Lexeme, super)
+	t.declare(stmt.Name, func() {
+		t.out.WriteString(class.String())
+		for _, method := range stmt.Methods {
+			fmt.Fprintf(&t.out, ",\n&loxMethod{name: %q, arity: %d, fn: func(this loxValue, ", method.Name.Lexeme, len(method.Params))
+			t.function(method, method.Name.Lexeme == "init")
+			t.out.WriteString("}")
+		}
+		if len(stmt.Methods) > 0 {
+			t.out.WriteString(",\n")
+		}
+		t.out.WriteString(")")
+	})
+	t.super = enclosingSuper
+	return nil, nil
+}
+
+func (t *goTranspiler) VisitErrorStmt(stmt *ErrorStmt) (interface{}, error) {
+	t.syntaxError(stmtToken(stmt))
+	return nil, nil
+}
+
+func (t *goTranspiler) VisitExprStmt(stmt *ExprStmt) (interface{}, error) {
+	switch expr := stmt.Expr.(type) {
+	case *AssignExpr:
+		if v := t.lookup(expr.Name); v != nil {
+			// the value of the assignment isn't needed
+			fmt.Fprintf(&t.out, "%s = %s\n", v.name, t.expr(expr.Val))
+			return nil, nil
+		}
+		t.out.WriteString(t.expr(expr) + "\n")
+	case *CallExpr, *SetExpr:
+		t.out.WriteString(t.expr(expr) + "\n")
+	default:
+		val := t.expr(expr)
+		if val == "nil" {
+			// an untyped nil can't be discarded
+			val = "loxValue(nil)"
+		}
+		t.out.WriteString("_ = " + val + "\n")
+	}
+	return nil, nil
+}
+
+func (t *goTranspiler) VisitFunctionStmt(stmt *FunctionStmt) (interface{}, error) {
+	t.declare(stmt.Name, func() {
+		fmt.Fprintf(&t.out, "loxNewFunction(%q, %d, func(", stmt.Name.Lexeme, len(stmt.Params))
+		t.function(stmt, false)
+		t.out.WriteString(")")
+	})
+	return nil, nil
+}
+
+func (t *goTranspiler) VisitIfStmt(stmt *IfStmt) (interface{}, error) {
+	fmt.Fprintf(&t.out, "if loxTruthy(%s) {\n", t.expr(stmt.Cond))
+	t.branch(stmt.ThenBranch)
+	for stmt.ElseBranch != nil {
+		elif, ok := stmt.ElseBranch.(*IfStmt)
+		if !ok {
+			t.out.WriteString("} else {\n")
+			t.branch(stmt.ElseBranch)
+			break
+		}
+		fmt.Fprintf(&t.out, "} else if loxTruthy(%s) {\n", t.expr(elif.Cond))
+		t.branch(elif.ThenBranch)
+		stmt = elif
+	}
+	t.out.WriteString("}\n")
+	return nil, nil
+}
+
+func (t *goTranspiler) VisitPrintStmt(stmt *PrintStmt) (interface{}, error) {
+	fmt.Fprintf(&t.out, "loxPrint(%s)\n", t.expr(stmt.Expr))
+	return nil, nil
+}
+
+func (t *goTranspiler) VisitReturnStmt(stmt *ReturnStmt) (interface{}, error) {
+	switch {
+	case t.initializer:
+		t.out.WriteString("return this\n")
+	case stmt.Val == nil:
+		t.out.WriteString("return nil\n")
+	default:
+		fmt.Fprintf(&t.out, "return %s\n", t.expr(stmt.Val))
+	}
+	return nil, nil
+}
+
+func (t *goTranspiler) VisitVarStmt(stmt *VarStmt) (interface{}, error) {
+	init := "nil"
+	if stmt.Init != nil {
+		init = t.expr(stmt.Init)
+	}
+	if len(t.scopes) == 0 {
+		fmt.Fprintf(&t.out, "loxDefine(%q, %s)\n", stmt.Name.Lexeme, init)
+		return nil, nil
+	}
+	v := t.define(stmt.Name)
+	if stmt.Init == nil {
+		fmt.Fprintf(&t.out, "var %s loxValue\n", v.name)
+	} else {
+		fmt.Fprintf(&t.out, "var %s loxValue = %s\n", v.name, init)
+	}
+	v.declEnd = t.out.Len()
+	return nil, nil
+}
+
+func (t *goTranspiler) VisitWhileStmt(stmt *WhileStmt) (interface{}, error) {
+	fmt.Fprintf(&t.out, "for loxTruthy(%s) {\n", t.expr(stmt.Cond))
+	t.branch(stmt.Body)
+	t.out.WriteString("}\n")
+	return nil, nil
+}
+
+func (t *goTranspiler) VisitAssignExpr(expr *AssignExpr) (interface{}, error) {
+	val := t.expr(expr.Val)
+	if v := t.lookup(expr.Name); v != nil {
+		// taking the address counts as a use
+		v.used = true
+		return fmt.Sprintf("loxAssign(&%s, %s)", v.name, val), nil
+	}
+	return fmt.Sprintf("loxAssignGlobal(%d, %q, %s)", expr.Name.Line, expr.Name.Lexeme, val), nil
+}
+
+// goBinaryOps maps the binary operators to the runtime functions implementing
+// them, the functions that can't fail don't take the line.
+var goBinaryOps = map[TokenType]string{
+	PLUS:          "loxAdd",
+	MINUS:         "loxSubtract",
+	STAR:          "loxMultiply",
+	SLASH:         "loxDivide",
+	GREATER:       "loxGreater",
+	GREATER_EQUAL: "loxGreaterEqual",
+	LESS:          "loxLess",
+	LESS_EQUAL:    "loxLessEqual",
+	EQUAL_EQUAL:   "loxEqual",
+	BANG_EQUAL:    "loxNotEqual",
+}
+
+func (t *goTranspiler) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
+	operands := t.operands(expr.Lhs, expr.Rhs)
+	fn := goBinaryOps[expr.Op.Type]
+	if expr.Op.Type == EQUAL_EQUAL || expr.Op.Type == BANG_EQUAL {
+		return fmt.Sprintf("%s(%s, %s)", fn, operands[0], operands[1]), nil
+	}
+	return fmt.Sprintf("%s(%d, %s, %s)", fn, expr.Op.Line, operands[0], operands[1]), nil
+}
+
+func (t *goTranspiler) VisitCallExpr(expr *CallExpr) (interface{}, error) {
+	operands := t.operands(append([]Expr{expr.Callee}, expr.Args...)...)
+	return fmt.Sprintf("loxCall(%d, %s)", expr.Paren.Line, strings.Join(operands, ", ")), nil
+}
+
+func (t *goTranspiler) VisitErrorExpr(expr *ErrorExpr) (interface{}, error) {
+	t.syntaxError(expr.Token)
+	return "nil", nil
+}
+
+func (t *goTranspiler) VisitGetExpr(expr *GetExpr) (interface{}, error) {
+	return fmt.Sprintf("loxGet(%d, %s, %q)", expr.Name.Line, t.expr(expr.Obj), expr.Name.Lexeme), nil
+}
+
+func (t *goTranspiler) VisitGroupExpr(expr *GroupExpr) (interface{}, error) {
+	// calls of the runtime make the grouping explicit
+	return t.expr(expr.Expr), nil
+}
+
+func (t *goTranspiler) VisitLiteralExpr(expr *LiteralExpr) (interface{}, error) {
+	switch val := expr.Val.(type) {
+	case nil:
+		return "nil", nil
+	case bool:
+		return strconv.FormatBool(val), nil
+	case string:
+		return strconv.Quote(val), nil
+	case float64:
+		if math.IsInf(val, 1) {
+			return "math.Inf(1)", nil
+		}
+		s := strconv.FormatFloat(val, 'f', -1, 64)
+		if !strings.Contains(s, ".") {
+			// an untyped integer constant would become an int
+			s += ".0"
+		}
+		return s, nil
+	}
+	panic(fmt.Sprintf("unexpected literal %#v", expr.Val))
+}
+
+func (t *goTranspiler) VisitLogicalExpr(expr *LogicalExpr) (interface{}, error) {
+	fn := "loxAnd"
+	if expr.Op.Type == OR {
+		fn = "loxOr"
+	}
+	// the right operand is only evaluated if it's needed
+	return fmt.Sprintf("%s(%s, func() loxValue { return %s })", fn, t.expr(expr.Lhs), t.expr(expr.Rhs)), nil
+}
+
+func (t *goTranspiler) VisitSetExpr(expr *SetExpr) (interface{}, error) {
+	// the object is checked before the value is evaluated
+	obj := fmt.Sprintf("loxFields(%d, %s)", expr.Name.Line, t.expr(expr.Obj))
+	return fmt.Sprintf("loxSet(%s, %q, %s)", obj, expr.Name.Lexeme, t.expr(expr.Val)), nil
+}
+
+func (t *goTranspiler) VisitSuperExpr(expr *SuperExpr) (interface{}, error) {
+	return fmt.Sprintf("loxSuper(%d, %s, this, %q)", expr.Method.Line, t.super, expr.Method.Lexeme), nil
+}
+
+func (t *goTranspiler) VisitThisExpr(expr *ThisExpr) (interface{}, error) {
+	return "this", nil
+}
+
+func (t *goTranspiler) VisitUnaryExpr(expr *UnaryExpr) (interface{}, error) {
+	if expr.Op.Type == BANG {
+		return fmt.Sprintf("loxNot(%s)", t.expr(expr.Expr)), nil
+	}
+	return fmt.Sprintf("loxNegate(%d, %s)", expr.Op.Line, t.expr(expr.Expr)), nil
+}
+
+func (t *goTranspiler) VisitVarExpr(expr *VarExpr) (interface{}, error) {
+	if v := t.lookup(expr.Name); v != nil {
+		v.used = true
+		return v.name, nil
+	}
+	return fmt.Sprintf("loxGlobal(%d, %q)", expr.Name.Line, expr.Name.Lexeme), nil
+}
+
+func (t *goTranspiler) stmt(stmt Stmt) {
+	if line := stmtLine(stmt); t.script != "" && line != 0 {
+		// directives must start the line, which go/format preserves
+		fmt.Fprintf(&t.out, "//line %s:%d\n", t.script, line)
+	}
+	stmt.Accept(t)
+}
+
+// syntaxError records the node of a syntax error found at the given token, if
+// it's the first one.
+func (t *goTranspiler) syntaxError(token *Token) {
+	if t.err != nil {
+		return
+	}
+	if token == nil {
+		t.err = errors.New("can't translate a syntax error")
+		return
+	}
+	t.err = fmt.Errorf("[line %d] can't translate a syntax error", token.Line)
+}
+
+func (t *goTranspiler) expr(expr Expr) string {
+	s, _ := expr.Accept(t)
+	return s.(string)
+}
+
+// operands returns the Go expressions of operands that are evaluated from left
+// to right. Go only orders the function calls of an expression, so a local
+// variable is read through a call if an operand after it has side effects.
+func (t *goTranspiler) operands(exprs ...Expr) []string {
+	operands := make([]string, len(exprs))
+	for i, expr := range exprs {
+		operands[i] = t.expr(expr)
+		for group, ok := expr.(*GroupExpr); ok; group, ok = expr.(*GroupExpr) {
+			expr = group.Expr
+		}
+		if v, ok := expr.(*VarExpr); ok && t.lookup(v.Name) != nil {
+			for _, next := range exprs[i+1:] {
+				if hasSideEffects(next) {
+					operands[i] = "loxLoad(" + operands[i] + ")"
+					break
+				}
+			}
+		}
+	}
+	return operands
+}
+
+// hasSideEffects returns true if the expression may assign a local variable.
+func hasSideEffects(expr Expr) bool {
+	switch expr := expr.(type) {
+	case *AssignExpr, *CallExpr:
+		return true
+	case *BinaryExpr:
+		return hasSideEffects(expr.Lhs) || hasSideEffects(expr.Rhs)
+	case *LogicalExpr:
+		return hasSideEffects(expr.Lhs) || hasSideEffects(expr.Rhs)
+	case *GroupExpr:
+		return hasSideEffects(expr.Expr)
+	case *UnaryExpr:
+		return hasSideEffects(expr.Expr)
+	case *GetExpr:
+		return hasSideEffects(expr.Obj)
+	case *SetExpr:
+		return hasSideEffects(expr.Obj) || hasSideEffects(expr.Val)
+	}
+	return false
+}
+
+// declare writes the declaration of a function or a class, whose value is
+// written by the given function. A local one is declared before its value is
+// assigned so it can refer to itself.
+func (t *goTranspiler) declare(name *Token, value func()) {
+	if len(t.scopes) == 0 {
+		fmt.Fprintf(&t.out, "loxDefine(%q, ", name.Lexeme)
+		value()
+		t.out.WriteString(")\n")
+		return
+	}
+	v := t.define(name)
+	fmt.Fprintf(&t.out, "var %s loxValue\n%s = ", v.name, v.name)
+	value()
+	t.out.WriteString("\n")
+	v.declEnd = t.out.Len()
+}
+
+// function writes the rest of a function literal whose parameters are being
+// written, starting with the slice of arguments.
+func (t *goTranspiler) function(stmt *FunctionStmt, initializer bool) {
+	enclosingInitializer := t.initializer
+	t.initializer = initializer
+	t.out.WriteString("loxArgs []loxValue) loxValue {\n")
+	t.beginScope()
+	if len(stmt.Params) > 0 {
+		names := make([]string, len(stmt.Params))
+		args := make([]string, len(stmt.Params))
+		for i, param := range stmt.Params {
+			names[i] = t.define(param).name
+			args[i] = fmt.Sprintf("loxArgs[%d]", i)
+		}
+		fmt.Fprintf(&t.out, "%s := %s\n", strings.Join(names, ", "), strings.Join(args, ", "))
+		for _, v := range t.scopes[len(t.scopes)-1].order {
+			v.declEnd = t.out.Len()
+		}
+	}
+	for _, stmt := range stmt.Body {
+		t.stmt(stmt)
+	}
+	if len(stmt.Body) == 0 {
+		t.returnDefault()
+	} else if _, ok := stmt.Body[len(stmt.Body)-1].(*ReturnStmt); !ok {
+		t.returnDefault()
+	}
+	t.endScope()
+	t.out.WriteString("}")
+	t.initializer = enclosingInitializer
+}
+
+func (t *goTranspiler) returnDefault() {
+	if t.initializer {
+		t.out.WriteString("return this\n")
+	} else {
+		t.out.WriteString("return nil\n")
+	}
+}
+
+// branch writes the body of a conditional or a loop, the braces are already
+// written.
+func (t *goTranspiler) branch(stmt Stmt) {
+	if block, ok := stmt.(*BlockStmt); ok {
+		t.block(block.Stmts)
+		return
+	}
+	// a single statement still gets its own scope
+	t.beginScope()
+	t.stmt(stmt)
+	t.endScope()
+}
+
+func (t *goTranspiler) block(statements []Stmt) {
+	t.beginScope()
+	for _, stmt := range statements {
+		t.stmt(stmt)
+	}
+	t.endScope()
+}
+
+func (t *goTranspiler) beginScope() {
+	t.scopes = append(t.scopes, &goScope{vars: make(map[string]*goVar)})
+}
+
+// endScope adds a blank assignment after the declaration of each variable of
+// the innermost scope that is never read.
+func (t *goTranspiler) endScope() {
+	scope := t.scopes[len(t.scopes)-1]
+	t.scopes = t.scopes[:len(t.scopes)-1]
+	var unused []*goVar
+	for _, v := range scope.order {
+		if !v.used {
+			unused = append(unused, v)
+		}
+	}
+	if len(unused) == 0 {
+		return
+	}
+	// inserting from the end keeps the offsets that are before valid
+	sort.SliceStable(unused, func(i, j int) bool { return unused[i].declEnd > unused[j].declEnd })
+	out := t.out.String()
+	for _, v := range unused {
+		out = out[:v.declEnd] + "_ = " + v.name + "\n" + out[v.declEnd:]
+	}
+	t.out.Reset()
+	t.out.WriteString(out)
+}
+
+func (t *goTranspiler) define(name *Token) *goVar {
+	scope := t.scopes[len(t.scopes)-1]
+	v := &goVar{name: goName(name.Lexeme)}
+	scope.vars[name.Lexeme] = v
+	scope.order = append(scope.order, v)
+	return v
+}
+
+func (t *goTranspiler) lookup(name *Token) *goVar {
+	for i := len(t.scopes) - 1; i >= 0; i-- {
+		if v, ok := t.scopes[i].vars[name.Lexeme]; ok {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package lox
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTranspileGo(t *testing.T) {
+	assert := assert.New(t)
+
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false)
+	stmts := parseScript(t, in, `
+var a = 1;
+{
+	var len = "l";
+	var unused;
+	fun f(x) {
+		len = x;
+		return nil;
+	}
+	print len + f("m");
+	if (!a) print -a; else if (a or nil) a = 2;
+}
+class A < Object {
+	init() { super.init(); this.x = a; }
+}
+`)
+	program, err := TranspileGo("script.lox", stmts, false)
+	assert.Nil(err)
+
+	assert.True(bytes.HasPrefix(program, []byte("// Code generated by glox from script.lox. DO NOT EDIT.\n\npackage main\n")))
+	main := string(program[bytes.Index(program, []byte("func loxMain()")):])
+	assert.Equal(`func loxMain() {
+	loxDefine("a", 1.0)
+	{
+		var len_ loxValue = "l"
+		var unused loxValue
+		_ = unused
+		var f loxValue
+		f = loxNewFunction("f", 1, func(loxArgs []loxValue) loxValue {
+			x := loxArgs[0]
+			len_ = x
+			return nil
+		})
+		loxPrint(loxAdd(10, loxLoad(len_), loxCall(10, f, "m")))
+		if loxTruthy(loxNot(loxGlobal(11, "a"))) {
+			loxPrint(loxNegate(11, loxGlobal(11, "a")))
+		} else if loxTruthy(loxOr(loxGlobal(11, "a"), func() loxValue { return nil })) {
+			loxAssignGlobal(11, "a", 2.0)
+		}
+	}
+	loxSuper1 := loxSuperclass(13, loxGlobal(13, "Object"))
+	loxDefine("A", loxNewClass("A", loxSuper1,
+		&loxMethod{name: "init", arity: 0, fn: func(this loxValue, loxArgs []loxValue) loxValue {
+			loxCall(14, loxSuper(14, loxSuper1, this, "init"))
+			loxSet(loxFields(14, this), "x", loxGlobal(14, "a"))
+			return this
+		}},
+	))
+}
+`, main)
+}
+
+func TestTranspileGoLineDirectives(t *testing.T) {
+	assert := assert.New(t)
+
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false)
+	program, err := TranspileGo("/src/script.lox", parseScript(t, in, "fun f() {\n\n  return 1;\n}\nprint f();\n"), true)
+	assert.Nil(err)
+
+	main := string(program[bytes.Index(program, []byte("func loxMain()")):])
+	assert.Equal(`func loxMain() {
+//line /src/script.lox:1
+	loxDefine("f", loxNewFunction("f", 0, func(loxArgs []loxValue) loxValue {
+//line /src/script.lox:3
+		return 1.0
+	}))
+//line /src/script.lox:5
+	loxPrint(loxCall(5, loxGlobal(5, "f")))
+}
+`, main)
+}
+
+// runGoProgram transpiles the script and runs the program that the go tool
+// builds from it, and returns what it wrote on stdout and stderr, and its exit
+// status. The test is skipped if the go tool isn't installed.
+func runGoProgram(t *testing.T, script string) (string, string, int) {
+	if testing.Short() {
+		t.Skip("building a program is slow")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("the go tool isn't installed")
+	}
+
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false)
+	program, err := TranspileGo("script.lox", parseScript(t, in, script), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "go.mod"), []byte("module loxprogram\n\ngo 1.16\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "main.go"), program, 0644); err != nil {
+		t.Fatal(err)
+	}
+	build := exec.Command(goTool, "build", "-o", "program", ".")
+	build.Dir = dir
+	if output, err := build.CombinedOutput(); err != nil {
+		t.Fatalf("%v\n%s", err, output)
+	}
+
+	var stdout, stderr strings.Builder
+	run := exec.Command(filepath.Join(dir, "program"))
+	run.Stdout = &stdout
+	run.Stderr = &stderr
+	status := 0
+	if err := run.Run(); err != nil {
+		exitErr, ok := err.(*exec.ExitError)
+		if !ok {
+			t.Fatal(err)
+		}
+		status = exitErr.ExitCode()
+	}
+	return stdout.String(), stderr.String(), status
+}
+
+func TestTranspileGoRuns(t *testing.T) {
+	assert := assert.New(t)
+
+	script := fibScript + `
+fun counter() {
+	var n = 0;
+	fun count() { n = n + 1; return n; }
+	return count;
+}
+var c = counter();
+c();
+print c();
+var s = "a";
+print s + (s = "b");
+class A {
+	init(x) { this.x = x; }
+	get() { return this.x; }
+	toString() { return "A(" + this.get() + ")"; }
+}
+class B < A {
+	get() { return "b" + super.get(); }
+}
+print B("x").toString();
+print B;
+print B("y");
+print counter;
+print -0;
+print 0.1 + 0.2;
+print 1 == 1 and "and" or nil;
+for (var i = 0; i < 2; i = i + 1) print i;
+print A(1).get() + "s";
+print "unreachable";
+`
+	stdout, stderr, status := runGoProgram(t, script)
+	assert.Equal(70, status)
+
+	out, errs := runScript(t, script)
+	assert.Equal(out, stdout)
+	assert.Equal(errs+"at top level line 34\n", stderr)
+}
+
+func TestTranspileGoStackTrace(t *testing.T) {
+	assert := assert.New(t)
+
+	stdout, stderr, status := runGoProgram(t, `class A {
+  init(n) {
+    this.n = n;
+    this.count();
+  }
+  count() {
+    if (this.n == 0) return -nil;
+    return A(this.n - 1);
+  }
+}
+print "start";
+A(1);
+`)
+	assert.Equal(70, status)
+	assert.Equal("start\n", stdout)
+	assert.Equal(`Operand must be a number.
+[line 7]
+in count at line 7
+in init at line 4
+in count at line 8
+in init at line 4
+at top level line 12
+`, stderr)
+}
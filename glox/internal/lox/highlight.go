@@ -0,0 +1,143 @@
+package lox
+
+import (
+	"html"
+	"strings"
+)
+
+// HighlightFormat is the markup used to highlight source code.
+type HighlightFormat uint
+
+const (
+	// HighlightANSI colors the source with the escape sequences understood by
+	// terminals.
+	HighlightANSI HighlightFormat = iota
+	// HighlightHTML wraps the source in "span" elements whose class is the kind
+	// of the token prefixed with "lox-", e.g. "lox-keyword". The text is
+	// escaped, and the styling is left to a stylesheet.
+	HighlightHTML
+)
+
+// HighlightCSS is a stylesheet for the HTML markup.
+const HighlightCSS = `.lox-keyword { color: #a626a4; font-weight: bold; }
+.lox-constant { color: #0184bc; }
+.lox-string { color: #50a14f; }
+.lox-number { color: #986801; }
+.lox-comment { color: #a0a1a7; font-style: italic; }
+.lox-operator { color: #4078f2; }
+`
+
+// highlightANSI holds the escape sequences of the kinds of tokens that are
+// colored in a terminal.
+var highlightANSI = map[string]string{
+	"keyword":  "\x1b[1;35m",
+	"constant": "\x1b[36m",
+	"string":   "\x1b[32m",
+	"number":   "\x1b[33m",
+	"comment":  "\x1b[90m",
+	"operator": "\x1b[34m",
+}
+
+// HighlightKind returns the kind of the token for highlighting, which is one
+// of "keyword", "constant", "string", "number", "identifier", "operator", and
+// "punctuation".
+func HighlightKind(tok *Token) string {
+	switch tok.Type {
+	case TRUE, FALSE, NIL:
+		return "constant"
+	case STRING:
+		return "string"
+	case NUMBER:
+		return "number"
+	case IDENT:
+		return "identifier"
+	case L_PAREN, R_PAREN, L_BRACE, R_BRACE, COMMA, DOT, SEMICOLON, EOF:
+		return "punctuation"
+	}
+	if _, ok := KeywordTokens[tok.Lexeme]; ok {
+		return "keyword"
+	}
+	return "operator"
+}
+
+// HighlightLines highlights the source that the given tokens were scanned from
+// and returns its lines, without their line breaks. The markup never spans
+// many lines so any range of lines can be shown on its own, e.g. as the
+// context of an error. Comments are highlighted too, and the characters that
+// the scanner rejected are kept as they are.
+func HighlightLines(source []byte, tokens []*Token, format HighlightFormat) []string {
+	h := &highlighter{format: format}
+	lineStarts := []int{0}
+	for i, c := range source {
+		if c == '\n' {
+			lineStarts = append(lineStarts, i+1)
+		}
+	}
+
+	prev := 0
+	for _, tok := range tokens {
+		start := len(source)
+		if tok.Type != EOF {
+			line := startLine(tok)
+			if tok.Column == 0 || line < 1 || line > len(lineStarts) {
+				// the token has no position in the source
+				continue
+			}
+			start = lineStarts[line-1] + tok.Column - 1
+			if start < prev || start+len(tok.Lexeme) > len(source) {
+				continue
+			}
+		}
+		// the text between two tokens is made of spaces, comments, and
+		// invalid characters
+		gap := string(source[prev:start])
+		for _, comment := range tok.Comments {
+			if i := strings.Index(gap, comment.Text); i >= 0 {
+				h.write(gap[:i], "")
+				h.write(comment.Text, "comment")
+				gap = gap[i+len(comment.Text):]
+			}
+		}
+		h.write(gap, "")
+		if tok.Type == EOF {
+			break
+		}
+		h.write(tok.Lexeme, HighlightKind(tok))
+		prev = start + len(tok.Lexeme)
+	}
+	return append(h.lines, h.line.String())
+}
+
+type highlighter struct {
+	format HighlightFormat
+	lines  []string
+	line   strings.Builder
+}
+
+// write appends the text, which is highlighted as the given kind of token if
+// it's not empty.
+func (h *highlighter) write(text string, kind string) {
+	for i, part := range strings.Split(text, "\n") {
+		if i > 0 {
+			h.lines = append(h.lines, h.line.String())
+			h.line.Reset()
+		}
+		if part == "" {
+			continue
+		}
+		switch h.format {
+		case HighlightANSI:
+			if color, ok := highlightANSI[kind]; ok {
+				h.line.WriteString(color + part + "\x1b[0m")
+			} else {
+				h.line.WriteString(part)
+			}
+		case HighlightHTML:
+			if kind == "" || kind == "identifier" || kind == "punctuation" {
+				h.line.WriteString(html.EscapeString(part))
+			} else {
+				h.line.WriteString(`<span class="lox-` + kind + `">` + html.EscapeString(part) + "</span>")
+			}
+		}
+	}
+}
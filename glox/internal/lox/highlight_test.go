@@ -0,0 +1,44 @@
+package lox
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHighlightLines(t *testing.T) {
+	assert := assert.New(t)
+
+	source := []byte("var a = \"x\ny\" < 1; /* a\nb */ @\nprint nil;")
+	tokens := NewScanner(source, NewSimpleReporter(ioutil.Discard)).Scan()
+
+	assert.Equal([]string{
+		"\x1b[1;35mvar\x1b[0m a \x1b[34m=\x1b[0m \x1b[32m\"x\x1b[0m",
+		"\x1b[32my\"\x1b[0m \x1b[34m<\x1b[0m \x1b[33m1\x1b[0m; \x1b[90m/* a\x1b[0m",
+		"\x1b[90mb */\x1b[0m @",
+		"\x1b[1;35mprint\x1b[0m \x1b[36mnil\x1b[0m;",
+	}, HighlightLines(source, tokens, HighlightANSI))
+
+	assert.Equal([]string{
+		`<span class="lox-keyword">var</span> a <span class="lox-operator">=</span> <span class="lox-string">&#34;x</span>`,
+		`<span class="lox-string">y&#34;</span> <span class="lox-operator">&lt;</span> <span class="lox-number">1</span>; <span class="lox-comment">/* a</span>`,
+		`<span class="lox-comment">b */</span> @`,
+		`<span class="lox-keyword">print</span> <span class="lox-constant">nil</span>;`,
+	}, HighlightLines(source, tokens, HighlightHTML))
+}
+
+func TestHighlightKind(t *testing.T) {
+	assert := assert.New(t)
+
+	kinds := make(map[string]string)
+	source := []byte(`class this true "s" 2 name >= ; (`)
+	for _, tok := range NewScanner(source, NewSimpleReporter(ioutil.Discard)).Scan() {
+		kinds[tok.Lexeme] = HighlightKind(tok)
+	}
+	assert.Equal(map[string]string{
+		"class": "keyword", "this": "keyword", "true": "constant",
+		`"s"`: "string", "2": "number", "name": "identifier",
+		">=": "operator", ";": "punctuation", "(": "punctuation", "": "punctuation",
+	}, kinds)
+}
@@ -0,0 +1,54 @@
+package lox
+
+// Hooks are callbacks that the interpreter invokes while it runs a script, so
+// tools such as debuggers, profilers, and coverage can be built outside of the
+// interpreter. Each of them can be nil.
+type Hooks struct {
+	// OnStatement is called before each statement is executed, with its line,
+	// which is 0 if the statement has none, e.g. an empty block.
+	OnStatement func(stmt Stmt, line int)
+	// OnCall is called before a function, a method, a class, or a native
+	// function is called by the script, with its arguments.
+	OnCall func(fn Value, args []Value)
+	// OnReturn is called once the call that OnCall was called for is over,
+	// with its result, or with the error that ended it.
+	OnReturn func(fn Value, result Value, err error)
+	// OnError is called with the runtime error that stops the script, before
+	// it's reported.
+	OnError func(err error)
+}
+
+// SetHooks makes the interpreter invoke the callbacks of the hooks while it
+// runs a script. Giving nil removes them.
+func (in *Interpreter) SetHooks(hooks *Hooks) {
+	in.hooks = hooks
+}
+
+func (h *Hooks) stmt(stmt Stmt) {
+	if h.OnStatement != nil {
+		h.OnStatement(stmt, stmtLine(stmt))
+	}
+}
+
+// call calls fn with the arguments, and invokes OnCall and OnReturn around it.
+func (h *Hooks) call(in *Interpreter, fn callable, args []interface{}) (interface{}, error) {
+	callee := Value{val: fn, in: in}
+	if h.OnCall != nil {
+		vals := make([]Value, len(args))
+		for i, arg := range args {
+			vals[i] = Value{val: arg, in: in}
+		}
+		h.OnCall(callee, vals)
+	}
+	result, err := fn.call(in, args)
+	if h.OnReturn != nil {
+		h.OnReturn(callee, Value{val: result, in: in}, err)
+	}
+	return result, err
+}
+
+func (h *Hooks) error(err error) {
+	if h.OnError != nil {
+		h.OnError(err)
+	}
+}
@@ -0,0 +1,54 @@
+package lox
+
+import (
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpreterHooks(t *testing.T) {
+	assert := assert.New(t)
+
+	var events []string
+	hooks := &Hooks{
+		OnStatement: func(stmt Stmt, line int) {
+			events = append(events, fmt.Sprintf("line %d", line))
+		},
+		OnCall: func(fn Value, args []Value) {
+			events = append(events, fmt.Sprintf("call %s %v", fn, args))
+		},
+		OnReturn: func(fn Value, result Value, err error) {
+			events = append(events, fmt.Sprintf("return %s %s %v", fn, result, err != nil))
+		},
+		OnError: func(err error) {
+			events = append(events, "error "+err.Error())
+		},
+	}
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false)
+	in.SetHooks(hooks)
+	in.Interpret(parseScript(t, in, `fun add(a, b) {
+  return a + b;
+}
+add(1, 2);
+add(nil, 1);`))
+	assert.Equal([]string{
+		"line 1",
+		"line 4",
+		"call <fn add> [1 2]",
+		"line 2",
+		"return <fn add> 3 false",
+		"line 5",
+		"call <fn add> [nil 1]",
+		"line 2",
+		"return <fn add> nil true",
+		"error Operands must be two numbers or two strings.\n[line 2]",
+	}, events)
+
+	// the hooks can be removed
+	events = nil
+	in.SetHooks(nil)
+	in.Interpret(parseScript(t, in, "add(1, 2);"))
+	assert.Empty(events)
+}
@@ -0,0 +1,120 @@
+package lox
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// inspectWidth is the length up to which an instance is shown on one line.
+const inspectWidth = 60
+
+// inspect formats a value for the REPL, showing more than "print" does.
+// Strings are quoted, functions and classes are shown with their arity, e.g.
+// "<fn add/2>", and instances are shown with the class name and the fields,
+// e.g. "Point { x: 1, y: 2 }". The fields of an instance are sorted, and are
+// put on lines of their own with indentation if they don't fit on one line.
+// An instance that contains itself is shown as "Point {...}" where it
+// appears again.
+func inspect(val interface{}) string {
+	return newInspector(false).value(val, "")
+}
+
+// inspector formats values as inspect does, and colors the literals like
+// "glox highlight" does if color is set.
+type inspector struct {
+	color bool
+	// visiting holds the instances that are being formatted
+	visiting map[*instance]bool
+}
+
+func newInspector(color bool) *inspector {
+	i := new(inspector)
+	i.color = color
+	i.visiting = make(map[*instance]bool)
+	return i
+}
+
+// value formats a value whose first line is at the given indentation.
+func (i *inspector) value(val interface{}, indent string) string {
+	switch val := val.(type) {
+	case nil, bool:
+		return i.colored(stringify(val), "constant")
+	case float64:
+		return i.colored(stringify(val), "number")
+	case string:
+		return i.colored(`"`+val+`"`, "string")
+	case *instance:
+		return i.instance(val, indent)
+	case *class:
+		name := val.name
+		if val.super != nil {
+			name += " < " + val.super.name
+		}
+		return fmt.Sprintf("<class %s/%d>", name, val.arity())
+	case *function:
+		return fmt.Sprintf("<fn %s/%d>", val.decl.Name.Lexeme, val.arity())
+	case callable:
+		return fmt.Sprintf("<native fn/%d>", val.arity())
+	}
+	return stringify(val)
+}
+
+func (i *inspector) colored(text string, kind string) string {
+	if !i.color {
+		return text
+	}
+	return highlightANSI[kind] + text + "\x1b[0m"
+}
+
+func (i *inspector) instance(inst *instance, indent string) string {
+	name := inst.class.name
+	if i.visiting[inst] {
+		return name + " {...}"
+	}
+	if len(inst.fields) == 0 {
+		return name + " {}"
+	}
+	i.visiting[inst] = true
+	defer delete(i.visiting, inst)
+
+	names := make([]string, 0, len(inst.fields))
+	for field := range inst.fields {
+		names = append(names, field)
+	}
+	sort.Strings(names)
+	inner := indent + formatIndent
+	fields := make([]string, len(names))
+	multiline := false
+	for n, field := range names {
+		fields[n] = field + ": " + i.value(inst.fields[field], inner)
+		multiline = multiline || strings.Contains(fields[n], "\n")
+	}
+
+	if line := name + " { " + strings.Join(fields, ", ") + " }"; !multiline && len(indent)+visibleLen(line) <= inspectWidth {
+		return line
+	}
+	var b strings.Builder
+	b.WriteString(name + " {\n")
+	for _, field := range fields {
+		b.WriteString(inner + field + ",\n")
+	}
+	b.WriteString(indent + "}")
+	return b.String()
+}
+
+// visibleLen returns the length of the text without its escape sequences.
+func visibleLen(text string) int {
+	n := 0
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\x1b' {
+			// the sequences that color the text end with 'm'
+			for i < len(text) && text[i] != 'm' {
+				i++
+			}
+			continue
+		}
+		n++
+	}
+	return n
+}
@@ -0,0 +1,83 @@
+package lox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInspect(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&out), true)
+	in.Interpret(parseScript(t, in, `
+class Point {
+  init(x, y) { this.x = x; this.y = y; }
+  norm() { return this.x * this.x + this.y * this.y; }
+}
+class Node < Point {}
+fun add(a, b) { return a + b; }
+var p = Point(1, "two");
+var node = Node(Point(1, 2), Point("a long string that doesn't fit", nil));
+node.self = node;
+`))
+	out.Reset()
+	in.Interpret(parseScript(t, in, `
+"s";
+1.5;
+nil;
+add;
+p.norm;
+clock;
+Point;
+Node;
+Node(1, 1).x;
+Point(1, 2) == nil;
+p;
+Point;
+node;
+`))
+
+	assert.Equal(`"s"
+1.5
+nil
+<fn add/2>
+<fn norm/0>
+<native fn/0>
+<class Point/2>
+<class Node < Point/2>
+1
+false
+Point { x: 1, y: "two" }
+<class Point/2>
+Node {
+  self: Node {...},
+  x: Point { x: 1, y: 2 },
+  y: Point { x: "a long string that doesn't fit", y: nil },
+}
+`, out.String())
+}
+
+func TestInspectEmptyInstance(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&out), true)
+	in.Interpret(parseScript(t, in, "class A {}\nA();\nvar a = A();\na;\n"))
+
+	assert.Equal("A {}\n", out.String())
+}
+
+func TestInspectColor(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&out), true)
+	in.SetColor(true)
+	in.Interpret(parseScript(t, in, "class P {}\nvar p = P();\np.a = \"a string that is long enough\";\np.b = 1;\np.c = nil;\np;\n"))
+
+	// the escape sequences don't count in the width of the line
+	assert.Equal("P { a: \x1b[32m\"a string that is long enough\"\x1b[0m, b: \x1b[33m1\x1b[0m, c: \x1b[36mnil\x1b[0m }\n", out.String())
+}
@@ -1,8 +1,13 @@
 package lox
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"io"
+	"io/ioutil"
+	"sort"
+	"strings"
 )
 
 // callable is implemented by Lox's objects that can be called.
@@ -13,40 +18,289 @@ type callable interface {
 
 // Interpreter exposes methods for evaluating then given Lox syntax tree. This
 // struct implements ExprVisitor
+//
+// All the state of a running script, e.g. the global environment, the native
+// functions, and the resolved variables, is owned by its interpreter. Different
+// interpreters can run concurrently on different goroutines, and they can
+// share the same syntax tree as long as each one of them resolves it. A single
+// interpreter, and the reporter that it's given, must not be used by more than
+// one goroutine at a time. InterpreterPool hands out interpreters that are
+// ready to run scripts in parallel.
 type Interpreter struct {
 	globals     *environment
 	environment *environment
 	locals      map[Expr]int
 	output      io.Writer
-	reporter    Reporter
-	isREPL      bool
+	// stdin is read by "readLine" and stderr is written by "printErr"
+	stdin      *bufio.Reader
+	stderr     io.Writer
+	reporter   Reporter
+	isREPL     bool
+	echoAll    bool
+	color      bool
+	envPool    environmentPool
+	limits     limits
+	profiler   *Profiler
+	pprofCtx   context.Context
+	pprofStack []context.Context
+	tracer     *tracer
+	debugger   *Debugger
+	coverage   *Coverage
+	hooks      *Hooks
+	// frames holds the calls to Lox functions that haven't returned, the last
+	// one is the innermost, and callSite is the token of the call expression
+	// that is being evaluated.
+	frames   []callFrame
+	callSite *Token
+	// calls is the number of calls to Lox functions that were made
+	calls int
+	// statement is the top-level statement that is being run
+	statement Stmt
+	// args holds the arguments that the script is given
+	args []string
+	// natives holds the functions registered by the host program, which are
+	// defined again when the interpreter is reset
+	natives map[string]callable
+	// modules finds the modules that are imported, and imported holds the
+	// names of the ones that were run
+	modules  ModuleResolver
+	imported map[string]bool
+	// policy restricts the capabilities of the native functions, every one
+	// is allowed if it's nil
+	policy *Policy
+	// ctx is the context given to InterpretContext, which is checked at each
+	// iteration of a loop and at each call
+	ctx context.Context
+	// strictVars is set if reading a variable that was declared without a
+	// value and never assigned is an error, see SetStrictVariables
+	strictVars bool
 }
 
-func NewInterpreter(output io.Writer, reporter Reporter, isREPL bool) *Interpreter {
-	env := newEnvironment(nil)
-	env.define("clock", new(functionClock))
+// callFrame is a call to a Lox function that hasn't returned yet.
+type callFrame struct {
+	decl *FunctionStmt
+	// call is the closing parenthesis of the call expression
+	call *Token
+	// callerEnv is the environment of the caller when the call was made, so
+	// the caller can be inspected while the function is running.
+	callerEnv *environment
+}
+
+// recordStack records the calls that haven't returned in the runtime error,
+// unless an inner call already did, since they're unwound as it's returned.
+func (in *Interpreter) recordStack(err error) {
+	e, ok := err.(*runtimeError)
+	if !ok || e.stack != nil || e.token == nil {
+		return
+	}
+	e.stack = make([]StackFrame, 0, len(in.frames)+1)
+	line := e.token.Line
+	for i := len(in.frames) - 1; i >= 0; i-- {
+		e.stack = append(e.stack, StackFrame{Function: in.frames[i].decl.Name.Lexeme, Line: line})
+		// the functions called by the host program, e.g. with Value.Call,
+		// have no call site
+		if in.frames[i].call == nil {
+			return
+		}
+		line = in.frames[i].call.Line
+	}
+	e.stack = append(e.stack, StackFrame{Line: line})
+}
+
+// Option configures an interpreter when it's created, see NewInterpreter.
+type Option func(in *Interpreter)
+
+// WithStdin sets the input that scripts read with "readLine", which is empty
+// by default.
+func WithStdin(r io.Reader) Option {
+	return func(in *Interpreter) {
+		in.stdin = bufio.NewReader(r)
+	}
+}
+
+// WithStderr sets where scripts write with "printErr", which is discarded by
+// default. The errors of the scripts are written by the reporter instead.
+func WithStderr(w io.Writer) Option {
+	return func(in *Interpreter) {
+		in.stderr = w
+	}
+}
 
+// NewInterpreter creates an interpreter whose scripts print to the output and
+// whose errors are given to the reporter. The options set the other streams
+// that the scripts use.
+func NewInterpreter(output io.Writer, reporter Reporter, isREPL bool, options ...Option) *Interpreter {
 	interpreter := new(Interpreter)
-	interpreter.globals = env
-	interpreter.environment = env
-	interpreter.locals = make(map[Expr]int)
+	interpreter.Reset()
 	interpreter.output = output
+	interpreter.stdin = bufio.NewReader(strings.NewReader(""))
+	interpreter.stderr = ioutil.Discard
 	interpreter.reporter = reporter
 	interpreter.isREPL = isREPL
+	interpreter.limits.maxCallDepth = DefaultMaxCallDepth
+	for _, option := range options {
+		option(interpreter)
+	}
 	return interpreter
 }
 
+// SetREPL sets whether the interpreter runs the statements entered in a REPL,
+// whose expressions have their values printed and kept in "_". It's given to
+// NewInterpreter, and can be changed afterward, e.g. to explore what a script
+// defined once it has run.
+func (in *Interpreter) SetREPL(enabled bool) {
+	in.isREPL = enabled
+}
+
+// SetEchoAll sets whether the REPL also prints the values of the calls and the
+// assignments that are entered, except when they are nil. They aren't printed
+// by default, since the value of an assignment is the one that was just
+// typed. The setting has no effect outside of the REPL.
+func (in *Interpreter) SetEchoAll(enabled bool) {
+	in.echoAll = enabled
+}
+
+// SetArgs sets the arguments that the script is given, "argc" is their number
+// and "args(i)" returns the one at index i.
+func (in *Interpreter) SetArgs(args []string) {
+	in.args = args
+	in.globals.define("argc", float64(len(args)))
+}
+
+// SetColor sets whether the values that the REPL prints are colored with ANSI
+// escape sequences, the same way as the tokens of "glox highlight".
+func (in *Interpreter) SetColor(enabled bool) {
+	in.color = enabled
+}
+
+// SetStrictVariables sets whether reading a variable that was declared without
+// an initializer, and that hasn't been assigned since, is a runtime error
+// instead of giving nil. It's disabled by default, as in the book, and only
+// applies to the variables declared after it's set.
+func (in *Interpreter) SetStrictVariables(enabled bool) {
+	in.strictVars = enabled
+}
+
+// Reset discards the global variables and the resolution of every statement
+// that was run, so the interpreter runs the next statements as if it had just
+// been created. The settings, e.g. the output, the limits, and the attached
+// tools, are kept.
+func (in *Interpreter) Reset() {
+	env := newEnvironment(nil)
+	env.define("clock", new(functionClock))
+	env.define("breakpoint", new(functionBreakpoint))
+	env.define("args", new(functionArgs))
+	env.define("argc", float64(len(in.args)))
+	env.define("readLine", new(functionReadLine))
+	env.define("printErr", new(functionPrintErr))
+	for name, native := range in.natives {
+		env.define(name, native)
+	}
+	if in.modules != nil {
+		env.define("import", new(functionImport))
+	}
+	in.imported = nil
+	in.globals = env
+	in.environment = env
+	in.locals = make(map[Expr]int)
+	in.frames = in.frames[:0]
+}
+
+// InterpretContext is like Interpret, but stops the script once the context is
+// done, i.e. it's canceled or its deadline passes, so the host program can
+// give up on a script that runs for too long. The context is checked at each
+// iteration of a loop and before each call. The runtime error that stopped
+// the script, if any, is returned after it's reported, the one that's caused
+// by the context wraps ErrCanceled.
+func (in *Interpreter) InterpretContext(ctx context.Context, statements []Stmt) error {
+	in.ctx = ctx
+	defer func() { in.ctx = nil }()
+	return in.interpret(statements)
+}
+
 func (in *Interpreter) Interpret(statements []Stmt) {
+	in.interpret(statements)
+}
+
+// Capture runs the statements like Interpret, but returns what they print
+// rather than writing it to the output, e.g. to check it in a test. The
+// runtime error that stopped the statements, if any, is returned after it's
+// reported.
+func (in *Interpreter) Capture(statements []Stmt) (string, error) {
+	var captured strings.Builder
+	output := in.output
+	in.output = &captured
+	defer func() { in.output = output }()
+	err := in.interpret(statements)
+	return captured.String(), err
+}
+
+// Run reads a script from the reader until its end, e.g. a network connection
+// or a pipe, then scans, parses, resolves, and runs it. The errors are given
+// to the reporter, and the first one is returned, which is the error of the
+// reader, the diagnostic of a compile error, in which case the script isn't
+// run, or the runtime error that stopped the script.
+func (in *Interpreter) Run(r io.Reader) error {
+	source, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	statements, diagnostics := Parse(source)
+	if len(diagnostics) == 0 {
+		collector := new(diagnosticCollector)
+		NewResolver(in, collector).Resolve(statements)
+		diagnostics = collector.diagnostics
+	}
+	for _, d := range diagnostics {
+		in.reporter.Report(d)
+	}
+	if len(diagnostics) > 0 {
+		return diagnostics[0]
+	}
+	return in.interpret(statements)
+}
+
+// interpret runs the statements, and returns the error that stopped them.
+func (in *Interpreter) interpret(statements []Stmt) error {
+	in.limits.start()
+	in.frames = in.frames[:0]
+	if in.coverage != nil {
+		in.coverage.addStmts(statements)
+	}
 	for _, stmt := range statements {
+		in.statement = stmt
 		if _, err := in.exec(stmt); err != nil {
-			in.reporter.Report(err)
-			break
+			// the globals that were defined are kept, so the statements run
+			// next, e.g. by the REPL, see them, but nothing of the calls that
+			// were interrupted by the error is
+			in.environment = in.globals
+			in.frames = in.frames[:0]
+			in.callSite = nil
+			in.statement = nil
+			// quitting the debugger stops the script without an error
+			if err == errDebugQuit {
+				return nil
+			}
+			if in.hooks != nil {
+				in.hooks.error(err)
+			}
+			in.reporter.Report(newDiagnostic(StageRuntime, err))
+			return err
 		}
 	}
+	in.statement = nil
+	return nil
 }
 
 func (in *Interpreter) VisitBlockStmt(stmt *BlockStmt) (interface{}, error) {
-	return nil, in.execBlock(stmt.Stmts, newEnvironment(in.environment))
+	env := in.envPool.get(in.environment)
+	err := in.execBlock(stmt.Stmts, env)
+	in.envPool.put(env)
+	return nil, err
+}
+
+func (in *Interpreter) VisitErrorStmt(stmt *ErrorStmt) (interface{}, error) {
+	return nil, newRuntimeError(stmtToken(stmt), CodeSyntaxErrorNode, "Can't run code that has a syntax error.")
 }
 
 func (in *Interpreter) VisitExprStmt(stmt *ExprStmt) (interface{}, error) {
@@ -54,12 +308,22 @@ func (in *Interpreter) VisitExprStmt(stmt *ExprStmt) (interface{}, error) {
 	if err != nil {
 		return nil, err
 	}
-	if in.isREPL {
+	// only the expressions entered at the prompt are shown, not the ones in
+	// the functions that they call
+	if in.isREPL && len(in.frames) == 0 {
 		switch stmt.Expr.(type) {
-		case *AssignExpr, *CallExpr:
-			/* expressions of these types are not printed */
+		case *AssignExpr, *SetExpr, *CallExpr:
+			// a function that returns nothing shouldn't print "nil", and the
+			// assignments in a loop aren't printed at each iteration
+			if in.echoAll && expr != nil && Stmt(stmt) == in.statement {
+				fmt.Fprintln(in.output, newInspector(in.color).value(expr, ""))
+			}
 		default:
-			fmt.Fprintln(in.output, stringify(expr))
+			fmt.Fprintln(in.output, newInspector(in.color).value(expr, ""))
+		}
+		// the value of a statement at the top level can be reused
+		if in.environment == in.globals {
+			in.globals.define("_", expr)
 		}
 	}
 	return nil, nil
@@ -76,7 +340,7 @@ func (in *Interpreter) VisitClassStmt(stmt *ClassStmt) (interface{}, error) {
 		var isClass bool
 		super, isClass = superObj.(*class)
 		if !isClass {
-			return nil, newRuntimeError(stmt.Super.Name,
+			return nil, newRuntimeError(stmt.Super.Name, CodeSuperclassNotClass,
 				"Superclass must be a class.")
 		}
 
@@ -87,6 +351,9 @@ func (in *Interpreter) VisitClassStmt(stmt *ClassStmt) (interface{}, error) {
 		in.environment.define("super", super)
 	}
 
+	if err := in.limits.allocObject(stmt.Name); err != nil {
+		return nil, err
+	}
 	methods := make(map[string]*function)
 	for _, method := range stmt.Methods {
 		isInitializer := method.Name.Lexeme == "init"
@@ -103,6 +370,9 @@ func (in *Interpreter) VisitClassStmt(stmt *ClassStmt) (interface{}, error) {
 }
 
 func (in *Interpreter) VisitFunctionStmt(stmt *FunctionStmt) (interface{}, error) {
+	if err := in.limits.allocObject(stmt.Name); err != nil {
+		return nil, err
+	}
 	fn := newFunction(stmt, in.environment, false)
 	in.environment.define(stmt.Name.Lexeme, fn)
 	return nil, nil
@@ -131,6 +401,10 @@ func (in *Interpreter) VisitPrintStmt(stmt *PrintStmt) (interface{}, error) {
 }
 
 func (in *Interpreter) VisitVarStmt(stmt *VarStmt) (interface{}, error) {
+	if stmt.Init == nil && in.strictVars {
+		in.environment.declare(stmt.Name.Lexeme)
+		return nil, nil
+	}
 	var initVal interface{}
 	if stmt.Init != nil {
 		var err error
@@ -168,6 +442,23 @@ func (in *Interpreter) VisitWhileStmt(stmt *WhileStmt) (interface{}, error) {
 		if err != nil {
 			return nil, err
 		}
+		if err := in.checkContext(stmt.Keyword); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// checkContext returns an error if the context given to InterpretContext is
+// done, the token is where the error is reported.
+func (in *Interpreter) checkContext(token *Token) error {
+	if in.ctx == nil {
+		return nil
+	}
+	select {
+	case <-in.ctx.Done():
+		return newCancelError(token, in.ctx.Err())
+	default:
+		return nil
 	}
 }
 
@@ -180,9 +471,10 @@ func (in *Interpreter) VisitAssignExpr(expr *AssignExpr) (interface{}, error) {
 	if steps, ok := in.locals[expr]; ok {
 		in.environment.assignAt(steps, expr.Name, val)
 		return val, nil
-	} else {
-		return val, in.globals.assign(expr.Name, val)
+	} else if err := in.globals.assign(expr.Name, val); err != nil {
+		return nil, suggest(err, expr.Name.Lexeme, in.environment.names())
 	}
+	return val, nil
 }
 
 func (in *Interpreter) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
@@ -211,7 +503,7 @@ func (in *Interpreter) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
 			result := leftNum > rightNum
 			return result, nil
 		}
-		return nil, newRuntimeError(expr.Op, "Operands must be numbers.")
+		return nil, newRuntimeError(expr.Op, CodeOperandType, "Operands must be numbers.")
 
 	case GREATER_EQUAL:
 		leftNum, okLeftNum := lhs.(float64)
@@ -220,7 +512,7 @@ func (in *Interpreter) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
 			result := leftNum >= rightNum
 			return result, nil
 		}
-		return nil, newRuntimeError(expr.Op, "Operands must be numbers.")
+		return nil, newRuntimeError(expr.Op, CodeOperandType, "Operands must be numbers.")
 
 	case LESS:
 		leftNum, okLeftNum := lhs.(float64)
@@ -229,7 +521,7 @@ func (in *Interpreter) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
 			result := leftNum < rightNum
 			return result, nil
 		}
-		return nil, newRuntimeError(expr.Op, "Operands must be numbers.")
+		return nil, newRuntimeError(expr.Op, CodeOperandType, "Operands must be numbers.")
 
 	case LESS_EQUAL:
 		leftNum, okLeftNum := lhs.(float64)
@@ -238,7 +530,7 @@ func (in *Interpreter) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
 			result := leftNum <= rightNum
 			return result, nil
 		}
-		return nil, newRuntimeError(expr.Op, "Operands must be numbers.")
+		return nil, newRuntimeError(expr.Op, CodeOperandType, "Operands must be numbers.")
 
 	case MINUS:
 		leftNum, okLeftNum := lhs.(float64)
@@ -247,13 +539,16 @@ func (in *Interpreter) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
 			result := leftNum - rightNum
 			return result, nil
 		}
-		return nil, newRuntimeError(expr.Op, "Operands must be numbers.")
+		return nil, newRuntimeError(expr.Op, CodeOperandType, "Operands must be numbers.")
 
 	case PLUS:
 		leftStr, okLeftStr := lhs.(string)
 		rightStr, okRightStr := rhs.(string)
 		if okLeftStr && okRightStr {
 			result := leftStr + rightStr
+			if err := in.limits.allocString(expr.Op, len(result)); err != nil {
+				return nil, err
+			}
 			return result, nil
 		}
 		leftNum, okLeftNum := lhs.(float64)
@@ -263,7 +558,7 @@ func (in *Interpreter) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
 			return result, nil
 		}
 
-		return nil, newRuntimeError(expr.Op, "Operands must be two numbers or two strings.")
+		return nil, newRuntimeError(expr.Op, CodeOperandType, "Operands must be two numbers or two strings.")
 
 	case SLASH:
 		leftNum, okLeftNum := lhs.(float64)
@@ -272,7 +567,7 @@ func (in *Interpreter) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
 			result := leftNum / rightNum
 			return result, nil
 		}
-		return nil, newRuntimeError(expr.Op, "Operands must be numbers.")
+		return nil, newRuntimeError(expr.Op, CodeOperandType, "Operands must be numbers.")
 
 	case STAR:
 		leftNum, okLeftNum := lhs.(float64)
@@ -281,7 +576,7 @@ func (in *Interpreter) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
 			result := leftNum * rightNum
 			return result, nil
 		}
-		return nil, newRuntimeError(expr.Op, "Operands must be numbers.")
+		return nil, newRuntimeError(expr.Op, CodeOperandType, "Operands must be numbers.")
 	}
 	panic("Unreachable")
 }
@@ -311,7 +606,7 @@ func (in *Interpreter) VisitCallExpr(expr *CallExpr) (interface{}, error) {
 
 	call, isCallable := callee.(callable)
 	if !isCallable {
-		return nil, newRuntimeError(expr.Paren, "Can only call functions and classes.")
+		return nil, newRuntimeError(expr.Paren, CodeNotCallable, "Can only call functions and classes.")
 	}
 	/*
 		NOTE: The arity check could be done within the Call() method. But we have lots
@@ -320,11 +615,34 @@ func (in *Interpreter) VisitCallExpr(expr *CallExpr) (interface{}, error) {
 		here.
 	*/
 	if len(args) != call.arity() {
-		return nil, newRuntimeError(expr.Paren, fmt.Sprintf(
+		return nil, newRuntimeError(expr.Paren, CodeArity, fmt.Sprintf(
 			"Expected %d arguments but got %d.", call.arity(), len(args),
 		))
 	}
-	return call.call(in, args)
+	if _, isClass := call.(*class); isClass {
+		if err := in.limits.allocObject(expr.Paren); err != nil {
+			return nil, err
+		}
+	}
+	if err := in.checkContext(expr.Paren); err != nil {
+		return nil, err
+	}
+	if err := in.limits.enterCall(expr.Paren); err != nil {
+		return nil, err
+	}
+	in.callSite = expr.Paren
+	var result interface{}
+	if in.hooks != nil {
+		result, err = in.hooks.call(in, call, args)
+	} else {
+		result, err = call.call(in, args)
+	}
+	in.limits.exitCall()
+	return result, err
+}
+
+func (in *Interpreter) VisitErrorExpr(expr *ErrorExpr) (interface{}, error) {
+	return nil, newRuntimeError(expr.Token, CodeSyntaxErrorNode, "Can't run code that has a syntax error.")
 }
 
 func (in *Interpreter) VisitGetExpr(expr *GetExpr) (interface{}, error) {
@@ -336,7 +654,7 @@ func (in *Interpreter) VisitGetExpr(expr *GetExpr) (interface{}, error) {
 	if inst, ok := obj.(*instance); ok {
 		return inst.get(expr.Name)
 	} else {
-		return nil, newRuntimeError(expr.Name, "Only instances have properties.")
+		return nil, newRuntimeError(expr.Name, CodeGetOnNonInstance, "Only instances have properties.")
 	}
 }
 
@@ -384,7 +702,7 @@ func (in *Interpreter) VisitSetExpr(expr *SetExpr) (interface{}, error) {
 		obj.set(expr.Name, val)
 		return val, nil
 	} else {
-		return nil, newRuntimeError(expr.Name, "Only instances have fields.")
+		return nil, newRuntimeError(expr.Name, CodeSetOnNonInstance, "Only instances have fields.")
 	}
 }
 
@@ -399,9 +717,10 @@ func (in *Interpreter) VisitSuperExpr(expr *SuperExpr) (interface{}, error) {
 	this := in.environment.getAt(steps-1, "this").(*instance)
 	method, hasMethod := super.findMethod(expr.Method.Lexeme)
 	if !hasMethod {
-		return nil, newRuntimeError(expr.Method, fmt.Sprintf(
+		err := newRuntimeError(expr.Method, CodeUndefinedProperty, fmt.Sprintf(
 			"Undefined property '%s'.", expr.Method.Lexeme,
 		))
+		return nil, suggestProperty(err, super.name, expr.Method.Lexeme, super.methodNames())
 	}
 	return method.bind(this), nil
 }
@@ -423,7 +742,7 @@ func (in *Interpreter) VisitUnaryExpr(expr *UnaryExpr) (interface{}, error) {
 		if exprNum, ok := exprVal.(float64); ok {
 			return -exprNum, nil
 		}
-		return nil, newRuntimeError(expr.Op, "Operand must be a number.")
+		return nil, newRuntimeError(expr.Op, CodeOperandType, "Operand must be a number.")
 	}
 	panic("Unreachable")
 }
@@ -432,25 +751,113 @@ func (in *Interpreter) VisitVarExpr(expr *VarExpr) (interface{}, error) {
 	return in.lookUpVar(expr.Name, expr)
 }
 
+// execBlock executes the statements within the given environment. The previous
+// environment is restored by hand on every path out of the function, instead
+// of using defer, since this is called for every block and function call.
 func (in *Interpreter) execBlock(statements []Stmt, env *environment) error {
 	prevEnv := in.environment
 	in.environment = env
-	defer func() {
-		in.environment = prevEnv
-	}()
 	for _, stmt := range statements {
 		if _, err := in.exec(stmt); err != nil {
+			in.environment = prevEnv
 			return err
 		}
 	}
+	in.environment = prevEnv
 	return nil
 }
 
+// exec dispatches the statement to its visiting method using a type switch
+// instead of going through Stmt.Accept. A type switch avoids a second
+// interface call for every node in the syntax tree, which adds up quickly
+// when running loops. The visitor interfaces are still kept for the resolver
+// and other tools that walk the tree.
 func (in *Interpreter) exec(stmt Stmt) (interface{}, error) {
+	if in.limits.enabled() {
+		if err := in.limits.step(); err != nil {
+			return nil, err
+		}
+	}
+	if in.tracer != nil {
+		in.tracer.stmt(stmt)
+	}
+	if in.coverage != nil {
+		in.coverage.stmt(stmt)
+	}
+	if in.hooks != nil {
+		in.hooks.stmt(stmt)
+	}
+	if in.debugger != nil {
+		if err := in.debugger.stmt(stmt); err != nil {
+			return nil, err
+		}
+	}
+	switch stmt := stmt.(type) {
+	case *BlockStmt:
+		return in.VisitBlockStmt(stmt)
+	case *ClassStmt:
+		return in.VisitClassStmt(stmt)
+	case *ExprStmt:
+		return in.VisitExprStmt(stmt)
+	case *FunctionStmt:
+		return in.VisitFunctionStmt(stmt)
+	case *IfStmt:
+		return in.VisitIfStmt(stmt)
+	case *PrintStmt:
+		return in.VisitPrintStmt(stmt)
+	case *ReturnStmt:
+		return in.VisitReturnStmt(stmt)
+	case *VarStmt:
+		return in.VisitVarStmt(stmt)
+	case *WhileStmt:
+		return in.VisitWhileStmt(stmt)
+	}
 	return stmt.Accept(in)
 }
 
+// eval dispatches the expression to its visiting method using a type switch,
+// see exec for the reasoning.
 func (in *Interpreter) eval(expr Expr) (interface{}, error) {
+	if in.limits.enabled() {
+		if err := in.limits.step(); err != nil {
+			return nil, err
+		}
+	}
+	if in.tracer != nil {
+		val, err := in.evalNode(expr)
+		in.tracer.expr(expr, val, err)
+		return val, err
+	}
+	return in.evalNode(expr)
+}
+
+func (in *Interpreter) evalNode(expr Expr) (interface{}, error) {
+	switch expr := expr.(type) {
+	case *AssignExpr:
+		return in.VisitAssignExpr(expr)
+	case *BinaryExpr:
+		return in.VisitBinaryExpr(expr)
+	case *CallExpr:
+		return in.VisitCallExpr(expr)
+	case *GetExpr:
+		return in.VisitGetExpr(expr)
+	case *GroupExpr:
+		return in.VisitGroupExpr(expr)
+	case *LiteralExpr:
+		return in.VisitLiteralExpr(expr)
+	case *LogicalExpr:
+		return in.VisitLogicalExpr(expr)
+	case *SetExpr:
+		return in.VisitSetExpr(expr)
+	case *SuperExpr:
+		return in.VisitSuperExpr(expr)
+	case *ThisExpr:
+		return in.VisitThisExpr(expr)
+	case *UnaryExpr:
+		return in.VisitUnaryExpr(expr)
+	case *VarExpr:
+		return in.VisitVarExpr(expr)
+	}
 	return expr.Accept(in)
 }
 
@@ -458,10 +865,77 @@ func (in *Interpreter) resolve(expr Expr, steps int) {
 	in.locals[expr] = steps
 }
 
+// AddResolutions adds the resolved expressions of the table to the ones of the
+// interpreter, so the statements that were resolved with Resolve can be run
+// without being resolved again by the interpreter.
+func (in *Interpreter) AddResolutions(table ResolutionTable) {
+	for expr, steps := range table {
+		in.locals[expr] = steps
+	}
+}
+
+// Resolution is a use of a local variable, or of "this" or "super", that was
+// resolved. Depth is the number of scopes between the use and the scope
+// where the variable is declared.
+type Resolution struct {
+	Name  *Token
+	Depth int
+}
+
+// Resolutions returns the uses of local variables that the resolver found in
+// the statements given to it, in the order they appear in the source. The
+// variables that aren't listed are globals.
+func (in *Interpreter) Resolutions() []Resolution {
+	resolutions := make([]Resolution, 0, len(in.locals))
+	for expr, depth := range in.locals {
+		var name *Token
+		switch expr := expr.(type) {
+		case *VarExpr:
+			name = expr.Name
+		case *AssignExpr:
+			name = expr.Name
+		case *ThisExpr:
+			name = expr.Keyword
+		case *SuperExpr:
+			name = expr.Keyword
+		default:
+			continue
+		}
+		resolutions = append(resolutions, Resolution{Name: name, Depth: depth})
+	}
+	sort.Slice(resolutions, func(i, j int) bool {
+		a, b := resolutions[i].Name, resolutions[j].Name
+		if a.Line != b.Line {
+			return a.Line < b.Line
+		}
+		return a.Column < b.Column
+	})
+	return resolutions
+}
+
 func (in *Interpreter) lookUpVar(name *Token, expr Expr) (interface{}, error) {
 	if steps, ok := in.locals[expr]; ok {
-		return in.environment.getAt(steps, name.Lexeme), nil
-	} else {
-		return in.globals.get(name)
+		env := in.environment.ancestor(steps)
+		if in.strictVars && env.isUninitialized(name.Lexeme) {
+			return nil, uninitializedError(name)
+		}
+		return env.values[name.Lexeme], nil
+	}
+	val, err := in.globals.get(name)
+	if err != nil {
+		// the name may be a typo of a local variable as well as of a global
+		return nil, suggest(err, name.Lexeme, in.environment.names())
 	}
+	if in.strictVars && in.globals.isUninitialized(name.Lexeme) {
+		return nil, uninitializedError(name)
+	}
+	return val, nil
+}
+
+// uninitializedError creates the error of reading a variable that has no
+// value, see SetStrictVariables.
+func uninitializedError(name *Token) error {
+	return newRuntimeError(name, CodeUninitialized, fmt.Sprintf(
+		"Uninitialized variable '%s'.", name.Lexeme,
+	))
 }
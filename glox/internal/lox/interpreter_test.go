@@ -0,0 +1,626 @@
+package lox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const fibScript = `
+fun fib(n) {
+	if (n < 2) return n;
+	return fib(n - 1) + fib(n - 2);
+}
+print fib(20);
+`
+
+// parseScript scans, parses, and resolves the given script for the given
+// interpreter, failing the test if there's any error.
+func parseScript(tb testing.TB, in *Interpreter, script string) []Stmt {
+	var errs strings.Builder
+	reporter := NewSimpleReporter(&errs)
+	tokens := NewScanner([]byte(script), reporter).Scan()
+	stmts := NewParser(tokens, reporter).Parse()
+	if !reporter.HadError() {
+		NewResolver(in, reporter).Resolve(stmts)
+	}
+	if reporter.HadError() {
+		tb.Fatalf("could not parse script: %s", errs.String())
+	}
+	return stmts
+}
+
+// runScript runs the given script with a new interpreter and returns what was
+// written to the output and to the reporter.
+func runScript(tb testing.TB, script string) (string, string) {
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	in.Interpret(parseScript(tb, in, script))
+	return out.String(), errs.String()
+}
+
+func TestInterpreterRunsFib(t *testing.T) {
+	assert := assert.New(t)
+
+	out, errs := runScript(t, fibScript)
+
+	assert.Equal("6765\n", out)
+	assert.Empty(errs)
+}
+
+func BenchmarkInterpreterFib(b *testing.B) {
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false)
+	stmts := parseScript(b, in, fibScript)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		in.Interpret(stmts)
+	}
+}
+
+// nopVisitor does nothing but return, it's used to measure the cost of the
+// dispatching mechanism alone.
+type nopVisitor struct{}
+
+func (v *nopVisitor) VisitAssignExpr(expr *AssignExpr) (interface{}, error)   { return nil, nil }
+func (v *nopVisitor) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error)   { return nil, nil }
+func (v *nopVisitor) VisitCallExpr(expr *CallExpr) (interface{}, error)       { return nil, nil }
+func (v *nopVisitor) VisitErrorExpr(expr *ErrorExpr) (interface{}, error)     { return nil, nil }
+func (v *nopVisitor) VisitGetExpr(expr *GetExpr) (interface{}, error)         { return nil, nil }
+func (v *nopVisitor) VisitGroupExpr(expr *GroupExpr) (interface{}, error)     { return nil, nil }
+func (v *nopVisitor) VisitLiteralExpr(expr *LiteralExpr) (interface{}, error) { return nil, nil }
+func (v *nopVisitor) VisitLogicalExpr(expr *LogicalExpr) (interface{}, error) { return nil, nil }
+func (v *nopVisitor) VisitSetExpr(expr *SetExpr) (interface{}, error)         { return nil, nil }
+func (v *nopVisitor) VisitSuperExpr(expr *SuperExpr) (interface{}, error)     { return nil, nil }
+func (v *nopVisitor) VisitThisExpr(expr *ThisExpr) (interface{}, error)       { return nil, nil }
+func (v *nopVisitor) VisitUnaryExpr(expr *UnaryExpr) (interface{}, error)     { return nil, nil }
+func (v *nopVisitor) VisitVarExpr(expr *VarExpr) (interface{}, error)         { return nil, nil }
+
+func dispatchBenchExprs() []Expr {
+	tok := NewToken(IDENT, "a", nil, 1)
+	return []Expr{
+		NewLiteralExpr(1.0),
+		NewVarExpr(tok),
+		NewBinaryExpr(NewToken(PLUS, "+", nil, 1), nil, nil),
+		NewUnaryExpr(NewToken(MINUS, "-", nil, 1), nil),
+		NewCallExpr(nil, tok, nil),
+		NewGetExpr(nil, tok),
+	}
+}
+
+func BenchmarkDispatchAccept(b *testing.B) {
+	exprs := dispatchBenchExprs()
+	var v ExprVisitor = new(nopVisitor)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, expr := range exprs {
+			expr.Accept(v)
+		}
+	}
+}
+
+func BenchmarkDispatchTypeSwitch(b *testing.B) {
+	exprs := dispatchBenchExprs()
+	v := new(nopVisitor)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, expr := range exprs {
+			switch expr := expr.(type) {
+			case *AssignExpr:
+				v.VisitAssignExpr(expr)
+			case *BinaryExpr:
+				v.VisitBinaryExpr(expr)
+			case *CallExpr:
+				v.VisitCallExpr(expr)
+			case *GetExpr:
+				v.VisitGetExpr(expr)
+			case *GroupExpr:
+				v.VisitGroupExpr(expr)
+			case *LiteralExpr:
+				v.VisitLiteralExpr(expr)
+			case *LogicalExpr:
+				v.VisitLogicalExpr(expr)
+			case *SetExpr:
+				v.VisitSetExpr(expr)
+			case *SuperExpr:
+				v.VisitSuperExpr(expr)
+			case *ThisExpr:
+				v.VisitThisExpr(expr)
+			case *UnaryExpr:
+				v.VisitUnaryExpr(expr)
+			case *VarExpr:
+				v.VisitVarExpr(expr)
+			}
+		}
+	}
+}
+
+func TestInterpreterInheritedMethods(t *testing.T) {
+	assert := assert.New(t)
+
+	out, errs := runScript(t, `
+class A {
+	name() { return "A"; }
+	greet() { return "hello from " + this.name(); }
+}
+class B < A {}
+class C < B {
+	name() { return "C"; }
+	parent() { return super.name(); }
+}
+print B().greet();
+print C().greet();
+print C().parent();
+`)
+
+	assert.Equal("hello from A\nhello from C\nA\n", out)
+	assert.Empty(errs)
+}
+
+const loopScript = `
+var sum = 0;
+for (var i = 0; i < 10000; i = i + 1) {
+	var sq = i * i;
+	{
+		var half = sq / 2;
+		sum = sum + half;
+	}
+}
+print sum;
+`
+
+func BenchmarkInterpreterLoop(b *testing.B) {
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false)
+	stmts := parseScript(b, in, loopScript)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		in.Interpret(stmts)
+	}
+}
+
+func TestInterpreterClosuresAreNotRecycled(t *testing.T) {
+	assert := assert.New(t)
+
+	out, errs := runScript(t, `
+var first;
+var second;
+for (var i = 0; i < 2; i = i + 1) {
+	var j = i;
+	fun get() { return j; }
+	if (i == 0) first = get; else second = get;
+}
+{ var k = "reused"; }
+print first();
+print second();
+`)
+
+	assert.Equal("0\n1\n", out)
+	assert.Empty(errs)
+}
+
+func TestInterpreterStepLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	reporter := &recordingReporter{Reporter: NewSimpleReporter(&errs)}
+	in := NewInterpreter(&out, reporter, false)
+	in.SetLimits(100, 0)
+	in.Interpret(parseScript(t, in, "while (true) {}"))
+
+	assert.True(reporter.HadRuntimeError())
+	assert.True(errors.Is(reporter.last, ErrLimitExceeded))
+	assert.Equal("Execution step limit exceeded.\n", errs.String())
+}
+
+func TestInterpreterTimeLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	reporter := &recordingReporter{Reporter: NewSimpleReporter(&errs)}
+	in := NewInterpreter(&out, reporter, false)
+	in.SetLimits(0, 10*time.Millisecond)
+	in.Interpret(parseScript(t, in, "while (true) {}"))
+
+	assert.True(errors.Is(reporter.last, ErrLimitExceeded))
+	assert.Equal("Execution time limit exceeded.\n", errs.String())
+
+	// limits are applied to each call to Interpret
+	errs.Reset()
+	in.Interpret(parseScript(t, in, "print 1;"))
+	assert.Equal("1\n", out.String())
+	assert.Empty(errs.String())
+}
+
+func TestInterpreterInterrupt(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	reporter := &recordingReporter{Reporter: NewSimpleReporter(&errs)}
+	in := NewInterpreter(&out, reporter, false)
+	in.SetInterruptible(true)
+	// an interrupt between two scripts doesn't stop the next one
+	in.Interrupt()
+	timer := time.AfterFunc(10*time.Millisecond, in.Interrupt)
+	defer timer.Stop()
+	in.Interpret(parseScript(t, in, "var n = 0;\nwhile (true) n = n + 1;"))
+
+	assert.True(errors.Is(reporter.last, ErrInterrupted))
+	assert.Equal("Interrupted.\n", errs.String())
+
+	// the globals are kept
+	errs.Reset()
+	in.Interpret(parseScript(t, in, "print n > 0;"))
+	assert.Equal("true\n", out.String())
+	assert.Empty(errs.String())
+}
+
+// recordingReporter keeps the last reported error around for inspection.
+type recordingReporter struct {
+	Reporter
+	last error
+}
+
+func (r *recordingReporter) Report(d *Diagnostic) {
+	r.last = d.Err
+	r.Reporter.Report(d)
+}
+
+func TestInterpreterMemoryBudget(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	reporter := &recordingReporter{Reporter: NewSimpleReporter(&errs)}
+	in := NewInterpreter(&out, reporter, false)
+	in.SetMemoryBudget(10, 16)
+
+	in.Interpret(parseScript(t, in, `
+class Point {}
+var points = 0;
+while (true) { Point(); points = points + 1; }
+`))
+	assert.True(errors.Is(reporter.last, ErrLimitExceeded))
+	assert.Equal("Object allocation limit exceeded.\n[line 4]\n", errs.String())
+
+	errs.Reset()
+	in.Interpret(parseScript(t, in, `
+var s = "abcd";
+while (true) { s = s + s; }
+`))
+	assert.True(errors.Is(reporter.last, ErrLimitExceeded))
+	assert.Equal("String allocation limit exceeded.\n[line 3]\n", errs.String())
+}
+
+func TestInterpreterLimitInInitializer(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	in.SetLimits(1000, 0)
+	in.Interpret(parseScript(t, in, `
+class Spin { init() { while (true) {} } }
+Spin();
+print "unreachable";
+`))
+	assert.Empty(out.String())
+	assert.Equal("Execution step limit exceeded.\n", errs.String())
+}
+
+func TestInterpreterStackOverflow(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	in.SetMaxCallDepth(100)
+	in.Interpret(parseScript(t, in, `
+fun recurse(n) {
+	return recurse(n + 1);
+}
+recurse(0);
+`))
+	assert.Equal("Stack overflow.\n[line 3]\n", errs.String())
+
+	// the depth is restored once the error is unwound
+	errs.Reset()
+	in.Interpret(parseScript(t, in, fibScript))
+	assert.Equal("6765\n", out.String())
+	assert.Empty(errs.String())
+}
+
+func TestProfilerCountsCalls(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	profiler := NewProfiler()
+	in.SetProfiler(profiler)
+	in.Interpret(parseScript(t, in, fibScript))
+
+	var report strings.Builder
+	assert.NoError(profiler.WriteReport(&report))
+	lines := strings.Split(strings.TrimSpace(report.String()), "\n")
+	assert.Len(lines, 2)
+	assert.Contains(lines[0], "calls")
+	assert.Regexp(`^\s*21891\s.*fib \(line 2\)$`, lines[1])
+}
+
+func TestInterpreterTrace(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs, trace strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	in.SetTrace(&trace)
+	in.Interpret(parseScript(t, in, "var a = 1;\nprint a + 2;"))
+
+	assert.Equal(`[line 1] var a
+[line 1] literal => 1
+[line 2] print
+[line 2] var a => 1
+[line 2] literal => 2
+[line 2] binary + => 3
+`, trace.String())
+	assert.Equal("3\n", out.String())
+}
+
+func TestInterpreterReset(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), true)
+	in.SetLimits(1000, 0)
+	in.Interpret(parseScript(t, in, "var a = 1; fun f() { return a; }"))
+	in.Reset()
+	in.Interpret(parseScript(t, in, "print clock() > 0; print a;"))
+
+	assert.Equal("true\n", out.String())
+	assert.Equal("Undefined variable 'a'.\n[line 1]\n", errs.String())
+	assert.Empty(in.locals)
+
+	// the limits are still enforced
+	errs.Reset()
+	in.Interpret(parseScript(t, in, "while (true) {}"))
+	assert.Equal("Execution step limit exceeded.\n", errs.String())
+}
+
+func TestInterpreterREPLLastResult(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), true)
+	for _, input := range []string{
+		"1 + 2;",
+		"print _ * 2;",
+		"fun f() { return \"called\"; }",
+		"f();",
+		"print _;",
+		"{ 4; }",
+		"print _;",
+	} {
+		in.Interpret(parseScript(t, in, input))
+	}
+
+	assert.Equal("3\n6\ncalled\n4\ncalled\n", out.String())
+	assert.Empty(errs.String())
+}
+
+func TestInterpreterREPLEchoAll(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&out), true)
+	script := "fun f() { return 1; } fun g() {} var a; class A {} var i = A();\nf(); g(); a = 2; i.x = 3; a;"
+	in.Interpret(parseScript(t, in, script))
+	assert.Equal("2\n", out.String())
+
+	out.Reset()
+	in.SetEchoAll(true)
+	in.Interpret(parseScript(t, in, script))
+	assert.Equal("1\n2\n3\n2\n", out.String())
+
+	// only the statements entered are echoed, not the ones that they run
+	out.Reset()
+	in.Interpret(parseScript(t, in, "for (var j = 0; j < 3; j = j + 1) a = j; { f(); }"))
+	assert.Empty(out.String())
+}
+
+func TestInterpreterREPLAfterRuntimeError(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	reporter := NewSimpleReporter(&errs)
+	in := NewInterpreter(&out, reporter, true)
+	in.Interpret(parseScript(t, in, "var a = 1; class A { m() { { var b = 2; nil(); } } } A().m(); var c = 3;"))
+	assert.True(reporter.HadRuntimeError())
+	assert.Equal(in.globals, in.environment)
+	assert.Empty(in.frames)
+
+	reporter.Reset()
+	in.Interpret(parseScript(t, in, "print a; print A; var b = 4; print b;"))
+	assert.Equal("Can only call functions and classes.\n[line 1]\n", errs.String())
+	assert.Equal("1\nA\n4\n", out.String())
+	assert.False(reporter.HadError())
+	assert.False(reporter.HadRuntimeError())
+}
+
+func TestInterpreterArgs(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	in.SetArgs([]string{"a", "b c"})
+	in.Interpret(parseScript(t, in, "for (var i = 0; i < argc; i = i + 1) print args(i);"))
+	assert.Equal("a\nb c\n", out.String())
+	assert.Empty(errs.String())
+
+	for script, err := range map[string]string{
+		"args(2);":     "Argument index out of range.\n[line 1]\n",
+		"args(-1);":    "Argument index out of range.\n[line 1]\n",
+		"args(0.5);":   "Argument index must be an integer.\n[line 1]\n",
+		"args(\"0\");": "Argument index must be an integer.\n[line 1]\n",
+	} {
+		errs.Reset()
+		in.Interpret(parseScript(t, in, script))
+		assert.Equal(err, errs.String(), script)
+	}
+
+	// the arguments are kept when the globals are reset
+	out.Reset()
+	in.Reset()
+	in.Interpret(parseScript(t, in, "print argc;"))
+	assert.Equal("2\n", out.String())
+}
+
+func TestInterpreterResolutions(t *testing.T) {
+	assert := assert.New(t)
+
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false)
+	parseScript(t, in, "var g = 1;\nfun f(a) {\n  { var b = a; b = g; }\n  return a;\n}\nclass A < B { m() { return this.x + super.m(); } }")
+	var got []string
+	for _, res := range in.Resolutions() {
+		got = append(got, fmt.Sprintf("%d:%d %s %d", res.Name.Line, res.Name.Column, res.Name.Lexeme, res.Depth))
+	}
+	assert.Equal([]string{"3:13 a 1", "3:16 b 0", "4:10 a 0", "6:28 this 1", "6:37 super 2"}, got)
+}
+
+func TestInterpreterSetREPL(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&out), false)
+	in.Interpret(parseScript(t, in, "var a = 1; a + 1;"))
+	assert.Empty(out.String())
+
+	in.SetREPL(true)
+	in.Interpret(parseScript(t, in, "a + 2; print _;"))
+	assert.Equal("3\n3\n", out.String())
+}
+
+func TestInterpreterStrictVariables(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&out), false)
+	in.Interpret(parseScript(t, in, "var a; print a;"))
+	assert.Equal("nil\n", out.String())
+
+	out.Reset()
+	in.SetStrictVariables(true)
+	in.Interpret(parseScript(t, in, `var a;
+fun f() {
+  var b;
+  fun g() { b = 2; }
+  g();
+  print b;
+  var c;
+  print c;
+}
+f();`))
+	assert.Equal("2\nUninitialized variable 'c'.\n[line 8]\n", out.String())
+
+	// the globals declared again without a value have none
+	out.Reset()
+	in.Interpret(parseScript(t, in, "var a = nil;\nprint a;\nvar a;\nprint a;"))
+	assert.Equal("nil\nUninitialized variable 'a'.\n[line 4]\n", out.String())
+}
+
+func TestInterpreterInterpretContext(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := in.InterpretContext(ctx, parseScript(t, in, "var n = 0;\nwhile (true) n = n + 1;"))
+	assert.True(errors.Is(err, ErrCanceled))
+	assert.Equal("Deadline exceeded.\n[line 2]\n", errs.String())
+
+	// the context is checked before each call, even without loops
+	errs.Reset()
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	err = in.InterpretContext(ctx, parseScript(t, in, "fun f() { print 1; }\nprint n > 0;\nf();"))
+	assert.True(errors.Is(err, ErrCanceled))
+	assert.Equal("true\n", out.String())
+	assert.Equal("Canceled.\n[line 3]\n", errs.String())
+
+	// the context isn't kept for the next scripts
+	errs.Reset()
+	in.Interpret(parseScript(t, in, "f();"))
+	assert.Empty(errs.String())
+	assert.NoError(in.InterpretContext(context.Background(), parseScript(t, in, "f();")))
+}
+
+func TestInterpreterStreams(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs, stderr strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false,
+		WithStdin(strings.NewReader("first\r\nsecond")), WithStderr(&stderr))
+	in.Interpret(parseScript(t, in, "var line = readLine();\nwhile (line != nil) { print line; printErr(line + \"!\"); line = readLine(); }"))
+	assert.Equal("first\nsecond\n", out.String())
+	assert.Equal("first!\nsecond!\n", stderr.String())
+	assert.Empty(errs.String())
+
+	// without the options, there's nothing to read and nothing is written
+	out.Reset()
+	in = NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	in.Interpret(parseScript(t, in, "print readLine(); printErr(1);"))
+	assert.Equal("nil\n", out.String())
+}
+
+func TestInterpreterCapture(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	captured, err := in.Capture(parseScript(t, in, "print 1;\nprint 2;\nprint -nil;"))
+	assert.Equal("1\n2\n", captured)
+	assert.Error(err)
+	assert.Equal("Operand must be a number.\n[line 3]\n", errs.String())
+
+	// the output is written again afterward
+	in.Interpret(parseScript(t, in, "print 3;"))
+	assert.Equal("3\n", out.String())
+}
+
+func TestInterpreterRun(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	r, w := io.Pipe()
+	go func() {
+		io.WriteString(w, "fun add(a, b) {\n  return a + b;\n}\n")
+		io.WriteString(w, "print add(1, 2);\n")
+		w.Close()
+	}()
+	assert.NoError(in.Run(r))
+	assert.Equal("3\n", out.String())
+
+	// the globals are kept
+	out.Reset()
+	err := in.Run(strings.NewReader("print add(\"a\", nil);"))
+	assert.EqualError(err, "Operands must be two numbers or two strings.\n[line 2]")
+	assert.Equal("Operands must be two numbers or two strings.\n[line 2]\n", errs.String())
+
+	errs.Reset()
+	err = in.Run(strings.NewReader("print 1;\nvar = 1;\nprint;"))
+	var d *Diagnostic
+	if assert.True(errors.As(err, &d)) {
+		assert.Equal(StageParse, d.Stage)
+		assert.Equal(2, d.Line)
+	}
+	assert.Equal("[line 2] Error at '=': Expect variable name.\n[line 3] Error at ';': Expect expression.\n", errs.String())
+	assert.Empty(out.String())
+
+	errRead := errors.New("read")
+	r, w = io.Pipe()
+	w.CloseWithError(errRead)
+	assert.Equal(errRead, in.Run(r))
+}
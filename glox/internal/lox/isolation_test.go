@@ -0,0 +1,78 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestInterpretersRunConcurrently runs many interpreters on different
+// goroutines, each one defines the same global names with different values.
+// Run with -race to check that the interpreters don't share any state.
+func TestInterpretersRunConcurrently(t *testing.T) {
+	assert := assert.New(t)
+
+	const workers = 8
+	var wg sync.WaitGroup
+	outputs := make([]string, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			script := fmt.Sprintf(`
+var id = %d;
+class Counter {
+	init() { this.count = id; }
+	inc() { this.count = this.count + 1; return this; }
+}
+fun fib(n) { if (n < 2) return n; return fib(n - 1) + fib(n - 2); }
+print Counter().inc().inc().count + fib(10);
+`, i)
+			var out, errs strings.Builder
+			in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+			in.Interpret(parseScript(t, in, script))
+			outputs[i] = out.String() + errs.String()
+		}(i)
+	}
+	wg.Wait()
+
+	for i, out := range outputs {
+		assert.Equal(fmt.Sprintf("%d\n", i+2+55), out)
+	}
+}
+
+// TestInterpretersShareSyntaxTree checks that a syntax tree can be resolved and
+// run by many interpreters at the same time.
+func TestInterpretersShareSyntaxTree(t *testing.T) {
+	assert := assert.New(t)
+
+	var errs strings.Builder
+	reporter := NewSimpleReporter(&errs)
+	tokens := NewScanner([]byte(fibScript), reporter).Scan()
+	stmts := NewParser(tokens, reporter).Parse()
+	assert.False(reporter.HadError())
+
+	const workers = 8
+	var wg sync.WaitGroup
+	outputs := make([]string, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			var out strings.Builder
+			reporter := NewSimpleReporter(&out)
+			in := NewInterpreter(&out, reporter, false)
+			NewResolver(in, reporter).Resolve(stmts)
+			in.Interpret(stmts)
+			outputs[i] = out.String()
+		}(i)
+	}
+	wg.Wait()
+
+	for _, out := range outputs {
+		assert.Equal("6765\n", out)
+	}
+}
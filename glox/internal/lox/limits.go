@@ -0,0 +1,150 @@
+package lox
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DefaultMaxCallDepth is the default maximum number of nested Lox calls. Lox
+// calls are executed using Go's stack, so we stop a runaway recursion long
+// before the Go runtime would crash the whole process.
+const DefaultMaxCallDepth = 10000
+
+// limitCheckInterval is the number of steps between two checks of the wall
+// clock, reading the time on every step would slow the interpreter down.
+const limitCheckInterval = 1024
+
+// limits tracks the resources used by the interpreter while a script is run.
+// A limit that is set to zero is not enforced.
+type limits struct {
+	maxSteps       int
+	maxDuration    time.Duration
+	maxObjects     int
+	maxStringBytes int
+	maxCallDepth   int
+
+	// interruptible is set if the script can be stopped by setting
+	// interrupted, which is done from other goroutines
+	interruptible bool
+	interrupted   int32
+
+	steps       int
+	deadline    time.Time
+	objects     int
+	stringBytes int
+	callDepth   int
+}
+
+// enabled returns true if any limit is enforced.
+func (l *limits) enabled() bool {
+	return l.maxSteps > 0 || l.maxDuration > 0 || l.interruptible
+}
+
+// start resets the usage counters, it's called each time the interpreter
+// is given a new list of statements.
+func (l *limits) start() {
+	l.steps = 0
+	l.objects = 0
+	l.stringBytes = 0
+	l.callDepth = 0
+	atomic.StoreInt32(&l.interrupted, 0)
+	if l.maxDuration > 0 {
+		l.deadline = time.Now().Add(l.maxDuration)
+	}
+}
+
+// step is called before a node is executed or evaluated.
+func (l *limits) step() error {
+	l.steps++
+	if l.maxSteps > 0 && l.steps > l.maxSteps {
+		return newLimitError(nil, CodeStepLimit, "Execution step limit exceeded.")
+	}
+	if l.maxDuration > 0 &&
+		l.steps%limitCheckInterval == 0 &&
+		time.Now().After(l.deadline) {
+		return newLimitError(nil, CodeTimeLimit, "Execution time limit exceeded.")
+	}
+	if l.interruptible &&
+		l.steps%limitCheckInterval == 0 &&
+		atomic.LoadInt32(&l.interrupted) != 0 {
+		return newInterruptError()
+	}
+	return nil
+}
+
+// allocObject is called when the script creates a new instance, function, or
+// class. The count is approximate, objects that become garbage are never
+// subtracted from it.
+func (l *limits) allocObject(token *Token) error {
+	l.objects++
+	if l.maxObjects > 0 && l.objects > l.maxObjects {
+		return newLimitError(token, CodeObjectLimit, "Object allocation limit exceeded.")
+	}
+	return nil
+}
+
+// allocString is called with the size of every string that is created by the
+// script at runtime.
+func (l *limits) allocString(token *Token, size int) error {
+	l.stringBytes += size
+	if l.maxStringBytes > 0 && l.stringBytes > l.maxStringBytes {
+		return newLimitError(token, CodeStringLimit, "String allocation limit exceeded.")
+	}
+	return nil
+}
+
+// enterCall is called before a Lox function, method, or class is called.
+func (l *limits) enterCall(token *Token) error {
+	if l.maxCallDepth > 0 && l.callDepth >= l.maxCallDepth {
+		return newRuntimeError(token, CodeStackOverflow, "Stack overflow.")
+	}
+	l.callDepth++
+	return nil
+}
+
+// exitCall is called after a call returns, successfully or not.
+func (l *limits) exitCall() {
+	l.callDepth--
+}
+
+// SetLimits sets the maximum number of steps, i.e. statements and expressions,
+// and the maximum amount of time that the interpreter can spend on each call
+// to Interpret. A runtime error wrapping ErrLimitExceeded is reported when a
+// script goes over the limits. Giving zero for a limit disables it.
+func (in *Interpreter) SetLimits(maxSteps int, maxDuration time.Duration) {
+	in.limits.maxSteps = maxSteps
+	in.limits.maxDuration = maxDuration
+}
+
+// SetMemoryBudget sets the maximum number of objects, i.e. instances, functions,
+// and classes, and the maximum number of string bytes that a script can
+// allocate on each call to Interpret. A runtime error wrapping
+// ErrLimitExceeded is reported when a script goes over the budget. Giving zero
+// for a limit disables it.
+func (in *Interpreter) SetMemoryBudget(maxObjects int, maxStringBytes int) {
+	in.limits.maxObjects = maxObjects
+	in.limits.maxStringBytes = maxStringBytes
+}
+
+// SetMaxCallDepth sets the maximum number of nested calls, a "Stack overflow."
+// runtime error is reported when a script goes deeper than that. Giving zero
+// disables the check, which lets a runaway recursion crash the Go runtime.
+func (in *Interpreter) SetMaxCallDepth(depth int) {
+	in.limits.maxCallDepth = depth
+}
+
+// SetInterruptible sets whether the script that is running can be stopped
+// with Interrupt. It's disabled by default, since checking whether the script
+// was interrupted slows every step down a little.
+func (in *Interpreter) SetInterruptible(enabled bool) {
+	in.limits.interruptible = enabled
+}
+
+// Interrupt stops the script that is running soon after it's called, with a
+// runtime error wrapping ErrInterrupted. The state of the interpreter is kept,
+// e.g. the global variables that were defined until then. Interrupt can be
+// called from any goroutine, e.g. one that handles SIGINT, and has no effect
+// if no script is running or if the interpreter isn't interruptible.
+func (in *Interpreter) Interrupt() {
+	atomic.StoreInt32(&in.limits.interrupted, 1)
+}
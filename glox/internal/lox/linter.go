@@ -0,0 +1,508 @@
+package lox
+
+import (
+	"io/ioutil"
+	"sort"
+	"strconv"
+)
+
+// LintRule identifies a check that is done by the linter, rules can be combined
+// into a set with bitwise or.
+type LintRule uint
+
+const (
+	// LintUnusedVariable reports local variables, functions, and classes that
+	// are never read. Names starting with an underscore are not reported.
+	LintUnusedVariable LintRule = 1 << iota
+	// LintUnusedParameter reports function parameters that are never read.
+	// Names starting with an underscore are not reported.
+	LintUnusedParameter
+	// LintShadow reports local declarations that hide a variable of the same
+	// name in an enclosing scope.
+	LintShadow
+	// LintSelfAssign reports assignments of a variable or a property to
+	// itself.
+	LintSelfAssign
+	// LintConstantCondition reports conditions of "if" statements and loops
+	// that are always true or always false. "while (true)" is not reported
+	// since it's the usual way to write an infinite loop.
+	LintConstantCondition
+	// LintBoolCompare reports comparisons of a boolean value with "true" or
+	// "false", which can be replaced by the value or its negation.
+	LintBoolCompare
+
+	// LintAllRules is the set of all the rules.
+	LintAllRules = LintUnusedVariable | LintUnusedParameter | LintShadow |
+		LintSelfAssign | LintConstantCondition | LintBoolCompare
+)
+
+// LintRules lists all the rules in the order they should be presented.
+var LintRules = []LintRule{
+	LintUnusedVariable,
+	LintUnusedParameter,
+	LintShadow,
+	LintSelfAssign,
+	LintConstantCondition,
+	LintBoolCompare,
+}
+
+func (rule LintRule) String() string {
+	switch rule {
+	case LintUnusedVariable:
+		return "unused-variable"
+	case LintUnusedParameter:
+		return "unused-parameter"
+	case LintShadow:
+		return "shadow"
+	case LintSelfAssign:
+		return "self-assign"
+	case LintConstantCondition:
+		return "constant-condition"
+	case LintBoolCompare:
+		return "bool-compare"
+	}
+	return "unknown"
+}
+
+// code returns the code of the warnings of the rule.
+func (rule LintRule) code() Code {
+	switch rule {
+	case LintUnusedVariable:
+		return CodeLintUnusedVariable
+	case LintUnusedParameter:
+		return CodeLintUnusedParameter
+	case LintShadow:
+		return CodeLintShadow
+	case LintSelfAssign:
+		return CodeLintSelfAssign
+	case LintConstantCondition:
+		return CodeLintConstantCondition
+	case LintBoolCompare:
+		return CodeLintBoolCompare
+	}
+	return ""
+}
+
+// lintVar is a declaration that is tracked by the linter
+type lintVar struct {
+	name     *Token
+	param    bool
+	used     bool
+	assigned bool
+	// decl is the declaration of a variable, it's nil for the parameters, the
+	// functions, and the classes
+	decl *VarStmt
+}
+
+// lintScope holds the declarations of a block scope, the order of declaration
+// is kept so warnings are reported in source order.
+type lintScope struct {
+	vars  map[string]*lintVar
+	order []*lintVar
+}
+
+// Linter performs static analysis on a resolved syntax tree and reports code
+// that is legal but most likely a mistake. Its findings are reported as
+// warnings, which are kept separate from the errors of the other phases.
+type Linter struct {
+	rules    LintRule
+	reporter Reporter
+	// scopes[0] holds the global declarations, which are only used to detect
+	// shadowing since they can be used by code that is run later
+	scopes []*lintScope
+	// constants evaluates the conditions that don't depend on any variable
+	constants *Interpreter
+	// warnings are collected and sorted by line before being reported, since
+	// unused declarations are only found at the end of their scope
+	warnings []*lintWarning
+	// fixes holds the mechanical fixes of the warnings, they are applied by
+	// Fix
+	fixes []lintFix
+}
+
+func NewLinter(rules LintRule, reporter Reporter) *Linter {
+	l := new(Linter)
+	l.rules = rules
+	l.reporter = reporter
+	l.scopes = []*lintScope{newLintScope()}
+	l.constants = NewInterpreter(ioutil.Discard, reporter, false)
+	return l
+}
+
+func newLintScope() *lintScope {
+	s := new(lintScope)
+	s.vars = make(map[string]*lintVar)
+	return s
+}
+
+func (l *Linter) Lint(statements []Stmt) {
+	l.fixes = l.fixes[:0]
+	for _, stmt := range statements {
+		l.lintStmt(stmt)
+	}
+	sort.SliceStable(l.warnings, func(i, j int) bool {
+		return l.warnings[i].line < l.warnings[j].line
+	})
+	for _, w := range l.warnings {
+		l.reporter.Report(newDiagnostic(StageLint, w))
+	}
+	l.warnings = l.warnings[:0]
+}
+
+func (l *Linter) VisitBlockStmt(stmt *BlockStmt) (interface{}, error) {
+	l.beginScope()
+	for _, stmt := range stmt.Stmts {
+		l.lintStmt(stmt)
+	}
+	l.endScope()
+	return nil, nil
+}
+
+func (l *Linter) VisitErrorStmt(stmt *ErrorStmt) (interface{}, error) {
+	return nil, nil
+}
+
+func (l *Linter) VisitExprStmt(stmt *ExprStmt) (interface{}, error) {
+	l.lintExpr(stmt.Expr)
+	return nil, nil
+}
+
+func (l *Linter) VisitClassStmt(stmt *ClassStmt) (interface{}, error) {
+	l.declare(stmt.Name, false)
+	if stmt.Super != nil {
+		l.lintExpr(stmt.Super)
+	}
+	for _, method := range stmt.Methods {
+		l.lintFunction(method)
+	}
+	return nil, nil
+}
+
+func (l *Linter) VisitFunctionStmt(stmt *FunctionStmt) (interface{}, error) {
+	l.declare(stmt.Name, false)
+	l.lintFunction(stmt)
+	return nil, nil
+}
+
+func (l *Linter) VisitIfStmt(stmt *IfStmt) (interface{}, error) {
+	l.lintCondition(stmt.Keyword, stmt.Cond, false)
+	l.lintStmt(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		l.lintStmt(stmt.ElseBranch)
+	}
+	return nil, nil
+}
+
+func (l *Linter) VisitPrintStmt(stmt *PrintStmt) (interface{}, error) {
+	l.lintExpr(stmt.Expr)
+	return nil, nil
+}
+
+func (l *Linter) VisitReturnStmt(stmt *ReturnStmt) (interface{}, error) {
+	if stmt.Val != nil {
+		l.lintExpr(stmt.Val)
+	}
+	return nil, nil
+}
+
+func (l *Linter) VisitVarStmt(stmt *VarStmt) (interface{}, error) {
+	if stmt.Init != nil {
+		l.lintExpr(stmt.Init)
+	}
+	l.declare(stmt.Name, false).decl = stmt
+	return nil, nil
+}
+
+func (l *Linter) VisitWhileStmt(stmt *WhileStmt) (interface{}, error) {
+	l.lintCondition(stmt.Keyword, stmt.Cond, true)
+	l.lintStmt(stmt.Body)
+	return nil, nil
+}
+
+func (l *Linter) VisitAssignExpr(expr *AssignExpr) (interface{}, error) {
+	if val, ok := expr.Val.(*VarExpr); ok && val.Name.Lexeme == expr.Name.Lexeme {
+		l.warn(LintSelfAssign, expr.Name.Line,
+			"Variable '"+expr.Name.Lexeme+"' is assigned to itself.")
+	}
+	// assigning to a variable doesn't count as using it
+	if v := l.lookup(expr.Name); v != nil {
+		v.assigned = true
+	}
+	l.lintExpr(expr.Val)
+	return nil, nil
+}
+
+func (l *Linter) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
+	if expr.Op.Type == EQUAL_EQUAL || expr.Op.Type == BANG_EQUAL {
+		l.lintBoolCompare(expr)
+	}
+	l.lintExpr(expr.Lhs)
+	l.lintExpr(expr.Rhs)
+	return nil, nil
+}
+
+func (l *Linter) VisitCallExpr(expr *CallExpr) (interface{}, error) {
+	l.lintExpr(expr.Callee)
+	for _, arg := range expr.Args {
+		l.lintExpr(arg)
+	}
+	return nil, nil
+}
+
+func (l *Linter) VisitErrorExpr(expr *ErrorExpr) (interface{}, error) {
+	return nil, nil
+}
+
+func (l *Linter) VisitGetExpr(expr *GetExpr) (interface{}, error) {
+	l.lintExpr(expr.Obj)
+	return nil, nil
+}
+
+func (l *Linter) VisitGroupExpr(expr *GroupExpr) (interface{}, error) {
+	l.lintExpr(expr.Expr)
+	return nil, nil
+}
+
+func (l *Linter) VisitLiteralExpr(expr *LiteralExpr) (interface{}, error) {
+	return nil, nil
+}
+
+func (l *Linter) VisitLogicalExpr(expr *LogicalExpr) (interface{}, error) {
+	l.lintExpr(expr.Lhs)
+	l.lintExpr(expr.Rhs)
+	return nil, nil
+}
+
+func (l *Linter) VisitSetExpr(expr *SetExpr) (interface{}, error) {
+	if val, ok := expr.Val.(*GetExpr); ok &&
+		val.Name.Lexeme == expr.Name.Lexeme && sameObject(val.Obj, expr.Obj) {
+		l.warn(LintSelfAssign, expr.Name.Line,
+			"Property '"+expr.Name.Lexeme+"' is assigned to itself.")
+	}
+	l.lintExpr(expr.Val)
+	l.lintExpr(expr.Obj)
+	return nil, nil
+}
+
+func (l *Linter) VisitSuperExpr(expr *SuperExpr) (interface{}, error) {
+	return nil, nil
+}
+
+func (l *Linter) VisitThisExpr(expr *ThisExpr) (interface{}, error) {
+	return nil, nil
+}
+
+func (l *Linter) VisitUnaryExpr(expr *UnaryExpr) (interface{}, error) {
+	l.lintExpr(expr.Expr)
+	return nil, nil
+}
+
+func (l *Linter) VisitVarExpr(expr *VarExpr) (interface{}, error) {
+	if v := l.lookup(expr.Name); v != nil {
+		v.used = true
+	}
+	return nil, nil
+}
+
+func (l *Linter) lintFunction(fn *FunctionStmt) {
+	// parameters and the body share a scope, like in the resolver
+	l.beginScope()
+	for _, p := range fn.Params {
+		l.declare(p, true)
+	}
+	for _, stmt := range fn.Body {
+		l.lintStmt(stmt)
+	}
+	l.endScope()
+}
+
+// lintCondition reports the condition if it only depends on literals, in which
+// case it can be evaluated without running the script.
+func (l *Linter) lintCondition(keyword *Token, cond Expr, loop bool) {
+	l.lintExpr(cond)
+	if !isConstantExpr(cond) {
+		return
+	}
+	if lit, ok := cond.(*LiteralExpr); ok && loop && lit.Val == true {
+		return
+	}
+	val, err := l.constants.eval(cond)
+	if err != nil {
+		// e.g. a type error, which will be reported when the script runs
+		return
+	}
+	message := "Condition is always false."
+	if truthy(val) {
+		message = "Condition is always true."
+	}
+	l.warn(LintConstantCondition, keyword.Line, message)
+}
+
+// lintBoolCompare reports the comparison if one of its operands is a boolean
+// literal and the other is always a boolean, the comparison is then the same
+// as the other operand or its negation.
+func (l *Linter) lintBoolCompare(expr *BinaryExpr) {
+	lit, ok := expr.Rhs.(*LiteralExpr)
+	other := expr.Lhs
+	if !ok {
+		lit, ok = expr.Lhs.(*LiteralExpr)
+		other = expr.Rhs
+	}
+	if !ok {
+		return
+	}
+	val, ok := lit.Val.(bool)
+	if !ok || !isBoolExpr(other) {
+		return
+	}
+	if _, ok := other.(*LiteralExpr); ok {
+		return
+	}
+	l.warn(LintBoolCompare, expr.Op.Line,
+		"Comparison with "+strconv.FormatBool(val)+" is redundant.")
+	l.suggest(LintBoolCompare, &boolCompareFix{
+		expr:   expr,
+		other:  other,
+		negate: val != (expr.Op.Type == EQUAL_EQUAL),
+	})
+}
+
+func (l *Linter) lintStmt(stmt Stmt) {
+	stmt.Accept(l)
+}
+
+func (l *Linter) lintExpr(expr Expr) {
+	expr.Accept(l)
+}
+
+func (l *Linter) beginScope() {
+	l.scopes = append(l.scopes, newLintScope())
+}
+
+// endScope leaves the innermost scope and reports its unused declarations
+func (l *Linter) endScope() {
+	scope := l.scopes[len(l.scopes)-1]
+	l.scopes = l.scopes[:len(l.scopes)-1]
+	for _, v := range scope.order {
+		if v.used || v.name.Lexeme[0] == '_' {
+			continue
+		}
+		if v.param {
+			l.warn(LintUnusedParameter, v.name.Line,
+				"Parameter '"+v.name.Lexeme+"' is never used.")
+		} else {
+			l.warn(LintUnusedVariable, v.name.Line,
+				"Local variable '"+v.name.Lexeme+"' is never used.")
+			// the declaration can only be removed if nothing else refers to
+			// the variable and its value can't have side effects
+			if v.decl != nil && !v.assigned && l.pureInit(v.decl.Init) {
+				l.suggest(LintUnusedVariable, &removeVarFix{decl: v.decl})
+			}
+		}
+	}
+}
+
+// pureInit returns true if evaluating the initializer of a variable has no
+// effect, not even an error.
+func (l *Linter) pureInit(init Expr) bool {
+	if init == nil {
+		return true
+	}
+	if !isConstantExpr(init) {
+		return false
+	}
+	_, err := l.constants.eval(init)
+	return err == nil
+}
+
+func (l *Linter) lookup(name *Token) *lintVar {
+	for i := len(l.scopes) - 1; i >= 0; i-- {
+		if v, ok := l.scopes[i].vars[name.Lexeme]; ok {
+			return v
+		}
+	}
+	return nil
+}
+
+func (l *Linter) declare(name *Token, param bool) *lintVar {
+	scope := l.scopes[len(l.scopes)-1]
+	if len(l.scopes) > 1 {
+		for i := len(l.scopes) - 2; i >= 0; i-- {
+			if _, ok := l.scopes[i].vars[name.Lexeme]; ok {
+				l.warn(LintShadow, name.Line,
+					"Declaration of '"+name.Lexeme+"' shadows a variable in an outer scope.")
+				break
+			}
+		}
+	}
+	v := new(lintVar)
+	v.name = name
+	v.param = param
+	scope.vars[name.Lexeme] = v
+	scope.order = append(scope.order, v)
+	return v
+}
+
+func (l *Linter) warn(rule LintRule, line int, message string) {
+	if l.rules&rule != 0 {
+		l.warnings = append(l.warnings, newLintWarning(line, rule, message))
+	}
+}
+
+// suggest records the fix of a warning that was reported for the rule.
+func (l *Linter) suggest(rule LintRule, fix lintFix) {
+	if l.rules&rule != 0 {
+		l.fixes = append(l.fixes, fix)
+	}
+}
+
+// isBoolExpr returns true if the value of the expression is always a boolean.
+func isBoolExpr(expr Expr) bool {
+	switch expr := expr.(type) {
+	case *LiteralExpr:
+		_, ok := expr.Val.(bool)
+		return ok
+	case *GroupExpr:
+		return isBoolExpr(expr.Expr)
+	case *UnaryExpr:
+		return expr.Op.Type == BANG
+	case *BinaryExpr:
+		switch expr.Op.Type {
+		case EQUAL_EQUAL, BANG_EQUAL, LESS, LESS_EQUAL, GREATER, GREATER_EQUAL:
+			return true
+		}
+	}
+	return false
+}
+
+// isConstantExpr returns true if the value of the expression only depends on
+// literals.
+func isConstantExpr(expr Expr) bool {
+	switch expr := expr.(type) {
+	case *LiteralExpr:
+		return true
+	case *GroupExpr:
+		return isConstantExpr(expr.Expr)
+	case *UnaryExpr:
+		return isConstantExpr(expr.Expr)
+	case *BinaryExpr:
+		return isConstantExpr(expr.Lhs) && isConstantExpr(expr.Rhs)
+	case *LogicalExpr:
+		return isConstantExpr(expr.Lhs) && isConstantExpr(expr.Rhs)
+	}
+	return false
+}
+
+// sameObject returns true if both expressions refer to the same object without
+// evaluating anything that could have side effects.
+func sameObject(a, b Expr) bool {
+	switch a := a.(type) {
+	case *ThisExpr:
+		_, ok := b.(*ThisExpr)
+		return ok
+	case *VarExpr:
+		b, ok := b.(*VarExpr)
+		return ok && a.Name.Lexeme == b.Name.Lexeme
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package lox
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func lint(tb testing.TB, rules LintRule, script string) string {
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false)
+	stmts := parseScript(tb, in, script)
+	var warnings strings.Builder
+	NewLinter(rules, NewSimpleReporter(&warnings)).Lint(stmts)
+	return warnings.String()
+}
+
+func TestLinterRules(t *testing.T) {
+	assert := assert.New(t)
+
+	script := `
+var g = 1;
+fun f(a, b, _c) {
+	var unused = 1;
+	var g = 2;
+	g = g;
+	if (1 < 2) print a;
+	while (true) {}
+	for (;;) {}
+	while (!true) print g;
+}
+class P {
+	init(x) { this.x = this.x; }
+}
+{ var _ok; var x; x = 1; }
+`
+	assert.Equal(`[line 3] Warning: Parameter 'b' is never used. (unused-parameter)
+[line 4] Warning: Local variable 'unused' is never used. (unused-variable)
+[line 5] Warning: Declaration of 'g' shadows a variable in an outer scope. (shadow)
+[line 6] Warning: Variable 'g' is assigned to itself. (self-assign)
+[line 7] Warning: Condition is always true. (constant-condition)
+[line 10] Warning: Condition is always false. (constant-condition)
+[line 13] Warning: Property 'x' is assigned to itself. (self-assign)
+[line 13] Warning: Parameter 'x' is never used. (unused-parameter)
+[line 15] Warning: Local variable 'x' is never used. (unused-variable)
+`, lint(t, LintAllRules, script))
+
+	assert.Equal(`[line 6] Warning: Variable 'g' is assigned to itself. (self-assign)
+[line 13] Warning: Property 'x' is assigned to itself. (self-assign)
+`, lint(t, LintSelfAssign, script))
+}
+
+func TestLinterAcceptsCleanCode(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Empty(lint(t, LintAllRules, fibScript))
+	assert.Empty(lint(t, LintAllRules, `
+fun counter() {
+	var n = 0;
+	fun next() { n = n + 1; return n; }
+	return next;
+}
+var c = counter();
+if (c() > 0 and "a" - 1) print c();
+`))
+}
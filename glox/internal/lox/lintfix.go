@@ -0,0 +1,337 @@
+package lox
+
+import (
+	"sort"
+	"strings"
+)
+
+// The fixes of the linter are edits of the tokens that the script was scanned
+// from, the fixed script is then printed by the formatter. Only the fixes
+// that can't change what the script does are made.
+
+// lintFix is the fix of a warning.
+type lintFix interface {
+	// edits returns the edits that fix the warning, given the tokens and the
+	// index of each one, or nothing if the tokens can't be found.
+	edits(tokens []*Token, index map[*Token]int) []tokenEdit
+}
+
+// tokenEdit replaces the tokens in [start, end) with the inserted ones. The
+// lines of the tokens after the edit are moved up by the given number of
+// lines, which is used when whole lines are removed.
+type tokenEdit struct {
+	start, end int
+	insert     []*Token
+	lines      int
+}
+
+// Fix applies the fixes of the warnings that were found by the last call to
+// Lint, which must have been given the statements parsed from the tokens. It
+// returns the fixed tokens and the number of warnings that were fixed. A fix
+// can make another one possible, e.g. removing an unused variable that was
+// the only use of another one, so fixing can be repeated until nothing is
+// fixed.
+func (l *Linter) Fix(tokens []*Token) ([]*Token, int) {
+	index := make(map[*Token]int, len(tokens))
+	for i, tok := range tokens {
+		index[tok] = i
+	}
+	var edits []tokenEdit
+	fixed := 0
+	for _, fix := range l.fixes {
+		if e := fix.edits(tokens, index); len(e) > 0 {
+			edits = append(edits, e...)
+			fixed++
+		}
+	}
+	l.fixes = l.fixes[:0]
+	return applyTokenEdits(tokens, edits), fixed
+}
+
+// applyTokenEdits applies the edits from the last one in the tokens to the
+// first one, so the positions of the edits that remain are still valid. The
+// edits that start at the same position are applied in the reverse order of
+// the list, which puts the insertions made first in front. Edits that
+// overlap one that was applied are dropped.
+func applyTokenEdits(tokens []*Token, edits []tokenEdit) []*Token {
+	tokens = append([]*Token(nil), tokens...)
+	order := make([]int, len(edits))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := edits[order[i]], edits[order[j]]
+		if a.start != b.start {
+			return a.start > b.start
+		}
+		return order[i] > order[j]
+	})
+
+	limit := len(tokens)
+	for _, i := range order {
+		edit := edits[i]
+		if edit.end > limit {
+			continue
+		}
+		limit = edit.start
+
+		var comments []*Comment
+		for _, tok := range tokens[edit.start:edit.end] {
+			comments = append(comments, tok.Comments...)
+		}
+		if edit.lines > 0 {
+			for _, tok := range tokens[edit.end:] {
+				tok.Line -= edit.lines
+				for _, c := range tok.Comments {
+					c.Line -= edit.lines
+				}
+			}
+		}
+		tail := append(append([]*Token{}, edit.insert...), tokens[edit.end:]...)
+		// the comments of the removed tokens are kept by the next token
+		if len(comments) > 0 && len(tail) > 0 {
+			tail[0].Comments = append(comments, tail[0].Comments...)
+		}
+		tokens = append(tokens[:edit.start], tail...)
+	}
+	return tokens
+}
+
+// removeVarFix removes the declaration of a variable that is never used.
+type removeVarFix struct {
+	decl *VarStmt
+}
+
+func (fix *removeVarFix) edits(tokens []*Token, index map[*Token]int) []tokenEdit {
+	name, ok := index[fix.decl.Name]
+	if !ok || name == 0 || tokens[name-1].Type != VAR {
+		return nil
+	}
+	start := name - 1
+	end := name
+	for depth := 0; end < len(tokens); end++ {
+		switch tokens[end].Type {
+		case L_PAREN:
+			depth++
+		case R_PAREN:
+			depth--
+		}
+		if depth == 0 && tokens[end].Type == SEMICOLON {
+			break
+		}
+	}
+	if end >= len(tokens)-1 {
+		return nil
+	}
+	edit := tokenEdit{start: start, end: end + 1}
+
+	// a declaration on its own lines is removed with its lines, so that the
+	// formatter doesn't see a blank line in their place
+	first, last, next := tokens[start], tokens[end], tokens[end+1]
+	alone := start == 0 || tokens[start-1].Line < startLine(first)
+	if len(next.Comments) > 0 {
+		alone = alone && next.Comments[0].Line > last.Line
+	} else {
+		alone = alone && startLine(next) > last.Line
+	}
+	if alone {
+		edit.lines = last.Line - startLine(first) + 1
+	}
+	return []tokenEdit{edit}
+}
+
+// boolCompareFix replaces the comparison of a boolean with a literal by the
+// boolean or its negation.
+type boolCompareFix struct {
+	expr   *BinaryExpr
+	other  Expr
+	negate bool
+}
+
+func (fix *boolCompareFix) edits(tokens []*Token, index map[*Token]int) []tokenEdit {
+	op, ok := index[fix.expr.Op]
+	if !ok {
+		return nil
+	}
+	line := fix.expr.Op.Line
+	// the negation of a binary expression needs parentheses
+	_, parens := fix.other.(*BinaryExpr)
+	not := []*Token{NewToken(BANG, "!", nil, line)}
+	if parens {
+		not = append(not, NewToken(L_PAREN, "(", nil, line))
+	}
+	var closing []*Token
+	if parens {
+		closing = []*Token{NewToken(R_PAREN, ")", nil, line)}
+	}
+
+	if _, ok := fix.expr.Rhs.(*LiteralExpr); ok {
+		// e.g. "a < b == true"
+		if !fix.negate {
+			return []tokenEdit{{start: op, end: op + 2}}
+		}
+		start := exprStart(fix.other, index)
+		if start < 0 {
+			return nil
+		}
+		return []tokenEdit{
+			{start: start, end: start, insert: not},
+			{start: op, end: op + 2, insert: closing},
+		}
+	}
+	// e.g. "true == a < b"
+	if !fix.negate {
+		return []tokenEdit{{start: op - 1, end: op + 1}}
+	}
+	end := exprEnd(fix.other, index)
+	if end < 0 {
+		return nil
+	}
+	return []tokenEdit{
+		{start: op - 1, end: op + 1, insert: not},
+		{start: end + 1, end: end + 1, insert: closing},
+	}
+}
+
+// exprStart returns the position of the first token of the expression, or -1
+// if it can't be found since literals don't keep their token.
+func exprStart(expr Expr, index map[*Token]int) int {
+	switch expr := expr.(type) {
+	case *BinaryExpr:
+		if _, ok := expr.Lhs.(*LiteralExpr); ok {
+			return index[expr.Op] - 1
+		}
+		return exprStart(expr.Lhs, index)
+	case *LogicalExpr:
+		if _, ok := expr.Lhs.(*LiteralExpr); ok {
+			return index[expr.Op] - 1
+		}
+		return exprStart(expr.Lhs, index)
+	case *GroupExpr:
+		if start := exprStart(expr.Expr, index); start > 0 {
+			return start - 1
+		}
+	case *UnaryExpr:
+		return index[expr.Op]
+	case *VarExpr:
+		return index[expr.Name]
+	case *AssignExpr:
+		return index[expr.Name]
+	case *CallExpr:
+		return exprStart(expr.Callee, index)
+	case *GetExpr:
+		return exprStart(expr.Obj, index)
+	case *SetExpr:
+		return exprStart(expr.Obj, index)
+	case *ThisExpr:
+		return index[expr.Keyword]
+	case *SuperExpr:
+		return index[expr.Keyword]
+	}
+	return -1
+}
+
+// exprEnd returns the position of the last token of the expression, or -1 if
+// it can't be found.
+func exprEnd(expr Expr, index map[*Token]int) int {
+	switch expr := expr.(type) {
+	case *BinaryExpr:
+		if _, ok := expr.Rhs.(*LiteralExpr); ok {
+			return index[expr.Op] + 1
+		}
+		return exprEnd(expr.Rhs, index)
+	case *LogicalExpr:
+		if _, ok := expr.Rhs.(*LiteralExpr); ok {
+			return index[expr.Op] + 1
+		}
+		return exprEnd(expr.Rhs, index)
+	case *GroupExpr:
+		if end := exprEnd(expr.Expr, index); end >= 0 {
+			return end + 1
+		}
+	case *UnaryExpr:
+		if _, ok := expr.Expr.(*LiteralExpr); ok {
+			return index[expr.Op] + 1
+		}
+		return exprEnd(expr.Expr, index)
+	case *VarExpr:
+		return index[expr.Name]
+	case *AssignExpr:
+		return exprEnd(expr.Val, index)
+	case *CallExpr:
+		return index[expr.Paren]
+	case *GetExpr:
+		return index[expr.Name]
+	case *SetExpr:
+		return exprEnd(expr.Val, index)
+	case *ThisExpr:
+		return index[expr.Keyword]
+	case *SuperExpr:
+		return index[expr.Method]
+	}
+	return -1
+}
+
+// errorRecorder keeps the errors that are reported to it.
+type errorRecorder struct {
+	errs []error
+}
+
+func (r *errorRecorder) Report(d *Diagnostic) {
+	r.errs = append(r.errs, d.Err)
+}
+
+func (r *errorRecorder) Reset() {
+	r.errs = nil
+}
+
+func (r *errorRecorder) HadError() bool {
+	return len(r.errs) > 0
+}
+
+func (r *errorRecorder) HadRuntimeError() bool {
+	return false
+}
+
+// FixSemicolons adds the semicolons that are missing at the end of a line, and
+// returns the fixed tokens and the number of semicolons that were added. A
+// semicolon is only added where the parser expected one and the next token is
+// on another line or closes a block, so the statement couldn't go on. Other
+// syntax errors are left as they are.
+func FixSemicolons(tokens []*Token) ([]*Token, int) {
+	tokens = append([]*Token(nil), tokens...)
+	added := 0
+	for added < len(tokens) {
+		reporter := new(errorRecorder)
+		NewParser(tokens, reporter).Parse()
+
+		at := -1
+		for _, err := range reporter.errs {
+			cerr, ok := err.(*compileError)
+			if !ok || !strings.HasPrefix(cerr.message, "Expect ';' after") ||
+				cerr.message == "Expect ';' after loop condition." {
+				continue
+			}
+			for i, tok := range tokens {
+				if tok == cerr.token {
+					at = i
+					break
+				}
+			}
+			if at > 0 && (cerr.token.Type == EOF || cerr.token.Type == R_BRACE ||
+				startLine(cerr.token) > tokens[at-1].Line) {
+				break
+			}
+			at = -1
+		}
+		if at < 0 {
+			break
+		}
+
+		prev := tokens[at-1]
+		semicolon := NewToken(SEMICOLON, ";", nil, prev.Line)
+		tokens = append(tokens[:at], append([]*Token{semicolon}, tokens[at:]...)...)
+		added++
+	}
+	return tokens, added
+}
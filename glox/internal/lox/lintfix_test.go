@@ -0,0 +1,75 @@
+package lox
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// lintFixScript lints the script with all the rules, and returns the number
+// of fixes and the formatted result of applying them.
+func lintFixScript(tb testing.TB, script string) (int, string) {
+	reporter := NewSimpleReporter(ioutil.Discard)
+	tokens := NewScanner([]byte(script), reporter).Scan()
+	stmts := NewParser(tokens, reporter).Parse()
+	NewResolver(NewInterpreter(ioutil.Discard, reporter, false), reporter).Resolve(stmts)
+	if reporter.HadError() {
+		tb.Fatal("could not parse script")
+	}
+	linter := NewLinter(LintAllRules, NewSimpleReporter(ioutil.Discard))
+	linter.Lint(stmts)
+	tokens, fixed := linter.Fix(tokens)
+	return fixed, string(Format(tokens))
+}
+
+func TestLinterFix(t *testing.T) {
+	assert := assert.New(t)
+
+	fixed, out := lintFixScript(t, `fun f(a) {
+  var unused = 1 + 2;
+  // kept
+  var call = a();
+  var assigned;
+  assigned = 1;
+  var error = "a" - 1;
+  if (a < 1 == true) print a;
+  if (a != 1 == false) print 1;
+  print false != (a >= 2);
+  print true == !a;
+  print a == true;
+}
+`)
+	assert.Equal(5, fixed)
+	assert.Equal(`fun f(a) {
+  // kept
+  var call = a();
+  var assigned;
+  assigned = 1;
+  var error = "a" - 1;
+  if (a < 1) print a;
+  if (!(a != 1)) print 1;
+  print (a >= 2);
+  print !a;
+  print a == true;
+}
+`, out)
+}
+
+func TestLinterFixNothing(t *testing.T) {
+	assert := assert.New(t)
+
+	fixed, out := lintFixScript(t, fibScript)
+	assert.Equal(0, fixed)
+	assert.Equal(string(Format(NewScanner([]byte(fibScript), NewSimpleReporter(ioutil.Discard)).Scan())), out)
+}
+
+func TestFixSemicolons(t *testing.T) {
+	assert := assert.New(t)
+
+	reporter := NewSimpleReporter(ioutil.Discard)
+	tokens := NewScanner([]byte("var a = 1\nprint a\nfun f() { return a }\nprint (a\n+ 1) print a"), reporter).Scan()
+	tokens, added := FixSemicolons(tokens)
+	assert.Equal(3, added)
+	assert.Equal("var a = 1;\nprint a;\nfun f() {\n  return a;\n}\nprint (a + 1) print a\n", string(Format(tokens)))
+}
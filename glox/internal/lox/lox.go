@@ -3,21 +3,58 @@ package lox
 
 import (
 	"fmt"
+	"io"
+	"math"
 	"strconv"
+	"strings"
 	"time"
 )
 
+// maxExactInteger is the largest magnitude below which every integer can be
+// represented exactly by a float64.
+const maxExactInteger = 1 << 53
+
+// smallIntegers holds the string form of small non-negative integers, which
+// are by far the most printed numbers, e.g. loop counters.
+var smallIntegers [256]string
+
+func init() {
+	for i := range smallIntegers {
+		smallIntegers[i] = strconv.Itoa(i)
+	}
+}
+
 func stringify(v interface{}) string {
 	switch v := v.(type) {
 	case nil:
-		return fmt.Sprint("nil")
+		return "nil"
+	case bool:
+		return strconv.FormatBool(v)
+	case string:
+		return v
 	case float64:
-		return fmt.Sprint(strconv.FormatFloat(v, 'f', -1, 64))
+		return stringifyNumber(v)
 	default:
 		return fmt.Sprint(v)
 	}
 }
 
+// stringifyNumber formats numbers with the least number of digits that is
+// needed to represent them, so integral numbers never have a fractional part.
+// Integers take a fast path that skips the float formatting algorithm.
+func stringifyNumber(v float64) string {
+	if v != math.Trunc(v) || v <= -maxExactInteger || v >= maxExactInteger {
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	if v == 0 && math.Signbit(v) {
+		return "-0"
+	}
+	if v >= 0 && v < float64(len(smallIntegers)) {
+		return smallIntegers[int(v)]
+	}
+	return strconv.FormatInt(int64(v), 10)
+}
+
 func truthy(value interface{}) bool {
 	if value == nil {
 		return false
@@ -34,11 +71,26 @@ type class struct {
 	methods map[string]*function
 }
 
+// newClass creates a new class whose method table is flattened, i.e. it contains
+// the given methods and all the methods that are inherited from the superclass
+// chain. Classes can't be changed once they are defined, so the table can be
+// built once here and every lookup afterward is a single map access instead of
+// a walk up the inheritance chain.
 func newClass(name string, super *class, methods map[string]*function) *class {
 	c := new(class)
 	c.name = name
 	c.super = super
-	c.methods = methods
+	if super == nil {
+		c.methods = methods
+		return c
+	}
+	c.methods = make(map[string]*function, len(super.methods)+len(methods))
+	for name, method := range super.methods {
+		c.methods[name] = method
+	}
+	for name, method := range methods {
+		c.methods[name] = method
+	}
 	return c
 }
 
@@ -60,19 +112,28 @@ func (c *class) call(
 	instance := newInstance(c)
 	// call the initializer on the instance if it's defined
 	if init, ok := c.findMethod("init"); ok {
-		init.bind(instance).call(interpreter, args)
+		if _, err := init.bind(instance).call(interpreter, args); err != nil {
+			return nil, err
+		}
 	}
 	return instance, nil
 }
 
 func (c *class) findMethod(name string) (*function, bool) {
 	method, ok := c.methods[name]
-	if !ok && c.super != nil {
-		method, ok = c.super.findMethod(name)
-	}
 	return method, ok
 }
 
+// methodNames returns the names of the methods of the class, including the
+// inherited ones.
+func (c *class) methodNames() []string {
+	names := make([]string, 0, len(c.methods))
+	for name := range c.methods {
+		names = append(names, name)
+	}
+	return names
+}
+
 type instance struct {
 	class  *class
 	fields map[string]interface{}
@@ -100,9 +161,14 @@ func (inst *instance) get(name *Token) (interface{}, error) {
 		return method.bind(inst), nil
 	}
 
-	return nil, newRuntimeError(name, fmt.Sprintf(
+	err := newRuntimeError(name, CodeUndefinedProperty, fmt.Sprintf(
 		"Undefined property '%s'.", name.Lexeme,
 	))
+	names := inst.class.methodNames()
+	for field := range inst.fields {
+		names = append(names, field)
+	}
+	return nil, suggestProperty(err, inst.class.name, name.Lexeme, names)
 }
 
 func (inst *instance) set(name *Token, val interface{}) {
@@ -140,6 +206,97 @@ func (fn *functionClock) String() string {
 	return "<native fn>"
 }
 
+// functionBreakpoint pauses the script in the attached debugger, it does
+// nothing when there's no debugger.
+type functionBreakpoint struct{}
+
+func (fn *functionBreakpoint) arity() int {
+	return 0
+}
+
+func (fn *functionBreakpoint) call(
+	in *Interpreter,
+	args []interface{},
+) (interface{}, error) {
+	if in.debugger == nil {
+		return nil, nil
+	}
+	return nil, in.debugger.breakpoint()
+}
+
+func (fn *functionBreakpoint) String() string {
+	return "<native fn>"
+}
+
+// functionArgs returns an argument that the script is given, by its index.
+type functionArgs struct{}
+
+func (fn *functionArgs) arity() int {
+	return 1
+}
+
+func (fn *functionArgs) call(
+	in *Interpreter,
+	args []interface{},
+) (interface{}, error) {
+	i, ok := args[0].(float64)
+	if !ok || i != math.Trunc(i) {
+		return nil, newRuntimeError(in.callSite, CodeArgumentIndex, "Argument index must be an integer.")
+	}
+	if i < 0 || int(i) >= len(in.args) {
+		return nil, newRuntimeError(in.callSite, CodeArgumentIndex, "Argument index out of range.")
+	}
+	return in.args[int(i)], nil
+}
+
+func (fn *functionArgs) String() string {
+	return "<native fn>"
+}
+
+// functionReadLine reads a line of the interpreter's input, without the line
+// break, it returns nil once there's nothing more to read.
+type functionReadLine struct{}
+
+func (fn *functionReadLine) arity() int {
+	return 0
+}
+
+func (fn *functionReadLine) call(
+	in *Interpreter,
+	args []interface{},
+) (interface{}, error) {
+	line, err := in.stdin.ReadString('\n')
+	if err != nil && (err != io.EOF || line == "") {
+		return nil, nil
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
+
+func (fn *functionReadLine) String() string {
+	return "<native fn>"
+}
+
+// functionPrintErr prints a value like the print statement does, but to the
+// interpreter's error stream.
+type functionPrintErr struct{}
+
+func (fn *functionPrintErr) arity() int {
+	return 1
+}
+
+func (fn *functionPrintErr) call(
+	in *Interpreter,
+	args []interface{},
+) (interface{}, error) {
+	fmt.Fprintln(in.stderr, stringify(args[0]))
+	return nil, nil
+}
+
+func (fn *functionPrintErr) String() string {
+	return "<native fn>"
+}
+
 // function represents a lox function that can be called
 type function struct {
 	decl          *FunctionStmt
@@ -148,6 +305,8 @@ type function struct {
 }
 
 func newFunction(decl *FunctionStmt, closure *environment, isInitializer bool) *function {
+	// the closure lives as long as the function does
+	closure.capture()
 	fn := new(function)
 	fn.decl = decl
 	fn.closure = closure
@@ -175,12 +334,19 @@ func (fn *function) call(
 		each needs its own environment, even though they are all calls to the same
 		function.
 	*/
-	env := newEnvironment(fn.closure)
+	env := interpreter.envPool.get(fn.closure)
 	for i, param := range fn.decl.Params {
 		env.define(param.Lexeme, args[i])
 	}
 
-	if err := interpreter.execBlock(fn.decl.Body, env); err != nil {
+	interpreter.enterFunction(fn.decl)
+	err := interpreter.execBlock(fn.decl.Body, env)
+	if err != nil {
+		interpreter.recordStack(err)
+	}
+	interpreter.exitFunction()
+	interpreter.envPool.put(env)
+	if err != nil {
 		/*
 			TODO: Here we treats return as an error so we can easily unwound the stack,
 			instead of of `error` we can use a custom interface that is returned as the
@@ -0,0 +1,49 @@
+package lox
+
+import (
+	"math"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStringifyNumber(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		val      float64
+		expected string
+	}{
+		{0, "0"},
+		{math.Copysign(0, -1), "-0"},
+		{3, "3"},
+		{255, "255"},
+		{256, "256"},
+		{-42, "-42"},
+		{3.5, "3.5"},
+		{0.30000000000000004, "0.30000000000000004"},
+		{1e15, "1000000000000000"},
+		{1 << 53, "9007199254740992"},
+		{-(1 << 60), "-1152921504606847000"},
+		{math.Inf(1), "+Inf"},
+		{math.NaN(), "NaN"},
+	}
+	for _, test := range tests {
+		assert.Equal(test.expected, stringifyNumber(test.val))
+		// the fast path must agree with the general formatting
+		assert.Equal(strconv.FormatFloat(test.val, 'f', -1, 64), stringifyNumber(test.val))
+	}
+}
+
+func BenchmarkStringifyNumber(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		stringifyNumber(float64(i % 1000))
+	}
+}
+
+func BenchmarkStringifyNumberFormatFloat(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		strconv.FormatFloat(float64(i%1000), 'f', -1, 64)
+	}
+}
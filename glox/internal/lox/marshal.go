@@ -0,0 +1,237 @@
+package lox
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Marshal converts a Go value to a Lox value. Booleans, strings, and numbers
+// of any type become their Lox counterparts, nil pointers and interfaces
+// become nil, and the values of other pointers and interfaces are converted.
+// A struct becomes an instance of a class named after the struct type, whose
+// fields are the exported fields of the struct, and a map with string keys
+// becomes an instance of a class named after the map type, or "Map", whose
+// fields are the entries of the map. The name of a field can be changed with
+// a "lox" tag, e.g. `lox:"name"`, and `lox:"-"` leaves the field out. Values
+// are kept as they are. An error is returned for the other types, such as
+// slices since Lox has no lists, and for values that contain themselves.
+func Marshal(v interface{}) (Value, error) {
+	m := &marshaler{classes: make(map[reflect.Type]*class), visiting: make(map[uintptr]bool)}
+	val, err := m.marshal(reflect.ValueOf(v))
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{val: val}, nil
+}
+
+type marshaler struct {
+	// classes holds the classes of the instances that were created, so the
+	// instances of the same type share one
+	classes map[reflect.Type]*class
+	// visiting holds the pointers that are being converted
+	visiting map[uintptr]bool
+}
+
+func (m *marshaler) marshal(v reflect.Value) (interface{}, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	if v.Type() == valueType {
+		return toLox(v), nil
+	}
+	if isLoxObject(v) {
+		return v.Interface(), nil
+	}
+	switch v.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return toLox(v), nil
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return m.marshal(v.Elem())
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		if m.visiting[v.Pointer()] {
+			return nil, fmt.Errorf("lox: cannot marshal %s that contains itself", v.Type())
+		}
+		m.visiting[v.Pointer()] = true
+		defer delete(m.visiting, v.Pointer())
+		return m.marshal(v.Elem())
+	case reflect.Struct:
+		inst := newInstance(m.class(v.Type(), "Object"))
+		for i := 0; i < v.NumField(); i++ {
+			name, ok := fieldName(v.Type().Field(i))
+			if !ok {
+				continue
+			}
+			val, err := m.marshal(v.Field(i))
+			if err != nil {
+				return nil, err
+			}
+			inst.fields[name] = val
+		}
+		return inst, nil
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			return nil, nil
+		}
+		inst := newInstance(m.class(v.Type(), "Map"))
+		iter := v.MapRange()
+		for iter.Next() {
+			val, err := m.marshal(iter.Value())
+			if err != nil {
+				return nil, err
+			}
+			inst.fields[iter.Key().String()] = val
+		}
+		return inst, nil
+	}
+	return nil, fmt.Errorf("lox: cannot marshal %s", v.Type())
+}
+
+// class returns the class of the instances that are created from values of
+// the given type, which is named after the type if it has a name.
+func (m *marshaler) class(t reflect.Type, name string) *class {
+	if c, ok := m.classes[t]; ok {
+		return c
+	}
+	if t.Name() != "" {
+		name = t.Name()
+	}
+	c := newClass(name, nil, make(map[string]*function))
+	m.classes[t] = c
+	return c
+}
+
+// Unmarshal converts a Lox value to the Go value that target points to, the
+// reverse of Marshal. The fields of an instance are stored in the fields of a
+// struct with the same names, or in the entries of a map with string keys,
+// and the fields of the struct that the instance doesn't have are left as
+// they are. Pointers are allocated as needed, a Value is given the Lox value
+// as it is, and an empty interface is given a bool, a float64, a string, or
+// nil, and a Value for the other values. An error is returned if a value
+// can't be stored in its destination, e.g. a string in an int or a number
+// that isn't an integer in an int.
+func Unmarshal(val Value, target interface{}) error {
+	v := reflect.ValueOf(target)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return fmt.Errorf("lox: cannot unmarshal into %T, which is not a non-nil pointer", target)
+	}
+	return unmarshal(val, v.Elem(), "")
+}
+
+// unmarshal stores the value in v, path is the field that v is, which is
+// included in the errors.
+func unmarshal(val Value, v reflect.Value, path string) error {
+	t := v.Type()
+	switch t.Kind() {
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			break
+		}
+		converted, _ := fromLox(val, t)
+		v.Set(converted)
+		return nil
+	case reflect.Ptr:
+		if val.IsNil() {
+			v.Set(reflect.Zero(t))
+			return nil
+		}
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return unmarshal(val, v.Elem(), path)
+	case reflect.Struct:
+		if t == valueType {
+			v.Set(reflect.ValueOf(val))
+			return nil
+		}
+		inst, ok := val.val.(*instance)
+		if !ok {
+			break
+		}
+		for i := 0; i < v.NumField(); i++ {
+			name, ok := fieldName(t.Field(i))
+			if !ok {
+				continue
+			}
+			if field, ok := inst.fields[name]; ok {
+				if err := unmarshal(Value{val: field, in: val.in}, v.Field(i), path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	case reflect.Map:
+		inst, ok := val.val.(*instance)
+		if !ok || t.Key().Kind() != reflect.String {
+			break
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMapWithSize(t, len(inst.fields)))
+		}
+		for name, field := range inst.fields {
+			elem := reflect.New(t.Elem()).Elem()
+			if err := unmarshal(Value{val: field, in: val.in}, elem, path+"."+name); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(name).Convert(t.Key()), elem)
+		}
+		return nil
+	default:
+		if !isLoxType(t) {
+			break
+		}
+		converted, err := fromLox(val, t)
+		if err != nil {
+			return fmt.Errorf("lox: cannot unmarshal %s into %s%s, it %s", typeOf(val.val), t, fieldPath(path), err)
+		}
+		v.Set(converted)
+		return nil
+	}
+	return fmt.Errorf("lox: cannot unmarshal %s into %s%s", typeOf(val.val), t, fieldPath(path))
+}
+
+func fieldPath(path string) string {
+	if path == "" {
+		return ""
+	}
+	return " at field " + strings.TrimPrefix(path, ".")
+}
+
+// fieldName returns the name of the Lox field for the field of a struct, ok
+// is false if the field is left out.
+func fieldName(field reflect.StructField) (name string, ok bool) {
+	if field.PkgPath != "" {
+		return "", false
+	}
+	tag := field.Tag.Get("lox")
+	if tag == "-" {
+		return "", false
+	}
+	if tag != "" {
+		return tag, true
+	}
+	return field.Name, true
+}
+
+// isLoxObject returns true if the value is an object of the interpreter.
+func isLoxObject(v reflect.Value) bool {
+	if !v.CanInterface() {
+		return false
+	}
+	switch v.Interface().(type) {
+	case *instance, *class, callable:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,92 @@
+package lox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type marshalPoint struct {
+	X       int     `lox:"x"`
+	Y       float64 `lox:"y"`
+	Label   string
+	Skipped bool `lox:"-"`
+	private int
+}
+
+type marshalShape struct {
+	Name   string
+	Origin *marshalPoint
+	Tags   map[string]string
+	Extra  interface{}
+}
+
+func TestMarshal(t *testing.T) {
+	assert := assert.New(t)
+
+	val, err := Marshal(marshalShape{
+		Name:   "square",
+		Origin: &marshalPoint{X: 1, Y: 2.5, Label: "o", Skipped: true, private: 1},
+		Tags:   map[string]string{"color": "red"},
+	})
+	assert.NoError(err)
+	assert.Equal("marshalShape {\n  Extra: nil,\n  Name: \"square\",\n  Origin: marshalPoint { Label: \"o\", x: 1, y: 2.5 },\n  Tags: Map { color: \"red\" },\n}", inspect(val.val))
+
+	// the value can be used by scripts
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&out), false)
+	in.globals.define("shape", val.val)
+	in.Interpret(parseScript(t, in, "print shape.Origin.x + shape.Origin.y;"))
+	assert.Equal("3.5\n", out.String())
+
+	for _, v := range []interface{}{nil, true, "s", 1, uint8(2), float32(0.5)} {
+		_, err := Marshal(v)
+		assert.NoError(err)
+	}
+	_, err = Marshal([]int{1})
+	assert.EqualError(err, "lox: cannot marshal []int")
+	_, err = Marshal(map[int]string{})
+	assert.Error(err)
+	type node struct{ Next *node }
+	loop := &node{}
+	loop.Next = loop
+	_, err = Marshal(loop)
+	assert.Error(err)
+}
+
+func TestUnmarshal(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&out), false)
+	in.Interpret(parseScript(t, in, `
+class Point {}
+var p = Point();
+p.x = 1; p.y = 2.5; p.Label = "o"; p.Skipped = true;
+var s = Point();
+s.Name = "square"; s.Origin = p; s.Tags = Point(); s.Tags.color = "red"; s.Extra = Point;
+`))
+	val, err := in.Eval("s")
+	assert.NoError(err)
+
+	var shape marshalShape
+	assert.NoError(Unmarshal(val, &shape))
+	assert.Equal("square", shape.Name)
+	assert.Equal(&marshalPoint{X: 1, Y: 2.5, Label: "o"}, shape.Origin)
+	assert.Equal(map[string]string{"color": "red"}, shape.Tags)
+	if assert.IsType(Value{}, shape.Extra) {
+		assert.True(shape.Extra.(Value).IsCallable())
+	}
+
+	var n int
+	assert.NoError(Unmarshal(NumberValue(3), &n))
+	assert.Equal(3, n)
+	assert.EqualError(Unmarshal(NumberValue(3.5), &n), "lox: cannot unmarshal number into int, it must be an integer")
+	assert.Error(Unmarshal(Value{}, n))
+
+	var point marshalPoint
+	p, _ := in.Eval("p")
+	p.val.(*instance).fields["x"] = "one"
+	assert.EqualError(Unmarshal(p, &point), "lox: cannot unmarshal string into int at field x, it must be an integer")
+}
@@ -0,0 +1,318 @@
+package lox
+
+import (
+	"bytes"
+	"strings"
+)
+
+// Minify prints the source that the given tokens were scanned from on a
+// single line, without comments and with a space only where two tokens would
+// otherwise run together. The names of the tokens that are in names are
+// replaced, e.g. with the short names from RenameLocals. Since everything is
+// on the first line, runtime errors of the minified script are reported on
+// line 1. The tokens should come from a source that parses without errors.
+func Minify(tokens []*Token, names map[*Token]string) []byte {
+	var out bytes.Buffer
+	prev := ""
+	for _, tok := range tokens {
+		if tok.Type == EOF {
+			break
+		}
+		lexeme := tok.Lexeme
+		if name, ok := names[tok]; ok {
+			lexeme = name
+		}
+		if prev != "" && minifySpace(prev, lexeme) {
+			out.WriteByte(' ')
+		}
+		out.WriteString(lexeme)
+		prev = lexeme
+	}
+	if out.Len() > 0 {
+		out.WriteByte('\n')
+	}
+	return out.Bytes()
+}
+
+// minifySpace returns true if the lexemes would be scanned as different
+// tokens without a space between them, e.g. "var" and "a", or "=" and "=".
+func minifySpace(prev, next string) bool {
+	a, b := prev[len(prev)-1], next[0]
+	switch {
+	case isWordChar(a) && isWordChar(b):
+		return true
+	case strings.IndexByte("!=<>", a) >= 0 && b == '=':
+		return true
+	case a == '/' && (b == '/' || b == '*'):
+		return true
+	}
+	return false
+}
+
+func isWordChar(c byte) bool {
+	return c == '_' || isDigit(c) || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// RenameLocals gives short names to the local variables and parameters that
+// are declared in the statements, which must have been resolved without
+// errors. It returns the new names keyed by the tokens that name the
+// variables, in their declarations and in the expressions that refer to them,
+// which can be given to Minify. The variables that are visible at the same
+// time get different names, and no name is a keyword or the name of anything
+// that isn't renamed, e.g. a global variable or a property. Functions and
+// classes keep their names even if they are local, since their names are
+// printed when they are.
+func RenameLocals(statements []Stmt) map[*Token]string {
+	r := new(renamer)
+	r.slots = make(map[*Token]int)
+	r.reserved = make(map[string]bool)
+	for _, stmt := range statements {
+		r.stmt(stmt)
+	}
+
+	var names []string
+	next := 0
+	renames := make(map[*Token]string, len(r.slots))
+	for tok, slot := range r.slots {
+		for len(names) <= slot {
+			name := shortName(next)
+			next++
+			if _, ok := KeywordTokens[name]; !ok && !r.reserved[name] {
+				names = append(names, name)
+			}
+		}
+		renames[tok] = names[slot]
+	}
+	return renames
+}
+
+// shortName returns the n-th name of the sequence "a", ..., "z", "aa", "ab",
+// and so on.
+func shortName(n int) string {
+	var name []byte
+	for n++; n > 0; n = (n - 1) / 26 {
+		name = append([]byte{byte('a' + (n-1)%26)}, name...)
+	}
+	return string(name)
+}
+
+// renamer finds the local variables to be renamed. Each variable gets a slot,
+// which is the number of the renamed variables that are visible where it's
+// declared, so the variables visible at the same time have different slots.
+// The slots are turned into names once all the names that must be kept are
+// known.
+type renamer struct {
+	// scopes map the names of the variables to their slots, or to -1 if they
+	// are kept, the global scope isn't included
+	scopes []map[string]int
+	// live is the number of renamed variables that are visible
+	live     int
+	slots    map[*Token]int
+	reserved map[string]bool
+}
+
+func (r *renamer) VisitBlockStmt(stmt *BlockStmt) (interface{}, error) {
+	r.beginScope()
+	for _, stmt := range stmt.Stmts {
+		r.stmt(stmt)
+	}
+	r.endScope()
+	return nil, nil
+}
+
+func (r *renamer) VisitClassStmt(stmt *ClassStmt) (interface{}, error) {
+	r.declare(stmt.Name, false)
+	if stmt.Super != nil {
+		r.expr(stmt.Super)
+	}
+	for _, method := range stmt.Methods {
+		r.reserved[method.Name.Lexeme] = true
+		r.function(method)
+	}
+	return nil, nil
+}
+
+func (r *renamer) VisitErrorStmt(stmt *ErrorStmt) (interface{}, error) {
+	return nil, nil
+}
+
+func (r *renamer) VisitExprStmt(stmt *ExprStmt) (interface{}, error) {
+	r.expr(stmt.Expr)
+	return nil, nil
+}
+
+func (r *renamer) VisitFunctionStmt(stmt *FunctionStmt) (interface{}, error) {
+	r.declare(stmt.Name, false)
+	r.function(stmt)
+	return nil, nil
+}
+
+func (r *renamer) VisitIfStmt(stmt *IfStmt) (interface{}, error) {
+	r.expr(stmt.Cond)
+	r.stmt(stmt.ThenBranch)
+	if stmt.ElseBranch != nil {
+		r.stmt(stmt.ElseBranch)
+	}
+	return nil, nil
+}
+
+func (r *renamer) VisitPrintStmt(stmt *PrintStmt) (interface{}, error) {
+	r.expr(stmt.Expr)
+	return nil, nil
+}
+
+func (r *renamer) VisitReturnStmt(stmt *ReturnStmt) (interface{}, error) {
+	if stmt.Val != nil {
+		r.expr(stmt.Val)
+	}
+	return nil, nil
+}
+
+func (r *renamer) VisitVarStmt(stmt *VarStmt) (interface{}, error) {
+	if stmt.Init != nil {
+		r.expr(stmt.Init)
+	}
+	r.declare(stmt.Name, true)
+	return nil, nil
+}
+
+func (r *renamer) VisitWhileStmt(stmt *WhileStmt) (interface{}, error) {
+	r.expr(stmt.Cond)
+	r.stmt(stmt.Body)
+	return nil, nil
+}
+
+func (r *renamer) VisitAssignExpr(expr *AssignExpr) (interface{}, error) {
+	r.expr(expr.Val)
+	r.use(expr.Name)
+	return nil, nil
+}
+
+func (r *renamer) VisitBinaryExpr(expr *BinaryExpr) (interface{}, error) {
+	r.expr(expr.Lhs)
+	r.expr(expr.Rhs)
+	return nil, nil
+}
+
+func (r *renamer) VisitCallExpr(expr *CallExpr) (interface{}, error) {
+	r.expr(expr.Callee)
+	for _, arg := range expr.Args {
+		r.expr(arg)
+	}
+	return nil, nil
+}
+
+func (r *renamer) VisitErrorExpr(expr *ErrorExpr) (interface{}, error) {
+	return nil, nil
+}
+
+func (r *renamer) VisitGetExpr(expr *GetExpr) (interface{}, error) {
+	r.expr(expr.Obj)
+	r.reserved[expr.Name.Lexeme] = true
+	return nil, nil
+}
+
+func (r *renamer) VisitGroupExpr(expr *GroupExpr) (interface{}, error) {
+	r.expr(expr.Expr)
+	return nil, nil
+}
+
+func (r *renamer) VisitLiteralExpr(expr *LiteralExpr) (interface{}, error) {
+	return nil, nil
+}
+
+func (r *renamer) VisitLogicalExpr(expr *LogicalExpr) (interface{}, error) {
+	r.expr(expr.Lhs)
+	r.expr(expr.Rhs)
+	return nil, nil
+}
+
+func (r *renamer) VisitSetExpr(expr *SetExpr) (interface{}, error) {
+	r.expr(expr.Obj)
+	r.expr(expr.Val)
+	r.reserved[expr.Name.Lexeme] = true
+	return nil, nil
+}
+
+func (r *renamer) VisitSuperExpr(expr *SuperExpr) (interface{}, error) {
+	r.reserved[expr.Method.Lexeme] = true
+	return nil, nil
+}
+
+func (r *renamer) VisitThisExpr(expr *ThisExpr) (interface{}, error) {
+	return nil, nil
+}
+
+func (r *renamer) VisitUnaryExpr(expr *UnaryExpr) (interface{}, error) {
+	r.expr(expr.Expr)
+	return nil, nil
+}
+
+func (r *renamer) VisitVarExpr(expr *VarExpr) (interface{}, error) {
+	r.use(expr.Name)
+	return nil, nil
+}
+
+func (r *renamer) stmt(stmt Stmt) {
+	stmt.Accept(r)
+}
+
+func (r *renamer) expr(expr Expr) {
+	expr.Accept(r)
+}
+
+func (r *renamer) function(fn *FunctionStmt) {
+	r.beginScope()
+	for _, param := range fn.Params {
+		r.declare(param, true)
+	}
+	for _, stmt := range fn.Body {
+		r.stmt(stmt)
+	}
+	r.endScope()
+}
+
+func (r *renamer) beginScope() {
+	r.scopes = append(r.scopes, make(map[string]int))
+}
+
+// endScope leaves the innermost scope, and frees the slots of its variables
+func (r *renamer) endScope() {
+	for _, slot := range r.scopes[len(r.scopes)-1] {
+		if slot >= 0 {
+			r.live--
+		}
+	}
+	r.scopes = r.scopes[:len(r.scopes)-1]
+}
+
+// declare declares a variable in the innermost scope, it's only renamed if
+// it's local and rename is true.
+func (r *renamer) declare(name *Token, rename bool) {
+	if len(r.scopes) == 0 || !rename {
+		r.reserved[name.Lexeme] = true
+		if len(r.scopes) > 0 {
+			r.scopes[len(r.scopes)-1][name.Lexeme] = -1
+		}
+		return
+	}
+	r.scopes[len(r.scopes)-1][name.Lexeme] = r.live
+	r.slots[name] = r.live
+	r.live++
+}
+
+// use records the reference to a variable, which gets the new name of the
+// variable if it's renamed.
+func (r *renamer) use(name *Token) {
+	for i := len(r.scopes) - 1; i >= 0; i-- {
+		if slot, ok := r.scopes[i][name.Lexeme]; ok {
+			if slot >= 0 {
+				r.slots[name] = slot
+			} else {
+				r.reserved[name.Lexeme] = true
+			}
+			return
+		}
+	}
+	r.reserved[name.Lexeme] = true
+}
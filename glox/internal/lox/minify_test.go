@@ -0,0 +1,88 @@
+package lox
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// minifyScript minifies the script, renaming its locals if rename is true.
+func minifyScript(tb testing.TB, script string, rename bool) string {
+	reporter := NewSimpleReporter(ioutil.Discard)
+	tokens := NewScanner([]byte(script), reporter).Scan()
+	var names map[*Token]string
+	if rename {
+		stmts := NewParser(tokens, reporter).Parse()
+		NewResolver(NewInterpreter(ioutil.Discard, reporter, false), reporter).Resolve(stmts)
+		names = RenameLocals(stmts)
+	}
+	if reporter.HadError() {
+		tb.Fatal("could not parse script")
+	}
+	return string(Minify(tokens, names))
+}
+
+const minifyTestScript = `// counts
+var a = 1; // the start
+fun count(from, to) {
+  /* the loop */
+  for (var i = from; i <= to; i = i + 1) {
+    var b = i;
+    {
+      var c = b * 2;
+      print c != a == !false;
+    }
+  }
+  fun inner() { return to; }
+  return inner;
+}
+class Point {
+  init(x) { this.x = x; }
+  get() { return this.x / -1; }
+}
+print count(a, 3)() - - Point(2).get();
+print "a  b";
+`
+
+func TestMinify(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("var a=1;fun count(from,to){for(var i=from;i<=to;i=i+1){var b=i;{var c=b*2;print c!=a==!false;}}"+
+		"fun inner(){return to;}return inner;}class Point{init(x){this.x=x;}get(){return this.x/-1;}}"+
+		"print count(a,3)()--Point(2).get();print\"a  b\";\n", minifyScript(t, minifyTestScript, false))
+	assert.Equal("", minifyScript(t, "// nothing\n", false))
+}
+
+func TestMinifyRenameLocals(t *testing.T) {
+	assert := assert.New(t)
+
+	minified := minifyScript(t, minifyTestScript, true)
+	assert.Equal("var a=1;fun count(b,c){for(var d=b;d<=c;d=d+1){var e=d;{var f=e*2;print f!=a==!false;}}"+
+		"fun inner(){return c;}return inner;}class Point{init(b){this.x=b;}get(){return this.x/-1;}}"+
+		"print count(a,3)()--Point(2).get();print\"a  b\";\n", minified)
+
+	out, errs := runScript(t, minifyTestScript)
+	minifiedOut, minifiedErrs := runScript(t, minified)
+	assert.Equal(out, minifiedOut)
+	assert.Equal(errs, minifiedErrs)
+}
+
+func TestRenameLocalsAvoidsKeptNames(t *testing.T) {
+	assert := assert.New(t)
+
+	minified := minifyScript(t, "fun f(x, y) { fun a() {} return b + x.c + y; }\n", true)
+	assert.Equal("fun f(d,e){fun a(){}return b+d.c+e;}\n", minified)
+}
+
+func TestShortName(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("a", shortName(0))
+	assert.Equal("z", shortName(25))
+	assert.Equal("aa", shortName(26))
+	assert.Equal("az", shortName(51))
+	assert.Equal("ba", shortName(52))
+	assert.Equal("zz", shortName(701))
+	assert.Equal("aaa", shortName(702))
+}
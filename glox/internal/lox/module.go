@@ -0,0 +1,123 @@
+package lox
+
+import (
+	"fmt"
+	"io/fs"
+)
+
+// ModuleResolver finds the source of the modules that scripts import with
+// "import(name)", so the host program decides where modules come from.
+type ModuleResolver interface {
+	Resolve(name string) (source string, err error)
+}
+
+// PrivilegedResolver is implemented by the module resolvers that need
+// capabilities to find the modules, e.g. to read files. Importing a module
+// through one is a runtime error wrapping ErrNotAllowed if the policy of the
+// interpreter doesn't allow them, see WithPolicy.
+type PrivilegedResolver interface {
+	ModuleResolver
+	Needs() Capability
+}
+
+// FSResolver finds the modules in a file system, e.g. a directory with
+// os.DirFS or the files embedded with go:embed. The source of the module
+// "name" is the file "name.lox". Importing through it needs CapFilesystem,
+// even if the file system is embedded, since the resolver can't tell.
+type FSResolver struct {
+	FS fs.FS
+}
+
+// Needs returns CapFilesystem, see PrivilegedResolver.
+func (r FSResolver) Needs() Capability {
+	return CapFilesystem
+}
+
+func (r FSResolver) Resolve(name string) (string, error) {
+	source, err := fs.ReadFile(r.FS, name+".lox")
+	if err != nil {
+		return "", err
+	}
+	return string(source), nil
+}
+
+// MapResolver holds the sources of the modules by their names, e.g. for modules
+// that the host program generates.
+type MapResolver map[string]string
+
+func (r MapResolver) Resolve(name string) (string, error) {
+	source, ok := r[name]
+	if !ok {
+		return "", fmt.Errorf("module %s: %w", name, fs.ErrNotExist)
+	}
+	return source, nil
+}
+
+// WithModules defines the global function "import", which runs the module
+// with the given name in the global environment, so the module can define
+// what the script uses. Each module is only run the first time that it's
+// imported. The sources of the modules are found by the resolver.
+func WithModules(resolver ModuleResolver) Option {
+	return func(in *Interpreter) {
+		in.modules = resolver
+		in.globals.define("import", new(functionImport))
+	}
+}
+
+// functionImport runs a module, see WithModules.
+type functionImport struct{}
+
+func (fn *functionImport) arity() int {
+	return 1
+}
+
+func (fn *functionImport) call(
+	in *Interpreter,
+	args []interface{},
+) (interface{}, error) {
+	name, ok := args[0].(string)
+	if !ok {
+		return nil, newRuntimeError(in.callSite, CodeModuleName, "Module name must be a string.")
+	}
+	if resolver, ok := in.modules.(PrivilegedResolver); ok {
+		if denied := in.deniedCapabilities(resolver.Needs()); denied != 0 {
+			return nil, in.notAllowedError("import", denied)
+		}
+	}
+	return nil, in.importModule(name)
+}
+
+func (fn *functionImport) String() string {
+	return "<native fn>"
+}
+
+// importModule runs the module with the given name in the global environment
+// unless it was already imported.
+func (in *Interpreter) importModule(name string) error {
+	if in.imported[name] {
+		return nil
+	}
+	source, err := in.modules.Resolve(name)
+	if err != nil {
+		e := newRuntimeError(in.callSite, CodeModuleNotFound, fmt.Sprintf("Can't import module '%s': %v.", name, err)).(*runtimeError)
+		e.cause = err
+		return e
+	}
+	statements, diagnostics := Parse([]byte(source))
+	if len(diagnostics) == 0 {
+		collector := new(diagnosticCollector)
+		NewResolver(in, collector).Resolve(statements)
+		diagnostics = collector.diagnostics
+	}
+	if len(diagnostics) > 0 {
+		e := newRuntimeError(in.callSite, CodeModuleError, fmt.Sprintf("Module '%s' has an error: %s", name, diagnostics[0])).(*runtimeError)
+		e.cause = diagnostics[0]
+		return e
+	}
+	// a module that imports the one importing it doesn't run it again
+	if in.imported == nil {
+		in.imported = make(map[string]bool)
+	}
+	in.imported[name] = true
+	return in.execBlock(statements, in.globals)
+}
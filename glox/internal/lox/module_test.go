@@ -0,0 +1,85 @@
+package lox
+
+import (
+	"errors"
+	"io/fs"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFSResolver(t *testing.T) {
+	assert := assert.New(t)
+
+	resolver := FSResolver{FS: fstest.MapFS{"lib/point.lox": {Data: []byte("class Point {}")}}}
+	source, err := resolver.Resolve("lib/point")
+	assert.NoError(err)
+	assert.Equal("class Point {}", source)
+	_, err = resolver.Resolve("point")
+	assert.True(errors.Is(err, fs.ErrNotExist))
+}
+
+func TestInterpreterImport(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	recorder := new(errorRecorder)
+	modules := MapResolver{
+		"math":    "import(\"util\");\nfun square(n) { return twice(n, n); }\nprint \"math\";",
+		"util":    "import(\"math\");\nfun twice(a, b) { return a * b; }",
+		"broken":  "fun f( {}",
+		"failing": "print -nil;",
+	}
+	in := NewInterpreter(&out, recorder, false, WithModules(modules))
+	in.Interpret(parseScript(t, in, "import(\"math\");\nimport(\"math\");\nprint square(3);"))
+	assert.Equal("math\n9\n", out.String())
+	assert.Empty(recorder.errs)
+
+	for script, message := range map[string]string{
+		"import(\"unknown\");": "Can't import module 'unknown': module unknown: file does not exist.\n[line 1]",
+		"import(\"broken\");":  "Module 'broken' has an error: [line 1] Error at '{': Expect parameter name.\n[line 1]",
+		"import(\"failing\");": "Operand must be a number.\n[line 1]",
+		"import(1);":           "Module name must be a string.\n[line 1]",
+	} {
+		recorder.Reset()
+		in.Interpret(parseScript(t, in, script))
+		if assert.Len(recorder.errs, 1, script) {
+			assert.Equal(message, recorder.errs[0].Error(), script)
+		}
+	}
+
+	// the modules are imported again once the globals are reset
+	out.Reset()
+	in.Reset()
+	in.Interpret(parseScript(t, in, "import(\"math\");"))
+	assert.Equal("math\n", out.String())
+
+	in = NewInterpreter(&out, recorder, false)
+	recorder.Reset()
+	in.Interpret(parseScript(t, in, "import(\"math\");"))
+	assert.EqualError(recorder.errs[0], "Undefined variable 'import'.\n[line 1]")
+}
+
+func TestInterpreterImportPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	recorder := new(errorRecorder)
+	resolver := FSResolver{FS: fstest.MapFS{"point.lox": {Data: []byte("print \"point\";")}}}
+	in := NewInterpreter(&out, recorder, false, WithModules(resolver), WithPolicy(Policy{Allow: AllCapabilities, Deny: CapFilesystem}))
+	in.Interpret(parseScript(t, in, "import(\"point\");"))
+	assert.Empty(out.String())
+	if assert.Len(recorder.errs, 1) {
+		assert.True(errors.Is(recorder.errs[0], ErrNotAllowed))
+		assert.Equal("Native function 'import' needs capabilities that aren't allowed: filesystem.\n[line 1]", recorder.errs[0].Error())
+	}
+
+	// the modules that don't come from files need no capability
+	recorder.Reset()
+	in = NewInterpreter(&out, recorder, false, WithModules(MapResolver{"point": "print \"point\";"}), WithPolicy(Policy{}))
+	in.Interpret(parseScript(t, in, "import(\"point\");"))
+	assert.Equal("point\n", out.String())
+	assert.Empty(recorder.errs)
+}
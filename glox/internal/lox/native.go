@@ -0,0 +1,238 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+)
+
+// valueType is the type of Value, which is given to and returned from native
+// functions as it is.
+var valueType = reflect.TypeOf(Value{})
+
+// nativeFunction is a Go function that was registered with RegisterNative.
+type nativeFunction struct {
+	name   string
+	params int
+	fn     func(args []Value) (Value, error)
+	// needs holds the capabilities that the function needs
+	needs Capability
+}
+
+func (fn *nativeFunction) arity() int {
+	return fn.params
+}
+
+func (fn *nativeFunction) call(
+	in *Interpreter,
+	args []interface{},
+) (interface{}, error) {
+	if denied := in.deniedCapabilities(fn.needs); denied != 0 {
+		return nil, in.notAllowedError(fn.name, denied)
+	}
+	vals := make([]Value, len(args))
+	for i, arg := range args {
+		vals[i] = Value{val: arg, in: in}
+	}
+	val, err := fn.fn(vals)
+	if err != nil {
+		var rerr *runtimeError
+		if errors.As(err, &rerr) {
+			return nil, err
+		}
+		// the error is reported at the call, and can still be found with
+		// errors.Is or errors.As
+		e := newRuntimeError(in.callSite, CodeNativeFailed, err.Error()).(*runtimeError)
+		e.cause = err
+		return nil, e
+	}
+	return val.val, nil
+}
+
+func (fn *nativeFunction) String() string {
+	return "<native fn>"
+}
+
+// RegisterNative defines a global function named name that calls fn with the
+// given number of arguments, so scripts can use what the host program
+// provides. The arguments can be called from fn, and an error that it returns
+// is reported as a runtime error at the call, whose message is the one of the
+// error. The function stays defined when the interpreter is reset.
+func (in *Interpreter) RegisterNative(name string, arity int, fn func(args []Value) (Value, error)) {
+	in.registerNative(&nativeFunction{name: name, params: arity, fn: fn})
+}
+
+// registerNative defines the native function, an error wrapping ErrNotAllowed
+// is returned if it needs capabilities that the policy doesn't allow.
+func (in *Interpreter) registerNative(native *nativeFunction) error {
+	if in.natives == nil {
+		in.natives = make(map[string]callable)
+	}
+	in.natives[native.name] = native
+	in.globals.define(native.name, native)
+	if denied := in.deniedCapabilities(native.needs); denied != 0 {
+		return fmt.Errorf("lox: native function %s needs capabilities that aren't allowed: %s: %w", native.name, denied, ErrNotAllowed)
+	}
+	return nil
+}
+
+// RegisterFunc is like RegisterNative, but takes an ordinary Go function whose
+// parameters and results are converted from and to Lox values. The
+// parameters can be numbers, of any integer or floating-point type, strings,
+// booleans, Value to take any Lox value, or an empty interface, which is
+// given a bool, a float64, a string, or nil, and a Value for the other
+// values. The function can return nothing, a value, an error, or a value and
+// an error, the value being a number, a string, a boolean, or a Value. An
+// error is returned if the function has other types. A number that isn't an
+// integer, or is out of range, can't be given to an integer parameter.
+func (in *Interpreter) RegisterFunc(name string, fn interface{}) error {
+	native, err := newNativeFunc(name, fn)
+	if err != nil {
+		return err
+	}
+	return in.registerNative(native)
+}
+
+// newNativeFunc creates the native function that calls fn for RegisterFunc.
+func newNativeFunc(name string, fn interface{}) (*nativeFunction, error) {
+	f := reflect.ValueOf(fn)
+	t := f.Type()
+	if t.Kind() != reflect.Func {
+		return nil, fmt.Errorf("%s is not a function", t)
+	}
+	if t.IsVariadic() {
+		return nil, fmt.Errorf("variadic function %s can't be called from Lox", t)
+	}
+	for i := 0; i < t.NumIn(); i++ {
+		if !isLoxType(t.In(i)) {
+			return nil, fmt.Errorf("parameter %d of %s can't be given a Lox value", i+1, t)
+		}
+	}
+	errorType := reflect.TypeOf((*error)(nil)).Elem()
+	switch {
+	case t.NumOut() == 0:
+	case t.NumOut() == 1 && (t.Out(0) == errorType || isLoxType(t.Out(0))):
+	case t.NumOut() == 2 && isLoxType(t.Out(0)) && t.Out(1) == errorType:
+	default:
+		return nil, fmt.Errorf("results of %s can't be returned to Lox", t)
+	}
+
+	call := func(args []Value) (Value, error) {
+		params := make([]reflect.Value, len(args))
+		for i, arg := range args {
+			v, err := fromLox(arg, t.In(i))
+			if err != nil {
+				return Value{}, fmt.Errorf("Argument %d of '%s' %s.", i+1, name, err)
+			}
+			params[i] = v
+		}
+		out := f.Call(params)
+		if len(out) > 0 && out[len(out)-1].Type() == errorType {
+			if err, _ := out[len(out)-1].Interface().(error); err != nil {
+				return Value{}, err
+			}
+			out = out[:len(out)-1]
+		}
+		if len(out) == 0 {
+			return Value{}, nil
+		}
+		return Value{val: toLox(out[0])}, nil
+	}
+	return &nativeFunction{name: name, params: t.NumIn(), fn: call}, nil
+}
+
+// isLoxType returns true if values of the type can be converted from and to
+// Lox values.
+func isLoxType(t reflect.Type) bool {
+	if t == valueType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Bool, reflect.String, reflect.Float32, reflect.Float64,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	case reflect.Interface:
+		return t.NumMethod() == 0
+	}
+	return false
+}
+
+// fromLox converts a Lox value to the given type, the error tells what the
+// value should have been.
+func fromLox(val Value, t reflect.Type) (reflect.Value, error) {
+	v := reflect.New(t).Elem()
+	if t == valueType {
+		v.Set(reflect.ValueOf(val))
+		return v, nil
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		switch val.val.(type) {
+		case nil:
+		case bool, float64, string:
+			v.Set(reflect.ValueOf(val.val))
+		default:
+			v.Set(reflect.ValueOf(val))
+		}
+		return v, nil
+	case reflect.Bool:
+		b, ok := val.val.(bool)
+		if !ok {
+			return v, errors.New("must be a boolean")
+		}
+		v.SetBool(b)
+	case reflect.String:
+		s, ok := val.val.(string)
+		if !ok {
+			return v, errors.New("must be a string")
+		}
+		v.SetString(s)
+	case reflect.Float32, reflect.Float64:
+		n, ok := val.val.(float64)
+		if !ok {
+			return v, errors.New("must be a number")
+		}
+		v.SetFloat(n)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := val.val.(float64)
+		if !ok || n != float64(int64(n)) || v.OverflowInt(int64(n)) {
+			return v, errors.New("must be an integer")
+		}
+		v.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := val.val.(float64)
+		if !ok || n < 0 || n != float64(uint64(n)) || v.OverflowUint(uint64(n)) {
+			return v, errors.New("must be a non-negative integer")
+		}
+		v.SetUint(uint64(n))
+	}
+	return v, nil
+}
+
+// toLox converts a Go value of one of the types accepted by isLoxType to the
+// interpreter's representation of a Lox value.
+func toLox(v reflect.Value) interface{} {
+	if v.Type() == valueType {
+		return v.Interface().(Value).val
+	}
+	if v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Bool:
+		return v.Bool()
+	case reflect.String:
+		return v.String()
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	}
+	return v.Interface()
+}
@@ -0,0 +1,83 @@
+package lox
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpreterRegisterNative(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	errNegative := errors.New("Expect a positive number.")
+	in.RegisterNative("half", 1, func(args []Value) (Value, error) {
+		if !args[0].IsNumber() || args[0].AsNumber() < 0 {
+			return Value{}, errNegative
+		}
+		return NumberValue(args[0].AsNumber() / 2), nil
+	})
+	in.Interpret(parseScript(t, in, "print half(3);\nprint half;\nhalf(-1);"))
+	assert.Equal("1.5\n<native fn>\n", out.String())
+	assert.Equal("Expect a positive number.\n[line 3]\n", errs.String())
+
+	// the native functions are kept when the globals are reset
+	out.Reset()
+	in.Reset()
+	in.Interpret(parseScript(t, in, "print half(1);"))
+	assert.Equal("0.5\n", out.String())
+}
+
+func TestInterpreterRegisterNativeError(t *testing.T) {
+	assert := assert.New(t)
+
+	recorder := new(errorRecorder)
+	in := NewInterpreter(ioutil.Discard, recorder, false)
+	errFailed := errors.New("failed")
+	in.RegisterNative("fail", 0, func(args []Value) (Value, error) {
+		return Value{}, errFailed
+	})
+	in.Interpret(parseScript(t, in, "fail();"))
+	if assert.Len(recorder.errs, 1) {
+		assert.True(errors.Is(recorder.errs[0], errFailed))
+	}
+}
+
+func TestInterpreterRegisterFunc(t *testing.T) {
+	assert := assert.New(t)
+
+	var out, errs strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&errs), false)
+	assert.NoError(in.RegisterFunc("repeat", strings.Repeat))
+	assert.NoError(in.RegisterFunc("isNil", func(v Value) bool { return v.IsNil() }))
+	assert.NoError(in.RegisterFunc("half", func(n uint8) (float32, error) {
+		if n == 0 {
+			return 0, errors.New("Expect a positive number.")
+		}
+		return float32(n) / 2, nil
+	}))
+	assert.NoError(in.RegisterFunc("nothing", func() {}))
+	in.Interpret(parseScript(t, in, "print repeat(\"ab\", 2);\nprint isNil(nil);\nprint half(3);\nprint nothing();"))
+	assert.Equal("abab\ntrue\n1.5\nnil\n", out.String())
+	assert.Empty(errs.String())
+
+	for script, err := range map[string]string{
+		"repeat(1, 2);":       "Argument 1 of 'repeat' must be a string.\n[line 1]\n",
+		"repeat(\"a\", 0.5);": "Argument 2 of 'repeat' must be an integer.\n[line 1]\n",
+		"half(256);":          "Argument 1 of 'half' must be a non-negative integer.\n[line 1]\n",
+		"half(0);":            "Expect a positive number.\n[line 1]\n",
+	} {
+		errs.Reset()
+		in.Interpret(parseScript(t, in, script))
+		assert.Equal(err, errs.String(), script)
+	}
+
+	assert.Error(in.RegisterFunc("notFunc", 1))
+	assert.Error(in.RegisterFunc("variadic", func(args ...string) {}))
+	assert.Error(in.RegisterFunc("slice", func(args []string) {}))
+	assert.Error(in.RegisterFunc("results", func() (int, int) { return 0, 0 }))
+}
@@ -7,12 +7,28 @@ import (
 
 const MAX_ARGS_COUNT = 255
 
+// maxNestingDepth bounds how deeply statements and expressions can be nested.
+// The parser, the resolver, and the interpreter all recurse on the syntax
+// tree, so a deep enough tree would overflow the goroutine's stack, which
+// can't be recovered from.
+const maxNestingDepth = 1000
+
 // Parser composes the syntax tree for the Lox language from the sequence of
 // valid tokens.
 type Parser struct {
 	current  int
 	tokens   []*Token
 	reporter Reporter
+	// depth is the current nesting depth, once it's too deep the remaining
+	// tokens are skipped and aborted is set so that the errors caused by
+	// skipping them aren't reported.
+	depth   int
+	aborted bool
+	// tolerant is set if the statements and the expressions that can't be
+	// parsed are kept as error nodes, see SetTolerant, and lastError is the
+	// token of the last error reported in this mode.
+	tolerant  bool
+	lastError *Token
 }
 
 // NewParse creates a new parse for the Lox language
@@ -21,7 +37,28 @@ func NewParser(tokens []*Token, reporter Reporter) *Parser {
 	parser.current = 0
 	parser.tokens = tokens
 	parser.reporter = reporter
-	return &Parser{0, tokens, reporter}
+	return parser
+}
+
+// Reset prepares the parser for parsing a new sequence of tokens, so that a
+// single parser can be used for many sources.
+func (parser *Parser) Reset(tokens []*Token) {
+	parser.current = 0
+	parser.tokens = tokens
+	parser.depth = 0
+	parser.aborted = false
+	parser.lastError = nil
+}
+
+// SetTolerant makes the parser keep what it can of the code that has syntax
+// errors, for tools such as editors that work on files being edited. A
+// statement that can't be parsed becomes an ErrorStmt holding the tokens that
+// were skipped instead of nil, a missing expression becomes an ErrorExpr, and
+// a missing ';' or closing '}' is reported without dropping the statement.
+// The errors are reported either way, and the trees that have error nodes
+// can't be run.
+func (parser *Parser) SetTolerant(enabled bool) {
+	parser.tolerant = enabled
 }
 
 func (parser *Parser) Parse() []Stmt {
@@ -33,15 +70,45 @@ func (parser *Parser) Parse() []Stmt {
 	return stmts
 }
 
+// report gives the error to the reporter as a diagnostic of the parser.
+func (parser *Parser) report(err error) {
+	if e, ok := err.(*compileError); ok && parser.tolerant {
+		// a missing expression is often followed by a missing ';' at the
+		// same token, which says nothing more
+		if e.token == parser.lastError {
+			return
+		}
+		parser.lastError = e.token
+	}
+	parser.reporter.Report(newDiagnostic(StageParse, err))
+}
+
+// ParseExpr parses the tokens as a single expression, nil is returned if there
+// was an error or if there are tokens left after the expression.
+func (parser *Parser) ParseExpr() Expr {
+	expr, err := parser.expr()
+	if err == nil && !parser.isEOF() {
+		err = newCompileError(parser.peek(), CodeUnexpectedToken, "Expect end of expression.")
+	}
+	if err != nil {
+		if !parser.aborted {
+			parser.report(err)
+		}
+		return nil
+	}
+	return expr
+}
+
 func (parser *Parser) decl() Stmt {
 	var stmt Stmt
 	var err error
+	start := parser.current
 
 	switch {
 	case parser.match(CLASS):
 		stmt, err = parser.classDecl()
 	case parser.match(FUN):
-		stmt, err = parser.function("function")
+		stmt, err = parser.function("function", parser.prev())
 	case parser.match(VAR):
 		stmt, err = parser.varDecl()
 	default:
@@ -49,14 +116,42 @@ func (parser *Parser) decl() Stmt {
 	}
 
 	if err != nil {
-		parser.reporter.Report(err)
-		parser.sync()
+		if !parser.aborted {
+			parser.report(err)
+			if parser.tolerant {
+				parser.syncTolerant()
+			} else {
+				parser.sync()
+			}
+		}
+		if parser.tolerant {
+			return parser.errorStmt(start)
+		}
 		return nil
 	}
+	if parser.tolerant && parser.current == start {
+		// only error nodes were made, the token must be skipped to move on
+		parser.advance()
+		return parser.errorStmt(start)
+	}
+	return stmt
+}
+
+// errorStmt makes the node of the statement that couldn't be parsed, from the
+// token at the given index to the last one that was skipped.
+func (parser *Parser) errorStmt(start int) Stmt {
+	tokens := parser.tokens[start:parser.current]
+	stmt := NewErrorStmt(tokens)
+	if len(tokens) > 0 {
+		stmt.setSpan(tokens[0], tokens[len(tokens)-1])
+	} else {
+		stmt.setSpan(parser.peek(), parser.peek())
+	}
 	return stmt
 }
 
 func (parser *Parser) classDecl() (Stmt, error) {
+	start := parser.prev()
 	name, err := parser.consume(IDENT, "Expect class name.")
 	if err != nil {
 		return nil, err
@@ -69,6 +164,7 @@ func (parser *Parser) classDecl() (Stmt, error) {
 			return nil, err
 		}
 		super = NewVarExpr(name)
+		super.setSpan(name, name)
 	}
 
 	_, err = parser.consume(L_BRACE, "Expect '{' before class body.")
@@ -77,23 +173,31 @@ func (parser *Parser) classDecl() (Stmt, error) {
 	}
 	var methods []*FunctionStmt
 	for !parser.check(R_BRACE) && !parser.isEOF() {
-		method, err := parser.function("method")
+		method, err := parser.function("method", parser.peek())
 		if err != nil {
-			return nil, err
+			if !parser.tolerant || parser.aborted {
+				return nil, err
+			}
+			// what isn't a method is skipped up to the next name, which may
+			// start one, so the class keeps the methods around it
+			parser.report(err)
+			parser.skip(IDENT)
+			continue
 		}
 		methods = append(methods, method)
 	}
-	_, err = parser.consume(R_BRACE, "Expect '}' after class body.")
+	err = parser.expect(R_BRACE, "Expect '}' after class body.")
 	if err != nil {
 		return nil, err
 	}
 
-	return NewClassStmt(name, super, methods), nil
+	return parser.finishStmt(NewClassStmt(name, super, methods), start), nil
 }
 
 // The parameter "kind" is used to control the error message when this method is
-// reused when parsing objects' methods.
-func (parser *Parser) function(kind string) (*FunctionStmt, error) {
+// reused when parsing objects' methods. The function starts at the given token,
+// i.e. "fun" or the name of the method.
+func (parser *Parser) function(kind string, start *Token) (*FunctionStmt, error) {
 	// function name
 	name, err := parser.consume(
 		IDENT,
@@ -102,8 +206,47 @@ func (parser *Parser) function(kind string) (*FunctionStmt, error) {
 	if err != nil {
 		return nil, err
 	}
+	params, err := parser.params(kind)
+	if err == nil {
+		// function body
+		_, err = parser.consume(
+			L_BRACE,
+			fmt.Sprintf("Expect '{' before %s body.", kind),
+		)
+	}
+	var body []Stmt
+	if err != nil {
+		if !parser.tolerant || parser.aborted {
+			return nil, err
+		}
+		// the function is kept with the parameters that were parsed, and its
+		// body if it's found after the tokens that are skipped
+		parser.report(err)
+		skipped := parser.current
+		parser.skip(L_BRACE, CLASS, FUN, VAR, FOR, IF, WHILE, PRINT, RETURN)
+		if parser.current > skipped || !parser.check(L_BRACE) {
+			body = append(body, parser.errorStmt(skipped))
+		}
+		if !parser.match(L_BRACE) {
+			fn := NewFunctionStmt(name, params, body)
+			fn.setSpan(start, parser.prev())
+			return fn, nil
+		}
+	}
+	stmts, err := parser.block()
+	if err != nil {
+		return nil, err
+	}
+	fn := NewFunctionStmt(name, params, append(body, stmts...))
+	fn.setSpan(start, parser.prev())
+	return fn, nil
+}
+
+// params parses the parameters of a function with their parentheses, the ones
+// that were parsed are returned along with the error if there's one.
+func (parser *Parser) params(kind string) ([]*Token, error) {
 	// function parameters, this works similarly to parsing function calls
-	_, err = parser.consume(
+	_, err := parser.consume(
 		L_PAREN,
 		fmt.Sprintf("Expect '(' after %s name.", kind),
 	)
@@ -114,15 +257,16 @@ func (parser *Parser) function(kind string) (*FunctionStmt, error) {
 	if !parser.check(R_PAREN) {
 		for {
 			if len(params) >= MAX_ARGS_COUNT {
-				parser.reporter.Report(newCompileError(
+				parser.report(newCompileError(
 					parser.peek(),
+					CodeTooManyArguments,
 					fmt.Sprintf("Can't have more than %d parameters.", MAX_ARGS_COUNT),
 				))
 			}
 
 			param, err := parser.consume(IDENT, "Expect parameter name.")
 			if err != nil {
-				return nil, err
+				return params, err
 			}
 			params = append(params, param)
 
@@ -132,25 +276,11 @@ func (parser *Parser) function(kind string) (*FunctionStmt, error) {
 		}
 	}
 	_, err = parser.consume(R_PAREN, "Expect ')' after parameters.")
-	if err != nil {
-		return nil, err
-	}
-	// function body
-	_, err = parser.consume(
-		L_BRACE,
-		fmt.Sprintf("Expect '{' before %s body.", kind),
-	)
-	if err != nil {
-		return nil, err
-	}
-	body, err := parser.block()
-	if err != nil {
-		return nil, err
-	}
-	return NewFunctionStmt(name, params, body), nil
+	return params, err
 }
 
 func (parser *Parser) varDecl() (Stmt, error) {
+	start := parser.prev()
 	name, err := parser.consume(IDENT, "Expect variable name.")
 	if err != nil {
 		return nil, err
@@ -165,14 +295,18 @@ func (parser *Parser) varDecl() (Stmt, error) {
 		}
 	}
 
-	_, err = parser.consume(SEMICOLON, "Expect ';' after variable declaration.")
+	err = parser.expect(SEMICOLON, "Expect ';' after variable declaration.")
 	if err != nil {
 		return nil, err
 	}
-	return NewVarStmt(name, initializer), nil
+	return parser.finishStmt(NewVarStmt(name, initializer), start), nil
 }
 
 func (parser *Parser) stmt() (Stmt, error) {
+	if err := parser.nest(); err != nil {
+		return nil, err
+	}
+	defer parser.unnest()
 	if parser.match(FOR) {
 		return parser.forStmt()
 	}
@@ -189,11 +323,12 @@ func (parser *Parser) stmt() (Stmt, error) {
 		return parser.whileStmt()
 	}
 	if parser.match(L_BRACE) {
+		start := parser.prev()
 		stmts, err := parser.block()
 		if err != nil {
 			return nil, err
 		}
-		return NewBlockStmt(stmts), nil
+		return parser.finishStmt(NewBlockStmt(stmts), start), nil
 	}
 	return parser.exprStmt()
 }
@@ -206,7 +341,7 @@ func (parser *Parser) block() ([]Stmt, error) {
 		stmt := parser.decl()
 		stmts = append(stmts, stmt)
 	}
-	_, err := parser.consume(R_BRACE, "Expect '}' after block.")
+	err := parser.expect(R_BRACE, "Expect '}' after block.")
 	if err != nil {
 		return nil, err
 	}
@@ -214,18 +349,20 @@ func (parser *Parser) block() ([]Stmt, error) {
 }
 
 func (parser *Parser) exprStmt() (Stmt, error) {
+	start := parser.peek()
 	expr, err := parser.expr()
 	if err != nil {
 		return nil, err
 	}
-	_, err = parser.consume(SEMICOLON, "Expect ';' after expression.")
+	err = parser.expect(SEMICOLON, "Expect ';' after expression.")
 	if err != nil {
 		return nil, err
 	}
-	return NewExprStmt(expr), nil
+	return parser.finishStmt(NewExprStmt(expr), start), nil
 }
 
 func (parser *Parser) forStmt() (Stmt, error) {
+	keyword := parser.prev()
 	_, err := parser.consume(L_PAREN, "Expect '(' after 'for'.")
 	if err != nil {
 		return nil, err
@@ -253,7 +390,7 @@ func (parser *Parser) forStmt() (Stmt, error) {
 			return nil, err
 		}
 	}
-	_, err = parser.consume(SEMICOLON, "Expect ';' after loop condition.")
+	condEnd, err := parser.consume(SEMICOLON, "Expect ';' after loop condition.")
 	if err != nil {
 		return nil, err
 	}
@@ -270,25 +407,31 @@ func (parser *Parser) forStmt() (Stmt, error) {
 		return nil, err
 	}
 
-	// desugaring for statement by building the AST by hand
+	// desugaring for statement by building the AST by hand, the nodes that
+	// weren't written span the whole loop, except for the missing condition
+	// which is at the ';' where it would be
 	body, err := parser.stmt()
 	if err != nil {
 		return nil, err
 	}
 	if inc != nil {
-		body = NewBlockStmt([]Stmt{body, NewExprStmt(inc)})
+		incStmt := NewExprStmt(inc)
+		incStmt.Span = *inc.span()
+		body = parser.finishStmt(NewBlockStmt([]Stmt{body, incStmt}), keyword)
 	}
 	if cond == nil {
 		cond = NewLiteralExpr(true)
+		cond.setSpan(condEnd, condEnd)
 	}
-	body = NewWhileStmt(cond, body)
+	body = parser.finishStmt(NewWhileStmt(keyword, cond, body), keyword)
 	if init != nil {
-		body = NewBlockStmt([]Stmt{init, body})
+		body = parser.finishStmt(NewBlockStmt([]Stmt{init, body}), keyword)
 	}
 	return body, nil
 }
 
 func (parser *Parser) ifStmt() (Stmt, error) {
+	keyword := parser.prev()
 	_, err := parser.consume(L_PAREN, "Expect '(' after 'if'.")
 	if err != nil {
 		return nil, err
@@ -314,19 +457,20 @@ func (parser *Parser) ifStmt() (Stmt, error) {
 			return nil, err
 		}
 	}
-	return NewIfStmt(cond, thenBranch, elseBranch), nil
+	return parser.finishStmt(NewIfStmt(keyword, cond, thenBranch, elseBranch), keyword), nil
 }
 
 func (parser *Parser) printStmt() (Stmt, error) {
+	keyword := parser.prev()
 	expr, err := parser.expr()
 	if err != nil {
 		return nil, err
 	}
-	_, err = parser.consume(SEMICOLON, "Expect ';' after value.")
+	err = parser.expect(SEMICOLON, "Expect ';' after value.")
 	if err != nil {
 		return nil, err
 	}
-	return NewPrintStmt(expr), nil
+	return parser.finishStmt(NewPrintStmt(keyword, expr), keyword), nil
 }
 
 func (parser *Parser) returnStmt() (Stmt, error) {
@@ -342,14 +486,15 @@ func (parser *Parser) returnStmt() (Stmt, error) {
 		}
 	}
 
-	_, err = parser.consume(SEMICOLON, "Expect ';' after return value.")
+	err = parser.expect(SEMICOLON, "Expect ';' after return value.")
 	if err != nil {
 		return nil, err
 	}
-	return NewReturnStmt(keyword, val), nil
+	return parser.finishStmt(NewReturnStmt(keyword, val), keyword), nil
 }
 
 func (parser *Parser) whileStmt() (Stmt, error) {
+	keyword := parser.prev()
 	_, err := parser.consume(L_PAREN, "Expect '(' after 'while'.")
 	if err != nil {
 		return nil, err
@@ -367,7 +512,7 @@ func (parser *Parser) whileStmt() (Stmt, error) {
 	if err != nil {
 		return nil, err
 	}
-	return NewWhileStmt(cond, body), nil
+	return parser.finishStmt(NewWhileStmt(keyword, cond, body), keyword), nil
 }
 
 func (parser *Parser) expr() (Expr, error) {
@@ -375,6 +520,11 @@ func (parser *Parser) expr() (Expr, error) {
 }
 
 func (parser *Parser) assign() (Expr, error) {
+	if err := parser.nest(); err != nil {
+		return nil, err
+	}
+	defer parser.unnest()
+	start := parser.peek()
 	lhs, err := parser.or()
 	if err != nil {
 		return nil, err
@@ -387,17 +537,18 @@ func (parser *Parser) assign() (Expr, error) {
 		}
 		switch lhs := lhs.(type) {
 		case *VarExpr:
-			return NewAssignExpr(lhs.Name, rhs), nil
+			return parser.finishExpr(NewAssignExpr(lhs.Name, rhs), start), nil
 		case *GetExpr:
-			return NewSetExpr(lhs.Obj, lhs.Name, rhs), nil
+			return parser.finishExpr(NewSetExpr(lhs.Obj, lhs.Name, rhs), start), nil
 		default:
-			parser.reporter.Report(newCompileError(op, "Invalid assignment target."))
+			parser.report(newCompileError(op, CodeInvalidAssignment, "Invalid assignment target."))
 		}
 	}
 	return lhs, nil
 }
 
 func (parser *Parser) or() (Expr, error) {
+	start := parser.peek()
 	lhs, err := parser.and()
 	if err != nil {
 		return nil, err
@@ -408,12 +559,13 @@ func (parser *Parser) or() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		lhs = NewLogicalExpr(op, lhs, rhs)
+		lhs = parser.finishExpr(NewLogicalExpr(op, lhs, rhs), start)
 	}
 	return lhs, nil
 }
 
 func (parser *Parser) and() (Expr, error) {
+	start := parser.peek()
 	lhs, err := parser.equality()
 	if err != nil {
 		return nil, err
@@ -424,12 +576,13 @@ func (parser *Parser) and() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		lhs = NewLogicalExpr(op, lhs, rhs)
+		lhs = parser.finishExpr(NewLogicalExpr(op, lhs, rhs), start)
 	}
 	return lhs, nil
 }
 
 func (parser *Parser) equality() (Expr, error) {
+	start := parser.peek()
 	lhs, err := parser.comparison()
 	if err != nil {
 		return nil, err
@@ -440,12 +593,13 @@ func (parser *Parser) equality() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		lhs = NewBinaryExpr(op, lhs, rhs)
+		lhs = parser.finishExpr(NewBinaryExpr(op, lhs, rhs), start)
 	}
 	return lhs, nil
 }
 
 func (parser *Parser) comparison() (Expr, error) {
+	start := parser.peek()
 	lhs, err := parser.term()
 	if err != nil {
 		return nil, err
@@ -456,12 +610,13 @@ func (parser *Parser) comparison() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		lhs = NewBinaryExpr(op, lhs, rhs)
+		lhs = parser.finishExpr(NewBinaryExpr(op, lhs, rhs), start)
 	}
 	return lhs, nil
 }
 
 func (parser *Parser) term() (Expr, error) {
+	start := parser.peek()
 	lhs, err := parser.factor()
 	if err != nil {
 		return nil, err
@@ -472,12 +627,13 @@ func (parser *Parser) term() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		lhs = NewBinaryExpr(op, lhs, rhs)
+		lhs = parser.finishExpr(NewBinaryExpr(op, lhs, rhs), start)
 	}
 	return lhs, nil
 }
 
 func (parser *Parser) factor() (Expr, error) {
+	start := parser.peek()
 	lhs, err := parser.unary()
 	if err != nil {
 		return nil, err
@@ -488,18 +644,23 @@ func (parser *Parser) factor() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		lhs = NewBinaryExpr(op, lhs, rhs)
+		lhs = parser.finishExpr(NewBinaryExpr(op, lhs, rhs), start)
 	}
 	return lhs, nil
 }
 
 func (parser *Parser) unary() (Expr, error) {
+	if err := parser.nest(); err != nil {
+		return nil, err
+	}
+	defer parser.unnest()
 	if parser.match(BANG, MINUS, PLUS, SLASH, STAR) {
 		op := parser.prev()
 		switch expr, err := parser.unary(); op.Type {
 		case PLUS, SLASH, STAR:
 			err = newCompileError(
 				op,
+				CodeUnsupportedUnary,
 				fmt.Sprintf("Unary '%s' expressions are not supported.", op.Lexeme),
 			)
 			fallthrough
@@ -507,7 +668,7 @@ func (parser *Parser) unary() (Expr, error) {
 			if err != nil {
 				return nil, err
 			}
-			return NewUnaryExpr(op, expr), nil
+			return parser.finishExpr(NewUnaryExpr(op, expr), op), nil
 		}
 	}
 	return parser.call()
@@ -516,6 +677,7 @@ func (parser *Parser) unary() (Expr, error) {
 // call parses function calls, refers to the grammar to see what's qualified as a
 // function call.
 func (parser *Parser) call() (Expr, error) {
+	start := parser.peek()
 	expr, err := parser.primary()
 	if err != nil {
 		return nil, err
@@ -527,12 +689,13 @@ func (parser *Parser) call() (Expr, error) {
 			if err != nil {
 				return nil, err
 			}
+			expr = parser.finishExpr(expr, start)
 		} else if parser.match(DOT) {
 			name, err := parser.consume(IDENT, "Expect property name after '.'.")
 			if err != nil {
 				return nil, err
 			}
-			expr = NewGetExpr(expr, name)
+			expr = parser.finishExpr(NewGetExpr(expr, name), start)
 		} else {
 			break
 		}
@@ -548,8 +711,9 @@ func (parser *Parser) finishCall(callee Expr) (Expr, error) {
 	if !parser.check(R_PAREN) {
 		for {
 			if len(args) >= MAX_ARGS_COUNT {
-				parser.reporter.Report(newCompileError(
+				parser.report(newCompileError(
 					parser.peek(),
+					CodeTooManyArguments,
 					fmt.Sprintf("Can't have more than %d arguments.", MAX_ARGS_COUNT),
 				))
 			}
@@ -574,8 +738,9 @@ func (parser *Parser) finishCall(callee Expr) (Expr, error) {
 }
 
 func (parser *Parser) primary() (Expr, error) {
+	start := parser.peek()
 	if parser.match(THIS) {
-		return NewThisExpr(parser.prev()), nil
+		return parser.finishExpr(NewThisExpr(parser.prev()), start), nil
 	}
 	if parser.match(SUPER) {
 		keyword := parser.prev()
@@ -587,22 +752,22 @@ func (parser *Parser) primary() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		return NewSuperExpr(keyword, method), nil
+		return parser.finishExpr(NewSuperExpr(keyword, method), start), nil
 	}
 	if parser.match(FALSE) {
-		return NewLiteralExpr(false), nil
+		return parser.finishExpr(NewLiteralExpr(false), start), nil
 	}
 	if parser.match(TRUE) {
-		return NewLiteralExpr(true), nil
+		return parser.finishExpr(NewLiteralExpr(true), start), nil
 	}
 	if parser.match(NIL) {
-		return NewLiteralExpr(nil), nil
+		return parser.finishExpr(NewLiteralExpr(nil), start), nil
 	}
 	if parser.match(NUMBER, STRING) {
-		return NewLiteralExpr(parser.prev().Literal), nil
+		return parser.finishExpr(NewLiteralExpr(parser.prev().Literal), start), nil
 	}
 	if parser.match(IDENT) {
-		return NewVarExpr(parser.prev()), nil
+		return parser.finishExpr(NewVarExpr(parser.prev()), start), nil
 	}
 	if parser.match(L_PAREN) {
 		expr, err := parser.expr()
@@ -613,9 +778,41 @@ func (parser *Parser) primary() (Expr, error) {
 		if err != nil {
 			return nil, err
 		}
-		return NewGroupExpr(expr), nil
+		return parser.finishExpr(NewGroupExpr(expr), start), nil
 	}
-	return nil, newCompileError(parser.peek(), "Expect expression.")
+	err := newCompileError(parser.peek(), CodeUnexpectedToken, "Expect expression.")
+	if parser.tolerant && !parser.aborted {
+		// the token is left for the enclosing construct, it's skipped by decl
+		// if nothing else takes it
+		parser.report(err)
+		expr := NewErrorExpr(parser.peek())
+		expr.setSpan(parser.peek(), parser.peek())
+		return expr, nil
+	}
+	return nil, err
+}
+
+// finishExpr sets the span of the expression that was just parsed, from the
+// given token to the last one that was consumed, and returns it.
+func (parser *Parser) finishExpr(expr Expr, start *Token) Expr {
+	expr.setSpan(start, parser.end(start))
+	return expr
+}
+
+// finishStmt sets the span of the statement that was just parsed, from the
+// given token to the last one that was consumed, and returns it.
+func (parser *Parser) finishStmt(stmt Stmt, start *Token) Stmt {
+	stmt.setSpan(start, parser.end(start))
+	return stmt
+}
+
+// end returns the last token that was consumed since the given one, or the
+// token itself if nothing was, i.e. only an error node was made in its place.
+func (parser *Parser) end(start *Token) *Token {
+	if parser.peek() == start {
+		return start
+	}
+	return parser.prev()
 }
 
 func (parser *Parser) match(types ...TokenType) bool {
@@ -633,7 +830,19 @@ func (parser *Parser) consume(typ TokenType, message string) (*Token, error) {
 		token := parser.advance()
 		return token, nil
 	}
-	return nil, newCompileError(parser.peek(), message)
+	return nil, newCompileError(parser.peek(), CodeUnexpectedToken, message)
+}
+
+// expect consumes the token that ends a construct. In tolerant mode, a missing
+// one is reported and nil is returned, so the construct is kept as if the
+// token was there.
+func (parser *Parser) expect(typ TokenType, message string) error {
+	_, err := parser.consume(typ, message)
+	if err != nil && parser.tolerant && !parser.aborted {
+		parser.report(err)
+		return nil
+	}
+	return err
 }
 
 func (parser *Parser) check(tt TokenType) bool {
@@ -662,6 +871,28 @@ func (parser *Parser) prev() *Token {
 	return parser.tokens[parser.current-1]
 }
 
+// nest is called before parsing a statement or an expression that can be
+// nested in another one of the same kind. If the nesting is too deep, the
+// error is reported and the remaining tokens are skipped.
+func (parser *Parser) nest() error {
+	parser.depth++
+	if parser.depth <= maxNestingDepth {
+		return nil
+	}
+	err := newCompileError(parser.peek(), CodeTooMuchNesting, "Too much nesting.")
+	if !parser.aborted {
+		parser.report(err)
+		parser.aborted = true
+		parser.current = len(parser.tokens) - 1
+	}
+	parser.depth--
+	return err
+}
+
+func (parser *Parser) unnest() {
+	parser.depth--
+}
+
 func (parser *Parser) sync() {
 	parser.advance()
 	for !parser.isEOF() {
@@ -676,6 +907,65 @@ func (parser *Parser) sync() {
 	}
 }
 
+// syncTolerant skips the rest of a statement that couldn't be parsed in
+// tolerant mode. Unlike sync, the pairs of braces are skipped as a whole and
+// it stops before a '}' closing the enclosing block, so the blocks, the
+// functions, and the classes around the statement are kept.
+func (parser *Parser) syncTolerant() {
+	depth := 0
+	for !parser.isEOF() {
+		switch parser.peek().Type {
+		case L_BRACE:
+			depth++
+		case R_BRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+			if depth == 0 {
+				parser.advance()
+				return
+			}
+		case SEMICOLON:
+			if depth == 0 {
+				parser.advance()
+				return
+			}
+		case CLASS, FUN, VAR, FOR, IF, WHILE, PRINT, RETURN:
+			if depth == 0 {
+				return
+			}
+		}
+		parser.advance()
+	}
+}
+
+// skip skips the tokens up to one of the given types, or up to a '}' closing
+// the enclosing block, the pairs of braces in between are skipped as a whole.
+func (parser *Parser) skip(stop ...TokenType) {
+	depth := 0
+	for !parser.isEOF() {
+		typ := parser.peek().Type
+		if depth == 0 {
+			for _, stopType := range stop {
+				if typ == stopType {
+					return
+				}
+			}
+		}
+		switch typ {
+		case L_BRACE:
+			depth++
+		case R_BRACE:
+			if depth == 0 {
+				return
+			}
+			depth--
+		}
+		parser.advance()
+	}
+}
+
 func isIdentRune(r rune) bool {
 	return isIdentBegin(r) || unicode.IsDigit(r)
 }
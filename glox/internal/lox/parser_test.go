@@ -0,0 +1,159 @@
+package lox
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParserNestingLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, script := range []string{
+		"print " + strings.Repeat("(", 2000) + "1" + strings.Repeat(")", 2000) + ";",
+		"print " + strings.Repeat("-", 2000) + "1;",
+		"var a; " + strings.Repeat("a = ", 2000) + "1;",
+		strings.Repeat("{", 2000) + strings.Repeat("}", 2000),
+		strings.Repeat("if (true) ", 2000) + "print 1;",
+	} {
+		var errs strings.Builder
+		reporter := NewSimpleReporter(&errs)
+		tokens := NewScanner([]byte(script), reporter).Scan()
+		parser := NewParser(tokens, reporter)
+		parser.Parse()
+		assert.Equal("[line 1] Error at", strings.SplitAfter(errs.String(), "at")[0])
+		assert.True(strings.HasSuffix(errs.String(), ": Too much nesting.\n"), errs.String())
+
+		// the parser can be used again after being reset
+		errs.Reset()
+		reporter.Reset()
+		parser.Reset(NewScanner([]byte("print "+strings.Repeat("(", 300)+"1"+strings.Repeat(")", 300)+";"), reporter).Scan())
+		assert.Len(parser.Parse(), 1)
+		assert.Empty(errs.String())
+	}
+}
+
+func TestParserSpans(t *testing.T) {
+	assert := assert.New(t)
+
+	source := "class A {\n  m() { return (a + 2) * f(\"x\ny\").b; }\n}\nfor (;;) a = 1;"
+	statements, diagnostics := Parse([]byte(source))
+	assert.Empty(diagnostics)
+	text := func(span *Span) string {
+		return source[span.Start.Offset:span.End.Offset]
+	}
+
+	class := statements[0].(*ClassStmt)
+	assert.Equal(source[:strings.Index(source, "\nfor")], text(class.span()))
+	method := class.Methods[0]
+	assert.Equal(`m() { return (a + 2) * f("x`+"\n"+`y").b; }`, text(method.span()))
+	ret := method.Body[0].(*ReturnStmt)
+	assert.Equal(`return (a + 2) * f("x`+"\n"+`y").b;`, text(ret.span()))
+	product := ret.Val.(*BinaryExpr)
+	assert.Equal("(a + 2)", text(product.Lhs.span()))
+	assert.Equal("a + 2", text(product.Lhs.(*GroupExpr).Expr.span()))
+	get := product.Rhs.(*GetExpr)
+	assert.Equal(`f("x`+"\n"+`y").b`, text(get.span()))
+	assert.Equal(Position{Line: 3, Column: 4, Offset: 43}, get.Obj.span().End)
+	assert.Equal(Position{Line: 2, Column: 17, Offset: 26}, product.Lhs.(*GroupExpr).Expr.span().Start)
+
+	// the nodes of a desugared loop span the loop, but its missing condition
+	loop := statements[1].(*WhileStmt)
+	assert.Equal("for (;;) a = 1;", text(loop.span()))
+	assert.Equal(";", text(loop.Cond.span()))
+	assert.Equal("a = 1;", text(loop.Body.span()))
+
+	// the nodes that aren't parsed have no span
+	assert.True(NewLiteralExpr(1.0).span().IsZero())
+	assert.False(loop.span().Contains(len(source)))
+	assert.True(loop.span().Contains(len(source) - 1))
+}
+
+func TestParserTolerant(t *testing.T) {
+	assert := assert.New(t)
+
+	source := "var a = 1;\nprint a +;\nfun f(x) { var = x; return x }\nclass A { m() { f(a, ); } \n"
+	var errs strings.Builder
+	reporter := NewSimpleReporter(&errs)
+	parser := NewParser(NewScanner([]byte(source), reporter).Scan(), reporter)
+	parser.SetTolerant(true)
+	statements := parser.Parse()
+	assert.Equal(`(var a 1)
+(print (+ a (error)))
+(fun f (x) (error var = x ;) (return x))
+(class A (method m () (; (call f a (error)))))
+`, NewAstPrinter().PrintStmts(statements))
+	assert.Equal(`[line 2] Error at ';': Expect expression.
+[line 3] Error at '=': Expect variable name.
+[line 3] Error at '}': Expect ';' after return value.
+[line 4] Error at ')': Expect expression.
+[line 5] Error at end: Expect '}' after class body.
+`, errs.String())
+
+	// the error nodes span what couldn't be parsed
+	text := func(span *Span) string {
+		return source[span.Start.Offset:span.End.Offset]
+	}
+	body := statements[2].(*FunctionStmt).Body
+	assert.Equal("var = x;", text(body[0].span()))
+	assert.Equal(";", text(statements[1].(*PrintStmt).Expr.(*BinaryExpr).Rhs.span()))
+
+	// a token that can't start anything is skipped on its own
+	errs.Reset()
+	parser.Reset(NewScanner([]byte("{ ) }"), reporter).Scan())
+	assert.Equal("(block (error )))\n", NewAstPrinter().PrintStmts(parser.Parse()))
+	assert.Equal("[line 1] Error at ')': Expect expression.\n", errs.String())
+
+	// the declarations are kept around the errors in a class body or a
+	// function header
+	tests := []struct {
+		source string
+		tree   string
+		err    string
+	}{
+		{
+			"class A { 1 }",
+			"(class A)\n",
+			"[line 1] Error at '1': Expect method name.\n",
+		},
+		{
+			"class A { foo( }",
+			"(class A (method foo () (error)))\n",
+			"[line 1] Error at '}': Expect parameter name.\n",
+		},
+		{
+			"fun f( { print 1; }",
+			"(fun f () (print 1))\n",
+			"[line 1] Error at '{': Expect parameter name.\n",
+		},
+		{
+			"fun f(a b) { print a; }",
+			"(fun f (a) (error b )) (print a))\n",
+			"[line 1] Error at 'b': Expect ')' after parameters.\n",
+		},
+		{
+			"class A { foo() { if (x { } } bar() {} }",
+			"(class A (method foo () (error if ( x { })) (method bar ()))\n",
+			"[line 1] Error at '{': Expect ')' after if condition.\n",
+		},
+		{
+			"class A { m() {} 1 2 n() {} }",
+			"(class A (method m ()) (method n ()))\n",
+			"[line 1] Error at '1': Expect method name.\n",
+		},
+	}
+	for _, tt := range tests {
+		errs.Reset()
+		parser.Reset(NewScanner([]byte(tt.source), reporter).Scan())
+		assert.Equal(tt.tree, NewAstPrinter().PrintStmts(parser.Parse()), tt.source)
+		assert.Equal(tt.err, errs.String(), tt.source)
+	}
+
+	// the trees with error nodes can't be run
+	errs.Reset()
+	in := NewInterpreter(ioutil.Discard, reporter, false)
+	in.Interpret(statements[:2])
+	assert.Equal("Can't run code that has a syntax error.\n[line 2]\n", errs.String())
+}
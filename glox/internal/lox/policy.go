@@ -0,0 +1,129 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Capability is a kind of access to the host system that a native function
+// needs, see Interpreter.RegisterPrivileged. Capabilities are combined with
+// "|".
+type Capability int
+
+const (
+	// CapFilesystem is needed to read or write files.
+	CapFilesystem Capability = 1 << iota
+	// CapNetwork is needed to open connections or listen on the network.
+	CapNetwork
+	// CapExec is needed to run other programs.
+	CapExec
+	// CapEnv is needed to read or change the environment variables.
+	CapEnv
+
+	// AllCapabilities is the combination of every capability.
+	AllCapabilities = CapFilesystem | CapNetwork | CapExec | CapEnv
+)
+
+var capabilityNames = []string{"filesystem", "network", "exec", "env"}
+
+// String returns the names of the capabilities, separated by commas.
+func (c Capability) String() string {
+	var names []string
+	for i, name := range capabilityNames {
+		if c&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+// ParseCapabilities parses a comma-separated list of the names of
+// capabilities, as given by Capability.String, e.g. "filesystem,exec". The
+// name "all" stands for AllCapabilities, and an empty list for none.
+func ParseCapabilities(list string) (Capability, error) {
+	var caps Capability
+	for _, name := range strings.Split(list, ",") {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		if name == "all" {
+			caps |= AllCapabilities
+			continue
+		}
+		found := false
+		for i, capName := range capabilityNames {
+			if capName == name {
+				caps |= 1 << i
+				found = true
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("lox: unknown capability %s", name)
+		}
+	}
+	return caps, nil
+}
+
+// Policy tells which capabilities the native functions of an interpreter can
+// have, so untrusted scripts can only reach what the host program allows. A
+// capability is allowed if it's in Allow and not in Deny, e.g. Policy{Allow:
+// CapEnv} only allows CapEnv, and Policy{Allow: AllCapabilities, Deny:
+// CapExec} allows every capability but CapExec.
+type Policy struct {
+	Allow Capability
+	Deny  Capability
+}
+
+// denied returns the capabilities among the given ones that aren't allowed.
+func (p *Policy) denied(needs Capability) Capability {
+	return needs&^p.Allow | needs&p.Deny
+}
+
+// WithPolicy restricts the capabilities of the native functions to the ones
+// that the policy allows. Every capability is allowed by default.
+func WithPolicy(policy Policy) Option {
+	return func(in *Interpreter) {
+		in.policy = &policy
+	}
+}
+
+// deniedCapabilities returns the capabilities among the given ones that the
+// policy of the interpreter doesn't allow.
+func (in *Interpreter) deniedCapabilities(needs Capability) Capability {
+	if in.policy == nil {
+		return 0
+	}
+	return in.policy.denied(needs)
+}
+
+// notAllowedError creates the runtime error of a call to the native function
+// with the given name that needs capabilities that aren't allowed.
+func (in *Interpreter) notAllowedError(name string, denied Capability) error {
+	e := newRuntimeError(in.callSite, CodeNativeNotAllowed, fmt.Sprintf(
+		"Native function '%s' needs capabilities that aren't allowed: %s.", name, denied,
+	)).(*runtimeError)
+	e.cause = ErrNotAllowed
+	return e
+}
+
+// RegisterPrivileged is like RegisterNative for a function that needs the
+// given capabilities, e.g. CapFilesystem for one that reads files. If the
+// policy of the interpreter doesn't allow them, an error wrapping
+// ErrNotAllowed is returned, and the function is still defined, but each call
+// to it is a runtime error wrapping ErrNotAllowed rather than calling fn, so
+// scripts get a clear error.
+func (in *Interpreter) RegisterPrivileged(name string, needs Capability, arity int, fn func(args []Value) (Value, error)) error {
+	return in.registerNative(&nativeFunction{name: name, params: arity, fn: fn, needs: needs})
+}
+
+// RegisterPrivilegedFunc is like RegisterFunc for a function that needs the
+// given capabilities, see RegisterPrivileged.
+func (in *Interpreter) RegisterPrivilegedFunc(name string, needs Capability, fn interface{}) error {
+	native, err := newNativeFunc(name, fn)
+	if err != nil {
+		return err
+	}
+	native.needs = needs
+	return in.registerNative(native)
+}
@@ -0,0 +1,78 @@
+package lox
+
+import (
+	"errors"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCapabilityString(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("", Capability(0).String())
+	assert.Equal("exec", CapExec.String())
+	assert.Equal("filesystem, network, exec, env", AllCapabilities.String())
+}
+
+func TestParseCapabilities(t *testing.T) {
+	assert := assert.New(t)
+
+	caps, err := ParseCapabilities("filesystem, exec")
+	assert.NoError(err)
+	assert.Equal(CapFilesystem|CapExec, caps)
+	caps, err = ParseCapabilities("all")
+	assert.NoError(err)
+	assert.Equal(AllCapabilities, caps)
+	caps, err = ParseCapabilities("")
+	assert.NoError(err)
+	assert.Equal(Capability(0), caps)
+	_, err = ParseCapabilities("disk")
+	assert.EqualError(err, "lox: unknown capability disk")
+}
+
+func TestInterpreterPolicy(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	recorder := new(errorRecorder)
+	in := NewInterpreter(&out, recorder, false, WithPolicy(Policy{Allow: AllCapabilities, Deny: CapExec}))
+	called := false
+	readFile := func(args []Value) (Value, error) {
+		return StringValue("content"), nil
+	}
+	run := func(args []Value) (Value, error) {
+		called = true
+		return Value{}, nil
+	}
+	assert.NoError(in.RegisterPrivileged("readFile", CapFilesystem, 0, readFile))
+	err := in.RegisterPrivileged("run", CapExec|CapFilesystem, 0, run)
+	assert.True(errors.Is(err, ErrNotAllowed))
+	assert.EqualError(err, "lox: native function run needs capabilities that aren't allowed: exec: not allowed")
+	err = in.RegisterPrivilegedFunc("getenv", CapEnv, func(name string) string { return "" })
+	assert.NoError(err)
+
+	// the functions that aren't allowed are still defined, but can't be called
+	in.Interpret(parseScript(t, in, "print readFile();\nrun();"))
+	assert.Equal("content\n", out.String())
+	assert.False(called)
+	if assert.Len(recorder.errs, 1) {
+		assert.True(errors.Is(recorder.errs[0], ErrNotAllowed))
+		assert.Equal("Native function 'run' needs capabilities that aren't allowed: exec.\n[line 2]", recorder.errs[0].Error())
+	}
+
+	// only the capabilities that are listed are allowed
+	in = NewInterpreter(ioutil.Discard, recorder, false, WithPolicy(Policy{Allow: CapEnv}))
+	assert.Error(in.RegisterPrivileged("readFile", CapFilesystem, 0, readFile))
+	assert.NoError(in.RegisterPrivilegedFunc("getenv", CapEnv, func(name string) string { return "" }))
+	in.RegisterNative("plain", 0, readFile)
+	recorder.errs = nil
+	in.Interpret(parseScript(t, in, "plain();"))
+	assert.Empty(recorder.errs)
+
+	// every capability is allowed without a policy
+	in = NewInterpreter(ioutil.Discard, recorder, false)
+	assert.NoError(in.RegisterPrivileged("run", AllCapabilities, 0, run))
+}
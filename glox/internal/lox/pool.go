@@ -0,0 +1,124 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"sync"
+)
+
+// InterpreterPool hands out interpreters that have already run the same
+// prelude, e.g. the classes and functions that every script of a server
+// uses, so the scripts can be run in parallel without setting up a new
+// interpreter each time. The prelude is parsed and resolved once, and its
+// syntax tree is shared by all the interpreters, but each interpreter has its
+// own globals, so the scripts never see each other's state. A pool can be used
+// by many goroutines at the same time, each interpreter that it gives out by
+// one goroutine at a time.
+type InterpreterPool struct {
+	prelude []Stmt
+	// locals is the resolution of the prelude, which is the same for every
+	// interpreter
+	locals  ResolutionTable
+	setup   func(in *Interpreter)
+	options []Option
+
+	mu        sync.Mutex
+	free      []*Interpreter
+	snapshots map[*Interpreter]*Snapshot
+}
+
+// NewInterpreterPool creates a pool of interpreters that run the prelude once
+// they are created. Each interpreter is created with the options, and is
+// given to setup, if it's not nil, before the prelude is run, e.g. to register
+// native functions. An error is returned if the prelude has a compile error,
+// or a runtime error, with the messages that a reporter would show.
+func NewInterpreterPool(prelude []byte, setup func(in *Interpreter), options ...Option) (*InterpreterPool, error) {
+	pool := new(InterpreterPool)
+	statements, diagnostics := Parse(prelude)
+	if len(diagnostics) == 0 {
+		pool.locals, diagnostics = Resolve(statements)
+	}
+	if len(diagnostics) > 0 {
+		var errs strings.Builder
+		for _, d := range diagnostics {
+			fmt.Fprintln(&errs, d)
+		}
+		return nil, errors.New(strings.TrimSpace(errs.String()))
+	}
+	pool.prelude = statements
+	pool.setup = setup
+	pool.options = options
+	pool.snapshots = make(map[*Interpreter]*Snapshot)
+
+	// the prelude is run right away to check that it has no runtime error
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false, options...)
+	if err := pool.start(in); err != nil {
+		return nil, err
+	}
+	pool.free = append(pool.free, in)
+	return pool, nil
+}
+
+// start runs the prelude with the interpreter, and records the state that it
+// goes back to when it's put back into the pool.
+func (pool *InterpreterPool) start(in *Interpreter) error {
+	if pool.setup != nil {
+		pool.setup(in)
+	}
+	in.AddResolutions(pool.locals)
+	var errs strings.Builder
+	in.reporter = NewSimpleReporter(&errs)
+	if err := in.interpret(pool.prelude); err != nil {
+		return errors.New(strings.TrimSpace(errs.String()))
+	}
+	snapshot := in.Snapshot()
+	pool.mu.Lock()
+	pool.snapshots[in] = snapshot
+	pool.mu.Unlock()
+	return nil
+}
+
+// Get returns an interpreter that has run the prelude, and nothing else, whose
+// scripts print to the output and whose errors are given to the reporter. A
+// new interpreter is created if there's none left in the pool, the error is
+// the one of its prelude.
+func (pool *InterpreterPool) Get(output io.Writer, reporter Reporter) (*Interpreter, error) {
+	pool.mu.Lock()
+	var in *Interpreter
+	if n := len(pool.free); n > 0 {
+		in = pool.free[n-1]
+		pool.free = pool.free[:n-1]
+	}
+	pool.mu.Unlock()
+	if in == nil {
+		in = NewInterpreter(ioutil.Discard, reporter, false, pool.options...)
+		if err := pool.start(in); err != nil {
+			return nil, err
+		}
+	}
+	in.output = output
+	in.reporter = reporter
+	return in, nil
+}
+
+// Put gives an interpreter that was returned by Get back to the pool, once
+// its scripts are over. Its globals are restored to the ones that the prelude
+// defined, so the next scripts run with it don't see what the previous ones
+// did. The interpreter must not be used afterward.
+func (pool *InterpreterPool) Put(in *Interpreter) {
+	pool.mu.Lock()
+	snapshot, ok := pool.snapshots[in]
+	pool.mu.Unlock()
+	if !ok {
+		return
+	}
+	in.Restore(snapshot)
+	in.output = ioutil.Discard
+	in.reporter = NewSimpleReporter(ioutil.Discard)
+	pool.mu.Lock()
+	pool.free = append(pool.free, in)
+	pool.mu.Unlock()
+}
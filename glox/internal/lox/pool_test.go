@@ -0,0 +1,112 @@
+package lox
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpreterPool(t *testing.T) {
+	assert := assert.New(t)
+
+	pool, err := NewInterpreterPool([]byte(`
+var count = 0;
+fun inc(n) { count = count + n; return count; }
+`), func(in *Interpreter) {
+		in.RegisterNative("ten", 0, func(args []Value) (Value, error) {
+			return NumberValue(10), nil
+		})
+	})
+	assert.NoError(err)
+
+	const workers = 8
+	var wg sync.WaitGroup
+	outputs := make([]string, workers)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 4; j++ {
+				var out strings.Builder
+				reporter := NewSimpleReporter(&out)
+				in, err := pool.Get(&out, reporter)
+				if err != nil {
+					outputs[i] = err.Error()
+					return
+				}
+				statements := parseScript(t, in, fmt.Sprintf("inc(%d);\nprint inc(ten());\nvar defined = true;", i))
+				in.Interpret(statements)
+				pool.Put(in)
+				outputs[i] += out.String()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// every script starts from the globals of the prelude
+	for i, out := range outputs {
+		assert.Equal(strings.Repeat(fmt.Sprintf("%d\n", i+10), 4), out)
+	}
+}
+
+func TestInterpreterPoolStrictVariables(t *testing.T) {
+	assert := assert.New(t)
+
+	pool, err := NewInterpreterPool([]byte("var cfg;"), func(in *Interpreter) {
+		in.SetStrictVariables(true)
+	})
+	assert.NoError(err)
+
+	// the global of the prelude is uninitialized again for every script
+	tests := []struct {
+		script string
+		out    string
+	}{
+		{"cfg = 1;\nprint cfg;", "1\n"},
+		{"print cfg;", "Uninitialized variable 'cfg'.\n[line 1]\n"},
+	}
+	for _, tt := range tests {
+		var out strings.Builder
+		in, err := pool.Get(&out, NewSimpleReporter(&out))
+		assert.NoError(err)
+		in.Interpret(parseScript(t, in, tt.script))
+		pool.Put(in)
+		assert.Equal(tt.out, out.String(), tt.script)
+	}
+}
+
+func TestInterpreterPoolShadowedNative(t *testing.T) {
+	assert := assert.New(t)
+
+	pool, err := NewInterpreterPool([]byte("fun ten() { return 11; }"), func(in *Interpreter) {
+		in.RegisterNative("ten", 0, func(args []Value) (Value, error) {
+			return NumberValue(10), nil
+		})
+	})
+	assert.NoError(err)
+
+	// the function of the prelude still shadows the native one once the
+	// interpreter is back in the pool
+	for i := 0; i < 2; i++ {
+		var out strings.Builder
+		in, err := pool.Get(&out, NewSimpleReporter(&out))
+		assert.NoError(err)
+		in.Interpret(parseScript(t, in, "print ten();"))
+		pool.Put(in)
+		assert.Equal("11\n", out.String())
+	}
+}
+
+func TestInterpreterPoolPreludeError(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := NewInterpreterPool([]byte("var a = ;"), nil)
+	assert.EqualError(err, "[line 1] Error at ';': Expect expression.")
+	_, err = NewInterpreterPool([]byte("fun f() { return; }\nreturn;"), nil)
+	assert.EqualError(err, "[line 2] Error at 'return': Can't return from top-level code.")
+	_, err = NewInterpreterPool([]byte("var a = 1;\nprint -nil;"), nil)
+	assert.EqualError(err, "Operand must be a number.\n[line 2]")
+}
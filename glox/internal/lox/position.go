@@ -0,0 +1,143 @@
+package lox
+
+import "strings"
+
+// Position is a place in a source. Line and Column count from 1, Column in
+// bytes from the start of the line, and Offset is the number of bytes before
+// it.
+type Position struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+	Offset int `json:"offset"`
+}
+
+// Span is the region of the source that a node of the syntax tree was parsed
+// from, Start is the position of its first token and End the position right
+// after its last one, e.g. the span of "a.b(c)" ends after ")". It's zero for
+// the nodes that weren't parsed from a source, e.g. the ones built by the
+// tools that rewrite the tree.
+type Span struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// IsZero returns true if the span isn't known.
+func (s Span) IsZero() bool {
+	return s.Start.Line == 0
+}
+
+// Contains returns true if the byte at the given offset is in the span.
+func (s Span) Contains(offset int) bool {
+	return !s.IsZero() && s.Start.Offset <= offset && offset < s.End.Offset
+}
+
+func (s *Span) span() *Span {
+	return s
+}
+
+// setSpan sets the span of a node from its first to its last token.
+func (s *Span) setSpan(start, end *Token) {
+	s.Start = Position{Line: start.Line, Column: start.Column, Offset: start.Offset}
+	s.End = Position{
+		Line:   end.Line,
+		Column: end.Column + len(end.Lexeme),
+		Offset: end.Offset + len(end.Lexeme),
+	}
+	// a string can span many lines
+	if n := strings.Count(end.Lexeme, "\n"); n > 0 {
+		s.End.Line += n
+		s.End.Column = len(end.Lexeme) - strings.LastIndexByte(end.Lexeme, '\n')
+	}
+}
+
+// exprLine returns the source line of the given expression, or 0 if the
+// expression doesn't hold any token that it can be located with and its span
+// isn't known.
+func exprLine(expr Expr) int {
+	if token := exprToken(expr); token != nil {
+		return token.Line
+	}
+	if expr != nil {
+		return expr.span().Start.Line
+	}
+	return 0
+}
+
+// stmtLine returns the source line of the given statement, or 0 if the
+// statement doesn't hold any token that it can be located with and its span
+// isn't known.
+func stmtLine(stmt Stmt) int {
+	if token := stmtToken(stmt); token != nil {
+		return token.Line
+	}
+	if stmt != nil {
+		return stmt.span().Start.Line
+	}
+	return 0
+}
+
+// exprToken returns the token that the given expression is located with, or
+// nil if it doesn't hold any.
+func exprToken(expr Expr) *Token {
+	switch expr := expr.(type) {
+	case *AssignExpr:
+		return expr.Name
+	case *BinaryExpr:
+		return expr.Op
+	case *CallExpr:
+		return expr.Paren
+	case *ErrorExpr:
+		return expr.Token
+	case *GetExpr:
+		return expr.Name
+	case *GroupExpr:
+		return exprToken(expr.Expr)
+	case *LogicalExpr:
+		return expr.Op
+	case *SetExpr:
+		return expr.Name
+	case *SuperExpr:
+		return expr.Keyword
+	case *ThisExpr:
+		return expr.Keyword
+	case *UnaryExpr:
+		return expr.Op
+	case *VarExpr:
+		return expr.Name
+	}
+	return nil
+}
+
+// stmtToken returns the token that the given statement is located with, or
+// nil if it doesn't hold any.
+func stmtToken(stmt Stmt) *Token {
+	switch stmt := stmt.(type) {
+	case *BlockStmt:
+		for _, inner := range stmt.Stmts {
+			if token := stmtToken(inner); token != nil {
+				return token
+			}
+		}
+	case *ClassStmt:
+		return stmt.Name
+	case *ErrorStmt:
+		if len(stmt.Tokens) > 0 {
+			return stmt.Tokens[0]
+		}
+	case *ExprStmt:
+		return exprToken(stmt.Expr)
+	case *FunctionStmt:
+		return stmt.Name
+	case *IfStmt:
+		return stmt.Keyword
+	case *PrintStmt:
+		return stmt.Keyword
+	case *ReturnStmt:
+		return stmt.Keyword
+	case *VarStmt:
+		return stmt.Name
+	case *WhileStmt:
+		return stmt.Keyword
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package lox
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime/pprof"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// pprofLabel is the key of the pprof label holding the name of the Lox function
+// that is being executed.
+const pprofLabel = "lox_function"
+
+// Profiler records the number of calls and the time spent in each Lox function
+// that is called by the interpreter it's attached to.
+type Profiler struct {
+	entries map[*FunctionStmt]*profileEntry
+	stack   []profileFrame
+}
+
+type profileEntry struct {
+	name  string
+	line  int
+	calls int
+	// total is the time spent in the function including its callees, self is
+	// the time spent in the function alone.
+	total time.Duration
+	self  time.Duration
+	// active is the number of calls to the function that have not returned,
+	// only the outermost one is added to total so recursion isn't counted twice.
+	active int
+}
+
+type profileFrame struct {
+	entry    *profileEntry
+	start    time.Time
+	children time.Duration
+}
+
+// NewProfiler creates a new profiler without any record.
+func NewProfiler() *Profiler {
+	p := new(Profiler)
+	p.entries = make(map[*FunctionStmt]*profileEntry)
+	return p
+}
+
+// SetProfiler attaches the profiler to the interpreter, giving nil detaches the
+// current one.
+func (in *Interpreter) SetProfiler(profiler *Profiler) {
+	in.profiler = profiler
+}
+
+// SetPprofLabels enables or disables labelling the interpreter's goroutine with
+// the name of the Lox function that is being executed, so samples collected
+// by Go's CPU profiler can be grouped by Lox functions. Labelling adds some
+// overhead to every call, so it's disabled by default.
+func (in *Interpreter) SetPprofLabels(enabled bool) {
+	if enabled {
+		in.pprofCtx = context.Background()
+	} else {
+		in.pprofCtx = nil
+	}
+	in.pprofStack = nil
+}
+
+// enterFunction is called right before the body of a Lox function is executed.
+func (in *Interpreter) enterFunction(decl *FunctionStmt) {
+	in.frames = append(in.frames, callFrame{decl: decl, call: in.callSite, callerEnv: in.environment})
+	in.calls++
+	if in.profiler != nil {
+		in.profiler.enter(decl)
+	}
+	if in.pprofCtx != nil {
+		in.pprofStack = append(in.pprofStack, in.pprofCtx)
+		in.pprofCtx = pprof.WithLabels(in.pprofCtx, pprof.Labels(pprofLabel, decl.Name.Lexeme))
+		pprof.SetGoroutineLabels(in.pprofCtx)
+	}
+}
+
+// exitFunction is called right after the body of a Lox function is executed.
+func (in *Interpreter) exitFunction() {
+	in.frames[len(in.frames)-1] = callFrame{}
+	in.frames = in.frames[:len(in.frames)-1]
+	if in.profiler != nil {
+		in.profiler.exit()
+	}
+	if in.pprofCtx != nil {
+		in.pprofCtx = in.pprofStack[len(in.pprofStack)-1]
+		in.pprofStack = in.pprofStack[:len(in.pprofStack)-1]
+		pprof.SetGoroutineLabels(in.pprofCtx)
+	}
+}
+
+func (p *Profiler) enter(decl *FunctionStmt) {
+	entry, ok := p.entries[decl]
+	if !ok {
+		entry = new(profileEntry)
+		entry.name = decl.Name.Lexeme
+		entry.line = decl.Name.Line
+		p.entries[decl] = entry
+	}
+	entry.calls++
+	entry.active++
+	p.stack = append(p.stack, profileFrame{entry: entry, start: time.Now()})
+}
+
+func (p *Profiler) exit() {
+	frame := p.stack[len(p.stack)-1]
+	p.stack = p.stack[:len(p.stack)-1]
+
+	elapsed := time.Since(frame.start)
+	frame.entry.self += elapsed - frame.children
+	frame.entry.active--
+	if frame.entry.active == 0 {
+		frame.entry.total += elapsed
+	}
+	if len(p.stack) > 0 {
+		p.stack[len(p.stack)-1].children += elapsed
+	}
+}
+
+// WriteReport writes a table of the recorded functions, sorted by the total
+// time spent in each of them, to the given writer.
+func (p *Profiler) WriteReport(w io.Writer) error {
+	entries := make([]*profileEntry, 0, len(p.entries))
+	for _, entry := range p.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].total != entries[j].total {
+			return entries[i].total > entries[j].total
+		}
+		return entries[i].line < entries[j].line
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(tw, "calls\ttotal\tself\tfunction\t")
+	for _, entry := range entries {
+		fmt.Fprintf(tw, "%d\t%v\t%v\t%s (line %d)\t\n",
+			entry.calls, entry.total, entry.self, entry.name, entry.line)
+	}
+	return tw.Flush()
+}
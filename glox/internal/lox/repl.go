@@ -0,0 +1,256 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Incomplete returns true if the source that was entered in a REPL is the
+// beginning of a longer input, so the REPL should read another line instead
+// of reporting errors. That's the case if the source ends within a string or
+// a comment, if a parenthesis or a brace is still open, or if the parser only
+// failed because it reached the end while expecting more, e.g. after an
+// operator or the condition of an "if". A missing semicolon at the end is an
+// error instead, since "print a" can't be what the user meant to continue.
+func Incomplete(source []byte) bool {
+	reporter := new(errorRecorder)
+	tokens := NewScanner(source, reporter).Scan()
+	for _, err := range reporter.errs {
+		if serr, ok := err.(*scanError); ok && strings.HasPrefix(serr.message, "Unterminated") {
+			return true
+		}
+	}
+	if reporter.HadError() {
+		return false
+	}
+
+	depth := 0
+	for _, tok := range tokens {
+		switch tok.Type {
+		case L_PAREN, L_BRACE:
+			depth++
+		case R_PAREN, R_BRACE:
+			depth--
+		}
+		// a bracket that closes nothing is an error that more input can't fix
+		if depth < 0 {
+			return false
+		}
+	}
+	if depth > 0 {
+		return true
+	}
+
+	NewParser(tokens, reporter).Parse()
+	if !reporter.HadError() {
+		return false
+	}
+	for _, err := range reporter.errs {
+		cerr, ok := err.(*compileError)
+		if !ok || cerr.token.Type != EOF || strings.HasPrefix(cerr.message, "Expect ';'") {
+			return false
+		}
+	}
+	return true
+}
+
+// Complete returns the words that the name at the end of the input can be
+// completed to, sorted and including the part that was already typed. After a
+// '.', the words are the fields and methods of the receiver, otherwise they
+// are the keywords and the global variables. The receiver is only known if it
+// is a global variable followed by property accesses, e.g. "a.b.", since it's
+// looked up without running any code.
+func (in *Interpreter) Complete(input string) []string {
+	start := len(input)
+	for start > 0 && isWordChar(input[start-1]) {
+		start--
+	}
+	prefix := input[start:]
+	if prefix != "" && isDigit(prefix[0]) {
+		return nil
+	}
+
+	var words []string
+	if start > 0 && input[start-1] == '.' {
+		words = in.properties(input[:start-1])
+	} else {
+		for keyword := range KeywordTokens {
+			if keyword != "eof" {
+				words = append(words, keyword)
+			}
+		}
+		for name := range in.globals.values {
+			words = append(words, name)
+		}
+	}
+
+	var completions []string
+	for _, word := range words {
+		if strings.HasPrefix(word, prefix) {
+			completions = append(completions, word)
+		}
+	}
+	sort.Strings(completions)
+	return completions
+}
+
+// properties returns the names of the fields and methods of the receiver at
+// the end of the input, or nothing if it isn't a path of names leading to an
+// instance.
+func (in *Interpreter) properties(input string) []string {
+	end := len(input)
+	start := end
+	for {
+		nameStart := start
+		for nameStart > 0 && isWordChar(input[nameStart-1]) {
+			nameStart--
+		}
+		if nameStart == start || isDigit(input[nameStart]) {
+			return nil
+		}
+		start = nameStart
+		if start == 0 || input[start-1] != '.' {
+			break
+		}
+		start--
+	}
+
+	path := strings.Split(input[start:end], ".")
+	val, ok := in.globals.values[path[0]]
+	for _, name := range path[1:] {
+		inst, isInstance := val.(*instance)
+		if !ok || !isInstance {
+			return nil
+		}
+		val, ok = inst.fields[name]
+	}
+	inst, isInstance := val.(*instance)
+	if !ok || !isInstance {
+		return nil
+	}
+	names := make([]string, 0, len(inst.fields)+len(inst.class.methods))
+	for name := range inst.fields {
+		names = append(names, name)
+	}
+	for name := range inst.class.methods {
+		if _, ok := inst.fields[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// TypeOf evaluates an expression in the global scope and describes the type of
+// its value: "nil", "boolean", "number", "string", "instance of" its class,
+// or the kind of callable it is with its name and its arity, e.g. "function
+// f, arity 2", "method get of Point, arity 0", or "class B < A, arity 1". The
+// expression can have side effects, e.g. calls.
+func (in *Interpreter) TypeOf(source string) (string, error) {
+	expr, err := in.parseGlobalExpr(source)
+	if err != nil {
+		return "", err
+	}
+	val, err := in.evalGlobalExpr(expr)
+	if err != nil {
+		return "", err
+	}
+	return typeOf(val), nil
+}
+
+// Timing is the measurement of the evaluation of an expression.
+type Timing struct {
+	// Value is the value of the expression formatted the way "print" writes
+	// it.
+	Value   string
+	Elapsed time.Duration
+	// Calls is the number of calls to Lox functions that were made, including
+	// methods and initializers.
+	Calls int
+}
+
+// Time evaluates an expression in the global scope, and measures how long it
+// took and how many calls were made. Parsing the expression isn't measured.
+func (in *Interpreter) Time(source string) (*Timing, error) {
+	expr, err := in.parseGlobalExpr(source)
+	if err != nil {
+		return nil, err
+	}
+	calls := in.calls
+	start := time.Now()
+	val, err := in.evalGlobalExpr(expr)
+	elapsed := time.Since(start)
+	if err != nil {
+		return nil, err
+	}
+	timing := new(Timing)
+	timing.Value = stringify(val)
+	timing.Elapsed = elapsed
+	timing.Calls = in.calls - calls
+	return timing, nil
+}
+
+// parseGlobalExpr parses an expression and resolves it in the global scope,
+// the error has the messages that the reporter would show.
+func (in *Interpreter) parseGlobalExpr(source string) (Expr, error) {
+	var errs strings.Builder
+	reporter := NewSimpleReporter(&errs)
+	tokens := NewScanner([]byte(source), reporter).Scan()
+	expr := NewParser(tokens, reporter).ParseExpr()
+	if !reporter.HadError() {
+		NewResolver(in, reporter).resolveExpr(expr)
+	}
+	if reporter.HadError() {
+		return nil, errors.New(strings.TrimSpace(errs.String()))
+	}
+	return expr, nil
+}
+
+// evalGlobalExpr evaluates an expression from parseGlobalExpr. A runtime
+// error only has its message, since the line is in the expression rather
+// than in a script.
+func (in *Interpreter) evalGlobalExpr(expr Expr) (interface{}, error) {
+	in.limits.start()
+	val, err := in.eval(expr)
+	// the expression is thrown away, so is its resolution
+	forgetLocals(in, expr)
+	if err != nil {
+		if rerr, ok := err.(*runtimeError); ok {
+			return nil, errors.New(rerr.message)
+		}
+		return nil, err
+	}
+	return val, nil
+}
+
+func typeOf(val interface{}) string {
+	switch val := val.(type) {
+	case nil:
+		return "nil"
+	case bool:
+		return "boolean"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case *instance:
+		return "instance of " + val.class.name
+	case *class:
+		name := val.name
+		if val.super != nil {
+			name += " < " + val.super.name
+		}
+		return fmt.Sprintf("class %s, arity %d", name, val.arity())
+	case *function:
+		// a method is bound to the instance that it was accessed on
+		if this, ok := val.closure.values["this"].(*instance); ok {
+			return fmt.Sprintf("method %s of %s, arity %d", val.decl.Name.Lexeme, this.class.name, val.arity())
+		}
+		return fmt.Sprintf("function %s, arity %d", val.decl.Name.Lexeme, val.arity())
+	case callable:
+		return fmt.Sprintf("native function, arity %d", val.arity())
+	}
+	return fmt.Sprintf("%T", val)
+}
@@ -0,0 +1,136 @@
+package lox
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIncomplete(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, source := range []string{
+		"fun f() {",
+		"class A {\n  init() {\n",
+		"print (1 +",
+		"print 1 +",
+		"var a =",
+		"if (a)",
+		"if (a) print a; else",
+		"fun f()",
+		"print \"multi\nline",
+		"/* comment",
+		"{ print a",
+	} {
+		assert.True(Incomplete([]byte(source)), source)
+	}
+	for _, source := range []string{
+		"",
+		"print 1;",
+		"fun f() {}",
+		"print a",
+		"var a = 1",
+		"print 1 + ;",
+		"print 1);",
+		"}",
+		"print @",
+		"class A { init() {} }",
+	} {
+		assert.False(Incomplete([]byte(source)), source)
+	}
+}
+
+func TestInterpreterComplete(t *testing.T) {
+	assert := assert.New(t)
+
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), true)
+	in.Interpret(parseScript(t, in, `
+class Point {
+  init(x) { this.x = x; this.next = nil; }
+  extend() { this.next = Point(this.x + 1); }
+}
+var point = Point(1);
+point.extend();
+var printed = 0;
+`))
+
+	assert.Equal([]string{"print", "printErr", "printed"}, in.Complete("pri"))
+	assert.Equal([]string{"class", "clock"}, in.Complete("var a = cl"))
+	assert.Subset(in.Complete("print (1 + "), []string{"Point", "point", "this"})
+	assert.Equal([]string{"extend", "init", "next", "x"}, in.Complete("point."))
+	assert.Equal([]string{"next"}, in.Complete("print point.next.n"))
+	assert.Equal([]string{"x"}, in.Complete("point.next.x"))
+	assert.Empty(in.Complete("point.next.next."))
+	assert.Empty(in.Complete("point.x."))
+	assert.Empty(in.Complete("point()."))
+	assert.Empty(in.Complete("Point."))
+	assert.Empty(in.Complete("unknown."))
+	assert.Empty(in.Complete("print 1"))
+}
+
+func TestInterpreterTypeOf(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(ioutil.Discard), true)
+	in.Interpret(parseScript(t, in, `
+class A {
+  init(x) {}
+  get() { return 1; }
+}
+class B < A {}
+fun f(a, b) { print "called"; return A; }
+var b = B(1);
+`))
+
+	for source, expected := range map[string]string{
+		"nil":       "nil",
+		"1 < 2":     "boolean",
+		"-1":        "number",
+		"\"s\"":     "string",
+		"b":         "instance of B",
+		"A":         "class A, arity 1",
+		"B":         "class B < A, arity 1",
+		"f":         "function f, arity 2",
+		"b.get":     "method get of B, arity 0",
+		"clock":     "native function, arity 0",
+		"f(1, 2)":   "class A, arity 1",
+		"(b.x = f)": "function f, arity 2",
+	} {
+		typ, err := in.TypeOf(source)
+		assert.Nil(err, source)
+		assert.Equal(expected, typ, source)
+	}
+	assert.Equal("called\n", out.String())
+	assert.Empty(in.locals)
+
+	_, err := in.TypeOf("1 +")
+	assert.EqualError(err, "[line 1] Error at end: Expect expression.")
+	_, err = in.TypeOf("unknown")
+	assert.EqualError(err, "Undefined variable 'unknown'.")
+}
+
+func TestInterpreterTime(t *testing.T) {
+	assert := assert.New(t)
+
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), true)
+	in.Interpret(parseScript(t, in, fibScript+`
+class A { init() {} get() { return fib(3); } }
+`))
+
+	timing, err := in.Time("fib(10)")
+	assert.Nil(err)
+	assert.Equal("55", timing.Value)
+	assert.Equal(177, timing.Calls)
+	assert.True(timing.Elapsed > 0)
+
+	timing, err = in.Time("A().get() + 1")
+	assert.Nil(err)
+	assert.Equal("3", timing.Value)
+	assert.Equal(2+5, timing.Calls)
+
+	_, err = in.Time("fib(nil)")
+	assert.EqualError(err, "Operands must be numbers.")
+}
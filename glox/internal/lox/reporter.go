@@ -1,8 +1,13 @@
 package lox
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
+	"strconv"
+	"strings"
+	"unicode/utf8"
 )
 
 // Reporter defines the interface for structure that can display errors to the
@@ -10,17 +15,286 @@ import (
 // displaying code. Fully-features languages have a complex setup for reporting
 // errors to user.
 type Reporter interface {
-	Report(err error)
+	Report(d *Diagnostic)
 	Reset()
 	HadError() bool
 	HadRuntimeError() bool
 }
 
+// Severity tells how serious the problem of a diagnostic is.
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Stage is the part of the pipeline that found the problem of a diagnostic.
+type Stage int
+
+const (
+	StageScan Stage = iota
+	StageParse
+	StageResolve
+	StageLint
+	StageRuntime
+)
+
+var stageNames = []string{"scan", "parse", "resolve", "lint", "runtime"}
+
+func (s Stage) String() string {
+	return stageNames[s]
+}
+
+// Diagnostic is a problem found in a script, which is given to a reporter. The
+// fields let reporters show the problem in other ways than the message of
+// the error, e.g. as JSON or to an editor.
+type Diagnostic struct {
+	Severity Severity
+	Stage    Stage
+	// Code identifies the kind of problem, it's empty for the errors that
+	// don't come from the interpreter, e.g. the ones of the host program.
+	Code Code
+	// Line and Column locate the problem, counting from 1, they're 0 when
+	// it's not known, e.g. Column for the warnings of the linter. Offset is the
+	// number of bytes before the problem in the source when Column is known,
+	// and Length is the number of bytes of the source that the problem spans
+	// from there, e.g. the lexeme of the token at fault.
+	Line   int
+	Column int
+	Offset int
+	Length int
+	// Message describes the problem without its location.
+	Message string
+	// Hint suggests how the problem can be fixed, it's empty if there's no
+	// suggestion.
+	Hint string
+	// Stack holds the calls that a runtime error went through, starting with
+	// the innermost one, it's empty if the error happened at the top level.
+	Stack []StackFrame
+	// Err is the error that the diagnostic was made from, which can be
+	// matched with errors.Is or errors.As on the diagnostic.
+	Err error
+}
+
+// newDiagnostic creates the diagnostic for an error found at the given stage.
+func newDiagnostic(stage Stage, err error) *Diagnostic {
+	d := new(Diagnostic)
+	d.Severity = SeverityError
+	d.Stage = stage
+	d.Message = err.Error()
+	d.Err = err
+	switch err := err.(type) {
+	case *scanError:
+		d.Line = err.line
+		d.Column = err.column
+		d.Offset = err.offset
+		d.Length = err.length
+		d.Code = err.code
+		d.Message = err.message
+	case *compileError:
+		d.Line = err.token.Line
+		d.Column = err.token.Column
+		d.Offset = err.token.Offset
+		d.Length = len(err.token.Lexeme)
+		d.Code = err.code
+		d.Message = err.message
+		if err.warning {
+			d.Severity = SeverityWarning
+		}
+	case *runtimeError:
+		if err.token != nil {
+			d.Line = err.token.Line
+			d.Column = err.token.Column
+			d.Offset = err.token.Offset
+			d.Length = len(err.token.Lexeme)
+		}
+		d.Code = err.code
+		d.Message = err.message
+		d.Hint = err.hint
+		d.Stack = err.stack
+	case *lintWarning:
+		d.Severity = SeverityWarning
+		d.Line = err.line
+		d.Code = err.code
+		d.Message = err.message
+	}
+	return d
+}
+
+// Error formats the diagnostic the way SimpleReporter writes it, which is the
+// message of the error followed by the hint.
+func (d *Diagnostic) Error() string {
+	return d.format(diagnosticFormat{})
+}
+
+// diagnosticFormat holds the settings of a reporter for what a diagnostic
+// shows besides its message, and whether it's colored, see SimpleReporter.
+type diagnosticFormat struct {
+	columns bool
+	source  []byte
+	stack   bool
+	color   bool
+	codes   bool
+}
+
+// diagnosticANSI holds the escape sequences of the parts of the diagnostics
+// that are colored in a terminal: the severities, the lexemes at fault, and
+// the line numbers of the snippets.
+var diagnosticANSI = map[string]string{
+	"error":   "\x1b[1;31m",
+	"warning": "\x1b[1;33m",
+	"lexeme":  "\x1b[1m",
+	"gutter":  "\x1b[34m",
+}
+
+// paint colors the text as the given part of a diagnostic if colors are
+// wanted.
+func (f diagnosticFormat) paint(text string, part string) string {
+	if !f.color || text == "" {
+		return text
+	}
+	return diagnosticANSI[part] + text + "\x1b[0m"
+}
+
+// withCode appends the code to the first line of the message if the codes are
+// wanted.
+func (f diagnosticFormat) withCode(message string, code Code) string {
+	if !f.codes || code == "" {
+		return message
+	}
+	return message + " [" + string(code) + "]"
+}
+
+// format formats the diagnostic, with the column next to the line if it's
+// wanted and the error can show it, the snippet of the source if one is
+// given, and the stack of the calls if it's wanted.
+func (d *Diagnostic) format(f diagnosticFormat) string {
+	message := d.Err.Error()
+	if p, ok := d.Err.(positionFormatter); ok {
+		message = p.format(f)
+	}
+	if snippet := d.snippet(f); snippet != "" {
+		message += "\n" + snippet
+	}
+	if f.stack && len(d.Stack) > 0 {
+		message += "\n" + formatStack(d.Stack)
+	}
+	if d.Hint == "" {
+		return message
+	}
+	return message + "\nHint: " + d.Hint
+}
+
+// formatStack writes a frame per line, the frames repeated in a row, e.g. by
+// a recursion that overflowed the stack, are written once with their count.
+func formatStack(stack []StackFrame) string {
+	lines := make([]string, 0, len(stack))
+	for i := 0; i < len(stack); {
+		j := i + 1
+		for j < len(stack) && stack[j] == stack[i] {
+			j++
+		}
+		if j-i > 1 {
+			lines = append(lines, fmt.Sprintf("%s (%d times)", stack[i], j-i))
+		} else {
+			lines = append(lines, stack[i].String())
+		}
+		i = j
+	}
+	return strings.Join(lines, "\n")
+}
+
+// snippet returns the line of the source where the problem is, numbered, with
+// the span of the problem underlined, e.g.
+//
+//	2 | print a +;
+//	  |          ^
+//
+// It's empty if the problem isn't located in the source, e.g. it's in a
+// function that was defined by another script. With colors, the span is
+// highlighted in the line and the underline has the color of the severity.
+func (d *Diagnostic) snippet(f diagnosticFormat) string {
+	source := f.source
+	if len(source) == 0 || d.Column == 0 || d.Offset > len(source) {
+		return ""
+	}
+	start := bytes.LastIndexByte(source[:d.Offset], '\n') + 1
+	end := bytes.IndexByte(source[d.Offset:], '\n')
+	if end < 0 {
+		end = len(source)
+	} else {
+		end += d.Offset
+	}
+	if bytes.Count(source[:start], []byte{'\n'})+1 != d.Line ||
+		d.Offset-start+1 != d.Column {
+		return ""
+	}
+	// the tabs are kept so the underline is aligned with the line
+	var underline strings.Builder
+	for _, r := range string(source[start:d.Offset]) {
+		if r == '\t' {
+			underline.WriteByte('\t')
+		} else {
+			underline.WriteByte(' ')
+		}
+	}
+	underline.WriteByte('^')
+	spanEnd := d.Offset + d.Length
+	if spanEnd > end {
+		spanEnd = end
+	}
+	if n := utf8.RuneCount(source[d.Offset:spanEnd]); n > 1 {
+		underline.WriteString(strings.Repeat("~", n-1))
+	}
+	line := string(source[start:d.Offset]) +
+		f.paint(strings.TrimRight(string(source[d.Offset:spanEnd]), "\r"), "lexeme") +
+		strings.TrimRight(string(source[spanEnd:end]), "\r")
+	number := strconv.Itoa(d.Line)
+	// the spaces are left out of the colors so the underline stays aligned
+	carets := strings.TrimLeft(underline.String(), " \t")
+	indent := underline.String()[:underline.Len()-len(carets)]
+	return fmt.Sprintf(
+		"%s %s\n%s %s%s",
+		f.paint(number+" |", "gutter"), line,
+		f.paint(strings.Repeat(" ", len(number))+" |", "gutter"),
+		indent, f.paint(carets, d.Severity.String()),
+	)
+}
+
+func (d *Diagnostic) Unwrap() error {
+	return d.Err
+}
+
 // SimpleReporter writes error as-is to inner writer
 type SimpleReporter struct {
-	writer        io.Writer
+	writer io.Writer
+	format diagnosticFormat
+	// json is set if the diagnostics are written as JSON, with the name of
+	// the file given to SetFile
+	json          bool
+	file          string
 	hadErr        bool
 	hadRuntimeErr bool
+	hadWarning    bool
+	// warningsAsErrors is set if the warnings count as errors
+	warningsAsErrors bool
+	// suppressed holds the codes of the warnings that aren't reported
+	suppressed map[Code]bool
+	// maxReported is the number of diagnostics that are written before the
+	// next ones are only counted, see SetMaxReported. The counts are since
+	// the last reset or summary.
+	maxReported     int
+	reported        int
+	omittedErrors   int
+	omittedWarnings int
 }
 
 func NewSimpleReporter(writer io.Writer) Reporter {
@@ -31,18 +305,204 @@ func NewSimpleReporter(writer io.Writer) Reporter {
 	return reporter
 }
 
-func (reporter *SimpleReporter) Report(err error) {
-	fmt.Fprintln(reporter.writer, err)
-	if _, isRuntimeErr := err.(*runtimeError); isRuntimeErr {
+// NewColorReporter creates a reporter that colors the errors with ANSI escape
+// sequences, for a terminal. The severities are colored, in red for the
+// errors and in yellow for the warnings, the lexemes at fault are in bold,
+// and the underlines of the snippets, see SetSource, have the color of the
+// severity.
+func NewColorReporter(writer io.Writer) Reporter {
+	reporter := NewSimpleReporter(writer).(*SimpleReporter)
+	reporter.format.color = true
+	return reporter
+}
+
+// NewJSONReporter creates a reporter that writes each diagnostic as a JSON
+// object on its own line, for the programs that read the errors of the
+// scripts, e.g. editors:
+//
+//	{"file":"a.lox","line":2,"column":10,"offset":20,"length":1,"code":"E0101","severity":"error","stage":"parse","message":"Expect expression."}
+//
+// The file is the one given to SetFile, it's left out if there's none, and so
+// is the code. The column, the offset, and the length are 0 if they aren't
+// known, the hint and the stack of the calls are only given if there are
+// some, see Diagnostic for their meaning.
+func NewJSONReporter(writer io.Writer) Reporter {
+	reporter := NewSimpleReporter(writer).(*SimpleReporter)
+	reporter.json = true
+	return reporter
+}
+
+// jsonDiagnostic is the JSON object of a diagnostic, see NewJSONReporter.
+type jsonDiagnostic struct {
+	File     string       `json:"file,omitempty"`
+	Line     int          `json:"line"`
+	Column   int          `json:"column"`
+	Offset   int          `json:"offset"`
+	Length   int          `json:"length"`
+	Code     Code         `json:"code,omitempty"`
+	Severity string       `json:"severity"`
+	Stage    string       `json:"stage"`
+	Message  string       `json:"message"`
+	Hint     string       `json:"hint,omitempty"`
+	Stack    []StackFrame `json:"stack,omitempty"`
+}
+
+// SetFile gives the name of the script whose errors are reported next, which
+// the reporter includes in the JSON diagnostics.
+func (reporter *SimpleReporter) SetFile(name string) {
+	reporter.file = name
+}
+
+// ShowColumns sets whether the errors show the column of their position next
+// to the line, e.g. "[line 2:5]". It's disabled by default, since the test
+// suite of Crafting Interpreters expects only the line.
+func (reporter *SimpleReporter) ShowColumns(enabled bool) {
+	reporter.format.columns = enabled
+}
+
+// SetSource gives the source of the script whose errors are reported next, so
+// the errors show the line where they are with the token at fault underlined.
+// The errors that aren't in the source, e.g. the runtime errors of functions
+// defined by another script, are written without it. A nil source disables
+// the snippets, which is the default.
+func (reporter *SimpleReporter) SetSource(source []byte) {
+	reporter.format.source = source
+}
+
+// ShowStack sets whether the runtime errors show the calls that they went
+// through, innermost first, e.g. "in fib at line 4" and then "at top level
+// line 12". It's disabled by default, since the test suite of Crafting
+// Interpreters expects only the line of the error.
+func (reporter *SimpleReporter) ShowStack(enabled bool) {
+	reporter.format.stack = enabled
+}
+
+// ShowCodes sets whether the errors show their codes at the end of their first
+// line, e.g. "[line 1] Error at ';': Expect expression. [E0101]". It's
+// disabled by default, since the test suite of Crafting Interpreters expects
+// only the messages. The JSON diagnostics always have their codes.
+func (reporter *SimpleReporter) ShowCodes(enabled bool) {
+	reporter.format.codes = enabled
+}
+
+// Suppress stops the reporter from reporting the warnings with the given
+// codes, e.g. "W0202" for the shadowed variables. The errors are always
+// reported, since the scripts can't run with them.
+func (reporter *SimpleReporter) Suppress(codes ...Code) {
+	if reporter.suppressed == nil {
+		reporter.suppressed = make(map[Code]bool)
+	}
+	for _, code := range codes {
+		reporter.suppressed[code] = true
+	}
+}
+
+// SetWarningsAsErrors sets whether the warnings count as errors for
+// HadError, e.g. so a script with warnings isn't run. The warnings are only
+// recorded for HadWarning by default.
+func (reporter *SimpleReporter) SetWarningsAsErrors(enabled bool) {
+	reporter.warningsAsErrors = enabled
+}
+
+// SetMaxReported limits the number of diagnostics that are written, so a
+// badly mangled script doesn't flood the terminal. The diagnostics past the
+// limit still count for HadError and HadRuntimeError, and Summarize writes
+// how many were left out. The JSON diagnostics are never left out since
+// they're read by programs. There's no limit if it's 0, which is the default.
+func (reporter *SimpleReporter) SetMaxReported(limit int) {
+	reporter.maxReported = limit
+}
+
+// Summarize writes how many diagnostics were left out because of the limit
+// given to SetMaxReported since the last reset or summary, e.g. "... and 12
+// more errors.", and nothing if there's none. The diagnostics reported after
+// it are written again, up to the limit.
+func (reporter *SimpleReporter) Summarize() {
+	var omitted []string
+	if n := reporter.omittedErrors; n > 0 {
+		omitted = append(omitted, pluralize(n, "more error"))
+	}
+	if n := reporter.omittedWarnings; n > 0 {
+		omitted = append(omitted, pluralize(n, "more warning"))
+	}
+	if len(omitted) > 0 {
+		fmt.Fprintf(reporter.writer, "... and %s.\n", strings.Join(omitted, " and "))
+	}
+	reporter.reported = 0
+	reporter.omittedErrors = 0
+	reporter.omittedWarnings = 0
+}
+
+// pluralize returns the count followed by the noun, which gets an "s" if the
+// count isn't 1.
+func pluralize(n int, noun string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, noun)
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+func (reporter *SimpleReporter) Report(d *Diagnostic) {
+	if d.Severity == SeverityWarning && reporter.suppressed[d.Code] {
+		return
+	}
+	switch {
+	case reporter.json:
+		reporter.writeJSON(d)
+	case reporter.maxReported > 0 && reporter.reported >= reporter.maxReported:
+		if d.Severity == SeverityWarning {
+			reporter.omittedWarnings++
+		} else {
+			reporter.omittedErrors++
+		}
+	default:
+		fmt.Fprintln(reporter.writer, d.format(reporter.format))
+		reporter.reported++
+	}
+	switch {
+	case d.Severity == SeverityWarning:
+		reporter.hadWarning = true
+		if reporter.warningsAsErrors {
+			reporter.hadErr = true
+		}
+	case d.Stage == StageRuntime:
 		reporter.hadRuntimeErr = true
-	} else {
+	default:
 		reporter.hadErr = true
 	}
 }
 
+func (reporter *SimpleReporter) writeJSON(d *Diagnostic) {
+	obj := new(jsonDiagnostic)
+	obj.File = reporter.file
+	obj.Code = d.Code
+	obj.Line = d.Line
+	obj.Column = d.Column
+	obj.Offset = d.Offset
+	obj.Length = d.Length
+	obj.Severity = d.Severity.String()
+	obj.Stage = d.Stage.String()
+	obj.Message = d.Message
+	obj.Hint = d.Hint
+	obj.Stack = d.Stack
+	enc := json.NewEncoder(reporter.writer)
+	// names such as "<stdin>" are kept as they are
+	enc.SetEscapeHTML(false)
+	enc.Encode(obj)
+}
+
 func (reporter *SimpleReporter) Reset() {
 	reporter.hadErr = false
 	reporter.hadRuntimeErr = false
+	reporter.hadWarning = false
+	reporter.reported = 0
+	reporter.omittedErrors = 0
+	reporter.omittedWarnings = 0
+}
+
+// HadWarning returns whether a warning was reported since the last reset.
+func (reporter *SimpleReporter) HadWarning() bool {
+	return reporter.hadWarning
 }
 
 func (reporter *SimpleReporter) HadError() bool {
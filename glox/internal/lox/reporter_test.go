@@ -25,7 +25,7 @@ func TestSimpleReporterSendAnyError(t *testing.T) {
 
 	var out strings.Builder
 	r := NewSimpleReporter(&out)
-	r.Report(err)
+	r.Report(newDiagnostic(StageParse, err))
 
 	assert.Equal(fmt.Sprintf("%v\n", err), out.String())
 	assert.True(r.HadError())
@@ -34,11 +34,11 @@ func TestSimpleReporterSendAnyError(t *testing.T) {
 
 func TestSimpleReporterSendRuntimeError(t *testing.T) {
 	assert := assert.New(t)
-	err := newRuntimeError(NewToken(MINUS, "-", nil, 1), "Operand must be numbers.")
+	err := newRuntimeError(NewToken(MINUS, "-", nil, 1), CodeOperandType, "Operand must be numbers.")
 
 	var out strings.Builder
 	r := NewSimpleReporter(&out)
-	r.Report(err)
+	r.Report(newDiagnostic(StageRuntime, err))
 
 	assert.Equal(fmt.Sprintf("%v\n", err), out.String())
 	assert.False(r.HadError())
@@ -48,12 +48,12 @@ func TestSimpleReporterSendRuntimeError(t *testing.T) {
 func TestSimpleReporterSendErrors(t *testing.T) {
 	assert := assert.New(t)
 	err1 := errors.New("Test error")
-	err2 := newRuntimeError(NewToken(MINUS, "-", nil, 1), "Operand must be numbers.")
+	err2 := newRuntimeError(NewToken(MINUS, "-", nil, 1), CodeOperandType, "Operand must be numbers.")
 
 	var out strings.Builder
 	r := NewSimpleReporter(&out)
-	r.Report(err1)
-	r.Report(err2)
+	r.Report(newDiagnostic(StageParse, err1))
+	r.Report(newDiagnostic(StageRuntime, err2))
 
 	assert.Equal(fmt.Sprintf("%v\n%v\n", err1, err2), out.String())
 	assert.True(r.HadError())
@@ -63,14 +63,198 @@ func TestSimpleReporterSendErrors(t *testing.T) {
 func TestSimpleReporterReset(t *testing.T) {
 	assert := assert.New(t)
 	err1 := errors.New("Test error")
-	err2 := newRuntimeError(NewToken(MINUS, "-", nil, 1), "Operand must be numbers.")
+	err2 := newRuntimeError(NewToken(MINUS, "-", nil, 1), CodeOperandType, "Operand must be numbers.")
 
 	var out strings.Builder
 	r := NewSimpleReporter(&out)
-	r.Report(err1)
-	r.Report(err2)
+	r.Report(newDiagnostic(StageParse, err1))
+	r.Report(newDiagnostic(StageRuntime, err2))
 
 	r.Reset()
 	assert.False(r.HadRuntimeError())
 	assert.False(r.HadError())
 }
+
+func TestSimpleReporterSetMaxReported(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	r := NewSimpleReporter(&out).(*SimpleReporter)
+	r.SetMaxReported(2)
+	for i := 1; i <= 5; i++ {
+		r.Report(newDiagnostic(StageParse, newCompileError(NewToken(SEMICOLON, ";", nil, i), CodeUnexpectedToken, "Expect expression.")))
+	}
+	r.Report(newDiagnostic(StageResolve, newCompileWarning(NewToken(IDENT, "a", nil, 6), CodeUnusedLocal, "Unused.")))
+	r.Summarize()
+	assert.Equal(`[line 1] Error at ';': Expect expression.
+[line 2] Error at ';': Expect expression.
+... and 3 more errors and 1 more warning.
+`, out.String())
+	assert.True(r.HadError())
+	assert.True(r.HadWarning())
+
+	// the diagnostics are written again after the summary, which says nothing
+	// if none was left out
+	out.Reset()
+	r.Report(newDiagnostic(StageParse, newCompileError(NewToken(SEMICOLON, ";", nil, 7), CodeUnexpectedToken, "Expect expression.")))
+	r.Summarize()
+	assert.Equal("[line 7] Error at ';': Expect expression.\n", out.String())
+}
+
+func TestColorReporter(t *testing.T) {
+	assert := assert.New(t)
+	err := newRuntimeError(NewToken(MINUS, "-", nil, 1), CodeOperandType, "Operand must be a number.")
+
+	var out strings.Builder
+	r := NewColorReporter(&out)
+	r.Report(newDiagnostic(StageRuntime, err))
+
+	assert.Equal("\x1b[1;31mOperand must be a number.\x1b[0m\n[line 1]\n", out.String())
+	assert.True(r.HadRuntimeError())
+
+	// the severity, the lexeme, and the underline are colored
+	out.Reset()
+	source := []byte("var a = 1;\nprint a +;")
+	r.(*SimpleReporter).SetSource(source)
+	NewParser(NewScanner(source, r).Scan(), r).Parse()
+	r.Report(newDiagnostic(StageResolve, newCompileWarning(NewToken(IDENT, "a", nil, 1), CodeUnusedLocal, "Unused.")))
+	assert.Equal(
+		"[line 2] \x1b[1;31mError\x1b[0m at '\x1b[1m;\x1b[0m': Expect expression.\n"+
+			"\x1b[34m2 |\x1b[0m print a +\x1b[1m;\x1b[0m\n"+
+			"\x1b[34m  |\x1b[0m          \x1b[1;31m^\x1b[0m\n"+
+			"[line 1] \x1b[1;33mWarning\x1b[0m at '\x1b[1ma\x1b[0m': Unused.\n",
+		out.String(),
+	)
+}
+
+func TestDiagnostic(t *testing.T) {
+	assert := assert.New(t)
+
+	token := NewToken(IDENT, "a", nil, 2)
+	token.Column = 5
+	d := newDiagnostic(StageResolve, newCompileError(token, CodeAlreadyDeclared, "Already a variable with this name in this scope."))
+	assert.Equal(SeverityError, d.Severity)
+	assert.Equal(StageResolve, d.Stage)
+	assert.Equal(2, d.Line)
+	assert.Equal(5, d.Column)
+	assert.Equal("Already a variable with this name in this scope.", d.Message)
+	assert.Equal("[line 2] Error at 'a': Already a variable with this name in this scope.", d.Error())
+	d.Hint = "Rename one of the variables."
+	assert.Equal("[line 2] Error at 'a': Already a variable with this name in this scope.\nHint: Rename one of the variables.", d.Error())
+
+	d = newDiagnostic(StageScan, newScanError(3, 2, 10, CodeUnexpectedCharacter, "Unexpected character."))
+	assert.Equal(3, d.Line)
+	assert.Equal(2, d.Column)
+	assert.Equal(10, d.Offset)
+	assert.Equal("scan", d.Stage.String())
+	assert.Equal("[line 3:2] Error: Unexpected character.", d.format(diagnosticFormat{columns: true}))
+	assert.Equal("[line 3] Error: Unexpected character.", d.Error())
+
+	d = newDiagnostic(StageLint, newLintWarning(4, LintUnusedVariable, "Unused variable 'a'."))
+	assert.Equal(SeverityWarning, d.Severity)
+	assert.Equal("warning", d.Severity.String())
+	assert.Equal(4, d.Line)
+	assert.Equal(0, d.Column)
+	assert.Equal(d.Error(), d.format(diagnosticFormat{columns: true}))
+
+	err := newLimitError(nil, CodeStepLimit, "Execution step limit exceeded.")
+	d = newDiagnostic(StageRuntime, err)
+	assert.Equal(0, d.Line)
+	assert.True(errors.Is(d, ErrLimitExceeded))
+}
+
+func TestSimpleReporterShowColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	r := NewSimpleReporter(&out).(*SimpleReporter)
+	r.ShowColumns(true)
+	tokens := NewScanner([]byte("var a = 1;\nprint a +;\nprint -nil;"), r).Scan()
+	statements := NewParser(tokens, r).Parse()
+	NewScanner([]byte("print 1;\n  @"), r).Scan()
+	in := NewInterpreter(ioutil.Discard, r, false)
+	in.Interpret(parseScript(t, in, "var a = 1;\nprint   -nil;"))
+	assert.Len(statements, 3)
+	assert.Equal("[line 2:10] Error at ';': Expect expression.\n[line 2:3] Error: Unexpected character.\nOperand must be a number.\n[line 2:9]\n", out.String())
+}
+
+func TestSimpleReporterSetSource(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	r := NewSimpleReporter(&out).(*SimpleReporter)
+	source := []byte("var a = 1;\n\tprint a +;\nprint a @ \"b\";")
+	r.SetSource(source)
+	NewParser(NewScanner(source, r).Scan(), r).Parse()
+	assert.Equal(
+		"[line 3] Error: Unexpected character.\n"+
+			"3 | print a @ \"b\";\n"+
+			"  |         ^\n"+
+			"[line 2] Error at ';': Expect expression.\n"+
+			"2 | \tprint a +;\n"+
+			"  | \t         ^\n"+
+			"[line 3] Error at '\"b\"': Expect ';' after value.\n"+
+			"3 | print a @ \"b\";\n"+
+			"  |           ^~~\n",
+		out.String(),
+	)
+
+	// the runtime errors of another script aren't shown with the source
+	out.Reset()
+	in := NewInterpreter(ioutil.Discard, r, false)
+	in.Interpret(parseScript(t, in, "fun f() {\n  return -nil;\n}"))
+	r.SetSource([]byte("f();"))
+	in.Interpret(parseScript(t, in, "f();"))
+	assert.Equal("Operand must be a number.\n[line 2]\n", out.String())
+	out.Reset()
+	r.SetSource([]byte("print -\"a\";"))
+	in.Interpret(parseScript(t, in, "print -\"a\";"))
+	assert.Equal("Operand must be a number.\n[line 1]\n1 | print -\"a\";\n  |       ^\n", out.String())
+}
+
+func TestSimpleReporterShowStack(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	r := NewSimpleReporter(&out).(*SimpleReporter)
+	r.ShowStack(true)
+	in := NewInterpreter(ioutil.Discard, r, false)
+	in.Interpret(parseScript(t, in, `fun fib(n) {
+  if (n < 2) return n + nil;
+  return fib(n - 1) + fib(n - 2);
+}
+class A {
+  m() {
+    return fib(3);
+  }
+}
+print A().m();`))
+	assert.Equal(`Operands must be two numbers or two strings.
+[line 2]
+in fib at line 2
+in fib at line 3 (2 times)
+in m at line 7
+at top level line 10
+`, out.String())
+
+	// the errors at the top level have no stack
+	out.Reset()
+	in.Interpret(parseScript(t, in, "print -nil;"))
+	assert.Equal("Operand must be a number.\n[line 1]\n", out.String())
+}
+
+func TestJSONReporter(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	r := NewJSONReporter(&out).(*SimpleReporter)
+	r.SetFile("<stdin>")
+	NewParser(NewScanner([]byte("var a = 1;\nprint a +;"), r).Scan(), r).Parse()
+	assert.True(r.HadError())
+	r.SetFile("")
+	in := NewInterpreter(ioutil.Discard, r, false)
+	in.Interpret(parseScript(t, in, "fun f() {\n  return -nil;\n}\nf();"))
+	assert.Equal(`{"file":"<stdin>","line":2,"column":10,"offset":20,"length":1,"code":"E0101","severity":"error","stage":"parse","message":"Expect expression."}
+{"line":2,"column":10,"offset":19,"length":1,"code":"R0101","severity":"error","stage":"runtime","message":"Operand must be a number.","stack":[{"function":"f","line":2},{"line":4}]}
+`, out.String())
+}
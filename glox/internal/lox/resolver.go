@@ -1,6 +1,10 @@
 package lox
 
-import "container/list"
+import (
+	"container/list"
+	"fmt"
+	"strings"
+)
 
 // Each map reprents a single block scope, variables at the global scope are not
 // tracked by the resolver. If it cannot resolve a variable in the local
@@ -24,40 +28,133 @@ const (
 	classTypeSubclass
 )
 
+// ResolutionTable maps the expressions that use a local variable, or "this"
+// or "super", to the number of scopes between the use and the scope where the
+// variable is declared. The variables of the expressions that aren't in the
+// table are globals.
+type ResolutionTable map[Expr]int
+
 // Resolver performs semantics analysis on the syntax tree.
 type Resolver struct {
-	scopes       *list.List
-	interpreter  *Interpreter
+	scopes *list.List
+	// record is given the depth of each expression that is resolved
+	record       func(expr Expr, steps int)
 	reporter     Reporter
 	currentFn    functionType
 	currentClass classType
+	// warnings is set if the code that is legal but most likely a mistake is
+	// reported, see SetWarnings. locals holds the declarations of the scopes
+	// that were begun by the resolver, and class what's known of the class
+	// being resolved, to find it.
+	warnings bool
+	locals   map[*list.Element]*resolverScope
+	class    *resolverClass
+}
+
+// resolverScope holds the declarations of a local scope, in order so the
+// warnings are reported in source order.
+type resolverScope struct {
+	vars  map[string]*resolverVar
+	order []*resolverVar
+}
+
+// resolverVar is a local declaration, whose name is a parameter if param is
+// set, and which is read if used is set.
+type resolverVar struct {
+	name  *Token
+	param bool
+	used  bool
+}
+
+// resolverClass holds the fields that the methods of a class set on "this",
+// and the parameters of all its methods. params holds the parameters of the
+// method being resolved by name, and initialized the ones that are stored in
+// the field of the same name, e.g. by "this.x = x;".
+type resolverClass struct {
+	fields       map[string]bool
+	methodParams []*Token
+	params       map[string]*Token
+	initialized  map[*Token]bool
+}
+
+func newResolverScope() *resolverScope {
+	s := new(resolverScope)
+	s.vars = make(map[string]*resolverVar)
+	return s
+}
+
+func newResolverClass() *resolverClass {
+	c := new(resolverClass)
+	c.fields = make(map[string]bool)
+	c.initialized = make(map[*Token]bool)
+	return c
 }
 
 func NewResolver(interpreter *Interpreter, reporter Reporter) *Resolver {
 	r := new(Resolver)
 	r.scopes = list.New()
-	r.interpreter = interpreter
+	r.record = interpreter.resolve
 	r.reporter = reporter
 	r.currentFn = functionTypeNone
 	r.currentClass = classTypeNone
 	return r
 }
 
+// Resolve resolves the statements on their own, and returns the table of the
+// resolved expressions and the diagnostics of the errors that were found,
+// e.g. a variable that is read in its own initializer. The table can be given
+// to an interpreter with Interpreter.AddResolutions.
+func Resolve(statements []Stmt) (ResolutionTable, []*Diagnostic) {
+	collector := new(diagnosticCollector)
+	table := make(ResolutionTable)
+	r := NewResolver(nil, collector)
+	r.record = func(expr Expr, steps int) {
+		table[expr] = steps
+	}
+	r.Resolve(statements)
+	return table, collector.diagnostics
+}
+
+// SetWarnings sets whether the resolver reports the code that is legal but
+// most likely a mistake: the local variables, functions, and classes that are
+// never read, the local declarations that shadow a variable of an enclosing
+// scope, the parameters of the methods that have the name of a field of their
+// class without initializing it, and the statements that can't be reached
+// because they come after a 'return'. The names that start with an
+// underscore aren't reported as unused. It's disabled by default, and must be
+// set before resolving. The warnings are reported with SeverityWarning, so
+// they aren't errors unless the reporter is told otherwise, see
+// SimpleReporter.SetWarningsAsErrors.
+func (r *Resolver) SetWarnings(enabled bool) {
+	r.warnings = enabled
+	r.locals = nil
+	if enabled {
+		r.locals = make(map[*list.Element]*resolverScope)
+	}
+}
+
 func (r *Resolver) Resolve(statements []Stmt) {
 	for _, stmt := range statements {
 		r.resolveStmt(stmt)
 	}
 }
 
+// report gives the error to the reporter as a diagnostic of the resolver.
+func (r *Resolver) report(err error) {
+	r.reporter.Report(newDiagnostic(StageResolve, err))
+}
+
 func (r *Resolver) VisitBlockStmt(stmt *BlockStmt) (interface{}, error) {
 	r.beginScope()
-	for _, stmt := range stmt.Stmts {
-		r.resolveStmt(stmt)
-	}
+	r.resolveStmts(stmt.Stmts)
 	r.endScope()
 	return nil, nil
 }
 
+func (r *Resolver) VisitErrorStmt(stmt *ErrorStmt) (interface{}, error) {
+	return nil, nil
+}
+
 func (r *Resolver) VisitExprStmt(stmt *ExprStmt) (interface{}, error) {
 	r.resolveExpr(stmt.Expr)
 	return nil, nil
@@ -66,13 +163,17 @@ func (r *Resolver) VisitExprStmt(stmt *ExprStmt) (interface{}, error) {
 func (r *Resolver) VisitClassStmt(stmt *ClassStmt) (interface{}, error) {
 	enclosingClass := r.currentClass
 	r.currentClass = classTypeClass
+	enclosingInfo := r.class
+	if r.warnings {
+		r.class = newResolverClass()
+	}
 
 	r.declare(stmt.Name)
 	r.define(stmt.Name)
 
 	if stmt.Super != nil {
 		if stmt.Super.Name.Lexeme == stmt.Name.Lexeme {
-			r.reporter.Report(newCompileError(stmt.Super.Name,
+			r.report(newCompileError(stmt.Super.Name, CodeInheritFromSelf,
 				"A class can't inherit from itself."))
 		}
 		r.currentClass = classTypeSubclass
@@ -86,7 +187,13 @@ func (r *Resolver) VisitClassStmt(stmt *ClassStmt) (interface{}, error) {
 	scope := r.scopes.Front().Value.(scopeMap)
 	scope["this"] = true
 
+	methods := make(map[string]bool, len(stmt.Methods))
 	for _, method := range stmt.Methods {
+		if methods[method.Name.Lexeme] {
+			r.report(newCompileError(method.Name, CodeDuplicateMethod,
+				"Already a method with this name in this class."))
+		}
+		methods[method.Name.Lexeme] = true
 		decl := functionTypeMethod
 		if method.Name.Lexeme == "init" {
 			decl = functionTypeInitializer
@@ -98,6 +205,16 @@ func (r *Resolver) VisitClassStmt(stmt *ClassStmt) (interface{}, error) {
 	if stmt.Super != nil {
 		r.endScope()
 	}
+	if r.class != nil {
+		for _, param := range r.class.methodParams {
+			if r.class.fields[param.Lexeme] && !r.class.initialized[param] {
+				r.report(newCompileWarning(param, CodeParameterNamedAfterField, fmt.Sprintf(
+					"Parameter '%s' has the same name as a field of '%s'.",
+					param.Lexeme, stmt.Name.Lexeme)))
+			}
+		}
+	}
+	r.class = enclosingInfo
 	r.currentClass = enclosingClass
 	return nil, nil
 }
@@ -125,12 +242,12 @@ func (r *Resolver) VisitPrintStmt(stmt *PrintStmt) (interface{}, error) {
 
 func (r *Resolver) VisitReturnStmt(stmt *ReturnStmt) (interface{}, error) {
 	if r.currentFn == functionTypeNone {
-		r.reporter.Report(newCompileError(stmt.Keyword,
+		r.report(newCompileError(stmt.Keyword, CodeTopLevelReturn,
 			"Can't return from top-level code."))
 	}
 	if stmt.Val != nil {
 		if r.currentFn == functionTypeInitializer {
-			r.reporter.Report(newCompileError(stmt.Keyword,
+			r.report(newCompileError(stmt.Keyword, CodeInitializerReturn,
 				"Can't return a value from an initializer."))
 		}
 		r.resolveExpr(stmt.Val)
@@ -173,6 +290,10 @@ func (r *Resolver) VisitCallExpr(expr *CallExpr) (interface{}, error) {
 	return nil, nil
 }
 
+func (r *Resolver) VisitErrorExpr(expr *ErrorExpr) (interface{}, error) {
+	return nil, nil
+}
+
 func (r *Resolver) VisitGetExpr(expr *GetExpr) (interface{}, error) {
 	// only resolve the ident on the left of the dot, the properties are dynamic
 	r.resolveExpr(expr.Obj)
@@ -197,15 +318,23 @@ func (r *Resolver) VisitLogicalExpr(expr *LogicalExpr) (interface{}, error) {
 func (r *Resolver) VisitSetExpr(expr *SetExpr) (interface{}, error) {
 	r.resolveExpr(expr.Val)
 	r.resolveExpr(expr.Obj)
+	if _, ok := expr.Obj.(*ThisExpr); ok && r.class != nil {
+		r.class.fields[expr.Name.Lexeme] = true
+		if val, ok := expr.Val.(*VarExpr); ok && val.Name.Lexeme == expr.Name.Lexeme {
+			if param, ok := r.class.params[val.Name.Lexeme]; ok {
+				r.class.initialized[param] = true
+			}
+		}
+	}
 	return nil, nil
 }
 
 func (r *Resolver) VisitSuperExpr(expr *SuperExpr) (interface{}, error) {
 	if r.currentClass == classTypeNone {
-		r.reporter.Report(newCompileError(expr.Keyword,
+		r.report(newCompileError(expr.Keyword, CodeSuperOutsideClass,
 			"Can't use 'super' outside of a class."))
 	} else if r.currentClass == classTypeClass {
-		r.reporter.Report(newCompileError(expr.Keyword,
+		r.report(newCompileError(expr.Keyword, CodeSuperWithoutSuperclass,
 			"Can't use 'super' in a class with no superclass."))
 	}
 
@@ -215,7 +344,7 @@ func (r *Resolver) VisitSuperExpr(expr *SuperExpr) (interface{}, error) {
 
 func (r *Resolver) VisitThisExpr(expr *ThisExpr) (interface{}, error) {
 	if r.currentClass == classTypeNone {
-		r.reporter.Report(newCompileError(expr.Keyword,
+		r.report(newCompileError(expr.Keyword, CodeThisOutsideClass,
 			"Can't use 'this' outside of a class."))
 		return nil, nil
 	}
@@ -232,12 +361,15 @@ func (r *Resolver) VisitVarExpr(expr *VarExpr) (interface{}, error) {
 	if r.scopes.Front() != nil {
 		scopeMap := r.scopes.Front().Value.(scopeMap)
 		if defined, exist := scopeMap[expr.Name.Lexeme]; exist && !defined {
-			r.reporter.Report(newCompileError(expr.Name,
+			r.report(newCompileError(expr.Name, CodeReadInOwnInitializer,
 				"Can't read local variable in its own initializer."))
 		}
 	}
 
 	r.resolveLocal(expr, expr.Name)
+	if r.warnings {
+		r.use(expr.Name)
+	}
 	return nil, nil
 }
 
@@ -247,11 +379,32 @@ func (r *Resolver) resolveFunction(fn *FunctionStmt, fnType functionType) {
 
 	r.beginScope()
 	for _, p := range fn.Params {
+		if _, ok := r.scopes.Front().Value.(scopeMap)[p.Lexeme]; ok {
+			r.report(newCompileError(p, CodeDuplicateParameter,
+				"Already a variable with this name in this scope."))
+			continue
+		}
 		r.declare(p)
 		r.define(p)
+		if scope, ok := r.locals[r.scopes.Front()]; ok {
+			scope.vars[p.Lexeme].param = true
+		}
 	}
-	for _, stmt := range fn.Body {
-		r.resolveStmt(stmt)
+	// the parameters of a method are checked against the fields of its class
+	// once they're all known
+	var enclosingParams map[string]*Token
+	isMethod := fnType == functionTypeMethod || fnType == functionTypeInitializer
+	if isMethod && r.class != nil {
+		enclosingParams = r.class.params
+		r.class.params = make(map[string]*Token, len(fn.Params))
+		for _, p := range fn.Params {
+			r.class.params[p.Lexeme] = p
+			r.class.methodParams = append(r.class.methodParams, p)
+		}
+	}
+	r.resolveStmts(fn.Body)
+	if isMethod && r.class != nil {
+		r.class.params = enclosingParams
 	}
 	r.endScope()
 
@@ -263,13 +416,50 @@ func (r *Resolver) resolveLocal(expr Expr, name *Token) {
 	for scope := r.scopes.Front(); scope != nil; scope = scope.Next() {
 		scopeMap := scope.Value.(scopeMap)
 		if _, ok := scopeMap[name.Lexeme]; ok {
-			r.interpreter.resolve(expr, steps)
+			r.record(expr, steps)
 			return
 		}
 		steps++
 	}
 }
 
+// resolveStmts resolves the statements of a block or of the body of a
+// function, warning about the first one that comes after a 'return'.
+func (r *Resolver) resolveStmts(statements []Stmt) {
+	warned := !r.warnings
+	for i, stmt := range statements {
+		r.resolveStmt(stmt)
+		if !warned && i+1 < len(statements) && alwaysReturns(stmt) {
+			warned = true
+			token := stmtToken(statements[i+1])
+			if token == nil {
+				token = stmtToken(stmt)
+			}
+			r.report(newCompileWarning(token, CodeUnreachableCode,
+				"Unreachable code after 'return'."))
+		}
+	}
+}
+
+// alwaysReturns reports whether running the statement always ends with a
+// 'return', so the statements after it are never run.
+func alwaysReturns(stmt Stmt) bool {
+	switch stmt := stmt.(type) {
+	case *ReturnStmt:
+		return true
+	case *BlockStmt:
+		for _, inner := range stmt.Stmts {
+			if alwaysReturns(inner) {
+				return true
+			}
+		}
+	case *IfStmt:
+		return stmt.ElseBranch != nil &&
+			alwaysReturns(stmt.ThenBranch) && alwaysReturns(stmt.ElseBranch)
+	}
+	return false
+}
+
 // Similar to Interpreter.exec
 func (r *Resolver) resolveStmt(stmt Stmt) {
 	stmt.Accept(r)
@@ -280,24 +470,70 @@ func (r *Resolver) resolveExpr(expr Expr) {
 	expr.Accept(r)
 }
 
+// use marks the local declaration that the name refers to as read.
+func (r *Resolver) use(name *Token) {
+	for scope := r.scopes.Front(); scope != nil; scope = scope.Next() {
+		if _, ok := scope.Value.(scopeMap)[name.Lexeme]; ok {
+			if locals, ok := r.locals[scope]; ok {
+				locals.vars[name.Lexeme].used = true
+			}
+			return
+		}
+	}
+}
+
 // called when resolver enters a new scope
 func (r *Resolver) beginScope() {
-	r.scopes.PushFront(make(scopeMap))
+	scope := r.scopes.PushFront(make(scopeMap))
+	if r.warnings {
+		r.locals[scope] = newResolverScope()
+	}
 }
 
 // called when resolver exits a new scope
 func (r *Resolver) endScope() {
-	r.scopes.Remove(r.scopes.Front())
+	scope := r.scopes.Front()
+	if locals, ok := r.locals[scope]; ok {
+		for _, v := range locals.order {
+			if !v.used && !v.param && !strings.HasPrefix(v.name.Lexeme, "_") {
+				r.report(newCompileWarning(v.name, CodeUnusedLocal, fmt.Sprintf(
+					"Local variable '%s' is never used.", v.name.Lexeme)))
+			}
+		}
+		delete(r.locals, scope)
+	}
+	r.scopes.Remove(scope)
 }
 
 func (r *Resolver) declare(name *Token) {
 	if r.scopes.Front() != nil {
 		scope := r.scopes.Front().Value.(scopeMap)
 		if _, hasName := scope[name.Lexeme]; hasName {
-			r.reporter.Report(newCompileError(name,
+			r.report(newCompileError(name, CodeAlreadyDeclared,
 				"Already a variable with this name in this scope."))
+		} else if r.warnings {
+			r.warnShadow(name)
 		}
 		scope[name.Lexeme] = false
+		if locals, ok := r.locals[r.scopes.Front()]; ok {
+			v := new(resolverVar)
+			v.name = name
+			locals.vars[name.Lexeme] = v
+			locals.order = append(locals.order, v)
+		}
+	}
+}
+
+// warnShadow reports the local declaration of the name if it hides a local
+// variable of an enclosing scope.
+func (r *Resolver) warnShadow(name *Token) {
+	for scope := r.scopes.Front().Next(); scope != nil; scope = scope.Next() {
+		if _, ok := scope.Value.(scopeMap)[name.Lexeme]; ok {
+			r.report(newCompileWarning(name, CodeShadowedVariable, fmt.Sprintf(
+				"Local variable '%s' shadows a variable of an enclosing scope.",
+				name.Lexeme)))
+			return
+		}
 	}
 }
 
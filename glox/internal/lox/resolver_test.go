@@ -0,0 +1,149 @@
+package lox
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResolve(t *testing.T) {
+	assert := assert.New(t)
+
+	statements, diagnostics := Parse([]byte(`var g = 1;
+fun f(a) {
+  var b = a;
+  { print a + b + g; }
+}
+f(2);`))
+	assert.Empty(diagnostics)
+	table, diagnostics := Resolve(statements)
+	assert.Empty(diagnostics)
+
+	depths := make(map[string]int)
+	for expr, depth := range table {
+		if v, ok := expr.(*VarExpr); ok {
+			depths[fmt.Sprintf("%s@%d", v.Name.Lexeme, v.Name.Line)] = depth
+		}
+	}
+	assert.Equal(map[string]int{"a@3": 0, "a@4": 1, "b@4": 1}, depths)
+
+	// the table can be used by an interpreter instead of resolving again
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&out), false)
+	in.AddResolutions(table)
+	in.Interpret(statements)
+	assert.Equal("5\n", out.String())
+
+	statements, _ = Parse([]byte("fun f() { var a = a; }\nreturn 1;"))
+	_, diagnostics = Resolve(statements)
+	if assert.Len(diagnostics, 2) {
+		assert.Equal(StageResolve, diagnostics[0].Stage)
+		assert.Equal("Can't read local variable in its own initializer.", diagnostics[0].Message)
+		assert.Equal(2, diagnostics[1].Line)
+	}
+}
+
+func TestResolverWarnings(t *testing.T) {
+	assert := assert.New(t)
+
+	statements, diagnostics := Parse([]byte(`var g = 1;
+fun f(a, b) {
+  var unused = 1;
+  var _ignored = 2;
+  var g = a;
+  {
+    var a = 3;
+    print a + g;
+  }
+  fun helper() {}
+}
+class Point {
+  init(x, y) {
+    this.x = x;
+    this.y = 0;
+  }
+  move(x) {
+    return this.x + x;
+  }
+}`))
+	assert.Empty(diagnostics)
+	var out strings.Builder
+	reporter := NewSimpleReporter(&out).(*SimpleReporter)
+	r := NewResolver(NewInterpreter(ioutil.Discard, reporter, false), reporter)
+	r.SetWarnings(true)
+	r.Resolve(statements)
+	assert.Equal(`[line 7] Warning at 'a': Local variable 'a' shadows a variable of an enclosing scope.
+[line 3] Warning at 'unused': Local variable 'unused' is never used.
+[line 10] Warning at 'helper': Local variable 'helper' is never used.
+[line 13] Warning at 'y': Parameter 'y' has the same name as a field of 'Point'.
+[line 17] Warning at 'x': Parameter 'x' has the same name as a field of 'Point'.
+`, out.String())
+	assert.True(reporter.HadWarning())
+	assert.False(reporter.HadError())
+
+	// the warnings are errors if the reporter is told so
+	reporter.Reset()
+	reporter.SetWarningsAsErrors(true)
+	r = NewResolver(NewInterpreter(ioutil.Discard, reporter, false), reporter)
+	r.SetWarnings(true)
+	r.Resolve(statements)
+	assert.True(reporter.HadError())
+
+	// no warning is reported by default
+	out.Reset()
+	reporter = NewSimpleReporter(&out).(*SimpleReporter)
+	NewResolver(NewInterpreter(ioutil.Discard, reporter, false), reporter).Resolve(statements)
+	assert.Empty(out.String())
+	assert.False(reporter.HadWarning())
+}
+
+func TestResolverChecks(t *testing.T) {
+	assert := assert.New(t)
+
+	statements, diagnostics := Parse([]byte(`class A < A {
+  init() {
+    return 1;
+  }
+  m(a, b, a) {}
+  m() {}
+}`))
+	assert.Empty(diagnostics)
+	_, diagnostics = Resolve(statements)
+	var codes []Code
+	for _, d := range diagnostics {
+		codes = append(codes, d.Code)
+	}
+	assert.Equal([]Code{CodeInheritFromSelf, CodeInitializerReturn, CodeDuplicateParameter, CodeDuplicateMethod}, codes)
+	if assert.Len(diagnostics, 4) {
+		assert.Equal("[line 5] Error at 'a': Already a variable with this name in this scope.", diagnostics[2].Error())
+		assert.Equal("[line 6] Error at 'm': Already a method with this name in this class.", diagnostics[3].Error())
+	}
+
+	statements, diagnostics = Parse([]byte(`fun f(a) {
+  if (a) {
+    return 1;
+  } else return 2;
+  print a;
+  return 3;
+}
+fun g(a) {
+  if (a) return 1;
+  {
+    return 2;
+    1;
+  }
+}`))
+	assert.Empty(diagnostics)
+	var out strings.Builder
+	reporter := NewSimpleReporter(&out).(*SimpleReporter)
+	r := NewResolver(NewInterpreter(ioutil.Discard, reporter, false), reporter)
+	r.SetWarnings(true)
+	r.Resolve(statements)
+	assert.Equal(`[line 5] Warning at 'print': Unreachable code after 'return'.
+[line 11] Warning at 'return': Unreachable code after 'return'.
+`, out.String())
+	assert.False(reporter.HadError())
+}
@@ -1,32 +1,60 @@
 package lox
 
 import (
+	"bytes"
 	"strconv"
-	"unicode"
+	"unicode/utf8"
 )
 
-// Scanner parses the input source and collects all the tokens that can be found
+// Scanner parses the input source and collects all the tokens that can be found.
+// The source is scanned byte by byte, UTF-8 sequences are only decoded when they
+// can be a part of an identifier, since every other token is made of ASCII
+// characters.
 type Scanner struct {
-	line     int
-	start    int
-	current  int
-	source   []rune
-	tokens   []*Token
-	reporter Reporter
+	line    int
+	start   int
+	current int
+	// lineStart is the offset of the first byte of the current line, and
+	// column is the column of the lexeme that starts at `start`
+	lineStart int
+	column    int
+	source    []byte
+	tokens    []*Token
+	reporter  Reporter
+	// comments holds the comments that were found since the last token
+	comments []*Comment
 }
 
 // New creates a new Lox token scanner
-func NewScanner(source []rune, reporter Reporter) *Scanner {
+func NewScanner(source []byte, reporter Reporter) *Scanner {
 	scanner := new(Scanner)
 	scanner.line = 1
 	scanner.start = 0
 	scanner.current = 0
+	scanner.lineStart = 0
 	scanner.source = source
 	scanner.tokens = make([]*Token, 0)
 	scanner.reporter = reporter
 	return scanner
 }
 
+// Reset prepares the scanner for scanning a new source. The slice that was
+// returned by the previous call to Scan is reused to hold the new tokens, so
+// it must not be used after calling Reset. The tokens themselves are not
+// reused, they can still be referenced by a syntax tree.
+func (scanner *Scanner) Reset(source []byte) {
+	scanner.line = 1
+	scanner.start = 0
+	scanner.current = 0
+	scanner.lineStart = 0
+	scanner.source = source
+	for i := range scanner.tokens {
+		scanner.tokens[i] = nil
+	}
+	scanner.tokens = scanner.tokens[:0]
+	scanner.comments = nil
+}
+
 // Scan reads the source and collect all the tokens that were found from the
 // source
 func (scanner *Scanner) Scan() []*Token {
@@ -36,11 +64,12 @@ func (scanner *Scanner) Scan() []*Token {
 
 	for scanner.hasNext() {
 		scanner.start = scanner.current
-		switch r := scanner.advance(); r {
+		scanner.column = scanner.start - scanner.lineStart + 1
+		switch c := scanner.advance(); c {
 		// Whitespaces
 		case ' ', '\r', '\t':
 		case '\n':
-			scanner.line++
+			scanner.newline()
 		// Single character tokens
 		case '(':
 			scanner.addToken(L_PAREN, nil)
@@ -95,8 +124,11 @@ func (scanner *Scanner) Scan() []*Token {
 				for scanner.peek() != '\n' && scanner.hasNext() {
 					scanner.advance()
 				}
+				scanner.addComment(scanner.line)
 			} else if scanner.match('*') {
+				line := scanner.line
 				scanner.scanMultilineComment()
+				scanner.addComment(line)
 			} else {
 				scanner.addToken(SLASH, nil)
 			}
@@ -104,31 +136,53 @@ func (scanner *Scanner) Scan() []*Token {
 		case '"':
 			scanner.scanString()
 		default:
-			if unicode.IsDigit(r) {
+			if isDigit(c) {
 				scanner.scanNumber()
-			} else if isIdentBegin(r) {
+			} else if isIdentBegin(scanner.decodeLexemeStart(c)) {
 				scanner.scanIdentifier()
 			} else {
-				scanner.reporter.Report(
-					newScanError(scanner.line, "Unexpected character."),
-				)
+				err := newScanError(scanner.line, scanner.column, scanner.start, CodeUnexpectedCharacter, "Unexpected character.")
+				err.(*scanError).length = scanner.current - scanner.start
+				scanner.report(err)
 			}
 		}
 	}
-	scanner.tokens = append(
-		scanner.tokens,
-		NewToken(EOF, "", nil, scanner.line),
-	)
+	scanner.start = scanner.current
+	scanner.column = scanner.start - scanner.lineStart + 1
+	scanner.addToken(EOF, nil)
 	return scanner.tokens
 }
 
+// report gives the error to the reporter as a diagnostic of the scanner.
+func (scanner *Scanner) report(err error) {
+	scanner.reporter.Report(newDiagnostic(StageScan, err))
+}
+
+// unterminated reports the string or the comment that begins at `start` on
+// the given line and that the end of the source interrupted, at where it
+// begins. Scanning then resumes at the end of that line, so the errors on the
+// lines after it are still found, lineStart is the offset of the line.
+func (scanner *Scanner) unterminated(line, lineStart int, code Code, message string) {
+	end := len(scanner.source)
+	if i := bytes.IndexByte(scanner.source[scanner.start:], '\n'); i >= 0 {
+		end = scanner.start + i
+	}
+	err := newScanError(line, scanner.column, scanner.start, code, message)
+	err.(*scanError).length = end - scanner.start
+	scanner.report(err)
+	scanner.current = end
+	scanner.line = line
+	scanner.lineStart = lineStart
+}
+
 func (scanner *Scanner) scanString() {
+	line, lineStart := scanner.line, scanner.lineStart
 	// read until EOF or found a maching '"' --> our string includes \n
 	for scanner.peek() != '"' && scanner.hasNext() {
-		if scanner.peek() == '\n' {
-			scanner.line++
-		}
 		scanner.advance()
+		if scanner.source[scanner.current-1] == '\n' {
+			scanner.newline()
+		}
 	}
 
 	if scanner.hasNext() {
@@ -138,22 +192,20 @@ func (scanner *Scanner) scanString() {
 		literal := string(scanner.source[scanner.start+1 : scanner.current-1])
 		scanner.addToken(STRING, literal)
 	} else {
-		scanner.reporter.Report(
-			newScanError(scanner.line, "Unterminated string."),
-		)
+		scanner.unterminated(line, lineStart, CodeUnterminatedString, "Unterminated string.")
 	}
 }
 
 func (scanner *Scanner) scanNumber() {
 	// go through continuous digits
-	for unicode.IsDigit(scanner.peek()) {
+	for isDigit(scanner.peek()) {
 		scanner.advance()
 	}
 	// check if there's a '.' with following digits
-	if scanner.peek() == '.' && unicode.IsDigit(scanner.peekNext()) {
+	if scanner.peek() == '.' && isDigit(scanner.peekNext()) {
 		scanner.advance()
 		// go through continuous digits
-		for unicode.IsDigit(scanner.peek()) {
+		for isDigit(scanner.peek()) {
 			scanner.advance()
 		}
 	}
@@ -165,24 +217,101 @@ func (scanner *Scanner) scanNumber() {
 }
 
 func (scanner *Scanner) scanIdentifier() {
-	for isIdentRune(scanner.peek()) {
-		scanner.advance()
+	for scanner.hasNext() {
+		c := scanner.peek()
+		if c < utf8.RuneSelf {
+			if !isIdentRune(rune(c)) {
+				break
+			}
+			scanner.advance()
+			continue
+		}
+		r, size := utf8.DecodeRune(scanner.source[scanner.current:])
+		if !isIdentRune(r) {
+			break
+		}
+		scanner.current += size
 	}
-	lexeme := string(scanner.source[scanner.start:scanner.current])
-	if tokenType, isKeyword := KeywordTokens[lexeme]; isKeyword {
-		scanner.addToken(tokenType, nil)
-	} else {
-		scanner.addToken(IDENT, nil)
+	scanner.addToken(keywordType(scanner.source[scanner.start:scanner.current]), nil)
+}
+
+// keywordType returns the type of the keyword that is spelled by the given
+// identifier, or IDENT if it's not a keyword. Similar to clox, we switch on the
+// first letters to narrow down the possible keywords, and then compare the
+// rest of the lexeme. This avoids hashing every identifier like a lookup in
+// KeywordTokens would.
+func keywordType(lexeme []byte) TokenType {
+	switch lexeme[0] {
+	case 'a':
+		return checkKeyword(lexeme, 1, "nd", AND)
+	case 'c':
+		return checkKeyword(lexeme, 1, "lass", CLASS)
+	case 'e':
+		if len(lexeme) > 1 {
+			switch lexeme[1] {
+			case 'l':
+				return checkKeyword(lexeme, 2, "se", ELSE)
+			case 'o':
+				return checkKeyword(lexeme, 2, "f", EOF)
+			}
+		}
+	case 'f':
+		if len(lexeme) > 1 {
+			switch lexeme[1] {
+			case 'a':
+				return checkKeyword(lexeme, 2, "lse", FALSE)
+			case 'o':
+				return checkKeyword(lexeme, 2, "r", FOR)
+			case 'u':
+				return checkKeyword(lexeme, 2, "n", FUN)
+			}
+		}
+	case 'i':
+		return checkKeyword(lexeme, 1, "f", IF)
+	case 'n':
+		return checkKeyword(lexeme, 1, "il", NIL)
+	case 'o':
+		return checkKeyword(lexeme, 1, "r", OR)
+	case 'p':
+		return checkKeyword(lexeme, 1, "rint", PRINT)
+	case 'r':
+		return checkKeyword(lexeme, 1, "eturn", RETURN)
+	case 's':
+		return checkKeyword(lexeme, 1, "uper", SUPER)
+	case 't':
+		if len(lexeme) > 1 {
+			switch lexeme[1] {
+			case 'h':
+				return checkKeyword(lexeme, 2, "is", THIS)
+			case 'r':
+				return checkKeyword(lexeme, 2, "ue", TRUE)
+			}
+		}
+	case 'v':
+		return checkKeyword(lexeme, 1, "ar", VAR)
+	case 'w':
+		return checkKeyword(lexeme, 1, "hile", WHILE)
+	}
+	return IDENT
+}
+
+// checkKeyword returns the given type if the lexeme ends with the given rest
+// starting from the given position, otherwise returns IDENT.
+func checkKeyword(lexeme []byte, start int, rest string, typ TokenType) TokenType {
+	if len(lexeme) == start+len(rest) && string(lexeme[start:]) == rest {
+		return typ
 	}
+	return IDENT
 }
 
 func (scanner *Scanner) scanMultilineComment() {
+	line, lineStart := scanner.line, scanner.lineStart
 	for {
 		for scanner.peek() != '*' && scanner.hasNext() {
-			if scanner.peek() == '\n' {
-				scanner.line++
-			}
 			scanner.advance()
+			if scanner.source[scanner.current-1] == '\n' {
+				scanner.newline()
+			}
 		}
 		if scanner.hasNext() {
 			scanner.advance()
@@ -191,11 +320,7 @@ func (scanner *Scanner) scanMultilineComment() {
 				break
 			}
 		} else {
-			scanner.reporter.Report(
-				newScanError(
-					scanner.line, "Unterminated multiline comment.",
-				),
-			)
+			scanner.unterminated(line, lineStart, CodeUnterminatedComment, "Unterminated multiline comment.")
 			break
 		}
 	}
@@ -206,24 +331,55 @@ func (scanner *Scanner) scanMultilineComment() {
 func (scanner *Scanner) addToken(typ TokenType, literal interface{}) {
 	lexeme := string(scanner.source[scanner.start:scanner.current])
 	tok := NewToken(typ, lexeme, literal, scanner.line)
+	tok.Column = scanner.column
+	tok.Offset = scanner.start
+	tok.Comments = scanner.comments
+	scanner.comments = nil
 	scanner.tokens = append(scanner.tokens, tok)
 }
 
+// addComment records the lexeme from `start` to `current` as a comment that
+// begins on the given line, it will be attached to the next token
+func (scanner *Scanner) addComment(line int) {
+	comment := new(Comment)
+	comment.Text = string(scanner.source[scanner.start:scanner.current])
+	comment.Line = line
+	scanner.comments = append(scanner.comments, comment)
+}
+
+// newline is called after consuming a line break
+func (scanner *Scanner) newline() {
+	scanner.line++
+	scanner.lineStart = scanner.current
+}
+
 // hasNext returns true if the scanner has not read pass the source length
 func (scanner *Scanner) hasNext() bool {
 	return scanner.current < len(scanner.source)
 }
 
-// advance consumes and returns the rune at the current possible
-func (scanner *Scanner) advance() rune {
-	r := scanner.source[scanner.current]
+// advance consumes and returns the byte at the current possible
+func (scanner *Scanner) advance() byte {
+	c := scanner.source[scanner.current]
 	scanner.current++
+	return c
+}
+
+// decodeLexemeStart returns the rune that begins the current lexeme given its
+// first byte. If the byte starts a multi-byte UTF-8 sequence, the rest of the
+// sequence is consumed. Invalid sequences are decoded as utf8.RuneError.
+func (scanner *Scanner) decodeLexemeStart(c byte) rune {
+	if c < utf8.RuneSelf {
+		return rune(c)
+	}
+	r, size := utf8.DecodeRune(scanner.source[scanner.start:])
+	scanner.current = scanner.start + size
 	return r
 }
 
-// match checks if the rune at the current possition is equal to the given rune,
-// if they are equal, consumes the rune at the current position.
-func (scanner *Scanner) match(expected rune) bool {
+// match checks if the byte at the current possition is equal to the given byte,
+// if they are equal, consumes the byte at the current position.
+func (scanner *Scanner) match(expected byte) bool {
 	if !scanner.hasNext() {
 		return false
 	}
@@ -234,18 +390,22 @@ func (scanner *Scanner) match(expected rune) bool {
 	return true
 }
 
-// peek returns the rune at the current position, but does not consume it
-func (scanner *Scanner) peek() rune {
+// peek returns the byte at the current position, but does not consume it
+func (scanner *Scanner) peek() byte {
 	if !scanner.hasNext() {
 		return '\x00'
 	}
 	return scanner.source[scanner.current]
 }
 
-// peek returns the rune at the next position, but does not consume it
-func (scanner *Scanner) peekNext() rune {
+// peek returns the byte at the next position, but does not consume it
+func (scanner *Scanner) peekNext() byte {
 	if scanner.current+1 >= len(scanner.source) {
 		return '\x00'
 	}
 	return scanner.source[scanner.current+1]
 }
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
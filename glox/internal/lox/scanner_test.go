@@ -0,0 +1,167 @@
+package lox
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func scanTypes(tokens []*Token) []TokenType {
+	var types []TokenType
+	for _, tok := range tokens {
+		types = append(types, tok.Type)
+	}
+	return types
+}
+
+func TestScannerUnicode(t *testing.T) {
+	assert := assert.New(t)
+
+	var errs strings.Builder
+	reporter := NewSimpleReporter(&errs)
+	tokens := NewScanner([]byte(`var café = "naïve ☕"; π1 € x;`), reporter).Scan()
+
+	assert.Equal([]TokenType{
+		VAR, IDENT, EQUAL, STRING, SEMICOLON, IDENT, IDENT, SEMICOLON, EOF,
+	}, scanTypes(tokens))
+	assert.Equal("café", tokens[1].Lexeme)
+	assert.Equal("naïve ☕", tokens[3].Literal)
+	assert.Equal("π1", tokens[5].Lexeme)
+	assert.Equal("[line 1] Error: Unexpected character.\n", errs.String())
+}
+
+func TestScannerNumbers(t *testing.T) {
+	assert := assert.New(t)
+
+	tokens := NewScanner([]byte("12 3.5 4."), NewSimpleReporter(ioutil.Discard)).Scan()
+
+	assert.Equal([]TokenType{NUMBER, NUMBER, NUMBER, DOT, EOF}, scanTypes(tokens))
+	assert.Equal(12.0, tokens[0].Literal)
+	assert.Equal(3.5, tokens[1].Literal)
+	assert.Equal(4.0, tokens[2].Literal)
+}
+
+// scannerBenchSource is a large script made of repeated declarations that
+// exercise every kind of token.
+var scannerBenchSource = []byte(strings.Repeat(`
+// compute some values
+class Point < Base {
+	init(x, y) { this.x = x; this.y = y; }
+	dist(other) {
+		var dx = this.x - other.x;
+		var dy = this.y - other.y;
+		return dx * dx + dy * dy >= 0.5 and !false or nil != "done";
+	}
+}
+/* block comment */
+for (var i = 0; i <= 100; i = i + 1) { print Point(i, i / 2).dist(Point(0, 0)); }
+`, 200))
+
+func BenchmarkScanner(b *testing.B) {
+	b.SetBytes(int64(len(scannerBenchSource)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		NewScanner(scannerBenchSource, NewSimpleReporter(ioutil.Discard)).Scan()
+	}
+}
+
+func TestScannerResetReusesTokenSlice(t *testing.T) {
+	assert := assert.New(t)
+
+	scanner := NewScanner([]byte("var a = 1;"), NewSimpleReporter(ioutil.Discard))
+	first := scanner.Scan()
+	firstVar := first[0]
+
+	scanner.Reset([]byte("print a;"))
+	second := scanner.Scan()
+
+	assert.Equal([]TokenType{PRINT, IDENT, SEMICOLON, EOF}, scanTypes(second))
+	assert.Same(&first[0], &second[0])
+	// tokens that were handed out are left untouched
+	assert.Equal(VAR, firstVar.Type)
+}
+
+func TestKeywordTypeMatchesKeywordTokens(t *testing.T) {
+	assert := assert.New(t)
+
+	for lexeme, typ := range KeywordTokens {
+		assert.Equal(typ, keywordType([]byte(lexeme)), lexeme)
+		assert.Equal(IDENT, keywordType([]byte(lexeme+"x")), lexeme+"x")
+		assert.Equal(IDENT, keywordType([]byte(lexeme[:len(lexeme)-1])), lexeme[:len(lexeme)-1])
+	}
+	for _, lexeme := range []string{"e", "f", "t", "x", "_", "classy", "thus", "fn"} {
+		assert.Equal(IDENT, keywordType([]byte(lexeme)), lexeme)
+	}
+}
+
+var keywordBenchLexemes = [][]byte{
+	[]byte("var"), []byte("counter"), []byte("while"), []byte("i"),
+	[]byte("return"), []byte("this"), []byte("total"), []byte("fun"),
+}
+
+func BenchmarkKeywordMap(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, lexeme := range keywordBenchLexemes {
+			if _, ok := KeywordTokens[string(lexeme)]; !ok {
+				_ = IDENT
+			}
+		}
+	}
+}
+
+func BenchmarkKeywordSwitch(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		for _, lexeme := range keywordBenchLexemes {
+			keywordType(lexeme)
+		}
+	}
+}
+
+func TestScannerAttachesComments(t *testing.T) {
+	assert := assert.New(t)
+
+	tokens := NewScanner([]byte("// one\nvar /* two\n */ a; // three"), NewSimpleReporter(ioutil.Discard)).Scan()
+
+	assert.Len(tokens, 4)
+	assert.Equal([]*Comment{{Text: "// one", Line: 1}}, tokens[0].Comments)
+	assert.Equal([]*Comment{{Text: "/* two\n */", Line: 2}}, tokens[1].Comments)
+	assert.Equal(3, tokens[1].Comments[0].EndLine())
+	assert.Empty(tokens[2].Comments)
+	assert.Equal(EOF, tokens[3].Type)
+	assert.Equal([]*Comment{{Text: "// three", Line: 3}}, tokens[3].Comments)
+}
+
+func TestScannerColumns(t *testing.T) {
+	assert := assert.New(t)
+
+	tokens := NewScanner([]byte("var a = \"x\ny\";\n\tprint a;"), NewSimpleReporter(ioutil.Discard)).Scan()
+
+	var positions [][2]int
+	for _, tok := range tokens {
+		positions = append(positions, [2]int{tok.Line, tok.Column})
+	}
+	// multi-line strings are located by where they start, but their line is
+	// where they end
+	assert.Equal([][2]int{{1, 1}, {1, 5}, {1, 7}, {2, 9}, {2, 3}, {3, 2}, {3, 8}, {3, 9}, {3, 10}}, positions)
+}
+
+func TestScannerRecoversFromUnterminated(t *testing.T) {
+	assert := assert.New(t)
+
+	var errs strings.Builder
+	reporter := NewSimpleReporter(&errs).(*SimpleReporter)
+	reporter.ShowColumns(true)
+	tokens := NewScanner([]byte("print \"a;\nprint 1 @\n  /* b\nprint 2;\n@"), reporter).Scan()
+
+	assert.Equal([]TokenType{
+		PRINT, PRINT, NUMBER, PRINT, NUMBER, SEMICOLON, EOF,
+	}, scanTypes(tokens))
+	assert.Equal(4, tokens[3].Line)
+	assert.Equal(1, tokens[3].Column)
+	assert.Equal("[line 1:7] Error: Unterminated string.\n"+
+		"[line 2:9] Error: Unexpected character.\n"+
+		"[line 3:3] Error: Unterminated multiline comment.\n"+
+		"[line 5:1] Error: Unexpected character.\n", errs.String())
+}
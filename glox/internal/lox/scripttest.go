@@ -0,0 +1,212 @@
+package lox
+
+import (
+	"bytes"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// The expectations are written the same way as in the test suite of Crafting
+// Interpreters, so its tests can be run as they are. Error lines can be given
+// for a specific implementation, those for "java" apply to this tree-walking
+// interpreter and those for "c" are ignored.
+var (
+	expectOutputPattern       = regexp.MustCompile(`// expect: ?(.*)`)
+	expectErrorPattern        = regexp.MustCompile(`// (Error.*)`)
+	expectErrorLinePattern    = regexp.MustCompile(`// \[((java|c) )?line (\d+)\] (Error.*)`)
+	expectRuntimeErrorPattern = regexp.MustCompile(`// expect runtime error: (.+)`)
+	nonTestPattern            = regexp.MustCompile(`// nontest`)
+)
+
+// ScriptTest is a Lox script whose expected output and errors are written in
+// comments next to the code that produces them:
+//
+//	print 1 + 2; // expect: 3
+//	print nil.x; // expect runtime error: Only instances have properties.
+//	var 1;       // Error at '1': Expect variable name.
+//	// [line 9] Error at end: Expect '}' after block.
+//
+// A script is expected to run without any error and without any output when
+// it has no expectation, so scripts can check themselves and fail with a
+// runtime error.
+type ScriptTest struct {
+	Name   string
+	Source []byte
+	// Output holds the expected lines of output, and Errors the expected lines
+	// of errors in the format used by SimpleReporter. A runtime error is the
+	// error's message followed by the line where it happened.
+	Output []string
+	Errors []string
+	// RuntimeError is true if the script is expected to stop with a runtime
+	// error, compile errors are expected otherwise.
+	RuntimeError bool
+	// Cover makes Run record the lines that are executed
+	Cover bool
+}
+
+// NewScriptTest reads the expectations written in the source.
+func NewScriptTest(name string, source []byte) *ScriptTest {
+	t := new(ScriptTest)
+	t.Name = name
+	t.Source = source
+	for i, line := range strings.Split(string(source), "\n") {
+		lineNum := i + 1
+		if m := expectOutputPattern.FindStringSubmatch(line); m != nil {
+			t.Output = append(t.Output, m[1])
+		} else if m := expectErrorPattern.FindStringSubmatch(line); m != nil {
+			t.Errors = append(t.Errors, fmt.Sprintf("[line %d] %s", lineNum, m[1]))
+		} else if m := expectErrorLinePattern.FindStringSubmatch(line); m != nil {
+			if m[2] != "c" {
+				t.Errors = append(t.Errors, fmt.Sprintf("[line %s] %s", m[3], m[4]))
+			}
+		} else if m := expectRuntimeErrorPattern.FindStringSubmatch(line); m != nil {
+			t.Errors = append(t.Errors, m[1], fmt.Sprintf("[line %d]", lineNum))
+			t.RuntimeError = true
+		}
+	}
+	return t
+}
+
+// IsScriptTest returns true if the file should be run as a test, i.e. if its
+// name ends with "_test.lox" or if it has expectations, unless it's marked
+// with a "// nontest" comment.
+func IsScriptTest(path string, source []byte) bool {
+	if nonTestPattern.Match(source) {
+		return false
+	}
+	if strings.HasSuffix(filepath.Base(path), "_test.lox") {
+		return true
+	}
+	return expectOutputPattern.Match(source) ||
+		expectErrorPattern.Match(source) ||
+		expectErrorLinePattern.Match(source) ||
+		expectRuntimeErrorPattern.Match(source)
+}
+
+// ScriptTestResult holds what a test script printed when it was run.
+type ScriptTestResult struct {
+	Output []string
+	Errors []string
+	// Failures describes how the script didn't meet its expectations, the test
+	// passed if it's empty.
+	Failures []string
+	// Coverage holds the executed lines if the test was run with Cover, it's
+	// empty if the script didn't compile.
+	Coverage *Coverage
+}
+
+// Passed returns true if the script met all of its expectations.
+func (r *ScriptTestResult) Passed() bool {
+	return len(r.Failures) == 0
+}
+
+// Run runs the script with a new interpreter, stopping it once the timeout is
+// over if it isn't zero, and checks its output and errors.
+func (t *ScriptTest) Run(timeout time.Duration) *ScriptTestResult {
+	var output, errs bytes.Buffer
+	reporter := NewSimpleReporter(&errs)
+	interpreter := NewInterpreter(&output, reporter, false)
+	interpreter.SetLimits(0, timeout)
+	var coverage *Coverage
+	if t.Cover {
+		coverage = NewCoverage(t.Name, t.Source)
+		interpreter.SetCoverage(coverage)
+	}
+
+	tokens := NewScanner(t.Source, reporter).Scan()
+	statements := NewParser(tokens, reporter).Parse()
+	if !reporter.HadError() {
+		NewResolver(interpreter, reporter).Resolve(statements)
+	}
+	if !reporter.HadError() {
+		interpreter.Interpret(statements)
+	}
+
+	r := new(ScriptTestResult)
+	r.Coverage = coverage
+	r.Output = splitOutput(output.String())
+	r.Errors = splitOutput(errs.String())
+	t.checkOutput(r)
+	if t.RuntimeError && len(t.Errors) != 2 {
+		r.Failures = append(r.Failures, "Cannot expect both compile and runtime errors, or many runtime errors.")
+	} else if t.RuntimeError {
+		t.checkRuntimeError(r)
+	} else {
+		t.checkCompileErrors(r)
+	}
+	return r
+}
+
+func (t *ScriptTest) checkOutput(r *ScriptTestResult) {
+	for i, line := range r.Output {
+		if i >= len(t.Output) {
+			r.Failures = append(r.Failures, fmt.Sprintf("Got output '%s' when none was expected.", line))
+			return
+		}
+		if line != t.Output[i] {
+			r.Failures = append(r.Failures, fmt.Sprintf("Expected output '%s' and got '%s'.", t.Output[i], line))
+			return
+		}
+	}
+	for _, line := range t.Output[len(r.Output):] {
+		r.Failures = append(r.Failures, fmt.Sprintf("Missing expected output '%s'.", line))
+	}
+}
+
+// checkRuntimeError checks that the first line of errors is the expected
+// message, and that the line where the error happened is given after it.
+func (t *ScriptTest) checkRuntimeError(r *ScriptTestResult) {
+	message := t.Errors[0]
+	if len(r.Errors) == 0 {
+		r.Failures = append(r.Failures, fmt.Sprintf("Expected runtime error '%s' and got none.", message))
+		return
+	}
+	if r.Errors[0] != message {
+		r.Failures = append(r.Failures, fmt.Sprintf("Expected runtime error '%s' and got '%s'.", message, r.Errors[0]))
+		return
+	}
+	for _, line := range r.Errors[1:] {
+		if line == t.Errors[1] {
+			return
+		}
+	}
+	r.Failures = append(r.Failures, fmt.Sprintf("Expected runtime error on %s.", strings.Trim(t.Errors[1], "[]")))
+}
+
+// checkCompileErrors checks that the errors are the expected ones, in any
+// order.
+func (t *ScriptTest) checkCompileErrors(r *ScriptTestResult) {
+	expected := make(map[string]int)
+	for _, line := range t.Errors {
+		expected[line]++
+	}
+	var unexpected []string
+	for _, line := range r.Errors {
+		if expected[line] > 0 {
+			expected[line]--
+		} else {
+			unexpected = append(unexpected, line)
+		}
+	}
+	for _, line := range unexpected {
+		r.Failures = append(r.Failures, fmt.Sprintf("Unexpected error '%s'.", line))
+	}
+	for _, line := range t.Errors {
+		if expected[line] > 0 {
+			expected[line]--
+			r.Failures = append(r.Failures, fmt.Sprintf("Missing expected error '%s'.", line))
+		}
+	}
+}
+
+// splitOutput splits the text into lines, without an empty line after the
+// last line break.
+func splitOutput(text string) []string {
+	if text == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
@@ -0,0 +1,68 @@
+package lox
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScriptTestExpectations(t *testing.T) {
+	assert := assert.New(t)
+
+	test := NewScriptTest("a.lox", []byte(`print 1; // expect: 1
+print ""; // expect:
+var 1; // Error at '1': Expect variable name.
+// [line 5] Error at end: Expect expression.
+// [java line 6] Error: for this interpreter.
+// [c line 7] Error: for another interpreter.
+`))
+	assert.Equal([]string{"1", ""}, test.Output)
+	assert.Equal([]string{
+		"[line 3] Error at '1': Expect variable name.",
+		"[line 5] Error at end: Expect expression.",
+		"[line 6] Error: for this interpreter.",
+	}, test.Errors)
+	assert.False(test.RuntimeError)
+
+	test = NewScriptTest("b.lox", []byte("\nnil(); // expect runtime error: Can only call functions and classes.\n"))
+	assert.Equal([]string{"Can only call functions and classes.", "[line 2]"}, test.Errors)
+	assert.True(test.RuntimeError)
+}
+
+func TestIsScriptTest(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.True(IsScriptTest("dir/a.lox", []byte("print 1; // expect: 1")))
+	assert.True(IsScriptTest("dir/a_test.lox", []byte("print 1;")))
+	assert.False(IsScriptTest("dir/a.lox", []byte("print 1;")))
+	assert.False(IsScriptTest("dir/a.lox", []byte("// nontest\nprint 1; // expect: 1")))
+}
+
+func TestScriptTestRun(t *testing.T) {
+	tests := []struct {
+		source   string
+		failures []string
+	}{
+		{"print 1 + 2; // expect: 3\nprint \"a\"; // expect: a", nil},
+		{"var a = 1;", nil},
+		{"print 1; // expect: 2", []string{"Expected output '2' and got '1'."}},
+		{"print 1;", []string{"Got output '1' when none was expected."}},
+		{"print 1; // expect: 1\n// expect: 2", []string{"Missing expected output '2'."}},
+		{"nil(); // expect runtime error: Can only call functions and classes.", nil},
+		{"\nnil();\n// expect runtime error: Can only call functions and classes.",
+			[]string{"Expected runtime error on line 3."}},
+		{"// expect runtime error: Oops.", []string{"Expected runtime error 'Oops.' and got none."}},
+		{"nil(); // expect runtime error: Oops.",
+			[]string{"Expected runtime error 'Oops.' and got 'Can only call functions and classes.'."}},
+		{"var 1; // Error at '1': Expect variable name.\nprint; // Error at ';': Expect expression.", nil},
+		{"var 1;", []string{"Unexpected error '[line 1] Error at '1': Expect variable name.'."}},
+		{"// Error: Oops.", []string{"Missing expected error '[line 1] Error: Oops.'."}},
+		{"while (true) {}", []string{"Unexpected error 'Execution time limit exceeded.'."}},
+	}
+	for _, tt := range tests {
+		result := NewScriptTest("test.lox", []byte(tt.source)).Run(10 * time.Millisecond)
+		assert.Equal(t, tt.failures, result.Failures, tt.source)
+		assert.Equal(t, tt.failures == nil, result.Passed(), tt.source)
+	}
+}
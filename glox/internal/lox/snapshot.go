@@ -0,0 +1,78 @@
+package lox
+
+// Snapshot is the state of an interpreter's global environment, and the
+// resolution of the statements that it ran, at some point in time. It's
+// taken with Interpreter.Snapshot and restored with Interpreter.Restore.
+type Snapshot struct {
+	globals  map[string]interface{}
+	locals   map[Expr]int
+	imported map[string]bool
+	// uninitialized holds the globals that weren't assigned yet, see
+	// Interpreter.SetStrictVariables.
+	uninitialized map[string]bool
+}
+
+// Snapshot records the global variables, including the ones that weren't
+// assigned yet, the resolution of the statements that were run until now, and
+// the modules that were imported, so the interpreter can go back to this state
+// later, e.g. to run each request in a fresh environment that starts from a
+// prelude without running the prelude again.
+// Only the variables are copied, the objects that they refer to, e.g.
+// instances, are shared with the snapshot, so changes made to their fields
+// aren't undone when it's restored.
+func (in *Interpreter) Snapshot() *Snapshot {
+	snapshot := new(Snapshot)
+	snapshot.globals = make(map[string]interface{}, len(in.globals.values))
+	for name, val := range in.globals.values {
+		snapshot.globals[name] = val
+	}
+	snapshot.locals = make(map[Expr]int, len(in.locals))
+	for expr, steps := range in.locals {
+		snapshot.locals[expr] = steps
+	}
+	snapshot.imported = make(map[string]bool, len(in.imported))
+	for name := range in.imported {
+		snapshot.imported[name] = true
+	}
+	snapshot.uninitialized = make(map[string]bool, len(in.globals.uninitialized))
+	for name := range in.globals.uninitialized {
+		snapshot.uninitialized[name] = true
+	}
+	return snapshot
+}
+
+// Restore brings the global variables, the resolution of the statements, and
+// the imported modules back to the state recorded by the snapshot, which must
+// have been taken by the same interpreter. The statements that were run after
+// the snapshot was taken have to be resolved again to be run again. The
+// snapshot can be restored any number of times. The settings are kept, as with
+// Reset, and so are the native functions registered after the snapshot was
+// taken, unless it has a global with the same name, e.g. a function that the
+// snapshotted code defined in place of a native one.
+func (in *Interpreter) Restore(snapshot *Snapshot) {
+	// the environment is kept, since the functions that were defined at the
+	// top level hold it as their closure
+	in.globals.values = make(map[string]interface{}, len(snapshot.globals))
+	for name, val := range snapshot.globals {
+		in.globals.values[name] = val
+	}
+	in.globals.uninitialized = nil
+	for name := range snapshot.uninitialized {
+		in.globals.declare(name)
+	}
+	for name, native := range in.natives {
+		if _, ok := snapshot.globals[name]; !ok {
+			in.globals.define(name, native)
+		}
+	}
+	in.locals = make(map[Expr]int, len(snapshot.locals))
+	for expr, steps := range snapshot.locals {
+		in.locals[expr] = steps
+	}
+	in.imported = make(map[string]bool, len(snapshot.imported))
+	for name := range snapshot.imported {
+		in.imported[name] = true
+	}
+	in.environment = in.globals
+	in.frames = in.frames[:0]
+}
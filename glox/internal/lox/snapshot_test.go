@@ -0,0 +1,42 @@
+package lox
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInterpreterSnapshot(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&out), false)
+	in.Interpret(parseScript(t, in, `
+var count = 0;
+fun add(n) { var total = count + n; count = total; return total; }
+`))
+	snapshot := in.Snapshot()
+
+	for i := 0; i < 2; i++ {
+		out.Reset()
+		in.Interpret(parseScript(t, in, "print add(2);\nvar defined = true;\nprint defined;"))
+		assert.Equal("2\ntrue\n", out.String())
+
+		in.Restore(snapshot)
+		out.Reset()
+		in.Interpret(parseScript(t, in, "print count;\nprint defined;"))
+		assert.Equal("0\nUndefined variable 'defined'.\n[line 2]\n", out.String())
+		in.Restore(snapshot)
+	}
+	assert.Len(in.locals, len(snapshot.locals))
+
+	// the native functions that are registered afterward are kept
+	in.RegisterNative("one", 0, func(args []Value) (Value, error) {
+		return NumberValue(1), nil
+	})
+	in.Restore(snapshot)
+	out.Reset()
+	in.Interpret(parseScript(t, in, "print add(one());"))
+	assert.Equal("1\n", out.String())
+}
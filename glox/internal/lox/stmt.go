@@ -2,10 +2,13 @@ package lox
 
 type Stmt interface {
 	Accept(visitor StmtVisitor) (interface{}, error)
+	span() *Span
+	setSpan(start, end *Token)
 }
 type StmtVisitor interface {
 	VisitBlockStmt(stmt *BlockStmt) (interface{}, error)
 	VisitClassStmt(stmt *ClassStmt) (interface{}, error)
+	VisitErrorStmt(stmt *ErrorStmt) (interface{}, error)
 	VisitExprStmt(stmt *ExprStmt) (interface{}, error)
 	VisitFunctionStmt(stmt *FunctionStmt) (interface{}, error)
 	VisitIfStmt(stmt *IfStmt) (interface{}, error)
@@ -16,10 +19,11 @@ type StmtVisitor interface {
 }
 type BlockStmt struct {
 	Stmts []Stmt
+	Span
 }
 
 func NewBlockStmt(Stmts []Stmt) *BlockStmt {
-	return &BlockStmt{Stmts}
+	return &BlockStmt{Stmts: Stmts}
 }
 func (stmt *BlockStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 	return visitor.VisitBlockStmt(stmt)
@@ -29,21 +33,35 @@ type ClassStmt struct {
 	Name    *Token
 	Super   *VarExpr
 	Methods []*FunctionStmt
+	Span
 }
 
 func NewClassStmt(Name *Token, Super *VarExpr, Methods []*FunctionStmt) *ClassStmt {
-	return &ClassStmt{Name, Super, Methods}
+	return &ClassStmt{Name: Name, Super: Super, Methods: Methods}
 }
 func (stmt *ClassStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 	return visitor.VisitClassStmt(stmt)
 }
 
+type ErrorStmt struct {
+	Tokens []*Token
+	Span
+}
+
+func NewErrorStmt(Tokens []*Token) *ErrorStmt {
+	return &ErrorStmt{Tokens: Tokens}
+}
+func (stmt *ErrorStmt) Accept(visitor StmtVisitor) (interface{}, error) {
+	return visitor.VisitErrorStmt(stmt)
+}
+
 type ExprStmt struct {
 	Expr Expr
+	Span
 }
 
 func NewExprStmt(Expr Expr) *ExprStmt {
-	return &ExprStmt{Expr}
+	return &ExprStmt{Expr: Expr}
 }
 func (stmt *ExprStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 	return visitor.VisitExprStmt(stmt)
@@ -53,34 +71,39 @@ type FunctionStmt struct {
 	Name   *Token
 	Params []*Token
 	Body   []Stmt
+	Span
 }
 
 func NewFunctionStmt(Name *Token, Params []*Token, Body []Stmt) *FunctionStmt {
-	return &FunctionStmt{Name, Params, Body}
+	return &FunctionStmt{Name: Name, Params: Params, Body: Body}
 }
 func (stmt *FunctionStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 	return visitor.VisitFunctionStmt(stmt)
 }
 
 type IfStmt struct {
+	Keyword    *Token
 	Cond       Expr
 	ThenBranch Stmt
 	ElseBranch Stmt
+	Span
 }
 
-func NewIfStmt(Cond Expr, ThenBranch Stmt, ElseBranch Stmt) *IfStmt {
-	return &IfStmt{Cond, ThenBranch, ElseBranch}
+func NewIfStmt(Keyword *Token, Cond Expr, ThenBranch Stmt, ElseBranch Stmt) *IfStmt {
+	return &IfStmt{Keyword: Keyword, Cond: Cond, ThenBranch: ThenBranch, ElseBranch: ElseBranch}
 }
 func (stmt *IfStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 	return visitor.VisitIfStmt(stmt)
 }
 
 type PrintStmt struct {
-	Expr Expr
+	Keyword *Token
+	Expr    Expr
+	Span
 }
 
-func NewPrintStmt(Expr Expr) *PrintStmt {
-	return &PrintStmt{Expr}
+func NewPrintStmt(Keyword *Token, Expr Expr) *PrintStmt {
+	return &PrintStmt{Keyword: Keyword, Expr: Expr}
 }
 func (stmt *PrintStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 	return visitor.VisitPrintStmt(stmt)
@@ -89,10 +112,11 @@ func (stmt *PrintStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 type ReturnStmt struct {
 	Keyword *Token
 	Val     Expr
+	Span
 }
 
 func NewReturnStmt(Keyword *Token, Val Expr) *ReturnStmt {
-	return &ReturnStmt{Keyword, Val}
+	return &ReturnStmt{Keyword: Keyword, Val: Val}
 }
 func (stmt *ReturnStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 	return visitor.VisitReturnStmt(stmt)
@@ -101,22 +125,25 @@ func (stmt *ReturnStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 type VarStmt struct {
 	Name *Token
 	Init Expr
+	Span
 }
 
 func NewVarStmt(Name *Token, Init Expr) *VarStmt {
-	return &VarStmt{Name, Init}
+	return &VarStmt{Name: Name, Init: Init}
 }
 func (stmt *VarStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 	return visitor.VisitVarStmt(stmt)
 }
 
 type WhileStmt struct {
-	Cond Expr
-	Body Stmt
+	Keyword *Token
+	Cond    Expr
+	Body    Stmt
+	Span
 }
 
-func NewWhileStmt(Cond Expr, Body Stmt) *WhileStmt {
-	return &WhileStmt{Cond, Body}
+func NewWhileStmt(Keyword *Token, Cond Expr, Body Stmt) *WhileStmt {
+	return &WhileStmt{Keyword: Keyword, Cond: Cond, Body: Body}
 }
 func (stmt *WhileStmt) Accept(visitor StmtVisitor) (interface{}, error) {
 	return visitor.VisitWhileStmt(stmt)
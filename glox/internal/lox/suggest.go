@@ -0,0 +1,149 @@
+package lox
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// maxListedProperties is the number of the closest properties that are listed
+// in the hint of an undefined property, see propertyHint.
+const maxListedProperties = 3
+
+// suggestion returns a hint suggesting the candidate that is the closest to
+// the name, e.g. "Did you mean 'count'?", if one is close enough to be a typo
+// of it, and nothing otherwise. The names of a single character get no
+// suggestion since any other name is as close.
+func suggestion(name string, candidates []string) string {
+	if len(name) < 2 {
+		return ""
+	}
+	maxDistance := len(name) / 3
+	if maxDistance < 1 {
+		maxDistance = 1
+	}
+	// the ties go to the first candidate in alphabetical order, so the
+	// suggestion doesn't depend on the order of the maps they come from
+	sort.Strings(candidates)
+	best := ""
+	for _, candidate := range candidates {
+		if candidate == name {
+			continue
+		}
+		if d := editDistance(name, candidate); d <= maxDistance {
+			best = candidate
+			maxDistance = d - 1
+		}
+	}
+	if best == "" {
+		return ""
+	}
+	return fmt.Sprintf("Did you mean '%s'?", best)
+}
+
+// editDistance returns the number of bytes to insert, delete, or substitute,
+// and of pairs of adjacent bytes to swap, to turn one string into the other,
+// i.e. their optimal string alignment distance. Swaps are counted as a single
+// edit since they're a common typo.
+func editDistance(a, b string) int {
+	// d[i][j] is the distance between a[:i] and b[:j]
+	d := make([][]int, len(a)+1)
+	for i := range d {
+		d[i] = make([]int, len(b)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			d[i][j] = min3(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] && d[i-2][j-2]+1 < d[i][j] {
+				d[i][j] = d[i-2][j-2] + 1
+			}
+		}
+	}
+	return d[len(a)][len(b)]
+}
+
+func min3(a, b, c int) int {
+	if b < a {
+		a = b
+	}
+	if c < a {
+		a = c
+	}
+	return a
+}
+
+// propertyHint returns a hint for the property that the instances of the class
+// don't have, with the properties that they have that are the closest to it,
+// e.g. "Did you mean 'width'? Point has 'width', 'init', and 'area'.", or
+// "Point has no properties." if they have none, so the class is always named.
+func propertyHint(class, name string, candidates []string) string {
+	if len(candidates) == 0 {
+		return fmt.Sprintf("%s has no properties.", class)
+	}
+	distances := make(map[string]int, len(candidates))
+	for _, candidate := range candidates {
+		distances[candidate] = editDistance(name, candidate)
+	}
+	names := make([]string, 0, len(distances))
+	for candidate := range distances {
+		names = append(names, candidate)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if distances[names[i]] != distances[names[j]] {
+			return distances[names[i]] < distances[names[j]]
+		}
+		return names[i] < names[j]
+	})
+
+	listed := names
+	if len(listed) > maxListedProperties {
+		listed = listed[:maxListedProperties]
+	}
+	quoted := make([]string, len(listed))
+	for i, listedName := range listed {
+		quoted[i] = "'" + listedName + "'"
+	}
+	var list string
+	switch more := len(names) - len(listed); {
+	case more > 0:
+		list = fmt.Sprintf("%s, and %d more", strings.Join(quoted, ", "), more)
+	case len(quoted) == 1:
+		list = quoted[0]
+	case len(quoted) == 2:
+		list = quoted[0] + " and " + quoted[1]
+	default:
+		list = strings.Join(quoted[:len(quoted)-1], ", ") + ", and " + quoted[len(quoted)-1]
+	}
+
+	hint := fmt.Sprintf("%s has %s.", class, list)
+	if s := suggestion(name, names); s != "" {
+		hint = s + " " + hint
+	}
+	return hint
+}
+
+// suggestProperty adds the hint for the property that the instances of the
+// class don't have to the runtime error, see propertyHint.
+func suggestProperty(err error, class, name string, candidates []string) error {
+	if e, ok := err.(*runtimeError); ok {
+		e.hint = propertyHint(class, name, candidates)
+	}
+	return err
+}
+
+// suggest adds the suggestion of the candidate that is the closest to the name
+// as the hint of the runtime error, see suggestion.
+func suggest(err error, name string, candidates []string) error {
+	if e, ok := err.(*runtimeError); ok {
+		e.hint = suggestion(name, candidates)
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package lox
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEditDistance(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(0, editDistance("count", "count"))
+	assert.Equal(1, editDistance("cout", "count"))
+	assert.Equal(1, editDistance("conut", "count"))
+	assert.Equal(2, editDistance("cnout", "count"))
+	assert.Equal(3, editDistance("kitten", "sitting"))
+	assert.Equal(5, editDistance("", "count"))
+}
+
+func TestSuggestion(t *testing.T) {
+	assert := assert.New(t)
+
+	candidates := []string{"count", "counter", "total"}
+	assert.Equal("Did you mean 'count'?", suggestion("cout", candidates))
+	assert.Equal("Did you mean 'counter'?", suggestion("countre", candidates))
+	assert.Equal("", suggestion("index", candidates))
+	assert.Equal("", suggestion("c", []string{"a"}))
+}
+
+func TestPropertyHint(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("Point has 'x'.", propertyHint("Point", "z", []string{"x"}))
+	assert.Equal("Did you mean 'y'? Point has 'y' and 'x'.", propertyHint("Point", "yy", []string{"x", "y", "x"}))
+	assert.Equal("Point has 'move', 'x', 'y', and 2 more.", propertyHint("Point", "mv", []string{"x", "y", "init", "move", "length"}))
+	assert.Equal("Point has no properties.", propertyHint("Point", "x", nil))
+}
+
+func TestDidYouMean(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(&out), false)
+	in.Interpret(parseScript(t, in, `var total = 0;
+fun add(amount) {
+  totl = totl + amout;
+}
+add(1);`))
+	assert.Equal("Undefined variable 'totl'.\n[line 3]\nHint: Did you mean 'total'?\n", out.String())
+
+	out.Reset()
+	in.Interpret(parseScript(t, in, "fun f(amount) { return amout; }\nf(1);"))
+	assert.Equal("Undefined variable 'amout'.\n[line 1]\nHint: Did you mean 'amount'?\n", out.String())
+
+	out.Reset()
+	in.Interpret(parseScript(t, in, `class Point {
+  init() { this.width = 1; }
+  area() { return this.width; }
+}
+class Square < Point {
+  size() { return super.aera(); }
+}
+print Point().widht;`))
+	assert.Equal("Undefined property 'widht'.\n[line 8]\nHint: Did you mean 'width'? Point has 'width', 'init', and 'area'.\n", out.String())
+
+	out.Reset()
+	in.Interpret(parseScript(t, in, "Square().size();"))
+	assert.Equal("Undefined property 'aera'.\n[line 6]\nHint: Did you mean 'area'? Point has 'area' and 'init'.\n", out.String())
+
+	// the class is named even if it has nothing to suggest
+	out.Reset()
+	in.Interpret(parseScript(t, in, "class Empty {}\nprint Empty().x;"))
+	assert.Equal("Undefined property 'x'.\n[line 2]\nHint: Empty has no properties.\n", out.String())
+}
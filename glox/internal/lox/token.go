@@ -1,14 +1,42 @@
 package lox
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+)
 
 // Token represents group a characters with additional information that was
 // obtained during the scanning phase.
 type Token struct {
-	Type    TokenType
-	Lexeme  string
-	Literal interface{}
-	Line    int
+	Type    TokenType   `json:"type"`
+	Lexeme  string      `json:"lexeme"`
+	Literal interface{} `json:"literal"`
+	Line    int         `json:"line"`
+	// Column is the byte offset of the first character of the token from the
+	// start of the line where the token begins, counting from 1. It's 0 for
+	// tokens that weren't produced by the scanner.
+	Column int `json:"column"`
+	// Offset is the number of bytes before the token in the source.
+	Offset int `json:"offset"`
+	// Comments holds the comments found between the previous token and this
+	// one, comments at the end of the source are attached to the EOF token.
+	// Together with the line numbers, this is enough for tools like the
+	// formatter to recover where comments and blank lines were.
+	Comments []*Comment `json:"comments,omitempty"`
+}
+
+// Comment is a comment found in the source, it's kept by the scanner so that
+// tools working with the source can round-trip it.
+type Comment struct {
+	// Text is the comment including its delimiters, e.g. "// text"
+	Text string `json:"text"`
+	// Line is the line where the comment starts
+	Line int `json:"line"`
+}
+
+// EndLine returns the line where the comment ends
+func (c *Comment) EndLine() int {
+	return c.Line + strings.Count(c.Text, "\n")
 }
 
 // New creates a new token
@@ -132,6 +160,26 @@ func (tt *TokenType) String() string {
 	return ""
 }
 
+// MarshalText encodes the token type as the string returned by String.
+func (tt TokenType) MarshalText() ([]byte, error) {
+	s := tt.String()
+	if s == "" {
+		return nil, fmt.Errorf("unknown token type %d", uint(tt))
+	}
+	return []byte(s), nil
+}
+
+// UnmarshalText decodes a token type that was encoded with MarshalText.
+func (tt *TokenType) UnmarshalText(text []byte) error {
+	for t := L_PAREN; t <= EOF; t++ {
+		if t.String() == string(text) {
+			*tt = t
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown token type %q", text)
+}
+
 const (
 	// Single-character tokens
 	L_PAREN TokenType = iota
@@ -0,0 +1,97 @@
+package lox
+
+import (
+	"fmt"
+	"io"
+)
+
+// tracer logs every statement that is executed and every expression that is
+// evaluated, along with the result of the expression.
+type tracer struct {
+	writer io.Writer
+	// line is the last known line, it's used for nodes that can't be located
+	line int
+}
+
+// SetTrace makes the interpreter log each statement and expression that it
+// runs, with their line and resulting value, to the given writer. Giving nil
+// disables tracing.
+func (in *Interpreter) SetTrace(writer io.Writer) {
+	if writer == nil {
+		in.tracer = nil
+		return
+	}
+	in.tracer = &tracer{writer: writer}
+}
+
+func (t *tracer) stmt(stmt Stmt) {
+	if line := stmtLine(stmt); line != 0 {
+		t.line = line
+	}
+	fmt.Fprintf(t.writer, "[line %d] %s\n", t.line, describeStmt(stmt))
+}
+
+func (t *tracer) expr(expr Expr, val interface{}, err error) {
+	if line := exprLine(expr); line != 0 {
+		t.line = line
+	}
+	if err != nil {
+		fmt.Fprintf(t.writer, "[line %d] %s => error\n", t.line, describeExpr(expr))
+		return
+	}
+	fmt.Fprintf(t.writer, "[line %d] %s => %s\n", t.line, describeExpr(expr), stringify(val))
+}
+
+func describeStmt(stmt Stmt) string {
+	switch stmt := stmt.(type) {
+	case *BlockStmt:
+		return "block"
+	case *ClassStmt:
+		return fmt.Sprintf("class %s", stmt.Name.Lexeme)
+	case *ExprStmt:
+		return "expression statement"
+	case *FunctionStmt:
+		return fmt.Sprintf("fun %s", stmt.Name.Lexeme)
+	case *IfStmt:
+		return "if"
+	case *PrintStmt:
+		return "print"
+	case *ReturnStmt:
+		return "return"
+	case *VarStmt:
+		return fmt.Sprintf("var %s", stmt.Name.Lexeme)
+	case *WhileStmt:
+		return "while"
+	}
+	return fmt.Sprintf("%T", stmt)
+}
+
+func describeExpr(expr Expr) string {
+	switch expr := expr.(type) {
+	case *AssignExpr:
+		return fmt.Sprintf("assign %s", expr.Name.Lexeme)
+	case *BinaryExpr:
+		return fmt.Sprintf("binary %s", expr.Op.Lexeme)
+	case *CallExpr:
+		return "call"
+	case *GetExpr:
+		return fmt.Sprintf("get .%s", expr.Name.Lexeme)
+	case *GroupExpr:
+		return "group"
+	case *LiteralExpr:
+		return "literal"
+	case *LogicalExpr:
+		return fmt.Sprintf("logical %s", expr.Op.Lexeme)
+	case *SetExpr:
+		return fmt.Sprintf("set .%s", expr.Name.Lexeme)
+	case *SuperExpr:
+		return fmt.Sprintf("super.%s", expr.Method.Lexeme)
+	case *ThisExpr:
+		return "this"
+	case *UnaryExpr:
+		return fmt.Sprintf("unary %s", expr.Op.Lexeme)
+	case *VarExpr:
+		return fmt.Sprintf("var %s", expr.Name.Lexeme)
+	}
+	return fmt.Sprintf("%T", expr)
+}
@@ -0,0 +1,169 @@
+package lox
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Value is a Lox value as it's seen from Go, e.g. the result of Eval or an
+// argument of a native function. Its methods tell what kind of value it is
+// and give its content, so the host program doesn't depend on how the
+// interpreter represents the values. The zero Value is nil.
+type Value struct {
+	val interface{}
+	// in is the interpreter that the value comes from, which runs the calls
+	in *Interpreter
+}
+
+// NumberValue returns the Lox number n.
+func NumberValue(n float64) Value {
+	return Value{val: n}
+}
+
+// StringValue returns the Lox string s.
+func StringValue(s string) Value {
+	return Value{val: s}
+}
+
+// BoolValue returns the Lox boolean b.
+func BoolValue(b bool) Value {
+	return Value{val: b}
+}
+
+// IsNil returns true if the value is nil.
+func (v Value) IsNil() bool {
+	return v.val == nil
+}
+
+// IsBool returns true if the value is a boolean.
+func (v Value) IsBool() bool {
+	_, ok := v.val.(bool)
+	return ok
+}
+
+// AsBool returns the boolean, or false if the value isn't one.
+func (v Value) AsBool() bool {
+	b, _ := v.val.(bool)
+	return b
+}
+
+// IsNumber returns true if the value is a number.
+func (v Value) IsNumber() bool {
+	_, ok := v.val.(float64)
+	return ok
+}
+
+// AsNumber returns the number, or 0 if the value isn't one.
+func (v Value) AsNumber() float64 {
+	n, _ := v.val.(float64)
+	return n
+}
+
+// IsString returns true if the value is a string.
+func (v Value) IsString() bool {
+	_, ok := v.val.(string)
+	return ok
+}
+
+// AsString returns the string, or "" if the value isn't one. Use String to
+// format any value.
+func (v Value) AsString() string {
+	s, _ := v.val.(string)
+	return s
+}
+
+// IsInstance returns true if the value is an instance of a class.
+func (v Value) IsInstance() bool {
+	_, ok := v.val.(*instance)
+	return ok
+}
+
+// Get returns the field of an instance with the given name, or the method of
+// its class bound to it, the way "instance.name" does in a script. ok is
+// false if the value isn't an instance or if it has no such property.
+func (v Value) Get(name string) (field Value, ok bool) {
+	inst, isInstance := v.val.(*instance)
+	if !isInstance {
+		return Value{}, false
+	}
+	val, err := inst.get(&Token{Type: IDENT, Lexeme: name})
+	if err != nil {
+		return Value{}, false
+	}
+	return Value{val: val, in: v.in}, true
+}
+
+// IsCallable returns true if the value is a function, a method, or a class.
+func (v Value) IsCallable() bool {
+	_, ok := v.val.(callable)
+	return ok
+}
+
+// Call calls the function, method, or class with the arguments, the way a
+// call expression does in a script, and returns its result. The value must
+// come from an interpreter, e.g. from Eval, which runs the call with its
+// limits. A runtime error in the call is returned rather than reported.
+func (v Value) Call(args ...Value) (Value, error) {
+	fn, ok := v.val.(callable)
+	if !ok {
+		return Value{}, fmt.Errorf("lox: cannot call %s, only functions and classes", typeOf(v.val))
+	}
+	if v.in == nil {
+		return Value{}, errors.New("lox: cannot call a value that doesn't come from an interpreter")
+	}
+	if len(args) != fn.arity() {
+		return Value{}, fmt.Errorf("lox: cannot call %s, it expects %d arguments but got %d", v, fn.arity(), len(args))
+	}
+	vals := make([]interface{}, len(args))
+	for i, arg := range args {
+		vals[i] = arg.val
+	}
+	val, err := v.in.callValue(fn, vals)
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{val: val, in: v.in}, nil
+}
+
+// String formats the value the way "print" writes it.
+func (v Value) String() string {
+	return stringify(v.val)
+}
+
+// callValue calls fn from the host program. The limits are started again
+// unless the call is made while a script is running, e.g. by a native
+// function, whose limits still apply.
+func (in *Interpreter) callValue(fn callable, args []interface{}) (interface{}, error) {
+	if in.limits.callDepth == 0 {
+		in.limits.start()
+	}
+	if _, isClass := fn.(*class); isClass {
+		if err := in.limits.allocObject(nil); err != nil {
+			return nil, err
+		}
+	}
+	if err := in.limits.enterCall(in.callSite); err != nil {
+		return nil, err
+	}
+	callSite := in.callSite
+	in.callSite = nil
+	val, err := fn.call(in, args)
+	in.callSite = callSite
+	in.limits.exitCall()
+	return val, err
+}
+
+// Eval evaluates an expression in the global scope, and returns its value. The
+// expression can have side effects, e.g. calls. A runtime error only has its
+// message, since the line is in the expression rather than in a script.
+func (in *Interpreter) Eval(source string) (Value, error) {
+	expr, err := in.parseGlobalExpr(source)
+	if err != nil {
+		return Value{}, err
+	}
+	val, err := in.evalGlobalExpr(expr)
+	if err != nil {
+		return Value{}, err
+	}
+	return Value{val: val, in: in}, nil
+}
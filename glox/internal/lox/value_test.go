@@ -0,0 +1,108 @@
+package lox
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValue(t *testing.T) {
+	assert := assert.New(t)
+
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false)
+	for source, check := range map[string]func(v Value) bool{
+		"nil":     func(v Value) bool { return v.IsNil() && v.String() == "nil" },
+		"1 < 2":   func(v Value) bool { return v.IsBool() && v.AsBool() },
+		"1 + 0.5": func(v Value) bool { return v.IsNumber() && v.AsNumber() == 1.5 },
+		`"a"`:     func(v Value) bool { return v.IsString() && v.AsString() == "a" },
+		"clock":   func(v Value) bool { return v.IsCallable() && !v.IsInstance() },
+	} {
+		val, err := in.Eval(source)
+		assert.NoError(err, source)
+		assert.True(check(val), source)
+	}
+	assert.Equal(0.0, StringValue("1").AsNumber())
+	assert.Equal("", NumberValue(1).AsString())
+	assert.False(BoolValue(true).IsNil())
+	assert.True(Value{}.IsNil())
+
+	_, err := in.Eval("1 +")
+	assert.EqualError(err, "[line 1] Error at end: Expect expression.")
+	_, err = in.Eval("-nil")
+	assert.EqualError(err, "Operand must be a number.")
+}
+
+func TestValueGet(t *testing.T) {
+	assert := assert.New(t)
+
+	in := NewInterpreter(ioutil.Discard, NewSimpleReporter(ioutil.Discard), false)
+	in.Interpret(parseScript(t, in, `
+class Point {
+  init(x) { this.x = x; }
+  double() { return this.x * 2; }
+}
+var point = Point(2);
+`))
+	point, err := in.Eval("point")
+	assert.NoError(err)
+	assert.True(point.IsInstance())
+
+	x, ok := point.Get("x")
+	assert.True(ok)
+	assert.Equal(2.0, x.AsNumber())
+	double, ok := point.Get("double")
+	if assert.True(ok) {
+		val, err := double.Call()
+		assert.NoError(err)
+		assert.Equal(4.0, val.AsNumber())
+	}
+	_, ok = point.Get("y")
+	assert.False(ok)
+	_, ok = x.Get("x")
+	assert.False(ok)
+}
+
+func TestValueCall(t *testing.T) {
+	assert := assert.New(t)
+
+	var out strings.Builder
+	in := NewInterpreter(&out, NewSimpleReporter(&out), false)
+	in.Interpret(parseScript(t, in, `
+fun add(a, b) { return a + b; }
+fun fail() { return nil + 1; }
+class Point { init(x) { this.x = x; } }
+`))
+	add, err := in.Eval("add")
+	assert.NoError(err)
+	val, err := add.Call(NumberValue(1), NumberValue(2))
+	assert.NoError(err)
+	assert.Equal(3.0, val.AsNumber())
+	val, err = add.Call(StringValue("a"), StringValue("b"))
+	assert.NoError(err)
+	assert.Equal("ab", val.AsString())
+
+	class, _ := in.Eval("Point")
+	point, err := class.Call(NumberValue(1))
+	assert.NoError(err)
+	x, _ := point.Get("x")
+	assert.Equal(1.0, x.AsNumber())
+
+	_, err = add.Call(NumberValue(1))
+	assert.EqualError(err, "lox: cannot call <fn add>, it expects 2 arguments but got 1")
+	_, err = x.Call()
+	assert.EqualError(err, "lox: cannot call number, only functions and classes")
+	fail, _ := in.Eval("fail")
+	_, err = fail.Call()
+	assert.EqualError(err, "Operands must be two numbers or two strings.\n[line 3]")
+	// the errors are returned rather than reported
+	assert.Empty(out.String())
+
+	// the arguments of a native function can be called while the script runs
+	in.RegisterNative("apply", 2, func(args []Value) (Value, error) {
+		return args[0].Call(args[1])
+	})
+	in.Interpret(parseScript(t, in, "print apply(Point, 3).x;\nprint apply(fail, 1);"))
+	assert.Equal("3\nlox: cannot call <fn fail>, it expects 0 arguments but got 1\n[line 2]\n", out.String())
+}